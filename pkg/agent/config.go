@@ -25,13 +25,17 @@ import (
 	"k8s.io/klog/v2/klogr"
 	"k8s.io/utils/exec"
 
+	"github.com/fabedge/fabedge/pkg/common/tunnelstatus"
+	"github.com/fabedge/fabedge/pkg/tunnel"
 	"github.com/fabedge/fabedge/pkg/tunnel/strongswan"
+	"github.com/fabedge/fabedge/pkg/tunnel/wireguard"
 	"github.com/fabedge/fabedge/pkg/util/ipset"
 	"github.com/fabedge/fabedge/third_party/ipvs"
 )
 
 type Config struct {
 	LocalCerts       []string
+	PSKDir           string
 	SyncPeriod       time.Duration
 	DebounceDuration time.Duration
 	TunnelsConfPath  string
@@ -75,11 +79,28 @@ type Config struct {
 	Workdir              string
 
 	TunnelInitTimeout uint
+
+	// ReportTunnelStatus makes agent report established tunnels to the annotations of its pod,
+	// agent pod must have the permission to patch itself
+	ReportTunnelStatus bool
+
+	// MetricsAddress is the address of metrics server, metrics server is disabled if it's empty
+	MetricsAddress string
+
+	// TunnelBackend decides which tunnel implementation is used by default: strongswan or wireguard,
+	// the backend of a connection may be different if endpoints of it specify their backends
+	TunnelBackend string
+	WireGuard     struct {
+		InterfaceName  string
+		ListenPort     uint
+		PrivateKeyFile string
+	}
 }
 
 func (cfg *Config) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&cfg.TunnelsConfPath, "tunnels-conf", "/etc/fabedge/tunnels.yaml", "The path to tunnels configuration file")
 
+	fs.StringVar(&cfg.PSKDir, "psk-dir", "/etc/fabedge-psk", "The directory of pre-shared keys, each file is named after the peer endpoint. Tunnels to peers without pre-shared key use certificates")
 	fs.StringSliceVar(&cfg.LocalCerts, "local-cert", []string{"edgecert.pem"}, "The path to cert files, comma separated. If it's a relative path, the cert file should be put under /etc/ipsec.d/certs")
 	fs.DurationVar(&cfg.DebounceDuration, "debounce", time.Second, "The debounce delay to avoid too much network reconfiguring")
 
@@ -111,6 +132,13 @@ func (cfg *Config) AddFlags(fs *pflag.FlagSet) {
 	fs.DurationVar(&cfg.BackupInterval, "backup-interval", 10*time.Second, "The interval between local endpoints backing up")
 	fs.DurationVar(&cfg.EndpointTTL, "endpoint-ttl", 20*time.Second, "The time to live for endpoint received from multicasting")
 
+	fs.StringVar(&cfg.TunnelBackend, "tunnel-backend", tunnel.BackendStrongSwan, "The default implementation of tunnels, possible values are: strongswan, wireguard")
+	fs.StringVar(&cfg.WireGuard.InterfaceName, "wireguard-interface", wireguard.DefaultInterfaceName, "The name of wireguard interface")
+	fs.UintVar(&cfg.WireGuard.ListenPort, "wireguard-listen-port", wireguard.DefaultListenPort, "The UDP port for wireguard to listen")
+	fs.StringVar(&cfg.WireGuard.PrivateKeyFile, "wireguard-private-key", "wireguard.key", "The path to wireguard private key file. If it's a relative path, the key file should be put under /etc/ipsec.d/private")
+	fs.BoolVar(&cfg.ReportTunnelStatus, "report-tunnel-status", false, "Report established tunnels to the annotations of agent pod, POD_NAME and POD_NAMESPACE environment variables are required")
+	fs.StringVar(&cfg.MetricsAddress, "metrics-address", "", "The address metrics server listens on, e.g. :30307. Metrics server is disabled if it's empty")
+
 }
 
 func (cfg *Config) Validate() error {
@@ -137,6 +165,10 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
+	if !cfg.tunnelRegistry().Has(cfg.TunnelBackend) {
+		return fmt.Errorf("unknown tunnel backend: %s", cfg.TunnelBackend)
+	}
+
 	if cfg.DNS.Enabled {
 		if net.ParseIP(cfg.DNS.BindIP) == nil {
 			return fmt.Errorf("invalid DNS bind IP address")
@@ -161,12 +193,7 @@ func (cfg *Config) Validate() error {
 }
 
 func (cfg Config) Manager() (*Manager, error) {
-	tm, err := strongswan.New(
-		strongswan.StartAction("clear"),
-		strongswan.DpdDelay("10s"),
-		strongswan.DpdAction("trap"),
-		strongswan.InitTimeout(cfg.TunnelInitTimeout),
-	)
+	tm, err := cfg.tunnelManager()
 	if err != nil {
 		return nil, err
 	}
@@ -185,5 +212,42 @@ func (cfg Config) Manager() (*Manager, error) {
 		ipset:   ipset.New(),
 	}
 
+	m.metrics = newMetrics(m)
+
+	if cfg.ReportTunnelStatus {
+		m.reporter, err = tunnelstatus.NewInClusterReporter()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return m, nil
 }
+
+func (cfg Config) tunnelManager() (*tunnel.MixedManager, error) {
+	return tunnel.NewMixedManager(cfg.tunnelRegistry(), cfg.TunnelBackend)
+}
+
+// tunnelRegistry returns a registry of all tunnel backends supported by agent,
+// the default backend is decided by TunnelBackend, others are only used when
+// some connection asks for them
+func (cfg Config) tunnelRegistry() *tunnel.Registry {
+	registry := tunnel.NewRegistry()
+	registry.Register(tunnel.BackendStrongSwan, func() (tunnel.Manager, error) {
+		return strongswan.New(
+			strongswan.StartAction("clear"),
+			strongswan.DpdDelay("10s"),
+			strongswan.DpdAction("trap"),
+			strongswan.InitTimeout(cfg.TunnelInitTimeout),
+		)
+	})
+	registry.Register(tunnel.BackendWireGuard, func() (tunnel.Manager, error) {
+		return wireguard.New(
+			wireguard.InterfaceName(cfg.WireGuard.InterfaceName),
+			wireguard.ListenPort(cfg.WireGuard.ListenPort),
+			wireguard.PrivateKeyFile(cfg.WireGuard.PrivateKeyFile),
+		)
+	})
+
+	return registry
+}
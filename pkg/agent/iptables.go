@@ -97,11 +97,13 @@ func (m *Manager) ensureIPTablesRules() error {
 		}
 
 		if err := m.ipset.EnsureIPSet(ipSet, c.peerIPSet); err != nil {
+			m.metrics.syncErrors.WithLabelValues(targetIPSet).Inc()
 			m.log.Error(err, "failed to sync ipset", "ipsetName", c.name)
 			errors = append(errors, err)
 		}
 
 		if err := c.ipt.Apply(); err != nil {
+			m.metrics.syncErrors.WithLabelValues(targetIPTables).Inc()
 			m.log.Error(err, "failed to sync iptables rules")
 			errors = append(errors, err)
 		} else {
@@ -28,6 +28,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/common/tunnelstatus"
 	"github.com/fabedge/fabedge/pkg/tunnel"
 	"github.com/fabedge/fabedge/pkg/util/ipset"
 	"github.com/fabedge/fabedge/pkg/util/iptables"
@@ -47,6 +48,9 @@ type Manager struct {
 
 	tm  tunnel.Manager
 	log logr.Logger
+	// reporter is nil unless ReportTunnelStatus is true
+	reporter *tunnelstatus.Reporter
+	metrics  *metrics
 
 	currentEndpoint  Endpoint
 	mediatorEndpoint *Endpoint
@@ -80,12 +84,16 @@ func (m *Manager) start() {
 		go m.runKubeProxy()
 	}
 
+	if m.MetricsAddress != "" {
+		go m.runMetricsServer()
+	}
+
 	var lastCancel context.CancelFunc = func() {}
 	defer func() {
 		lastCancel()
 	}()
 
-	m.log.V(3).Info("Waiting for strongswan to start")
+	m.log.V(3).Info("Waiting for tunnel manager to start", "backend", m.TunnelBackend)
 	for {
 		if m.tm.IsRunning() {
 			break
@@ -107,11 +115,13 @@ func (m *Manager) start() {
 		lastCancel = cancel
 
 		go retryForever(ctx, m.maintainNetwork, func(n uint, err error) {
+			m.metrics.retries.WithLabelValues(taskMaintainNetwork).Inc()
 			m.log.Error(err, "failed to configure network", "retryNum", n)
 		})
 
 		if m.DNS.Enabled {
 			go retryForever(ctx, m.ensureDummyDevice, func(n uint, err error) {
+				m.metrics.retries.WithLabelValues(taskDummyDevice).Inc()
 				m.log.Error(err, "failed to maintain dummy interface", "retryNum", n)
 			})
 		}
@@ -149,6 +159,14 @@ func (m *Manager) maintainNetwork() error {
 	m.log.V(3).Info("clean expired endpoints")
 	m.cleanExpiredEndpoints()
 
+	// certificate renewed by operator is updated by kubelet in mounted secret volume,
+	// reloading it won't break established tunnels
+	if reloader, ok := m.tm.(tunnel.CertReloader); ok {
+		if err := reloader.ReloadCerts(); err != nil {
+			m.log.Error(err, "failed to reload certificates")
+		}
+	}
+
 	m.log.V(3).Info("synchronize tunnels")
 	if err := m.ensureConnections(); err != nil {
 		return err
@@ -160,7 +178,34 @@ func (m *Manager) maintainNetwork() error {
 	}
 
 	m.log.V(3).Info("keep iptables rules")
-	return m.ensureIPTablesRules()
+	if err := m.ensureIPTablesRules(); err != nil {
+		return err
+	}
+
+	m.metrics.lastSyncTime.SetToCurrentTime()
+	m.reportTunnelStatus()
+	return nil
+}
+
+// reportTunnelStatus reports established tunnels, failure of reporting
+// won't affect the network, so the error is only logged
+func (m *Manager) reportTunnelStatus() {
+	if m.reporter == nil {
+		return
+	}
+
+	names, err := m.tm.ListEstablishedConnNames()
+	if err != nil {
+		m.log.Error(err, "failed to get established tunnels")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err = m.reporter.Report(ctx, m.getCurrentEndpoint().Name, names); err != nil {
+		m.log.Error(err, "failed to report tunnel status")
+	}
 }
 
 func (m *Manager) ensureConnections() error {
@@ -179,7 +224,15 @@ func (m *Manager) ensureConnections() error {
 		}
 	}
 
+	// if pre-shared keys are not available, connections will use certificates
+	psks, err := tunnel.LoadPSKs(m.PSKDir)
+	if err != nil {
+		m.log.Error(err, "failed to load pre-shared keys")
+	}
+
 	newNames := sets.NewString()
+	// peers whose routes should be kept in strongswan table
+	var routedPeers []Endpoint
 
 	mediator := m.getMediatorEndpoint()
 	if mediator != nil {
@@ -190,12 +243,17 @@ func (m *Manager) ensureConnections() error {
 
 	for _, peer := range peers {
 		if peer.IsLocal {
+			routedPeers = append(routedPeers, peer)
 			if err := addRoutesToPeer(peer); err != nil {
 				m.log.Error(err, "failed to add routes to peer", "peer", peer)
 			}
 		} else {
 			newNames.Insert(peer.Name)
-			m.ensureConnection(current, peer, mediator, gw, gw6)
+			// routes of connections of other backends are maintained by their tunnel managers
+			if m.getTunnelBackend(current, peer) == tunnel.BackendStrongSwan {
+				routedPeers = append(routedPeers, peer)
+			}
+			m.ensureConnection(current, peer, mediator, psks[peer.Name], gw, gw6)
 		}
 	}
 
@@ -217,7 +275,7 @@ func (m *Manager) ensureConnections() error {
 		}
 	}
 
-	return delStaleRoutes(peers)
+	return delStaleRoutes(routedPeers)
 }
 
 func (m *Manager) ensureMediatorConnection(current, peer Endpoint) {
@@ -236,6 +294,8 @@ func (m *Manager) ensureMediatorConnection(current, peer Endpoint) {
 		RemoteType:    peer.Type,
 		RemotePort:    peer.Port,
 
+		// mediation is a feature of strongswan
+		Backend:   tunnel.BackendStrongSwan,
 		Mediation: true,
 	}
 
@@ -254,9 +314,10 @@ func (m *Manager) ensureMediatorConnection(current, peer Endpoint) {
 	}
 }
 
-func (m *Manager) ensureConnection(current, peer Endpoint, mediator *Endpoint, gw, gw6 net.IP) {
+func (m *Manager) ensureConnection(current, peer Endpoint, mediator *Endpoint, psk tunnel.PSK, gw, gw6 net.IP) {
 	conn := tunnel.ConnConfig{
-		Name: peer.Name,
+		Name:    peer.Name,
+		Backend: m.getTunnelBackend(current, peer),
 
 		LocalID:          current.ID,
 		LocalSubnets:     current.Subnets,
@@ -270,7 +331,10 @@ func (m *Manager) ensureConnection(current, peer Endpoint, mediator *Endpoint, g
 		RemoteNodeSubnets: peer.NodeSubnets,
 		RemoteType:        peer.Type,
 		RemotePort:        peer.Port,
+		RemotePublicKey:   peer.PublicKey,
 	}
+	conn.SetIPSecParameters(peer.IPSec)
+	conn.SetPSK(psk)
 	if mediator != nil && peer.Type == apis.EdgeNode {
 		conn.NeedMediation = true
 		conn.MediatedBy = mediator.Name
@@ -291,6 +355,11 @@ func (m *Manager) ensureConnection(current, peer Endpoint, mediator *Endpoint, g
 		return
 	}
 
+	// wireguard routes traffic by its own interface, routes via gateway are only needed by strongswan
+	if conn.Backend != tunnel.BackendStrongSwan {
+		return
+	}
+
 	m.log.V(5).Info("try to add routes to peer", "name", peer.Name)
 	for _, ip := range []net.IP{gw, gw6} {
 		if ip == nil {
@@ -303,6 +372,11 @@ func (m *Manager) ensureConnection(current, peer Endpoint, mediator *Endpoint, g
 	}
 }
 
+// getTunnelBackend returns the tunnel backend of the connection between current endpoint and peer
+func (m *Manager) getTunnelBackend(current, peer Endpoint) string {
+	return tunnel.NegotiateBackend(current.TunnelBackend, peer.TunnelBackend)
+}
+
 func (m *Manager) generateCNIConfig() error {
 	current := m.getCurrentEndpoint()
 
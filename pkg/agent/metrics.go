@@ -0,0 +1,117 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/fabedge/fabedge/pkg/tunnel"
+)
+
+const metricsNamespace = "fabedge_agent"
+
+// tasks retried by retryForever
+const (
+	taskMaintainNetwork = "maintain_network"
+	taskDummyDevice     = "dummy_device"
+)
+
+// targets whose synchronization may fail
+const (
+	targetIPTables = "iptables"
+	targetIPSet    = "ipset"
+)
+
+type metrics struct {
+	registry *prometheus.Registry
+
+	lastSyncTime prometheus.Gauge
+	retries      *prometheus.CounterVec
+	syncErrors   *prometheus.CounterVec
+}
+
+func newMetrics(m *Manager) *metrics {
+	mt := &metrics{
+		registry: prometheus.NewRegistry(),
+		lastSyncTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "last_successful_sync_timestamp_seconds",
+			Help:      "Unix time of the last successful network maintenance",
+		}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "retries_total",
+			Help:      "Number of retries of failed tasks",
+		}, []string{"task"}),
+		syncErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "sync_errors_total",
+			Help:      "Number of failures of synchronizing iptables rules or ipsets",
+		}, []string{"target"}),
+	}
+
+	discoveredPeers := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "discovered_peers",
+		Help:      "Number of peers discovered by multicast auto networking",
+	}, func() float64 {
+		return float64(m.countDiscoveredPeers())
+	})
+
+	mt.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		tunnel.NewCollector(metricsNamespace, m.tm),
+		mt.lastSyncTime,
+		mt.retries,
+		mt.syncErrors,
+		discoveredPeers,
+	)
+
+	return mt
+}
+
+func (m *Manager) runMetricsServer() {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.metrics.registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{
+		Addr:    m.MetricsAddress,
+		Handler: mux,
+	}
+
+	m.log.V(3).Info("start metrics server", "address", m.MetricsAddress)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		m.log.Error(err, "failed to start metrics server")
+	}
+}
+
+func (m *Manager) countDiscoveredPeers() int {
+	m.endpointLock.RLock()
+	defer m.endpointLock.RUnlock()
+
+	count := 0
+	for _, peer := range m.peerEndpoints {
+		if peer.IsLocal {
+			count++
+		}
+	}
+
+	return count
+}
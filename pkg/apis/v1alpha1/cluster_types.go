@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -24,6 +25,17 @@ type Endpoint struct {
 	Type EndpointType `yaml:"type,omitempty" json:"type,omitempty"`
 	// public UDP port for IKE communication, only used to configure remote_port. Default: 500
 	Port *uint `yaml:"port,omitempty" json:"port,omitempty"`
+	// public key of WireGuard, only used when WireGuard is used as tunnel backend
+	PublicKey string `yaml:"publicKey,omitempty" json:"publicKey,omitempty"`
+	// the tunnel backend used by this endpoint: strongswan or wireguard. If it's empty,
+	// strongswan is used. Strongswan is also used by connections whose endpoints have different backends
+	TunnelBackend string `yaml:"tunnelBackend,omitempty" json:"tunnelBackend,omitempty"`
+	// IPsec parameters used by tunnels to this endpoint, operator fills
+	// them with IPSec parameters of the cluster which the endpoint belongs to
+	IPSec *IPSecParameters `yaml:"ipsec,omitempty" json:"ipsec,omitempty"`
+	// Name of secret in fabedge namespace whose key "psk" is used as pre-shared key of
+	// tunnels to this endpoint. If it's empty, certificates are used for authentication
+	PSKSecretName string `yaml:"pskSecretName,omitempty" json:"pskSecretName,omitempty"`
 }
 
 type ClusterSpec struct {
@@ -34,18 +46,105 @@ type ClusterSpec struct {
 	CIDRs []string `json:"cidrs,omitempty"`
 	// Endpoints of connector and exported edge nodes of a cluster
 	EndPoints []Endpoint `json:"endpoints,omitempty"`
+	// IPSec parameters used by tunnels to endpoints of this cluster,
+	// parameters of communities take precedence over them
+	IPSec *IPSecParameters `json:"ipsec,omitempty"`
+}
+
+type ClusterConditionType string
+
+const (
+	// ClusterReady means the cluster reported its endpoints recently and at least one connector endpoint is reported
+	ClusterReady ClusterConditionType = "Ready"
+	// ClusterTokenExpired means the token of cluster is expired or used up, the cluster need a new token to join again
+	ClusterTokenExpired ClusterConditionType = "TokenExpired"
+	// ClusterCIDRConflict means some CIDRs of the cluster overlap with those of other clusters
+	ClusterCIDRConflict ClusterConditionType = "CIDRConflict"
+)
+
+// ClusterQuarantined is the reason of CIDRConflict condition when the cluster is quarantined, endpoints
+// and CIDRs of a quarantined cluster are not provided to other clusters until conflicts are resolved
+const ClusterQuarantined = "Quarantined"
+
+type ClusterCondition struct {
+	Type   ClusterConditionType   `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+	// Last time the condition transitioned from one status to another.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// The reason for the condition's last transition.
+	Reason string `json:"reason,omitempty"`
+	// A human readable message indicating details about the transition.
+	Message string `json:"message,omitempty"`
+}
+
+type ClusterStatus struct {
+	// The last time when the cluster reported its endpoints and CIDRs
+	LastHeartbeatTime *metav1.Time `json:"lastHeartbeatTime,omitempty"`
+	// Connector endpoints reported by the cluster in last heartbeat
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+	// CIDRs reported by the cluster in last heartbeat
+	CIDRs []string `json:"cidrs,omitempty"`
+	// Conditions of cluster: Ready, TokenExpired and CIDRConflict
+	Conditions []ClusterCondition `json:"conditions,omitempty"`
+	// FabEdge version of the cluster
+	Version string `json:"version,omitempty"`
 }
 
 // Cluster is used to represent a cluster's endpoints of connector and edge nodes
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="CIDRs",type="string",JSONPath=".spec.cidrs",description="pod and service cidr list of cluster"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status",description="Whether the cluster reported its endpoints recently"
+// +kubebuilder:printcolumn:name="Last-Heartbeat",type="date",JSONPath=".status.lastHeartbeatTime",description="The last time when the cluster reported its endpoints"
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.version",description="FabEdge version of the cluster"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="How long a community is created"
 type Cluster struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec ClusterSpec `json:"spec,omitempty"`
+	Spec   ClusterSpec   `json:"spec,omitempty"`
+	Status ClusterStatus `json:"status,omitempty"`
+}
+
+// GetCondition returns the condition of specified type, nil will be returned if it's not found
+func (c *ClusterStatus) GetCondition(conditionType ClusterConditionType) *ClusterCondition {
+	for i := range c.Conditions {
+		if c.Conditions[i].Type == conditionType {
+			return &c.Conditions[i]
+		}
+	}
+
+	return nil
+}
+
+// IsQuarantined returns true if the cluster is quarantined because of CIDR conflicts
+func (c *ClusterStatus) IsQuarantined() bool {
+	condition := c.GetCondition(ClusterCIDRConflict)
+	return condition != nil && condition.Status == corev1.ConditionTrue && condition.Reason == ClusterQuarantined
+}
+
+// SetCondition adds or updates a condition, LastTransitionTime is changed only when status is changed
+func (c *ClusterStatus) SetCondition(condition ClusterCondition) {
+	existing := c.GetCondition(condition.Type)
+	if existing == nil {
+		if condition.LastTransitionTime.IsZero() {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		c.Conditions = append(c.Conditions, condition)
+		return
+	}
+
+	if existing.Status != condition.Status {
+		existing.Status = condition.Status
+		existing.LastTransitionTime = condition.LastTransitionTime
+		if existing.LastTransitionTime.IsZero() {
+			existing.LastTransitionTime = metav1.Now()
+		}
+	}
+	existing.Reason = condition.Reason
+	existing.Message = condition.Message
 }
 
 // ClusterList contains a list of clusters
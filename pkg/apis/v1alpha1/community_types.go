@@ -20,19 +20,46 @@ import (
 
 type CommunitySpec struct {
 	Members []string `json:"members,omitempty"`
+	// NodeSelector selects edge nodes of current cluster as members
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	// ClusterSelector selects clusters whose connectors will be members,
+	// it only works in host cluster
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+	// IPSec parameters used by tunnels between members. If an endpoint pair
+	// belongs to many communities, parameters of the first community
+	// ordered by name are used
+	IPSec *IPSecParameters `json:"ipsec,omitempty"`
+}
+
+type CommunityStatus struct {
+	// ResolvedMembers are members which can be found as endpoints
+	ResolvedMembers []string `json:"resolvedMembers,omitempty"`
+	// UnresolvedMembers are members which can't be found as endpoints,
+	// usually they are misspelled or the endpoints are not ready yet
+	UnresolvedMembers []string `json:"unresolvedMembers,omitempty"`
+	// ExpectedTunnels is the number of tunnels between resolved members
+	ExpectedTunnels int `json:"expectedTunnels"`
+	// EstablishedTunnels is the number of expected tunnels which are
+	// reported as established by agents and connectors
+	EstablishedTunnels int `json:"establishedTunnels"`
 }
 
 // Community is used to manage a communication unit, it's members
 // should be edge nodes
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Members",type="string",JSONPath=".spec.members",description="community members"
+// +kubebuilder:printcolumn:name="Expected-Tunnels",type="integer",JSONPath=".status.expectedTunnels",description="The number of tunnels between resolved members"
+// +kubebuilder:printcolumn:name="Established-Tunnels",type="integer",JSONPath=".status.establishedTunnels",description="The number of established tunnels between resolved members"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="How long a community is created"
 type Community struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec CommunitySpec `json:"spec,omitempty"`
+	Spec   CommunitySpec   `json:"spec,omitempty"`
+	Status CommunityStatus `json:"status,omitempty"`
 }
 
 // CommunityList contains a list of Community
@@ -6,6 +6,7 @@
 package v1alpha1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -15,6 +16,7 @@ func (in *Cluster) DeepCopyInto(out *Cluster) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Cluster.
@@ -35,6 +37,22 @@ func (in *Cluster) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterCondition) DeepCopyInto(out *ClusterCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterCondition.
+func (in *ClusterCondition) DeepCopy() *ClusterCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterList) DeepCopyInto(out *ClusterList) {
 	*out = *in
@@ -82,6 +100,11 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.IPSec != nil {
+		in, out := &in.IPSec, &out.IPSec
+		*out = new(IPSecParameters)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSpec.
@@ -94,12 +117,51 @@ func (in *ClusterSpec) DeepCopy() *ClusterSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
+	*out = *in
+	if in.LastHeartbeatTime != nil {
+		in, out := &in.LastHeartbeatTime, &out.LastHeartbeatTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]Endpoint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CIDRs != nil {
+		in, out := &in.CIDRs, &out.CIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]ClusterCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterStatus.
+func (in *ClusterStatus) DeepCopy() *ClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Community) DeepCopyInto(out *Community) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Community.
@@ -160,6 +222,21 @@ func (in *CommunitySpec) DeepCopyInto(out *CommunitySpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterSelector != nil {
+		in, out := &in.ClusterSelector, &out.ClusterSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IPSec != nil {
+		in, out := &in.IPSec, &out.IPSec
+		*out = new(IPSecParameters)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommunitySpec.
@@ -172,6 +249,31 @@ func (in *CommunitySpec) DeepCopy() *CommunitySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CommunityStatus) DeepCopyInto(out *CommunityStatus) {
+	*out = *in
+	if in.ResolvedMembers != nil {
+		in, out := &in.ResolvedMembers, &out.ResolvedMembers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnresolvedMembers != nil {
+		in, out := &in.UnresolvedMembers, &out.UnresolvedMembers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommunityStatus.
+func (in *CommunityStatus) DeepCopy() *CommunityStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CommunityStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Endpoint) DeepCopyInto(out *Endpoint) {
 	*out = *in
@@ -190,6 +292,16 @@ func (in *Endpoint) DeepCopyInto(out *Endpoint) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(uint)
+		**out = **in
+	}
+	if in.IPSec != nil {
+		in, out := &in.IPSec, &out.IPSec
+		*out = new(IPSecParameters)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Endpoint.
@@ -201,3 +313,43 @@ func (in *Endpoint) DeepCopy() *Endpoint {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPSecParameters) DeepCopyInto(out *IPSecParameters) {
+	*out = *in
+	if in.IKEProposals != nil {
+		in, out := &in.IKEProposals, &out.IKEProposals
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ESPProposals != nil {
+		in, out := &in.ESPProposals, &out.ESPProposals
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IKERekeyTimeSeconds != nil {
+		in, out := &in.IKERekeyTimeSeconds, &out.IKERekeyTimeSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.RekeyTimeSeconds != nil {
+		in, out := &in.RekeyTimeSeconds, &out.RekeyTimeSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.LifeTimeSeconds != nil {
+		in, out := &in.LifeTimeSeconds, &out.LifeTimeSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPSecParameters.
+func (in *IPSecParameters) DeepCopy() *IPSecParameters {
+	if in == nil {
+		return nil
+	}
+	out := new(IPSecParameters)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ClusterSpec struct {
+	// Token is used by child cluster to access root cluster's apiserver
+	Token string `json:"token,omitempty"`
+	// CIDRs is supposed to contain cluster-cidr and cluster-service-ip-range of a cluster,
+	// these are mainly used to create ippools to avoid SNAT in calico environment
+	CIDRs []string `json:"cidrs,omitempty"`
+	// Endpoints of connector and exported edge nodes of a cluster
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+	// IPSec parameters used by tunnels to endpoints of this cluster,
+	// parameters of communities take precedence over them
+	IPSec *IPSecParameters `json:"ipsec,omitempty"`
+}
+
+type ClusterConditionType string
+
+const (
+	// ClusterReady means the cluster reported its endpoints recently and at least one connector endpoint is reported
+	ClusterReady ClusterConditionType = "Ready"
+	// ClusterTokenExpired means the token of cluster is expired or used up, the cluster need a new token to join again
+	ClusterTokenExpired ClusterConditionType = "TokenExpired"
+	// ClusterCIDRConflict means some CIDRs of the cluster overlap with those of other clusters
+	ClusterCIDRConflict ClusterConditionType = "CIDRConflict"
+)
+
+type ClusterCondition struct {
+	Type   ClusterConditionType   `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+	// Last time the condition transitioned from one status to another.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// The reason for the condition's last transition.
+	Reason string `json:"reason,omitempty"`
+	// A human readable message indicating details about the transition.
+	Message string `json:"message,omitempty"`
+}
+
+type ClusterStatus struct {
+	// The last time when the cluster reported its endpoints and CIDRs
+	LastHeartbeatTime *metav1.Time `json:"lastHeartbeatTime,omitempty"`
+	// Connector endpoints reported by the cluster in last heartbeat
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+	// CIDRs reported by the cluster in last heartbeat
+	CIDRs []string `json:"cidrs,omitempty"`
+	// Conditions of cluster: Ready, TokenExpired and CIDRConflict
+	Conditions []ClusterCondition `json:"conditions,omitempty"`
+	// FabEdge version of the cluster
+	Version string `json:"version,omitempty"`
+}
+
+// Cluster is used to represent a cluster's endpoints of connector and edge nodes
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="CIDRs",type="string",JSONPath=".spec.cidrs",description="pod and service cidr list of cluster"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status",description="Whether the cluster reported its endpoints recently"
+// +kubebuilder:printcolumn:name="Last-Heartbeat",type="date",JSONPath=".status.lastHeartbeatTime",description="The last time when the cluster reported its endpoints"
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.version",description="FabEdge version of the cluster"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="How long a community is created"
+// +kubebuilder:unservedversion
+type Cluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterSpec   `json:"spec,omitempty"`
+	Status ClusterStatus `json:"status,omitempty"`
+}
+
+// ClusterList contains a list of clusters
+// +kubebuilder:object:root=true
+type ClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Cluster `json:"items"`
+}
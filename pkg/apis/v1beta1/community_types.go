@@ -0,0 +1,71 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type CommunitySpec struct {
+	Members []string `json:"members,omitempty"`
+	// NodeSelector selects edge nodes of current cluster as members
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	// ClusterSelector selects clusters whose connectors will be members,
+	// it only works in host cluster
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+	// IPSec parameters used by tunnels between members. If an endpoint pair
+	// belongs to many communities, parameters of the first community
+	// ordered by name are used
+	IPSec *IPSecParameters `json:"ipsec,omitempty"`
+}
+
+type CommunityStatus struct {
+	// ResolvedMembers are members which can be found as endpoints
+	ResolvedMembers []string `json:"resolvedMembers,omitempty"`
+	// UnresolvedMembers are members which can't be found as endpoints,
+	// usually they are misspelled or the endpoints are not ready yet
+	UnresolvedMembers []string `json:"unresolvedMembers,omitempty"`
+	// ExpectedTunnels is the number of tunnels between resolved members
+	ExpectedTunnels int `json:"expectedTunnels"`
+	// EstablishedTunnels is the number of expected tunnels which are
+	// reported as established by agents and connectors
+	EstablishedTunnels int `json:"establishedTunnels"`
+}
+
+// Community is used to manage a communication unit, it's members
+// should be edge nodes
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Members",type="string",JSONPath=".spec.members",description="community members"
+// +kubebuilder:printcolumn:name="Expected-Tunnels",type="integer",JSONPath=".status.expectedTunnels",description="The number of tunnels between resolved members"
+// +kubebuilder:printcolumn:name="Established-Tunnels",type="integer",JSONPath=".status.establishedTunnels",description="The number of established tunnels between resolved members"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="How long a community is created"
+// +kubebuilder:unservedversion
+type Community struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CommunitySpec   `json:"spec,omitempty"`
+	Status CommunityStatus `json:"status,omitempty"`
+}
+
+// CommunityList contains a list of Community
+// +kubebuilder:object:root=true
+type CommunityList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Community `json:"items"`
+}
@@ -0,0 +1,399 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	"encoding/json"
+	"net"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+)
+
+var _ conversion.Convertible = &Cluster{}
+var _ conversion.Convertible = &Community{}
+
+// AnnotationConversionData is the annotation of v1beta1 cluster which keeps the original order
+// of addresses and subnets of v1alpha1 endpoints, v1beta1 groups them by family and
+// can't keep their order, the order is restored when the cluster is converted back
+const AnnotationConversionData = "fabedge.io/conversion-data"
+
+type conversionData struct {
+	Spec   []endpointOrder `json:"spec,omitempty"`
+	Status []endpointOrder `json:"status,omitempty"`
+}
+
+// endpointOrder keeps addresses and subnets of an endpoint in their original order
+type endpointOrder struct {
+	Name            string   `json:"name"`
+	PublicAddresses []string `json:"publicAddresses,omitempty"`
+	Subnets         []string `json:"subnets,omitempty"`
+	NodeSubnets     []string `json:"nodeSubnets,omitempty"`
+}
+
+// ConvertTo converts this cluster to the hub version(v1alpha1)
+func (c *Cluster) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.Cluster)
+
+	dst.ObjectMeta = c.ObjectMeta
+	data := popConversionData(&dst.ObjectMeta)
+	dst.Spec = v1alpha1.ClusterSpec{
+		Token:     c.Spec.Token,
+		CIDRs:     c.Spec.CIDRs,
+		EndPoints: restoreEndpointOrders(ConvertEndpointsToV1alpha1(c.Spec.Endpoints), data.Spec),
+		IPSec:     convertIPSecToV1alpha1(c.Spec.IPSec),
+	}
+
+	dst.Status = v1alpha1.ClusterStatus{
+		LastHeartbeatTime: c.Status.LastHeartbeatTime,
+		Endpoints:         restoreEndpointOrders(ConvertEndpointsToV1alpha1(c.Status.Endpoints), data.Status),
+		CIDRs:             c.Status.CIDRs,
+		Version:           c.Status.Version,
+	}
+	for _, condition := range c.Status.Conditions {
+		dst.Status.Conditions = append(dst.Status.Conditions, v1alpha1.ClusterCondition{
+			Type:               v1alpha1.ClusterConditionType(condition.Type),
+			Status:             condition.Status,
+			LastTransitionTime: condition.LastTransitionTime,
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+		})
+	}
+
+	return nil
+}
+
+// ConvertFrom converts from the hub version(v1alpha1) to this version
+func (c *Cluster) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.Cluster)
+
+	c.ObjectMeta = src.ObjectMeta
+	c.Spec = ClusterSpec{
+		Token:     src.Spec.Token,
+		CIDRs:     src.Spec.CIDRs,
+		Endpoints: ConvertEndpointsFromV1alpha1(src.Spec.EndPoints),
+		IPSec:     convertIPSecFromV1alpha1(src.Spec.IPSec),
+	}
+
+	c.Status = ClusterStatus{
+		LastHeartbeatTime: src.Status.LastHeartbeatTime,
+		Endpoints:         ConvertEndpointsFromV1alpha1(src.Status.Endpoints),
+		CIDRs:             src.Status.CIDRs,
+		Version:           src.Status.Version,
+	}
+	for _, condition := range src.Status.Conditions {
+		c.Status.Conditions = append(c.Status.Conditions, ClusterCondition{
+			Type:               ClusterConditionType(condition.Type),
+			Status:             condition.Status,
+			LastTransitionTime: condition.LastTransitionTime,
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+		})
+	}
+
+	data := conversionData{
+		Spec:   getEndpointOrders(src.Spec.EndPoints),
+		Status: getEndpointOrders(src.Status.Endpoints),
+	}
+	if len(data.Spec) > 0 || len(data.Status) > 0 {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+
+		c.Annotations = copyAnnotations(src.Annotations)
+		c.Annotations[AnnotationConversionData] = string(raw)
+	}
+
+	return nil
+}
+
+// ConvertTo converts this community to the hub version(v1alpha1)
+func (c *Community) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.Community)
+
+	dst.ObjectMeta = c.ObjectMeta
+	dst.Spec = v1alpha1.CommunitySpec{
+		Members:         c.Spec.Members,
+		NodeSelector:    c.Spec.NodeSelector,
+		ClusterSelector: c.Spec.ClusterSelector,
+		IPSec:           convertIPSecToV1alpha1(c.Spec.IPSec),
+	}
+	dst.Status = v1alpha1.CommunityStatus{
+		ResolvedMembers:    c.Status.ResolvedMembers,
+		UnresolvedMembers:  c.Status.UnresolvedMembers,
+		ExpectedTunnels:    c.Status.ExpectedTunnels,
+		EstablishedTunnels: c.Status.EstablishedTunnels,
+	}
+
+	return nil
+}
+
+// ConvertFrom converts from the hub version(v1alpha1) to this version
+func (c *Community) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.Community)
+
+	c.ObjectMeta = src.ObjectMeta
+	c.Spec = CommunitySpec{
+		Members:         src.Spec.Members,
+		NodeSelector:    src.Spec.NodeSelector,
+		ClusterSelector: src.Spec.ClusterSelector,
+		IPSec:           convertIPSecFromV1alpha1(src.Spec.IPSec),
+	}
+	c.Status = CommunityStatus{
+		ResolvedMembers:    src.Status.ResolvedMembers,
+		UnresolvedMembers:  src.Status.UnresolvedMembers,
+		ExpectedTunnels:    src.Status.ExpectedTunnels,
+		EstablishedTunnels: src.Status.EstablishedTunnels,
+	}
+
+	return nil
+}
+
+func ConvertEndpointsToV1alpha1(endpoints []Endpoint) []v1alpha1.Endpoint {
+	if endpoints == nil {
+		return nil
+	}
+
+	results := make([]v1alpha1.Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		results = append(results, ConvertEndpointToV1alpha1(endpoint))
+	}
+
+	return results
+}
+
+func ConvertEndpointsFromV1alpha1(endpoints []v1alpha1.Endpoint) []Endpoint {
+	if endpoints == nil {
+		return nil
+	}
+
+	results := make([]Endpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		results = append(results, ConvertEndpointFromV1alpha1(endpoint))
+	}
+
+	return results
+}
+
+// ConvertEndpointToV1alpha1 merges addresses and subnets of all families into
+// flat lists, IPv4 ones come first, then IPv6 ones and hostnames
+func ConvertEndpointToV1alpha1(endpoint Endpoint) v1alpha1.Endpoint {
+	return v1alpha1.Endpoint{
+		ID:              endpoint.ID,
+		Name:            endpoint.Name,
+		Type:            v1alpha1.EndpointType(endpoint.Type),
+		PublicAddresses: concat(endpoint.Addresses.IPv4, endpoint.Addresses.IPv6, endpoint.Addresses.Hostnames),
+		Subnets:         concat(endpoint.Subnets.IPv4, endpoint.Subnets.IPv6),
+		NodeSubnets:     concat(endpoint.NodeSubnets.IPv4, endpoint.NodeSubnets.IPv6),
+		Port:            endpoint.Tunnel.Port,
+		PublicKey:       endpoint.Tunnel.PublicKey,
+		TunnelBackend:   endpoint.Tunnel.Backend,
+		IPSec:           convertIPSecToV1alpha1(endpoint.Tunnel.IPSec),
+		PSKSecretName:   endpoint.Tunnel.PSKSecretName,
+	}
+}
+
+// ConvertEndpointFromV1alpha1 splits addresses and subnets by family,
+// public addresses which are not IP are taken as hostnames
+func ConvertEndpointFromV1alpha1(endpoint v1alpha1.Endpoint) Endpoint {
+	result := Endpoint{
+		ID:   endpoint.ID,
+		Name: endpoint.Name,
+		Type: EndpointType(endpoint.Type),
+		Tunnel: TunnelParameters{
+			Backend:       endpoint.TunnelBackend,
+			Port:          endpoint.Port,
+			PublicKey:     endpoint.PublicKey,
+			IPSec:         convertIPSecFromV1alpha1(endpoint.IPSec),
+			PSKSecretName: endpoint.PSKSecretName,
+		},
+		Subnets:     splitCIDRs(endpoint.Subnets),
+		NodeSubnets: splitCIDRs(endpoint.NodeSubnets),
+	}
+
+	for _, address := range endpoint.PublicAddresses {
+		ip := net.ParseIP(address)
+		switch {
+		case ip == nil:
+			result.Addresses.Hostnames = append(result.Addresses.Hostnames, address)
+		case ip.To4() != nil:
+			result.Addresses.IPv4 = append(result.Addresses.IPv4, address)
+		default:
+			result.Addresses.IPv6 = append(result.Addresses.IPv6, address)
+		}
+	}
+
+	return result
+}
+
+// getEndpointOrders returns orders of endpoints whose addresses or subnets
+// will be reordered by conversion
+func getEndpointOrders(endpoints []v1alpha1.Endpoint) []endpointOrder {
+	var orders []endpointOrder
+	for _, endpoint := range endpoints {
+		converted := ConvertEndpointToV1alpha1(ConvertEndpointFromV1alpha1(endpoint))
+		if equal(converted.PublicAddresses, endpoint.PublicAddresses) &&
+			equal(converted.Subnets, endpoint.Subnets) &&
+			equal(converted.NodeSubnets, endpoint.NodeSubnets) {
+			continue
+		}
+
+		orders = append(orders, endpointOrder{
+			Name:            endpoint.Name,
+			PublicAddresses: endpoint.PublicAddresses,
+			Subnets:         endpoint.Subnets,
+			NodeSubnets:     endpoint.NodeSubnets,
+		})
+	}
+
+	return orders
+}
+
+// restoreEndpointOrders restores the original order of addresses and subnets of endpoints,
+// if addresses or subnets are changed after conversion, they are kept as they are
+func restoreEndpointOrders(endpoints []v1alpha1.Endpoint, orders []endpointOrder) []v1alpha1.Endpoint {
+	for _, order := range orders {
+		for i := range endpoints {
+			endpoint := &endpoints[i]
+			if endpoint.Name != order.Name {
+				continue
+			}
+
+			endpoint.PublicAddresses = restoreOrder(endpoint.PublicAddresses, order.PublicAddresses)
+			endpoint.Subnets = restoreOrder(endpoint.Subnets, order.Subnets)
+			endpoint.NodeSubnets = restoreOrder(endpoint.NodeSubnets, order.NodeSubnets)
+		}
+	}
+
+	return endpoints
+}
+
+// restoreOrder returns original if it has the same elements as current
+func restoreOrder(current, original []string) []string {
+	if len(current) != len(original) {
+		return current
+	}
+
+	sortedCurrent := append([]string{}, current...)
+	sortedOriginal := append([]string{}, original...)
+	sort.Strings(sortedCurrent)
+	sort.Strings(sortedOriginal)
+	if !equal(sortedCurrent, sortedOriginal) {
+		return current
+	}
+
+	return original
+}
+
+// popConversionData removes conversion data from annotations of meta and returns it,
+// annotations are copied first because they may be shared with the source object
+func popConversionData(meta *metav1.ObjectMeta) conversionData {
+	var data conversionData
+
+	raw, ok := meta.Annotations[AnnotationConversionData]
+	if !ok {
+		return data
+	}
+
+	meta.Annotations = copyAnnotations(meta.Annotations)
+	delete(meta.Annotations, AnnotationConversionData)
+	if len(meta.Annotations) == 0 {
+		meta.Annotations = nil
+	}
+
+	// broken data is ignored, addresses and subnets are just grouped by family
+	_ = json.Unmarshal([]byte(raw), &data)
+
+	return data
+}
+
+func copyAnnotations(annotations map[string]string) map[string]string {
+	result := make(map[string]string, len(annotations)+1)
+	for key, value := range annotations {
+		result[key] = value
+	}
+
+	return result
+}
+
+func convertIPSecToV1alpha1(params *IPSecParameters) *v1alpha1.IPSecParameters {
+	if params == nil {
+		return nil
+	}
+
+	return &v1alpha1.IPSecParameters{
+		IKEProposals:        params.IKEProposals,
+		ESPProposals:        params.ESPProposals,
+		IKERekeyTimeSeconds: params.IKERekeyTimeSeconds,
+		RekeyTimeSeconds:    params.RekeyTimeSeconds,
+		LifeTimeSeconds:     params.LifeTimeSeconds,
+	}
+}
+
+func convertIPSecFromV1alpha1(params *v1alpha1.IPSecParameters) *IPSecParameters {
+	if params == nil {
+		return nil
+	}
+
+	return &IPSecParameters{
+		IKEProposals:        params.IKEProposals,
+		ESPProposals:        params.ESPProposals,
+		IKERekeyTimeSeconds: params.IKERekeyTimeSeconds,
+		RekeyTimeSeconds:    params.RekeyTimeSeconds,
+		LifeTimeSeconds:     params.LifeTimeSeconds,
+	}
+}
+
+func splitCIDRs(cidrs []string) DualStackCIDRs {
+	var result DualStackCIDRs
+	for _, cidr := range cidrs {
+		ip, _, err := net.ParseCIDR(cidr)
+		// invalid CIDRs are kept in IPv4 list, so they won't be lost
+		if err != nil || ip.To4() != nil {
+			result.IPv4 = append(result.IPv4, cidr)
+		} else {
+			result.IPv6 = append(result.IPv6, cidr)
+		}
+	}
+
+	return result
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func concat(lists ...[]string) []string {
+	var result []string
+	for _, list := range lists {
+		result = append(result, list...)
+	}
+
+	return result
+}
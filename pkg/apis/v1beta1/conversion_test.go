@@ -0,0 +1,149 @@
+package v1beta1_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/apis/v1beta1"
+)
+
+func TestClusterConversion(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	port := uint(4500)
+	hub := v1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "beijing"},
+		Spec: v1alpha1.ClusterSpec{
+			Token: "token",
+			CIDRs: []string{"10.233.0.0/16", "fd85:ee78:d8a6:8607::1:0/112"},
+			EndPoints: []v1alpha1.Endpoint{
+				{
+					ID:              "C=CN, O=fabedge.io, CN=beijing.connector",
+					Name:            "beijing.connector",
+					Type:            v1alpha1.Connector,
+					PublicAddresses: []string{"10.40.20.181", "2001:db8::1", "connector.beijing.local"},
+					Subnets:         []string{"2.2.0.0/16", "fd85:ee78:d8a6:8607::1:0/112"},
+					NodeSubnets:     []string{"10.40.20.181/32"},
+					Port:            &port,
+					TunnelBackend:   "strongswan",
+					PSKSecretName:   "beijing-psk",
+				},
+			},
+		},
+	}
+
+	var cluster v1beta1.Cluster
+	g.Expect(cluster.ConvertFrom(&hub)).To(Succeed())
+
+	endpoint := cluster.Spec.Endpoints[0]
+	g.Expect(endpoint.Name).To(Equal("beijing.connector"))
+	g.Expect(endpoint.Type).To(Equal(v1beta1.Connector))
+	g.Expect(endpoint.Addresses).To(Equal(v1beta1.EndpointAddresses{
+		IPv4:      []string{"10.40.20.181"},
+		IPv6:      []string{"2001:db8::1"},
+		Hostnames: []string{"connector.beijing.local"},
+	}))
+	g.Expect(endpoint.Subnets).To(Equal(v1beta1.DualStackCIDRs{
+		IPv4: []string{"2.2.0.0/16"},
+		IPv6: []string{"fd85:ee78:d8a6:8607::1:0/112"},
+	}))
+	g.Expect(endpoint.NodeSubnets.IPv4).To(Equal([]string{"10.40.20.181/32"}))
+	g.Expect(endpoint.NodeSubnets.IPv6).To(BeEmpty())
+	g.Expect(*endpoint.Tunnel.Port).To(Equal(port))
+	g.Expect(endpoint.Tunnel.Backend).To(Equal("strongswan"))
+	g.Expect(endpoint.Tunnel.PSKSecretName).To(Equal("beijing-psk"))
+
+	var converted v1alpha1.Cluster
+	g.Expect(cluster.ConvertTo(&converted)).To(Succeed())
+	g.Expect(converted).To(Equal(hub))
+}
+
+func TestClusterConversionKeepsOrder(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	hub := v1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "beijing",
+			Annotations: map[string]string{"owner": "fabedge"},
+		},
+		Spec: v1alpha1.ClusterSpec{
+			EndPoints: []v1alpha1.Endpoint{
+				{
+					Name:            "beijing.connector",
+					PublicAddresses: []string{"connector.beijing.local", "2001:db8::1", "10.40.20.181"},
+					Subnets:         []string{"fd85:ee78:d8a6:8607::1:0/112", "2.2.0.0/16"},
+					NodeSubnets:     []string{"10.40.20.181/32"},
+				},
+				{
+					Name:            "beijing.edge1",
+					PublicAddresses: []string{"10.40.20.182"},
+				},
+			},
+		},
+		Status: v1alpha1.ClusterStatus{
+			Endpoints: []v1alpha1.Endpoint{
+				{
+					Name:        "beijing.connector",
+					NodeSubnets: []string{"fd00::1/128", "10.40.20.181/32"},
+				},
+			},
+		},
+	}
+
+	var cluster v1beta1.Cluster
+	g.Expect(cluster.ConvertFrom(&hub)).To(Succeed())
+	g.Expect(cluster.Annotations).To(HaveKey(v1beta1.AnnotationConversionData))
+	g.Expect(hub.Annotations).NotTo(HaveKey(v1beta1.AnnotationConversionData))
+	g.Expect(cluster.Spec.Endpoints[0].Addresses.IPv4).To(Equal([]string{"10.40.20.181"}))
+
+	var converted v1alpha1.Cluster
+	g.Expect(cluster.ConvertTo(&converted)).To(Succeed())
+	g.Expect(converted).To(Equal(hub))
+
+	// addresses changed in v1beta1 are grouped by family
+	cluster.Spec.Endpoints[0].Addresses.IPv4 = append(cluster.Spec.Endpoints[0].Addresses.IPv4, "10.40.20.180")
+	g.Expect(cluster.ConvertTo(&converted)).To(Succeed())
+	g.Expect(converted.Annotations).To(Equal(map[string]string{"owner": "fabedge"}))
+	g.Expect(converted.Spec.EndPoints[0].PublicAddresses).To(Equal([]string{"10.40.20.181", "10.40.20.180", "2001:db8::1", "connector.beijing.local"}))
+	g.Expect(converted.Spec.EndPoints[0].Subnets).To(Equal(hub.Spec.EndPoints[0].Subnets))
+	g.Expect(converted.Status.Endpoints).To(Equal(hub.Status.Endpoints))
+}
+
+func TestCommunityConversion(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	rekeyTime := int64(3600)
+	hub := v1alpha1.Community{
+		ObjectMeta: metav1.ObjectMeta{Name: "connectors"},
+		Spec: v1alpha1.CommunitySpec{
+			Members: []string{"beijing.connector", "shanghai.connector"},
+			IPSec: &v1alpha1.IPSecParameters{
+				ESPProposals:     []string{"aes256gcm16-ecp384"},
+				RekeyTimeSeconds: &rekeyTime,
+			},
+			NodeSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"region": "beijing"},
+			},
+		},
+		Status: v1alpha1.CommunityStatus{
+			ResolvedMembers:    []string{"beijing.connector", "shanghai.connector"},
+			ExpectedTunnels:    1,
+			EstablishedTunnels: 1,
+		},
+	}
+
+	var community v1beta1.Community
+	g.Expect(community.ConvertFrom(&hub)).To(Succeed())
+	g.Expect(community.Spec.Members).To(Equal(hub.Spec.Members))
+	g.Expect(community.Spec.NodeSelector).To(Equal(hub.Spec.NodeSelector))
+	g.Expect(community.Spec.IPSec.ESPProposals).To(Equal([]string{"aes256gcm16-ecp384"}))
+	g.Expect(*community.Spec.IPSec.RekeyTimeSeconds).To(Equal(rekeyTime))
+	g.Expect(community.Status.ExpectedTunnels).To(Equal(1))
+
+	var converted v1alpha1.Community
+	g.Expect(community.ConvertTo(&converted)).To(Succeed())
+	g.Expect(converted).To(Equal(hub))
+}
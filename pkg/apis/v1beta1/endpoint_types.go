@@ -0,0 +1,67 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+type EndpointType string
+
+const (
+	Connector EndpointType = "Connector"
+	EdgeNode  EndpointType = "EdgeNode"
+)
+
+// Endpoint separates how to reach an endpoint(addresses and tunnel parameters)
+// from what can be reached through it(subnets and node subnets)
+type Endpoint struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	// Type of endpoints: Connector or EdgeNode
+	Type EndpointType `json:"type,omitempty"`
+	// Public addresses of endpoint, grouped by address family
+	Addresses EndpointAddresses `json:"addresses,omitempty"`
+	// Tunnel parameters used by other endpoints to connect to this endpoint
+	Tunnel TunnelParameters `json:"tunnel,omitempty"`
+	// Pod subnets behind endpoint
+	Subnets DualStackCIDRs `json:"subnets,omitempty"`
+	// Subnets of internal IPs of kubernetes nodes behind endpoint
+	NodeSubnets DualStackCIDRs `json:"nodeSubnets,omitempty"`
+}
+
+type EndpointAddresses struct {
+	IPv4 []string `json:"ipv4,omitempty"`
+	IPv6 []string `json:"ipv6,omitempty"`
+	// Hostnames are DNS names which will be resolved by tunnel backends
+	Hostnames []string `json:"hostnames,omitempty"`
+}
+
+type DualStackCIDRs struct {
+	IPv4 []string `json:"ipv4,omitempty"`
+	IPv6 []string `json:"ipv6,omitempty"`
+}
+
+type TunnelParameters struct {
+	// Backend is the tunnel backend used by this endpoint: strongswan or wireguard.
+	// If it's empty, strongswan is used. Strongswan is also used by connections whose endpoints have different backends
+	Backend string `json:"backend,omitempty"`
+	// Public UDP port for IKE communication or wireguard, default: 500 for strongswan
+	Port *uint `json:"port,omitempty"`
+	// Public key of WireGuard, only used when WireGuard is used as tunnel backend
+	PublicKey string `json:"publicKey,omitempty"`
+	// IPsec parameters used by tunnels to this endpoint, operator fills
+	// them with IPSec parameters of the cluster which the endpoint belongs to
+	IPSec *IPSecParameters `json:"ipsec,omitempty"`
+	// Name of secret in fabedge namespace whose key "psk" is used as pre-shared key of
+	// tunnels to this endpoint. If it's empty, certificates are used for authentication
+	PSKSecretName string `json:"pskSecretName,omitempty"`
+}
@@ -0,0 +1,32 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+// IPSecParameters are crypto proposals and rekey settings of IPsec tunnels,
+// they are only used by strongswan backend, empty values mean strongswan defaults.
+// Check https://docs.strongswan.org/docs/5.9/swanctl/swanctlConf.html for details
+type IPSecParameters struct {
+	// IKE proposals, e.g. aes256gcm16-prfsha384-ecp384
+	IKEProposals []string `json:"ikeProposals,omitempty"`
+	// ESP proposals of CHILD_SAs, e.g. aes256gcm16-ecp384
+	ESPProposals []string `json:"espProposals,omitempty"`
+	// Time to schedule IKE rekeying, in seconds
+	IKERekeyTimeSeconds *int64 `json:"ikeRekeyTimeSeconds,omitempty"`
+	// Time to schedule CHILD_SA rekeying, in seconds
+	RekeyTimeSeconds *int64 `json:"rekeyTimeSeconds,omitempty"`
+	// Maximum lifetime of CHILD_SA before it gets closed, in seconds.
+	// It should be greater than RekeyTimeSeconds
+	LifeTimeSeconds *int64 `json:"lifeTimeSeconds,omitempty"`
+}
@@ -42,3 +42,8 @@ func DisplayAndExitIfRequested() {
 func DisplayVersion() {
 	fmt.Printf("Version: %s\nBuildTime: %s\nGitCommit: %s\n", version, buildTime, gitCommit)
 }
+
+// GetVersion returns the semantic version of current build
+func GetVersion() string {
+	return version
+}
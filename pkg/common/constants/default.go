@@ -23,12 +23,21 @@ const (
 	KeyCluster             = "fabedge.io/cluster"
 	KeyNodePublicAddresses = "fabedge.io/node-public-addresses"
 	KeyPodHash             = "fabedge.io/pod-spec-hash"
+	KeyWireGuardPublicKey  = "fabedge.io/wireguard-public-key"
+	KeyTunnelBackend       = "fabedge.io/tunnel-backend"
+	KeyEndpointName        = "fabedge.io/endpoint-name"
+	KeyEstablishedTunnels  = "fabedge.io/established-tunnels"
+	KeyPSKSecretName       = "fabedge.io/psk-secret-name"
+	KeyRotateCA            = "fabedge.io/rotate-ca"
+	KeyCARotationTime      = "fabedge.io/ca-rotation-time"
+	KeyUsedTokenID         = "fabedge.io/used-token-id"
 	AppAgent               = "fabedge-agent"
 	AppOperator            = "fabedge-operator"
 
 	ConnectorConfigFileName = "tunnels.yaml"
 	ConnectorConfigName     = "connector-config"
 	ConnectorTLSName        = "connector-tls"
+	ConnectorPSKName        = "connector-psk"
 )
 
 const (
@@ -0,0 +1,135 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tunnelstatus is used by agents and connectors to report which
+// tunnels are established. The status is saved as annotations of the pod
+// where an agent or a connector runs, so that operator can collect them.
+package tunnelstatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/fabedge/fabedge/pkg/common/constants"
+)
+
+const (
+	EnvPodName      = "POD_NAME"
+	EnvPodNamespace = "POD_NAMESPACE"
+)
+
+type Reporter struct {
+	client    kubernetes.Interface
+	namespace string
+	podName   string
+
+	mux sync.Mutex
+	// lastReported is the tunnel status which is reported successfully last time,
+	// an unchanged status is not reported again
+	lastReported map[string]string
+}
+
+func NewReporter(client kubernetes.Interface, namespace, podName string) *Reporter {
+	return &Reporter{
+		client:    client,
+		namespace: namespace,
+		podName:   podName,
+	}
+}
+
+// NewInClusterReporter creates a reporter for the pod where current process runs,
+// the pod's name and namespace are read from environment variables
+func NewInClusterReporter() (*Reporter, error) {
+	podName, namespace := os.Getenv(EnvPodName), os.Getenv(EnvPodNamespace)
+	if podName == "" || namespace == "" {
+		return nil, fmt.Errorf("both %s and %s environment variables are required", EnvPodName, EnvPodNamespace)
+	}
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewReporter(client, namespace, podName), nil
+}
+
+// Report saves the endpoint name and names of peers which the endpoint
+// has established tunnels with to the annotations of reporter's pod,
+// nothing is sent if they are the same as those reported last time
+func (r *Reporter) Report(ctx context.Context, endpointName string, peers []string) error {
+	annotations := map[string]string{
+		constants.KeyEndpointName:       endpointName,
+		constants.KeyEstablishedTunnels: strings.Join(sets.NewString(peers...).List(), ","),
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if reflect.DeepEqual(annotations, r.lastReported) {
+		return nil
+	}
+
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.CoreV1().Pods(r.namespace).Patch(ctx, r.podName, types.MergePatchType, data, metav1.PatchOptions{})
+	if err != nil {
+		return err
+	}
+
+	r.lastReported = annotations
+	return nil
+}
+
+// Parse extracts endpoint name and names of established peers from
+// annotations, ok is false if annotations contain no tunnel status
+func Parse(annotations map[string]string) (endpointName string, peers sets.String, ok bool) {
+	endpointName = annotations[constants.KeyEndpointName]
+	if endpointName == "" {
+		return "", nil, false
+	}
+
+	peers = sets.NewString()
+	for _, name := range strings.Split(annotations[constants.KeyEstablishedTunnels], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			peers.Insert(name)
+		}
+	}
+
+	return endpointName, peers, true
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnelstatus_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/fabedge/fabedge/pkg/common/constants"
+	"github.com/fabedge/fabedge/pkg/common/tunnelstatus"
+)
+
+var _ = Describe("Reporter", func() {
+	var (
+		client   *fake.Clientset
+		reporter *tunnelstatus.Reporter
+	)
+
+	BeforeEach(func() {
+		client = fake.NewSimpleClientset(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "fabedge-agent-edge1",
+				Namespace: "fabedge",
+			},
+		})
+		reporter = tunnelstatus.NewReporter(client, "fabedge", "fabedge-agent-edge1")
+	})
+
+	countPatches := func() int {
+		count := 0
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "patch" {
+				count++
+			}
+		}
+		return count
+	}
+
+	It("should save tunnel status to annotations of pod", func() {
+		Expect(reporter.Report(context.Background(), "cluster.edge1", []string{"cluster.edge2", "cluster.connector"})).To(Succeed())
+
+		pod, err := client.CoreV1().Pods("fabedge").Get(context.Background(), "fabedge-agent-edge1", metav1.GetOptions{})
+		Expect(err).To(BeNil())
+		Expect(pod.Annotations).To(HaveKeyWithValue(constants.KeyEndpointName, "cluster.edge1"))
+		Expect(pod.Annotations).To(HaveKeyWithValue(constants.KeyEstablishedTunnels, "cluster.connector,cluster.edge2"))
+
+		endpointName, peers, ok := tunnelstatus.Parse(pod.Annotations)
+		Expect(ok).To(BeTrue())
+		Expect(endpointName).To(Equal("cluster.edge1"))
+		Expect(peers.List()).To(ConsistOf("cluster.connector", "cluster.edge2"))
+	})
+
+	It("should not report tunnel status again if it's not changed", func() {
+		Expect(reporter.Report(context.Background(), "cluster.edge1", []string{"cluster.edge2", "cluster.connector"})).To(Succeed())
+		Expect(reporter.Report(context.Background(), "cluster.edge1", []string{"cluster.connector", "cluster.edge2"})).To(Succeed())
+		Expect(countPatches()).To(Equal(1))
+
+		Expect(reporter.Report(context.Background(), "cluster.edge1", []string{"cluster.connector"})).To(Succeed())
+		Expect(countPatches()).To(Equal(2))
+	})
+
+	It("should report tunnel status again if last report failed", func() {
+		Expect(client.CoreV1().Pods("fabedge").Delete(context.Background(), "fabedge-agent-edge1", metav1.DeleteOptions{})).To(Succeed())
+		Expect(reporter.Report(context.Background(), "cluster.edge1", []string{"cluster.edge2"})).NotTo(Succeed())
+
+		_, err := client.CoreV1().Pods("fabedge").Create(context.Background(), &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "fabedge-agent-edge1",
+				Namespace: "fabedge",
+			},
+		}, metav1.CreateOptions{})
+		Expect(err).To(BeNil())
+		Expect(reporter.Report(context.Background(), "cluster.edge1", []string{"cluster.edge2"})).To(Succeed())
+		Expect(countPatches()).To(Equal(2))
+	})
+})
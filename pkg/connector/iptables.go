@@ -186,3 +186,16 @@ func (h *IPTablesHandler) getEdgeNodeCIDRs() []string {
 
 	return nil
 }
+
+// getIPSetEntryCounts returns the number of entries of each ipset
+func (h *IPTablesHandler) getIPSetEntryCounts() map[string]int {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	counts := make(map[string]int, len(h.specs))
+	for _, spec := range h.specs {
+		counts[spec.Name] = spec.EntrySet.Len()
+	}
+
+	return counts
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/pflag"
 	"go.uber.org/atomic"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -38,16 +39,18 @@ import (
 
 	cloud_agent "github.com/fabedge/fabedge/pkg/cloud-agent"
 	"github.com/fabedge/fabedge/pkg/common/about"
+	"github.com/fabedge/fabedge/pkg/common/tunnelstatus"
 	"github.com/fabedge/fabedge/pkg/connector/routing"
 	"github.com/fabedge/fabedge/pkg/tunnel"
 	"github.com/fabedge/fabedge/pkg/tunnel/strongswan"
+	"github.com/fabedge/fabedge/pkg/tunnel/wireguard"
 	"github.com/fabedge/fabedge/pkg/util/memberlist"
 )
 
 type Manager struct {
 	Config
 
-	tm          tunnel.Manager
+	tm          *tunnel.MixedManager
 	iptHandler  *IPTablesHandler
 	ipt6Handler *IPTablesHandler
 	connections []tunnel.ConnConfig
@@ -55,8 +58,17 @@ type Manager struct {
 	mc          *memberlist.Client
 	log         logr.Logger
 
+	// endpointName is the name of connector endpoint, it's read from tunnel config file
+	endpointName string
+
 	kubeClient *clientset.Clientset
 	isLeader   *atomic.Bool
+	// reporter reports established tunnels to the annotations of connector pod,
+	// it's nil if connector doesn't know the name of its pod
+	reporter *tunnelstatus.Reporter
+	metrics  *metrics
+	// routesSynced is true if routes are programmed by last synchronization
+	routesSynced *atomic.Bool
 
 	cloudAgent *cloud_agent.CloudAgent
 
@@ -69,12 +81,23 @@ type Config struct {
 	DebounceDuration  time.Duration
 	TunnelConfigFile  string
 	CertFile          string
+	PSKDir            string
+	CRLDir            string
 	ViciSocket        string
 	CNIType           string
 	InitMembers       []string
 	TunnelInitTimeout uint
 	ListenAddress     string
 
+	// TunnelBackend decides which tunnel implementation is used by default: strongswan or wireguard,
+	// the backend of a connection may be different if endpoints of it specify their backends
+	TunnelBackend string
+	WireGuard     struct {
+		InterfaceName  string
+		ListenPort     uint
+		PrivateKeyFile string
+	}
+
 	LeaderElection struct {
 		LockName      string
 		LeaseDuration time.Duration
@@ -86,18 +109,20 @@ type Config struct {
 func (c *Config) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&c.TunnelConfigFile, "tunnel-config", "/etc/fabedge/tunnels.yaml", "tunnel config file")
 	fs.StringVar(&c.CertFile, "cert-file", "/etc/ipsec.d/certs/tls.crt", "TLS certificate file")
+	fs.StringVar(&c.PSKDir, "psk-dir", "/etc/fabedge-psk", "The directory of pre-shared keys, each file is named after the peer endpoint. Tunnels to peers without pre-shared key use certificates")
+	fs.StringVar(&c.CRLDir, "crl-dir", "/etc/fabedge-crl", "The directory of certificate revocation lists, certificates revoked by them are not accepted by strongswan")
 	fs.StringVar(&c.ViciSocket, "vici-socket", "/var/run/charon.vici", "vici socket file")
 	fs.DurationVar(&c.DebounceDuration, "debounce-duration", 5*time.Second, "period to sync routes/rules")
 	fs.StringVar(&c.LeaderElection.LockName, "leader-lock-name", "connector", "The name of leader lock")
 	fs.StringVar(&c.ListenAddress, "listen-address", "127.0.0.1:30306", "The address of http server")
+	fs.StringVar(&c.TunnelBackend, "tunnel-backend", tunnel.BackendStrongSwan, "The default implementation of tunnels, possible values are: strongswan, wireguard")
+	fs.StringVar(&c.WireGuard.InterfaceName, "wireguard-interface", wireguard.DefaultInterfaceName, "The name of wireguard interface")
+	fs.UintVar(&c.WireGuard.ListenPort, "wireguard-listen-port", wireguard.DefaultListenPort, "The UDP port for wireguard to listen")
+	fs.StringVar(&c.WireGuard.PrivateKeyFile, "wireguard-private-key", "wireguard.key", "The path to wireguard private key file. If it's a relative path, the key file should be put under /etc/ipsec.d/private")
 }
 
 func (c Config) Manager() (*Manager, error) {
-	tm, err := strongswan.New(
-		strongswan.SocketFile(c.ViciSocket),
-		strongswan.StartAction("none"),
-		strongswan.InitTimeout(10),
-	)
+	tm, err := c.tunnelManager()
 	if err != nil {
 		return nil, err
 	}
@@ -135,8 +160,9 @@ func (c Config) Manager() (*Manager, error) {
 		ipt6Handler: ipt6,
 		router:      router,
 
-		kubeClient: client,
-		isLeader:   atomic.NewBool(false),
+		kubeClient:   client,
+		isLeader:     atomic.NewBool(false),
+		routesSynced: atomic.NewBool(false),
 
 		cloudAgent: cloudAgent,
 
@@ -146,15 +172,48 @@ func (c Config) Manager() (*Manager, error) {
 		debounce: debpkg.New(c.DebounceDuration),
 	}
 
+	manager.metrics = newMetrics(manager)
+
 	mc, err := memberlist.New(c.InitMembers, manager.handleMessage, manager.handleNodeLeave)
 	if err != nil {
 		return nil, err
 	}
 	manager.mc = mc
 
+	if podName := os.Getenv(tunnelstatus.EnvPodName); podName != "" {
+		manager.reporter = tunnelstatus.NewReporter(client, getNamespace(), podName)
+	}
+
 	return manager, nil
 }
 
+func (c Config) tunnelManager() (*tunnel.MixedManager, error) {
+	return tunnel.NewMixedManager(c.tunnelRegistry(), c.TunnelBackend)
+}
+
+// tunnelRegistry returns a registry of all tunnel backends supported by connector,
+// connections of different backends can exist at the same time
+func (c Config) tunnelRegistry() *tunnel.Registry {
+	registry := tunnel.NewRegistry()
+	registry.Register(tunnel.BackendStrongSwan, func() (tunnel.Manager, error) {
+		return strongswan.New(
+			strongswan.SocketFile(c.ViciSocket),
+			strongswan.CRLsDir(c.CRLDir),
+			strongswan.StartAction("none"),
+			strongswan.InitTimeout(10),
+		)
+	})
+	registry.Register(tunnel.BackendWireGuard, func() (tunnel.Manager, error) {
+		return wireguard.New(
+			wireguard.InterfaceName(c.WireGuard.InterfaceName),
+			wireguard.ListenPort(c.WireGuard.ListenPort),
+			wireguard.PrivateKeyFile(c.WireGuard.PrivateKeyFile),
+		)
+	})
+
+	return registry
+}
+
 func (m *Manager) startTick() {
 	tick := time.NewTicker(m.SyncPeriod)
 	defer tick.Stop()
@@ -208,12 +267,16 @@ func (m *Manager) runLeaderElection() {
 					m.log.V(3).Info("Get leader role, clear iptables rules generated as cloud agent")
 					m.cloudAgent.CleanAll()
 					m.isLeader.Store(true)
+					m.metrics.leaderTransitions.WithLabelValues(leaderEventStarted).Inc()
 					m.notify()
 				},
 				OnStoppedLeading: func() {
 					m.log.V(3).Info("Lose leader role, clear iptables and routes")
 					m.clearAll()
 					m.isLeader.Store(false)
+					m.metrics.leaderTransitions.WithLabelValues(leaderEventStopped).Inc()
+					// tunnels are cleared, remove stale tunnel status
+					m.reportTunnelStatus(nil)
 				},
 				OnNewLeader: func(currentID string) {
 					if currentID == leaderID {
@@ -238,6 +301,7 @@ func (m *Manager) gracefulShutdown() {
 }
 
 func (m *Manager) clearAll() {
+	m.routesSynced.Store(false)
 	err := m.router.CleanRoutes(m.connections)
 	if err != nil {
 		m.log.Error(err, "failed to clean routes")
@@ -265,7 +329,13 @@ func (m *Manager) removeAllChains() {
 
 func (m *Manager) maintainRoutes() {
 	m.log.V(5).Info("tunnel manager is active, try to synchronize routes in table 220")
-	if err := m.router.SyncRoutes(m.connections); err != nil {
+	// routes of connections of other backends are maintained by their tunnel managers
+	start := time.Now()
+	err := m.router.SyncRoutes(m.getConnectionsOfBackend(tunnel.BackendStrongSwan))
+	m.metrics.routeSyncDuration.Observe(time.Since(start).Seconds())
+	m.routesSynced.Store(err == nil)
+	if err != nil {
+		m.metrics.routeSyncErrors.Inc()
 		m.log.Error(err, "failed to sync routes")
 		return
 	}
@@ -273,6 +343,12 @@ func (m *Manager) maintainRoutes() {
 }
 
 func (m *Manager) maintainTunnels() {
+	// certificate renewed by operator is updated by kubelet in mounted secret volume,
+	// reloading it won't break established tunnels
+	if err := m.tm.ReloadCerts(); err != nil {
+		m.log.Error(err, "failed to reload certificates")
+	}
+
 	if err := m.syncConnections(); err != nil {
 		m.log.Error(err, "error when to sync tunnels")
 	} else {
@@ -280,6 +356,18 @@ func (m *Manager) maintainTunnels() {
 	}
 }
 
+// getConnectionsOfBackend returns connections which use specified tunnel backend
+func (m *Manager) getConnectionsOfBackend(backend string) []tunnel.ConnConfig {
+	var connections []tunnel.ConnConfig
+	for _, conn := range m.connections {
+		if m.tm.BackendOf(conn) == backend {
+			connections = append(connections, conn)
+		}
+	}
+
+	return connections
+}
+
 // broadcastConnectorPrefixes broadcasts the active routing info to all cloud agents.
 func (m *Manager) broadcastConnectorPrefixes() {
 	cp, err := m.router.GetConnectorPrefixes()
@@ -288,6 +376,21 @@ func (m *Manager) broadcastConnectorPrefixes() {
 		return
 	}
 
+	// remote prefixes are collected from strongswan table, those of other backends have to be added here
+	for _, conn := range m.connections {
+		if conn.Mediation || m.tm.BackendOf(conn) == tunnel.BackendStrongSwan {
+			continue
+		}
+
+		for _, subnet := range conn.RemoteSubnets {
+			if isIPv6(subnet) {
+				cp.RemotePrefixes6 = append(cp.RemotePrefixes6, subnet)
+			} else {
+				cp.RemotePrefixes = append(cp.RemotePrefixes, subnet)
+			}
+		}
+	}
+
 	log := m.log.WithValues("connectorPrefixes", cp)
 
 	log.V(5).Info("get connector prefixes")
@@ -328,6 +431,32 @@ func (m *Manager) workLoop() {
 		// maintainTunnels may last for minutes, so put it at the end, otherwise it may cause error, such as wrong iptables
 		// rules and wrong routes are generated after isLeader is set to false
 		m.maintainTunnels()
+
+		m.reportEstablishedTunnels()
+	}
+}
+
+func (m *Manager) reportEstablishedTunnels() {
+	names, err := m.tm.ListEstablishedConnNames()
+	if err != nil {
+		m.log.Error(err, "failed to get established tunnels")
+		return
+	}
+
+	m.reportTunnelStatus(names)
+}
+
+func (m *Manager) reportTunnelStatus(establishedConnNames []string) {
+	if m.reporter == nil || m.endpointName == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// connections are named after peer endpoints
+	if err := m.reporter.Report(ctx, m.endpointName, establishedConnNames); err != nil {
+		m.log.Error(err, "failed to report tunnel status")
 	}
 }
 
@@ -337,6 +466,9 @@ func (m *Manager) runHTTPServer() {
 	r.Get("/is-leader", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(m.isLeader.String()))
 	})
+	r.Get("/healthz", m.healthz)
+	r.Get("/readyz", m.readyz)
+	r.Method(http.MethodGet, "/metrics", promhttp.HandlerFor(m.metrics.registry, promhttp.HandlerOpts{}))
 	server := &http.Server{
 		Addr:    m.ListenAddress,
 		Handler: r,
@@ -350,6 +482,33 @@ func (m *Manager) runHTTPServer() {
 	}
 }
 
+// healthz checks if charon and other tunnel backends are reachable
+func (m *Manager) healthz(w http.ResponseWriter, r *http.Request) {
+	if !m.tm.IsRunning() {
+		http.Error(w, "tunnel backends are not reachable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Write([]byte("ok"))
+}
+
+// readyz checks if tunnel backends are reachable and, when this connector
+// is the leader, routes are programmed. Standby connectors don't program routes,
+// so they are ready as long as tunnel backends are reachable
+func (m *Manager) readyz(w http.ResponseWriter, r *http.Request) {
+	if !m.tm.IsRunning() {
+		http.Error(w, "tunnel backends are not reachable", http.StatusServiceUnavailable)
+		return
+	}
+
+	if m.isLeader.Load() && !m.routesSynced.Load() {
+		http.Error(w, "routes are not programmed", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Write([]byte("ok"))
+}
+
 // getConnectorName will return a valid name as leader election ID
 func getConnectorName() string {
 	hostname, _ := os.Hostname()
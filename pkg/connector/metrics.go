@@ -0,0 +1,109 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"github.com/fabedge/fabedge/pkg/tunnel"
+)
+
+const metricsNamespace = "fabedge_connector"
+
+// events of leader election
+const (
+	leaderEventStarted = "started"
+	leaderEventStopped = "stopped"
+)
+
+type metrics struct {
+	registry *prometheus.Registry
+
+	routeSyncDuration prometheus.Histogram
+	routeSyncErrors   prometheus.Counter
+	leaderTransitions *prometheus.CounterVec
+}
+
+func newMetrics(m *Manager) *metrics {
+	mt := &metrics{
+		registry: prometheus.NewRegistry(),
+		routeSyncDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "route_sync_duration_seconds",
+			Help:      "Time spent on synchronizing routes of tunnels",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+		}),
+		routeSyncErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "route_sync_errors_total",
+			Help:      "Number of failures of synchronizing routes of tunnels",
+		}),
+		leaderTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "leader_transitions_total",
+			Help:      "Number of times this connector started or stopped leading",
+		}, []string{"event"}),
+	}
+
+	isLeader := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "is_leader",
+		Help:      "Whether this connector is the leader, 1 means yes",
+	}, func() float64 {
+		if m.isLeader.Load() {
+			return 1
+		}
+		return 0
+	})
+
+	mt.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		tunnel.NewCollector(metricsNamespace, m.tm),
+		&ipsetCollector{
+			handlers: []*IPTablesHandler{m.iptHandler, m.ipt6Handler},
+			entries: prometheus.NewDesc(
+				prometheus.BuildFQName(metricsNamespace, "", "ipset_entries"),
+				"Number of entries of ipsets maintained by connector",
+				[]string{"ipset"}, nil,
+			),
+		},
+		mt.routeSyncDuration,
+		mt.routeSyncErrors,
+		mt.leaderTransitions,
+		isLeader,
+	)
+
+	return mt
+}
+
+// ipsetCollector reports the number of entries which IPTablesHandlers are supposed to keep in ipsets
+type ipsetCollector struct {
+	handlers []*IPTablesHandler
+	entries  *prometheus.Desc
+}
+
+func (c *ipsetCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.entries
+}
+
+func (c *ipsetCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, h := range c.handlers {
+		for name, count := range h.getIPSetEntryCounts() {
+			ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(count), name)
+		}
+	}
+}
@@ -32,6 +32,12 @@ func (m *Manager) readCfgFromFile() error {
 		return err
 	}
 
+	// if pre-shared keys are not available, connections will use certificates
+	psks, err := tunnel.LoadPSKs(m.PSKDir)
+	if err != nil {
+		m.log.Error(err, "failed to load pre-shared keys")
+	}
+
 	connections := make([]tunnel.ConnConfig, 0, len(nc.Peers)+1)
 	// for now, connector is the only mediator candidate,
 	// for mediator itself, there is no need to configure remote settings,
@@ -40,6 +46,8 @@ func (m *Manager) readCfgFromFile() error {
 		mediator := nc.Mediator
 		connections = append(connections, tunnel.ConnConfig{
 			Name: mediator.Name,
+			// mediation is a feature of strongswan
+			Backend: tunnel.BackendStrongSwan,
 
 			LocalID:    mediator.ID,
 			LocalCerts: []string{m.CertFile},
@@ -49,7 +57,8 @@ func (m *Manager) readCfgFromFile() error {
 
 	for _, peer := range nc.Peers {
 		conn := tunnel.ConnConfig{
-			Name: peer.Name,
+			Name:    peer.Name,
+			Backend: tunnel.NegotiateBackend(nc.TunnelBackend, peer.TunnelBackend),
 
 			LocalID:          nc.ID,
 			LocalCerts:       []string{m.CertFile},
@@ -64,10 +73,14 @@ func (m *Manager) readCfgFromFile() error {
 			RemoteNodeSubnets: peer.NodeSubnets,
 			RemoteType:        peer.Type,
 			RemotePort:        peer.Port,
+			RemotePublicKey:   peer.PublicKey,
 		}
+		conn.SetIPSecParameters(peer.IPSec)
+		conn.SetPSK(psks[peer.Name])
 		connections = append(connections, conn)
 	}
 	m.connections = connections
+	m.endpointName = nc.Name
 
 	m.classifyConnectionSubnets()
 	return nil
@@ -36,6 +36,14 @@ type Interface interface {
 	IsAllocated(net.IPNet) bool
 	Contains(ipNet net.IPNet) bool
 	GetFreeSubnetBlock(hostname string) (*net.IPNet, error)
+	// Usage returns the pool CIDR, the number of allocated blocks and the number of all blocks
+	Usage() Usage
+}
+
+type Usage struct {
+	Pool  string
+	Used  int
+	Total *big.Int
 }
 
 var _ Interface = &allocator{}
@@ -142,6 +150,24 @@ func (a *allocator) GetFreeSubnetBlock(hostname string) (*net.IPNet, error) {
 	return nil, ErrNoAvailableSubnet
 }
 
+func (a *allocator) Usage() Usage {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+
+	return Usage{
+		Pool:  a.netCIDR,
+		Used:  len(a.subnetCache),
+		Total: a.countBlocks(),
+	}
+}
+
+func (a *allocator) countBlocks() *big.Int {
+	poolOnes, _ := a.pool.Mask.Size()
+	blockOnes, _ := a.blockMask.Size()
+
+	return new(big.Int).Exp(big.NewInt(2), big.NewInt(int64(blockOnes-poolOnes)), nil)
+}
+
 func (a *allocator) generateNextBlock(hostname string) NextBlockFunc {
 	pool, baseIP, blockMask := a.pool, a.pool.IP, a.blockMask
 
@@ -94,4 +94,22 @@ var _ = Describe("Allocator", func() {
 		Entry("IPv4", "2.2.0.0/16", 26),
 		Entry("IPv6", "fd85:ee78:d8a6:8607::1:0000/112", 122),
 	)
+
+	DescribeTable("should report usage of pool", func(netCIDR string, subnetMaskSize int, total int64) {
+		alloc, err := allocator.New(netCIDR, subnetMaskSize)
+		Expect(err).To(BeNil())
+
+		_, err = alloc.GetFreeSubnetBlock("node1")
+		Expect(err).To(BeNil())
+		_, err = alloc.GetFreeSubnetBlock("node2")
+		Expect(err).To(BeNil())
+
+		usage := alloc.Usage()
+		Expect(usage.Pool).To(Equal(netCIDR))
+		Expect(usage.Used).To(Equal(2))
+		Expect(usage.Total.Int64()).To(Equal(total))
+	},
+		Entry("IPv4", "2.2.0.0/16", 26, int64(1024)),
+		Entry("IPv6", "fd85:ee78:d8a6:8607::1:0000/112", 122, int64(1024)),
+	)
 })
@@ -1,11 +1,14 @@
 package apiserver
 
 import (
-	"crypto/rsa"
+	"context"
+	"crypto"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"reflect"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
@@ -13,10 +16,14 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/golang-jwt/jwt/v4"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/common/constants"
+	"github.com/fabedge/fabedge/pkg/operator/crl"
+	operatormetrics "github.com/fabedge/fabedge/pkg/operator/metrics"
 	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
 	"github.com/fabedge/fabedge/pkg/operator/types"
 	certutil "github.com/fabedge/fabedge/pkg/util/cert"
@@ -29,24 +36,46 @@ const (
 	URLUpdateCluster              = "/api/cluster"
 	URLGetEndpointsAndCommunities = "/api/endpoints-and-communities"
 	URLGetCIDRs                   = "/api/cidrs"
+	URLRevokeCert                 = "/api/revoke-cert"
+	URLGetCRL                     = "/api/crl"
+	URLWatch                      = "/api/watch"
 
 	HeaderClusterName   = "X-FabEdge-Cluster"
 	HeaderAuthorization = "Authorization"
+
+	// clientCertSuffix is the suffix of common name of API client certificate of a member cluster,
+	// the cluster name is the part before it
+	clientCertSuffix = ".fabedge-client"
 )
 
+type contextKey string
+
+const keyClusterName contextKey = "clusterName"
+
 type Config struct {
 	Addr        string
-	PublicKey   *rsa.PublicKey
+	Namespace   string
+	PublicKey   crypto.PublicKey
 	CertManager certutil.Manager
 	Client      client.Client
 	Log         logr.Logger
 	Store       storepkg.Interface
 	CIDRMap     *types.ClusterCIDRsMap
+	// Cluster is the name of local cluster, EdgePodCIDRs are CIDRs of edge pods of it. CIDRs
+	// reported by clusters are refused if they overlap with them or CIDRs in CIDRMap
+	Cluster      string
+	EdgePodCIDRs []string
+	// Revoker and RevocationChecker are optional, if they are nil,
+	// certificates can't be revoked through API server
+	Revoker           crl.Revoker
+	RevocationChecker crl.Checker
 }
 
 type EndpointsAndCommunity struct {
 	Communities map[string][]string `json:"communities,omitempty"`
 	Endpoints   []apis.Endpoint     `json:"endpoints,omitempty"`
+	// IPSec parameters of communities, communities without IPSec parameters are omitted
+	IPSec map[string]*apis.IPSecParameters `json:"ipsec,omitempty"`
 }
 
 func New(cfg Config) (*http.Server, error) {
@@ -63,6 +92,10 @@ func New(cfg Config) (*http.Server, error) {
 
 		r.Get(URLGetEndpointsAndCommunities, cfg.getEndpointsAndCommunity)
 		r.Get(URLGetCIDRs, cfg.getCIDRs)
+		r.Get(URLWatch, cfg.watch)
+
+		r.Post(URLRevokeCert, cfg.revokeCert)
+		r.Get(URLGetCRL, cfg.getCRL)
 	})
 
 	return &http.Server{
@@ -75,69 +108,186 @@ func (cfg Config) health(w http.ResponseWriter, r *http.Request) {
 	w.Write(cfg.CertManager.GetCACertPEM())
 }
 
+// getCACert returns all trusted CA certs, the CA used to sign certificates comes first,
+// so clients only read the first one still work
 func (cfg Config) getCACert(w http.ResponseWriter, r *http.Request) {
-	w.Write(cfg.CertManager.GetCACertPEM())
+	w.Write(cfg.CertManager.GetCABundlePEM())
 }
 
 func (cfg Config) signCert(w http.ResponseWriter, r *http.Request) {
 	if r.TLS != nil && len(r.TLS.PeerCertificates) != 0 {
-		if err := cfg.CertManager.VerifyCert(r.TLS.PeerCertificates[0], certutil.ExtKeyUsagesServerAndClient); err != nil {
+		if err := cfg.verifyClientCert(r); err != nil {
 			cfg.Log.Error(err, "client certificate is invalid")
 			cfg.response(w, http.StatusUnauthorized, fmt.Sprintf("invalid certificate: %s", err))
 			return
 		}
 
-		cfg.doSignCert(w, r)
+		clusterName, err := authenticateCluster(r)
+		if err != nil {
+			cfg.response(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		if certDER := cfg.doSignCert(w, r, clusterName); certDER != nil {
+			w.Write(certutil.EncodeCertPEM(certDER))
+		}
 		return
 	}
 
-	if err := cfg.verifyAuthorization(r); err != nil {
+	cluster, claims, err := cfg.verifyAuthorization(r)
+	if err != nil {
 		cfg.response(w, http.StatusUnauthorized, fmt.Sprintf("invalid token: %s", err))
 		return
 	}
 
-	cfg.doSignCert(w, r)
+	certDER := cfg.doSignCert(w, r, cluster.Name)
+	if certDER == nil {
+		return
+	}
+
+	// a single-use token is marked as used after the certificate is signed, so it can be
+	// used again if signing fails. If the token is used by another request meanwhile,
+	// the certificate is discarded
+	if claims.SingleUse {
+		if err = cfg.markTokenUsed(r.Context(), cluster, claims.ID); err != nil {
+			cfg.Log.Error(err, "failed to mark token as used", "cluster", cluster.Name)
+			cfg.response(w, http.StatusUnauthorized, fmt.Sprintf("invalid token: %s", err))
+			return
+		}
+	}
+
+	w.Write(certutil.EncodeCertPEM(certDER))
 }
 
-func (cfg Config) doSignCert(w http.ResponseWriter, r *http.Request) {
+// doSignCert signs the certificate request in request body, a cluster can only
+// request certificates whose common names it owns, see ownsName. The signed
+// certificate is returned, if the request is not signed, the error is responded
+// and nil is returned
+func (cfg Config) doSignCert(w http.ResponseWriter, r *http.Request, clusterName string) []byte {
 	csrPEM, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		cfg.response(w, http.StatusBadRequest, fmt.Sprintf("failed to read request body: %s", err))
-		return
+		return nil
 	}
 
 	csr, err := certutil.DecodePEM(csrPEM)
 	if err != nil {
 		cfg.response(w, http.StatusBadRequest, err.Error())
-		return
+		return nil
+	}
+
+	req, err := x509.ParseCertificateRequest(csr)
+	if err != nil {
+		cfg.response(w, http.StatusBadRequest, err.Error())
+		return nil
+	}
+
+	clusters, err := cfg.listClusters(r.Context())
+	if err != nil {
+		cfg.response(w, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+
+	if !ownsName(req.Subject.CommonName, clusterName, clusters) {
+		cfg.response(w, http.StatusForbidden, fmt.Sprintf("cluster %s is not allowed to request certificate for %s", clusterName, req.Subject.CommonName))
+		return nil
 	}
 
+	// an intermediate cluster signs certificates through its host cluster, which may fail
 	certDER, err := cfg.CertManager.SignCert(csr)
-	certPEM := certutil.EncodeCertPEM(certDER)
+	if err != nil {
+		cfg.Log.Error(err, "failed to sign certificate", "cluster", clusterName, "commonName", req.Subject.CommonName)
+		cfg.response(w, http.StatusInternalServerError, fmt.Sprintf("failed to sign certificate: %s", err))
+		return nil
+	}
 
-	w.Write(certPEM)
+	return certDER
 }
 
-func (cfg Config) verifyAuthorization(r *http.Request) error {
-	tokenString := r.Header.Get("authorization")
-	if tokenString == "" {
-		return fmt.Errorf("invalid authorization token")
+// verifyAuthorization checks the token in authorization header and returns the cluster
+// which the token belongs to. A token is only accepted if it's the current token in the
+// spec of its cluster, so a token is revoked when spec.token is changed, and if the token
+// is single-use, it must not be used yet
+func (cfg Config) verifyAuthorization(r *http.Request) (*apis.Cluster, *types.ClusterTokenClaims, error) {
+	// the token has a prefix "bearer " which is 7 chars long
+	tokenString := r.Header.Get(HeaderAuthorization)
+	if len(tokenString) <= 7 {
+		return nil, nil, fmt.Errorf("invalid authorization token")
 	}
+	tokenString = tokenString[7:]
+
+	var claims types.ClusterTokenClaims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		// tokens are signed by the key loaded at startup, which may differ from
+		// the key of current CA after CA is rotated
+		key := cfg.PublicKey
+		if key == nil {
+			key = cfg.CertManager.GetCACert().PublicKey
+		}
+
+		// only the signing method matching the key is accepted, e.g. a token signed with
+		// HMAC using public key as secret is rejected
+		method, err := types.SigningMethodOf(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if token.Method.Alg() != method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+		}
 
-	var claims jwt.StandardClaims
-	// tokenString has a prefix "bearer " which is 7 chars long
-	token, err := jwt.ParseWithClaims(tokenString[7:], &claims, func(token *jwt.Token) (interface{}, error) {
-		return cfg.CertManager.GetCACert().PublicKey, nil
+		return key, nil
 	})
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if !token.Valid {
-		return fmt.Errorf("invalid authorization token")
+		return nil, nil, fmt.Errorf("invalid authorization token")
 	}
 
-	return nil
+	if claims.Subject == "" {
+		return nil, nil, fmt.Errorf("token has no subject")
+	}
+
+	// cluster name in header is optional, but if it's provided, it must be the subject of token
+	if clusterName := r.Header.Get(HeaderClusterName); clusterName != "" && clusterName != claims.Subject {
+		return nil, nil, fmt.Errorf("token is not issued for cluster %s", clusterName)
+	}
+
+	var cluster apis.Cluster
+	if err = cfg.Client.Get(r.Context(), client.ObjectKey{Name: claims.Subject}, &cluster); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil, fmt.Errorf("unknown cluster %s", claims.Subject)
+		}
+		return nil, nil, err
+	}
+
+	if cluster.Spec.Token != tokenString {
+		return nil, nil, fmt.Errorf("token is revoked")
+	}
+
+	if claims.SingleUse && cluster.Annotations[constants.KeyUsedTokenID] == claims.ID {
+		return nil, nil, fmt.Errorf("token is used")
+	}
+
+	return &cluster, &claims, nil
+}
+
+// markTokenUsed records ID of a single-use token in annotations of its cluster. The cluster
+// is updated with the resourceVersion read when the token is verified, if two requests use
+// the same token at the same time, the update of one of them fails because of conflict
+func (cfg Config) markTokenUsed(ctx context.Context, cluster *apis.Cluster, tokenID string) error {
+	if tokenID == "" {
+		return fmt.Errorf("single-use token has no ID")
+	}
+
+	if cluster.Annotations == nil {
+		cluster.Annotations = make(map[string]string)
+	}
+	cluster.Annotations[constants.KeyUsedTokenID] = tokenID
+
+	return cfg.Client.Update(ctx, cluster)
 }
 
 func (cfg Config) verifyCert(next http.Handler) http.Handler {
@@ -147,18 +297,116 @@ func (cfg Config) verifyCert(next http.Handler) http.Handler {
 			return
 		}
 
-		if err := cfg.CertManager.VerifyCert(r.TLS.PeerCertificates[0], certutil.ExtKeyUsagesServerAndClient); err != nil {
+		if err := cfg.verifyClientCert(r); err != nil {
 			cfg.Log.Error(err, "client certificate is invalid")
 			cfg.response(w, http.StatusUnauthorized, fmt.Sprintf("invalid certificate: %s", err))
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		clusterName, err := authenticateCluster(r)
+		if err != nil {
+			cfg.response(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), keyClusterName, clusterName)))
 	}
 
 	return http.HandlerFunc(fn)
 }
 
+// authenticateCluster returns the cluster which the verified client certificate belongs to,
+// the cluster name in header is optional, but if it's provided, it must match the certificate
+func authenticateCluster(r *http.Request) (string, error) {
+	cert := r.TLS.PeerCertificates[0]
+	clusterName, ok := GetClusterName(cert.Subject.CommonName)
+	if !ok {
+		return "", fmt.Errorf("certificate %s is not issued for a cluster", cert.Subject.CommonName)
+	}
+
+	if name := r.Header.Get(HeaderClusterName); name != "" && name != clusterName {
+		return "", fmt.Errorf("certificate of cluster %s can't be used for cluster %s", clusterName, name)
+	}
+
+	return clusterName, nil
+}
+
+// ClientCommonName returns common name of API client certificate of cluster
+func ClientCommonName(clusterName string) string {
+	return clusterName + clientCertSuffix
+}
+
+// GetClusterName returns the cluster name in common name of API client certificate
+func GetClusterName(commonName string) (string, bool) {
+	if !strings.HasSuffix(commonName, clientCertSuffix) {
+		return "", false
+	}
+
+	clusterName := strings.TrimSuffix(commonName, clientCertSuffix)
+	return clusterName, clusterName != ""
+}
+
+// BelongsToCluster checks if name, which is a name of endpoint or common name of a certificate,
+// belongs to cluster. Names of endpoints and certificates of a cluster start with "<cluster>.".
+// Child clusters of an intermediate cluster are named "<cluster>.<child>", so their endpoints
+// and certificates belong to the intermediate cluster too
+func BelongsToCluster(name, clusterName string) bool {
+	return strings.HasPrefix(name, clusterName+".")
+}
+
+// ownsName checks if name, which is a name of endpoint or common name of a certificate, can be
+// used by cluster. Cluster names may contain dots, so a name may belong to several clusters,
+// e.g. "a.b.edge1" belongs to both cluster "a" and cluster "a.b". A name is owned only by the
+// most specific registered cluster it belongs to, so cluster "a" can't act for cluster "a.b".
+// Child clusters of an intermediate cluster are not registered in host cluster, so their names
+// are still owned by the intermediate cluster
+func ownsName(name, clusterName string, clusters []apis.Cluster) bool {
+	return BelongsToCluster(name, clusterName) && getOwnerCluster(name, clusters) == clusterName
+}
+
+func (cfg Config) listClusters(ctx context.Context) ([]apis.Cluster, error) {
+	var clusterList apis.ClusterList
+	if err := cfg.Client.List(ctx, &clusterList); err != nil {
+		return nil, err
+	}
+
+	return clusterList.Items, nil
+}
+
+// checkEndpoints makes sure a cluster only reports its own endpoints
+func checkEndpoints(endpoints []apis.Endpoint, clusterName string, clusters []apis.Cluster) error {
+	for _, endpoint := range endpoints {
+		if !ownsName(endpoint.Name, clusterName, clusters) {
+			return fmt.Errorf("endpoint %s doesn't belong to cluster %s", endpoint.Name, clusterName)
+		}
+	}
+
+	return nil
+}
+
+// verifyClientCert checks if client certificate is issued by trusted CAs and not revoked
+func (cfg Config) verifyClientCert(r *http.Request) error {
+	cert := r.TLS.PeerCertificates[0]
+	if err := cfg.CertManager.VerifyCert(cert, certutil.ExtKeyUsagesServerAndClient); err != nil {
+		return err
+	}
+
+	if cfg.RevocationChecker == nil {
+		return nil
+	}
+
+	revoked, err := cfg.RevocationChecker.IsRevoked(r.Context(), cert)
+	if err != nil {
+		return fmt.Errorf("failed to check revocation: %w", err)
+	}
+
+	if revoked {
+		return fmt.Errorf("certificate is revoked")
+	}
+
+	return nil
+}
+
 func (cfg Config) updateCluster(w http.ResponseWriter, r *http.Request) {
 	jsonData, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -178,7 +426,18 @@ func (cfg Config) updateCluster(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	clusters, err := cfg.listClusters(r.Context())
+	if err != nil {
+		cfg.response(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	clusterName := cfg.getCluster(r)
+	if err = checkEndpoints(reqCluster.Spec.EndPoints, clusterName, clusters); err != nil {
+		cfg.response(w, http.StatusForbidden, err.Error())
+		return
+	}
+
 	var cluster apis.Cluster
 	err = cfg.Client.Get(r.Context(), client.ObjectKey{Name: clusterName}, &cluster)
 	if err != nil {
@@ -191,12 +450,40 @@ func (cfg Config) updateCluster(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cluster.Spec.CIDRs = reqCluster.Spec.CIDRs
-	cluster.Spec.EndPoints = reqCluster.Spec.EndPoints
-	if err := cfg.Client.Update(r.Context(), &cluster); err != nil {
+	// changed CIDRs which overlap with CIDRs of other clusters are refused, but endpoints and
+	// heartbeat are still saved, the conflicts are shown in CIDRConflict condition of cluster
+	cidrs, conflicts := reqCluster.Spec.CIDRs, []types.CIDRConflict(nil)
+	if !reflect.DeepEqual(cluster.Spec.CIDRs, cidrs) {
+		conflicts = cfg.findCIDRConflicts(clusterName, cidrs, clusters)
+		if len(conflicts) > 0 {
+			cidrs = cluster.Spec.CIDRs
+		}
+	}
+
+	if !reflect.DeepEqual(cluster.Spec.CIDRs, cidrs) || !reflect.DeepEqual(cluster.Spec.EndPoints, reqCluster.Spec.EndPoints) {
+		cluster.Spec.CIDRs = cidrs
+		cluster.Spec.EndPoints = reqCluster.Spec.EndPoints
+		if err := cfg.Client.Update(r.Context(), &cluster); err != nil {
+			cfg.response(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	err = cfg.updateClusterStatus(r.Context(), &cluster, reqCluster.Spec.EndPoints, reqCluster.Spec.CIDRs, reqCluster.Status.Version)
+	if err != nil {
 		cfg.response(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	operatormetrics.ClusterLastReportTime.WithLabelValues(clusterName).SetToCurrentTime()
+
+	if len(conflicts) > 0 {
+		messages := make([]string, 0, len(conflicts))
+		for _, c := range conflicts {
+			messages = append(messages, c.String())
+		}
+		cfg.response(w, http.StatusConflict, fmt.Sprintf("CIDRs are refused: %s", strings.Join(messages, "; ")))
+		return
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 	w.Write(nil)
@@ -220,7 +507,18 @@ func (cfg Config) updateEndpoints(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	clusters, err := cfg.listClusters(r.Context())
+	if err != nil {
+		cfg.response(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	clusterName := cfg.getCluster(r)
+	if err = checkEndpoints(endpoints, clusterName, clusters); err != nil {
+		cfg.response(w, http.StatusForbidden, err.Error())
+		return
+	}
+
 	var cluster apis.Cluster
 	err = cfg.Client.Get(r.Context(), client.ObjectKey{Name: clusterName}, &cluster)
 	if err != nil {
@@ -239,15 +537,57 @@ func (cfg Config) updateEndpoints(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = cfg.updateClusterStatus(r.Context(), &cluster, endpoints, cluster.Status.CIDRs, cluster.Status.Version)
+	if err != nil {
+		cfg.response(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 	w.Write(nil)
 }
 
+// findCIDRConflicts checks CIDRs of cluster against CIDRs of other clusters and edge pod CIDRs,
+// CIDRs of child clusters of the cluster are not counted since they're part of the cluster
+func (cfg Config) findCIDRConflicts(clusterName string, cidrs []string, clusters []apis.Cluster) []types.CIDRConflict {
+	cidrsByName := make(map[string][]string)
+	for name, otherCIDRs := range cfg.CIDRMap.GetCopy() {
+		if !ownsName(name, clusterName, clusters) {
+			cidrsByName[name] = otherCIDRs
+		}
+	}
+
+	if len(cfg.EdgePodCIDRs) > 0 {
+		cidrsByName[cfg.Cluster] = append(append([]string{}, cidrsByName[cfg.Cluster]...), cfg.EdgePodCIDRs...)
+	}
+
+	return types.FindCIDRConflicts(clusterName, cidrs, cidrsByName)
+}
+
+// updateClusterStatus records the heartbeat of cluster and what it reported, conditions are
+// left to cluster controller. A merge patch is used to avoid conflicts with cluster controller
+func (cfg Config) updateClusterStatus(ctx context.Context, cluster *apis.Cluster, endpoints []apis.Endpoint, cidrs []string, version string) error {
+	var connectors []apis.Endpoint
+	for _, endpoint := range endpoints {
+		if endpoint.Type == apis.Connector {
+			connectors = append(connectors, endpoint)
+		}
+	}
+
+	base := cluster.DeepCopy()
+	now := metav1.Now()
+	cluster.Status.LastHeartbeatTime = &now
+	cluster.Status.Endpoints = connectors
+	cluster.Status.CIDRs = cidrs
+	cluster.Status.Version = version
+
+	return cfg.Client.Status().Patch(ctx, cluster, client.MergeFrom(base))
+}
+
 func (cfg Config) getEndpointsAndCommunity(w http.ResponseWriter, r *http.Request) {
 	clusterName := cfg.getCluster(r)
 
-	var cluster apis.Cluster
-	err := cfg.Client.Get(r.Context(), client.ObjectKey{Name: clusterName}, &cluster)
+	ea, err := cfg.buildEndpointsAndCommunity(r.Context(), clusterName)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			cfg.response(w, http.StatusNotFound, fmt.Sprintf("unknown cluster %s", clusterName))
@@ -258,7 +598,27 @@ func (cfg Config) getEndpointsAndCommunity(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	content, _ := json.Marshal(&ea)
+
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(content)
+}
+
+// buildEndpointsAndCommunity returns communities which endpoints of cluster belong to
+// and endpoints of other clusters in those communities
+func (cfg Config) buildEndpointsAndCommunity(ctx context.Context, clusterName string) (EndpointsAndCommunity, error) {
+	var cluster apis.Cluster
+	if err := cfg.Client.Get(ctx, client.ObjectKey{Name: clusterName}, &cluster); err != nil {
+		return EndpointsAndCommunity{}, err
+	}
+
+	clusters, err := cfg.listClusters(ctx)
+	if err != nil {
+		return EndpointsAndCommunity{}, err
+	}
+
 	communitySet := make(map[string][]string)
+	ipsecParams := make(map[string]*apis.IPSecParameters)
 	endpointNameSet := sets.NewString()
 	for _, endpoint := range cluster.Spec.EndPoints {
 		communities := cfg.Store.GetCommunitiesByEndpoint(endpoint.Name)
@@ -270,24 +630,68 @@ func (cfg Config) getEndpointsAndCommunity(w http.ResponseWriter, r *http.Reques
 			}
 
 			communitySet[community.Name] = community.Members.List()
+			if community.IPSec != nil {
+				ipsecParams[community.Name] = community.IPSec
+			}
 			for _, name := range communitySet[community.Name] {
-				// skip endpoints which are from child cluster
-				if !strings.HasPrefix(name, clusterName) {
+				// skip endpoints of the cluster itself and its child clusters
+				if !ownsName(name, clusterName, clusters) {
 					endpointNameSet.Insert(name)
 				}
 			}
 		}
 	}
 
-	ea := EndpointsAndCommunity{
-		Endpoints:   cfg.Store.GetEndpoints(endpointNameSet.List()...),
+	endpoints := cfg.Store.GetEndpoints(endpointNameSet.List()...)
+	resolveIPSecParameters(cluster, endpoints, clusters)
+
+	return EndpointsAndCommunity{
+		Endpoints:   endpoints,
 		Communities: communitySet,
+		IPSec:       ipsecParams,
+	}, nil
+}
+
+// resolveIPSecParameters sets IPSec parameters of endpoints provided to cluster. Endpoints
+// in store carry IPSec parameters of their own clusters, parameters of the requesting
+// cluster are also considered here, so tunnels get the same parameters on both sides,
+// e.g. the connector of host cluster uses parameters of the member cluster too
+func resolveIPSecParameters(cluster apis.Cluster, endpoints []apis.Endpoint, clusters []apis.Cluster) {
+	for i := range endpoints {
+		endpoint := &endpoints[i]
+		if endpoint.IPSec == nil {
+			endpoint.IPSec = cluster.Spec.IPSec
+			continue
+		}
+
+		if cluster.Spec.IPSec == nil {
+			continue
+		}
+
+		endpoint.IPSec = types.ResolveClusterIPSecParameters(cluster.Name, cluster.Spec.IPSec, getOwnerCluster(endpoint.Name, clusters), endpoint.IPSec)
 	}
+}
 
-	content, _ := json.Marshal(&ea)
+// getOwnerCluster returns the name of the most specific cluster which the endpoint belongs to,
+// the endpoint name is returned if the endpoint doesn't belong to any known cluster. A name of
+// cluster is owned by the cluster itself
+func getOwnerCluster(endpointName string, clusters []apis.Cluster) string {
+	owner := ""
+	for _, cluster := range clusters {
+		if endpointName == cluster.Name {
+			return cluster.Name
+		}
 
-	w.Header().Add("Content-Type", "application/json")
-	w.Write(content)
+		if BelongsToCluster(endpointName, cluster.Name) && len(cluster.Name) > len(owner) {
+			owner = cluster.Name
+		}
+	}
+
+	if owner == "" {
+		return endpointName
+	}
+
+	return owner
 }
 
 func (cfg Config) getCIDRs(w http.ResponseWriter, r *http.Request) {
@@ -298,6 +702,85 @@ func (cfg Config) getCIDRs(w http.ResponseWriter, r *http.Request) {
 	w.Write(content)
 }
 
+// revokeCert revokes the certificate in request body, only certificates
+// issued by trusted CAs and belonging to the requesting cluster can be revoked
+func (cfg Config) revokeCert(w http.ResponseWriter, r *http.Request) {
+	if cfg.Revoker == nil {
+		cfg.response(w, http.StatusNotImplemented, "certificate revocation is not supported")
+		return
+	}
+
+	certPEM, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		cfg.response(w, http.StatusBadRequest, fmt.Sprintf("failed to read request body: %s", err))
+		return
+	}
+
+	certDER, err := certutil.DecodePEM(certPEM)
+	if err != nil {
+		cfg.response(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		cfg.response(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err = cfg.CertManager.VerifyCert(cert, []x509.ExtKeyUsage{x509.ExtKeyUsageAny}); err != nil {
+		cfg.response(w, http.StatusBadRequest, fmt.Sprintf("certificate is not issued by trusted CAs: %s", err))
+		return
+	}
+
+	clusters, err := cfg.listClusters(r.Context())
+	if err != nil {
+		cfg.response(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if clusterName := cfg.getCluster(r); !canRevoke(cert.Subject.CommonName, clusterName, cfg.Cluster, clusters) {
+		cfg.response(w, http.StatusForbidden, fmt.Sprintf("cluster %s is not allowed to revoke certificate of %s", clusterName, cert.Subject.CommonName))
+		return
+	}
+
+	if err = cfg.Revoker.Revoke(r.Context(), cert); err != nil {
+		cfg.Log.Error(err, "failed to revoke certificate", "cluster", cfg.getCluster(r), "serialNumber", cert.SerialNumber)
+		cfg.response(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cfg.Log.V(3).Info("certificate is revoked", "cluster", cfg.getCluster(r), "serialNumber", cert.SerialNumber, "commonName", cert.Subject.CommonName)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// canRevoke checks if a cluster can revoke the certificate with commonName. A cluster can
+// only revoke certificates of its own, except its API client certificate, which would lock
+// it out of API server, and certificates of local cluster, whose name may be nested under it
+// and which may be not registered yet
+func canRevoke(commonName, clusterName, localClusterName string, clusters []apis.Cluster) bool {
+	if !ownsName(commonName, clusterName, clusters) || commonName == ClientCommonName(clusterName) {
+		return false
+	}
+
+	return !BelongsToCluster(commonName, localClusterName) || BelongsToCluster(clusterName, localClusterName)
+}
+
+func (cfg Config) getCRL(w http.ResponseWriter, r *http.Request) {
+	crlPEM, err := crl.GetPEM(r.Context(), cfg.Client, cfg.Namespace)
+	if err != nil {
+		cfg.response(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if len(crlPEM) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Write(crlPEM)
+}
+
 func (cfg Config) response(w http.ResponseWriter, statusCode int, msg string) {
 	w.WriteHeader(statusCode)
 	_, err := w.Write([]byte(msg))
@@ -306,6 +789,8 @@ func (cfg Config) response(w http.ResponseWriter, statusCode int, msg string) {
 	}
 }
 
+// getCluster returns the cluster authenticated by client certificate
 func (cfg Config) getCluster(r *http.Request) string {
-	return r.Header.Get(HeaderClusterName)
+	clusterName, _ := r.Context().Value(keyClusterName).(string)
+	return clusterName
 }
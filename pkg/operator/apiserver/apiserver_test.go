@@ -7,6 +7,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -15,13 +16,16 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2/klogr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/common/constants"
 	"github.com/fabedge/fabedge/pkg/operator/apiserver"
+	"github.com/fabedge/fabedge/pkg/operator/crl"
 	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
 	"github.com/fabedge/fabedge/pkg/operator/types"
 	certutil "github.com/fabedge/fabedge/pkg/util/cert"
@@ -77,6 +81,7 @@ var _ = Describe("APIServer", func() {
 			PublicAddresses: []string{"10.1.1.2"},
 			Subnets:         []string{"2.2.1.65/24"},
 			NodeSubnets:     []string{"10.10.1.2/32"},
+			Type:            apis.Connector,
 		}
 
 		store.SaveEndpoint(rootEndpoint)
@@ -144,19 +149,42 @@ var _ = Describe("APIServer", func() {
 	Context("With token", func() {
 		var clusterToken string
 
-		BeforeEach(func() {
-			token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.StandardClaims{
-				Subject: clusterName,
-			})
+		signToken := func(claims types.ClusterTokenClaims) string {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(privateKey)
+			Expect(err).Should(BeNil())
+			return token
+		}
+
+		saveToken := func(token string) {
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: clusterName}, &cluster)).Should(Succeed())
+			cluster.Spec.Token = token
+			Expect(k8sClient.Update(context.Background(), &cluster)).Should(Succeed())
+		}
 
-			var err error
-			clusterToken, err = token.SignedString(privateKey)
+		newSignCertRequest := func(token string) *http.Request {
+			_, csr, err := certutil.NewCertRequest(certutil.Request{CommonName: apiserver.ClientCommonName(clusterName)})
 			Expect(err).Should(BeNil())
+
+			reqBody := bytes.NewBuffer(certutil.EncodeCertRequestPEM(csr))
+			req, _ := http.NewRequest("POST", apiserver.URLSignCERT, reqBody)
+			req.Header.Add("Authorization", "bearer "+token)
+
+			return req
+		}
+
+		BeforeEach(func() {
+			clusterToken = signToken(types.ClusterTokenClaims{
+				RegisteredClaims: jwt.RegisteredClaims{
+					ID:      "token1",
+					Subject: clusterName,
+				},
+			})
+			saveToken(clusterToken)
 		})
 
 		It("can sign cert for child cluster", func() {
 			keyDER, csr, err := certutil.NewCertRequest(certutil.Request{
-				CommonName:   "test",
+				CommonName:   apiserver.ClientCommonName(clusterName),
 				Organization: []string{"test"},
 			})
 			Expect(err).Should(BeNil())
@@ -166,6 +194,7 @@ var _ = Describe("APIServer", func() {
 			reqBody := bytes.NewBuffer(certutil.EncodeCertRequestPEM(csr))
 			req, _ := http.NewRequest("POST", apiserver.URLSignCERT, reqBody)
 			req.Header.Add("Authorization", "bearer "+clusterToken)
+			req.Header.Add(apiserver.HeaderClusterName, clusterName)
 
 			resp := executeRequest(req, server)
 			Expect(resp.Code).Should(Equal(http.StatusOK))
@@ -180,11 +209,100 @@ var _ = Describe("APIServer", func() {
 			Expect(err).Should(BeNil())
 
 			Expect(cert.IsCA).Should(BeFalse())
-			Expect(cert.Subject.CommonName).Should(Equal("test"))
+			Expect(cert.Subject.CommonName).Should(Equal(apiserver.ClientCommonName(clusterName)))
 			Expect(cert.Subject.Organization).Should(ConsistOf("test"))
 			Expect(cert.PublicKey).Should(Equal(privateKey.Public()))
 			Expect(certManager.VerifyCert(cert, certutil.ExtKeyUsagesServerAndClient)).Should(Succeed())
 		})
+
+		It("should reject token whose signing method doesn't match the key", func() {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+				Subject: clusterName,
+			})
+			hmacToken, err := token.SignedString(x509.MarshalPKCS1PublicKey(&privateKey.PublicKey))
+			Expect(err).Should(BeNil())
+			saveToken(hmacToken)
+
+			resp := executeRequest(newSignCertRequest(hmacToken), server)
+			Expect(resp.Code).Should(Equal(http.StatusUnauthorized))
+		})
+
+		It("should reject token if it's not issued for the cluster in header", func() {
+			req := newSignCertRequest(clusterToken)
+			req.Header.Add(apiserver.HeaderClusterName, "cluster2")
+
+			resp := executeRequest(req, server)
+			Expect(resp.Code).Should(Equal(http.StatusUnauthorized))
+		})
+
+		It("should reject token which is not the token in cluster spec", func() {
+			saveToken(signToken(types.ClusterTokenClaims{
+				RegisteredClaims: jwt.RegisteredClaims{
+					ID:      "token2",
+					Subject: clusterName,
+				},
+			}))
+
+			resp := executeRequest(newSignCertRequest(clusterToken), server)
+			Expect(resp.Code).Should(Equal(http.StatusUnauthorized))
+		})
+
+		It("should accept single-use token only once", func() {
+			token := signToken(types.ClusterTokenClaims{
+				RegisteredClaims: jwt.RegisteredClaims{
+					ID:      "token3",
+					Subject: clusterName,
+				},
+				SingleUse: true,
+			})
+			saveToken(token)
+
+			resp := executeRequest(newSignCertRequest(token), server)
+			Expect(resp.Code).Should(Equal(http.StatusOK))
+
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: clusterName}, &cluster)).Should(Succeed())
+			Expect(cluster.Annotations[constants.KeyUsedTokenID]).Should(Equal("token3"))
+
+			resp = executeRequest(newSignCertRequest(token), server)
+			Expect(resp.Code).Should(Equal(http.StatusUnauthorized))
+		})
+
+		It("should not use up single-use token if certificate is not signed", func() {
+			token := signToken(types.ClusterTokenClaims{
+				RegisteredClaims: jwt.RegisteredClaims{
+					ID:      "token4",
+					Subject: clusterName,
+				},
+				SingleUse: true,
+			})
+			saveToken(token)
+
+			_, csr, err := certutil.NewCertRequest(certutil.Request{CommonName: apiserver.ClientCommonName("cluster2")})
+			Expect(err).Should(BeNil())
+
+			req, _ := http.NewRequest("POST", apiserver.URLSignCERT, bytes.NewBuffer(certutil.EncodeCertRequestPEM(csr)))
+			req.Header.Add("Authorization", "bearer "+token)
+
+			resp := executeRequest(req, server)
+			Expect(resp.Code).Should(Equal(http.StatusForbidden))
+
+			Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: clusterName}, &cluster)).Should(Succeed())
+			Expect(cluster.Annotations[constants.KeyUsedTokenID]).Should(BeEmpty())
+
+			resp = executeRequest(newSignCertRequest(token), server)
+			Expect(resp.Code).Should(Equal(http.StatusOK))
+		})
+
+		It("should not sign cert for other clusters", func() {
+			_, csr, err := certutil.NewCertRequest(certutil.Request{CommonName: apiserver.ClientCommonName("cluster2")})
+			Expect(err).Should(BeNil())
+
+			req, _ := http.NewRequest("POST", apiserver.URLSignCERT, bytes.NewBuffer(certutil.EncodeCertRequestPEM(csr)))
+			req.Header.Add("Authorization", "bearer "+clusterToken)
+
+			resp := executeRequest(req, server)
+			Expect(resp.Code).Should(Equal(http.StatusForbidden))
+		})
 	})
 
 	Context("With client certificate", func() {
@@ -192,7 +310,7 @@ var _ = Describe("APIServer", func() {
 
 		BeforeEach(func() {
 			certDER, _, err := certManager.NewCertKey(certutil.Config{
-				CommonName:     "client",
+				CommonName:     apiserver.ClientCommonName(clusterName),
 				Organization:   []string{certutil.DefaultOrganization},
 				ValidityPeriod: time.Hour,
 			})
@@ -223,13 +341,96 @@ var _ = Describe("APIServer", func() {
 			Expect(ea.Communities[community.Name]).Should(ConsistOf(rootConnector.Name, childConnector.Name))
 		})
 
+		It("should resolve IPSec parameters of endpoints with those of requesting cluster", func() {
+			gcm := &apis.IPSecParameters{ESPProposals: []string{"aes256gcm16-ecp384"}}
+			cbc := &apis.IPSecParameters{ESPProposals: []string{"aes256-sha256-modp2048"}}
+
+			cluster.Spec.IPSec = gcm
+			Expect(k8sClient.Update(context.Background(), &cluster)).Should(Succeed())
+
+			// endpoints of other member clusters carry IPSec parameters of their clusters
+			cluster3 := apis.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster3"},
+				Spec:       apis.ClusterSpec{IPSec: cbc},
+			}
+			Expect(k8sClient.Create(context.Background(), &cluster3)).Should(Succeed())
+			defer k8sClient.Delete(context.Background(), &cluster3)
+
+			otherConnector := apis.Endpoint{
+				Name:            "cluster3.connector",
+				PublicAddresses: []string{"10.1.3.2"},
+				Subnets:         []string{"2.2.3.0/24"},
+				NodeSubnets:     []string{"10.10.3.2/32"},
+				Type:            apis.Connector,
+				IPSec:           cbc,
+			}
+			store.SaveEndpoint(otherConnector)
+			store.SaveCommunity(types.Community{
+				Name:    community.Name,
+				Members: sets.NewString(childConnector.Name, rootConnector.Name, otherConnector.Name),
+			})
+
+			req, _ := http.NewRequest("GET", apiserver.URLGetEndpointsAndCommunities, nil)
+			req.TLS = connectionState
+
+			resp := executeRequest(req, server)
+			Expect(resp.Code).Should(Equal(http.StatusOK))
+
+			var ea apiserver.EndpointsAndCommunity
+			Expect(json.Unmarshal(resp.Body.Bytes(), &ea)).Should(Succeed())
+
+			params := make(map[string]*apis.IPSecParameters)
+			for _, endpoint := range ea.Endpoints {
+				params[endpoint.Name] = endpoint.IPSec
+			}
+			// the connector of host cluster has no parameters, it uses those of cluster1
+			// which are also used by itself for tunnels to cluster1
+			Expect(params[rootConnector.Name]).Should(Equal(gcm))
+			// both clusters have parameters, those of cluster1 are used because its name is smaller
+			Expect(params[otherConnector.Name]).Should(Equal(gcm))
+			// endpoints in store are not changed
+			Expect(store.GetEndpoints(rootConnector.Name)[0].IPSec).Should(BeNil())
+		})
+
+		It("should skip endpoints of requesting cluster and its child clusters only", func() {
+			nestedConnector := apis.Endpoint{
+				Name:            "cluster1.beijing.connector",
+				PublicAddresses: []string{"10.1.2.2"},
+				Subnets:         []string{"2.2.2.0/24"},
+				NodeSubnets:     []string{"10.10.2.2/32"},
+				Type:            apis.Connector,
+			}
+			similarConnector := apis.Endpoint{
+				Name:            "cluster10.connector",
+				PublicAddresses: []string{"10.1.3.2"},
+				Subnets:         []string{"2.2.3.0/24"},
+				NodeSubnets:     []string{"10.10.3.2/32"},
+				Type:            apis.Connector,
+			}
+			store.SaveEndpoint(nestedConnector)
+			store.SaveEndpoint(similarConnector)
+			store.SaveCommunity(types.Community{
+				Name:    community.Name,
+				Members: sets.NewString(childConnector.Name, rootConnector.Name, nestedConnector.Name, similarConnector.Name),
+			})
+
+			req, _ := http.NewRequest("GET", apiserver.URLGetEndpointsAndCommunities, nil)
+			req.TLS = connectionState
+
+			resp := executeRequest(req, server)
+			Expect(resp.Code).Should(Equal(http.StatusOK))
+
+			var ea apiserver.EndpointsAndCommunity
+			Expect(json.Unmarshal(resp.Body.Bytes(), &ea)).Should(Succeed())
+			Expect(ea.Endpoints).Should(ConsistOf(rootConnector, similarConnector))
+		})
+
 		It("can provide cluster CIDRs", func() {
 			clusterName, cidrs := "beijing", []string{"192.168.0.0/18"}
 			cidrMap.Set(clusterName, cidrs)
 
 			req, _ := http.NewRequest("GET", apiserver.URLGetCIDRs, nil)
 			req.TLS = connectionState
-			req.Header.Add(apiserver.HeaderClusterName, clusterName)
 
 			resp := executeRequest(req, server)
 			Expect(resp.Code).Should(Equal(http.StatusOK))
@@ -243,6 +444,52 @@ var _ = Describe("APIServer", func() {
 			Expect(cidrMap2).To(HaveKeyWithValue(clusterName, cidrs))
 		})
 
+		It("can stream endpoints, communities and CIDRs when they're changed", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			req, _ := http.NewRequestWithContext(ctx, "GET", apiserver.URLWatch, nil)
+			req.TLS = connectionState
+
+			reader, writer := io.Pipe()
+			go func() {
+				server.Handler.ServeHTTP(&streamRecorder{header: http.Header{}, PipeWriter: writer}, req)
+				writer.Close()
+			}()
+			decoder := json.NewDecoder(reader)
+
+			var event apiserver.WatchEvent
+			Expect(decoder.Decode(&event)).Should(Succeed())
+			Expect(event.Type).Should(Equal(apiserver.WatchEventChanged))
+			Expect(event.EndpointsAndCommunity.Endpoints).Should(ConsistOf(rootConnector))
+			Expect(event.EndpointsAndCommunity.Communities[community.Name]).Should(ConsistOf(rootConnector.Name, childConnector.Name))
+			Expect(event.CIDRs).Should(BeEmpty())
+			version := event.ResourceVersion
+
+			By("changing CIDRs")
+			cidrMap.Set("beijing", []string{"192.168.0.0/18"})
+
+			event = apiserver.WatchEvent{}
+			Expect(decoder.Decode(&event)).Should(Succeed())
+			Expect(event.Type).Should(Equal(apiserver.WatchEventChanged))
+			Expect(event.ResourceVersion).Should(BeNumerically(">", version))
+			Expect(event.CIDRs).Should(HaveKeyWithValue("beijing", []string{"192.168.0.0/18"}))
+			version = event.ResourceVersion
+
+			By("changing endpoints")
+			rootConnector.PublicAddresses = []string{"10.40.1.2"}
+			store.SaveEndpoint(rootConnector)
+
+			event = apiserver.WatchEvent{}
+			Expect(decoder.Decode(&event)).Should(Succeed())
+			Expect(event.ResourceVersion).Should(BeNumerically(">", version))
+			Expect(event.EndpointsAndCommunity.Endpoints).Should(ConsistOf(rootConnector))
+
+			By("stopping watch")
+			cancel()
+			Expect(decoder.Decode(&event)).Should(Equal(io.EOF))
+		})
+
 		It("can update cluster info of requesting cluster", func() {
 			requestCluster := apis.Cluster{
 				ObjectMeta: metav1.ObjectMeta{
@@ -254,6 +501,9 @@ var _ = Describe("APIServer", func() {
 						childConnector,
 					},
 				},
+				Status: apis.ClusterStatus{
+					Version: "v0.8.0",
+				},
 			}
 
 			clusterJson, err := json.Marshal(requestCluster)
@@ -271,6 +521,47 @@ var _ = Describe("APIServer", func() {
 			Expect(err).Should(BeNil())
 			Expect(cluster.Spec.CIDRs).Should(Equal(requestCluster.Spec.CIDRs))
 			Expect(cluster.Spec.EndPoints).Should(ConsistOf(childConnector))
+
+			Expect(cluster.Status.LastHeartbeatTime).ShouldNot(BeNil())
+			Expect(cluster.Status.CIDRs).Should(Equal(requestCluster.Spec.CIDRs))
+			Expect(cluster.Status.Endpoints).Should(ConsistOf(childConnector))
+			Expect(cluster.Status.Version).Should(Equal("v0.8.0"))
+		})
+
+		It("should refuse CIDRs which overlap with CIDRs of other clusters but save other info", func() {
+			cidrMap.Set(clusterName, clusterCIDRs)
+			cidrMap.Set("cluster2", []string{"2.2.0.0/16"})
+
+			requestCluster := apis.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: clusterName,
+				},
+				Spec: apis.ClusterSpec{
+					CIDRs: []string{"2.2.0.0/17"},
+					EndPoints: []apis.Endpoint{
+						childConnector,
+					},
+				},
+			}
+
+			clusterJson, err := json.Marshal(requestCluster)
+			Expect(err).Should(BeNil())
+
+			req, _ := http.NewRequest("PUT", apiserver.URLUpdateCluster, bytes.NewBuffer(clusterJson))
+			req.TLS = connectionState
+			req.Header.Add(apiserver.HeaderClusterName, clusterName)
+
+			resp := executeRequest(req, server)
+			Expect(resp.Code).Should(Equal(http.StatusConflict))
+			Expect(resp.Body.String()).Should(ContainSubstring("2.2.0.0/17 overlaps with 2.2.0.0/16 of cluster cluster2"))
+
+			err = k8sClient.Get(context.Background(), client.ObjectKey{Name: clusterName}, &cluster)
+			Expect(err).Should(BeNil())
+			Expect(cluster.Spec.CIDRs).Should(Equal(clusterCIDRs))
+			Expect(cluster.Spec.EndPoints).Should(ConsistOf(childConnector))
+
+			Expect(cluster.Status.LastHeartbeatTime).ShouldNot(BeNil())
+			Expect(cluster.Status.CIDRs).Should(Equal(requestCluster.Spec.CIDRs))
 		})
 
 		It("can update endpoints of requesting cluster", func() {
@@ -294,11 +585,13 @@ var _ = Describe("APIServer", func() {
 
 			Expect(cluster.Spec.CIDRs).Should(Equal(clusterCIDRs))
 			Expect(cluster.Spec.EndPoints).Should(ConsistOf(childConnector))
+			Expect(cluster.Status.LastHeartbeatTime).ShouldNot(BeNil())
+			Expect(cluster.Status.Endpoints).Should(ConsistOf(childConnector))
 		})
 
 		It("can sign cert for child cluster", func() {
 			_, csr, err := certutil.NewCertRequest(certutil.Request{
-				CommonName:   "test",
+				CommonName:   childEndpoint.Name,
 				Organization: []string{"test"},
 			})
 			Expect(err).Should(BeNil())
@@ -320,6 +613,159 @@ var _ = Describe("APIServer", func() {
 			Expect(err).Should(BeNil())
 			Expect(certManager.VerifyCert(cert, certutil.ExtKeyUsagesServerAndClient)).Should(Succeed())
 		})
+
+		It("should reject requests whose cluster name in header doesn't match the certificate", func() {
+			req, _ := http.NewRequest("GET", apiserver.URLGetEndpointsAndCommunities, nil)
+			req.TLS = connectionState
+			req.Header.Add(apiserver.HeaderClusterName, "cluster2")
+
+			resp := executeRequest(req, server)
+			Expect(resp.Code).Should(Equal(http.StatusForbidden))
+		})
+
+		It("should reject requests with certificates which are not issued for clusters", func() {
+			certDER, _, err := certManager.NewCertKey(certutil.Config{
+				CommonName:     childConnector.Name,
+				Organization:   []string{certutil.DefaultOrganization},
+				ValidityPeriod: time.Hour,
+			})
+			Expect(err).Should(BeNil())
+
+			cert, err := x509.ParseCertificate(certDER)
+			Expect(err).Should(BeNil())
+
+			req, _ := http.NewRequest("GET", apiserver.URLGetEndpointsAndCommunities, nil)
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+
+			resp := executeRequest(req, server)
+			Expect(resp.Code).Should(Equal(http.StatusForbidden))
+		})
+
+		It("should not update endpoints of other clusters", func() {
+			endpointsJson, err := json.Marshal([]apis.Endpoint{rootConnector})
+			Expect(err).Should(BeNil())
+
+			req, _ := http.NewRequest("PUT", apiserver.URLUpdateEndpoints, bytes.NewBuffer(endpointsJson))
+			req.TLS = connectionState
+
+			resp := executeRequest(req, server)
+			Expect(resp.Code).Should(Equal(http.StatusForbidden))
+		})
+
+		It("should not sign cert for other clusters", func() {
+			_, csr, err := certutil.NewCertRequest(certutil.Request{CommonName: rootConnector.Name})
+			Expect(err).Should(BeNil())
+
+			req, _ := http.NewRequest("POST", apiserver.URLSignCERT, bytes.NewBuffer(certutil.EncodeCertRequestPEM(csr)))
+			req.TLS = connectionState
+
+			resp := executeRequest(req, server)
+			Expect(resp.Code).Should(Equal(http.StatusForbidden))
+		})
+
+		It("should not act for a registered cluster whose name is nested under its name", func() {
+			nestedCluster := apis.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: clusterName + ".nested",
+				},
+			}
+			Expect(k8sClient.Create(context.Background(), &nestedCluster)).Should(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(context.Background(), &nestedCluster)).Should(Succeed())
+			}()
+
+			nestedConnector := apis.Endpoint{
+				ID:   nestedCluster.Name + ".connector",
+				Name: nestedCluster.Name + ".connector",
+				Type: apis.Connector,
+			}
+			endpointsJson, err := json.Marshal([]apis.Endpoint{childConnector, nestedConnector})
+			Expect(err).Should(BeNil())
+
+			req, _ := http.NewRequest("PUT", apiserver.URLUpdateEndpoints, bytes.NewBuffer(endpointsJson))
+			req.TLS = connectionState
+
+			resp := executeRequest(req, server)
+			Expect(resp.Code).Should(Equal(http.StatusForbidden))
+
+			_, csr, err := certutil.NewCertRequest(certutil.Request{CommonName: nestedConnector.Name})
+			Expect(err).Should(BeNil())
+
+			req, _ = http.NewRequest("POST", apiserver.URLSignCERT, bytes.NewBuffer(certutil.EncodeCertRequestPEM(csr)))
+			req.TLS = connectionState
+
+			resp = executeRequest(req, server)
+			Expect(resp.Code).Should(Equal(http.StatusForbidden))
+		})
+
+		It("can revoke certificates of its own and reject requests with revoked certificates", func() {
+			namespace := "default"
+			revoker := &crl.LocalRevoker{
+				Namespace: namespace,
+				Signer:    certManager.(certutil.CRLSigner),
+				Client:    k8sClient,
+			}
+			server, err := apiserver.New(apiserver.Config{
+				Addr:        "localhost:8080",
+				CertManager: certManager,
+				Client:      k8sClient,
+				Store:       store,
+				CIDRMap:     cidrMap,
+				Namespace:   namespace,
+				// the name of local cluster is nested under the name of requesting cluster
+				Cluster: clusterName + ".local",
+				Revoker: revoker,
+				RevocationChecker: &crl.ConfigMapChecker{
+					Namespace: namespace,
+					Client:    k8sClient,
+				},
+				Log: klogr.New(),
+			})
+			Expect(err).Should(BeNil())
+
+			revokeCert := func(commonName string) *httptest.ResponseRecorder {
+				certDER, _, err := certManager.NewCertKey(certutil.Config{
+					CommonName:     commonName,
+					Usages:         certutil.ExtKeyUsagesServerAndClient,
+					ValidityPeriod: time.Hour,
+				})
+				Expect(err).Should(BeNil())
+
+				req, _ := http.NewRequest("POST", apiserver.URLRevokeCert, bytes.NewReader(certutil.EncodeCertPEM(certDER)))
+				req.TLS = connectionState
+				return executeRequest(req, server)
+			}
+
+			By("revoking certificate of its own edge node")
+			Expect(revokeCert(childEndpoint.Name).Code).Should(Equal(http.StatusNoContent))
+
+			list, err := crl.Get(context.Background(), k8sClient, namespace)
+			Expect(err).Should(BeNil())
+			Expect(list.TBSCertList.RevokedCertificates).Should(HaveLen(1))
+
+			By("revoking certificates of other clusters, local cluster and its API client certificate")
+			Expect(revokeCert(rootEndpoint.Name).Code).Should(Equal(http.StatusForbidden))
+			Expect(revokeCert(clusterName + ".local.connector").Code).Should(Equal(http.StatusForbidden))
+			Expect(revokeCert(apiserver.ClientCommonName(clusterName)).Code).Should(Equal(http.StatusForbidden))
+
+			list, err = crl.Get(context.Background(), k8sClient, namespace)
+			Expect(err).Should(BeNil())
+			Expect(list.TBSCertList.RevokedCertificates).Should(HaveLen(1))
+
+			By("requesting with a revoked client certificate")
+			Expect(revoker.Revoke(context.Background(), connectionState.PeerCertificates[0])).Should(Succeed())
+
+			req, _ := http.NewRequest("GET", apiserver.URLGetCIDRs, nil)
+			req.TLS = connectionState
+			req.Header.Add(apiserver.HeaderClusterName, clusterName)
+
+			resp := executeRequest(req, server)
+			Expect(resp.Code).Should(Equal(http.StatusUnauthorized))
+
+			Expect(k8sClient.Delete(context.Background(), &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: crl.ConfigMapName, Namespace: namespace},
+			})).Should(Succeed())
+		})
 	})
 
 	Context("Without token or client certificate", func() {
@@ -401,3 +847,17 @@ func executeRequest(req *http.Request, s *http.Server) *httptest.ResponseRecorde
 
 	return rr
 }
+
+// streamRecorder writes response into a pipe, so response can be read while it's being written
+type streamRecorder struct {
+	*io.PipeWriter
+	header http.Header
+}
+
+func (r *streamRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *streamRecorder) WriteHeader(int) {}
+
+func (r *streamRecorder) Flush() {}
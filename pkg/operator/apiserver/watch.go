@@ -0,0 +1,135 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+type WatchEventType string
+
+const (
+	// WatchEventChanged carries endpoints, communities and CIDRs needed by a cluster, it's sent
+	// when a watch is started and whenever any of them is changed
+	WatchEventChanged WatchEventType = "Changed"
+	// WatchEventHeartbeat carries only resource version, it's sent periodically to keep
+	// connection alive, so clients can tell a broken watch from a quiet one
+	WatchEventHeartbeat WatchEventType = "Heartbeat"
+
+	// WatchHeartbeatInterval is how often heartbeat events are sent
+	WatchHeartbeatInterval = 30 * time.Second
+)
+
+// WatchEvent is sent by watch API, events are encoded as JSON objects separated by newlines
+type WatchEvent struct {
+	Type WatchEventType `json:"type"`
+	// ResourceVersion increases when endpoints, communities or CIDRs in host cluster
+	// are changed, it's reset when fabedge-operator of host cluster restarts
+	ResourceVersion       uint64                 `json:"resourceVersion"`
+	EndpointsAndCommunity *EndpointsAndCommunity `json:"endpointsAndCommunity,omitempty"`
+	CIDRs                 map[string][]string    `json:"cidrs,omitempty"`
+}
+
+// watch streams endpoints, communities and CIDRs needed by the requesting cluster. The current
+// data is sent first, then data is sent again only if it's changed for this cluster
+func (cfg Config) watch(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		cfg.response(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	ctx, clusterName := r.Context(), cfg.getCluster(r)
+
+	// channels are got before data is read, so changes during reading won't be missed
+	storeChanged, cidrsChanged := cfg.Store.Changed(), cfg.CIDRMap.Changed()
+	last, err := cfg.buildWatchEvent(ctx, clusterName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			cfg.response(w, http.StatusNotFound, fmt.Sprintf("unknown cluster %s", clusterName))
+			return
+		}
+
+		cfg.response(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	send := func(event WatchEvent) bool {
+		if err := encoder.Encode(event); err != nil {
+			cfg.Log.V(5).Info("watch is stopped", "cluster", clusterName, "error", err)
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !send(last) {
+		return
+	}
+
+	heartbeat := time.NewTicker(WatchHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if !send(WatchEvent{Type: WatchEventHeartbeat, ResourceVersion: cfg.resourceVersion()}) {
+				return
+			}
+			continue
+		case <-storeChanged:
+		case <-cidrsChanged:
+		}
+
+		storeChanged, cidrsChanged = cfg.Store.Changed(), cfg.CIDRMap.Changed()
+		event, err := cfg.buildWatchEvent(ctx, clusterName)
+		if err != nil {
+			// the client will watch again or fall back to polling
+			cfg.Log.Error(err, "failed to build watch event", "cluster", clusterName)
+			return
+		}
+
+		// changes of other clusters may not matter to this cluster
+		if reflect.DeepEqual(event.EndpointsAndCommunity, last.EndpointsAndCommunity) &&
+			reflect.DeepEqual(event.CIDRs, last.CIDRs) {
+			continue
+		}
+
+		if !send(event) {
+			return
+		}
+		last = event
+	}
+}
+
+func (cfg Config) buildWatchEvent(ctx context.Context, clusterName string) (WatchEvent, error) {
+	// version is read before data, so data is at least as new as the version
+	version := cfg.resourceVersion()
+
+	ea, err := cfg.buildEndpointsAndCommunity(ctx, clusterName)
+	if err != nil {
+		return WatchEvent{}, err
+	}
+
+	return WatchEvent{
+		Type:                  WatchEventChanged,
+		ResourceVersion:       version,
+		EndpointsAndCommunity: &ea,
+		CIDRs:                 cfg.CIDRMap.GetCopy(),
+	}, nil
+}
+
+// resourceVersion combines versions of store and CIDR map, both of them only increase,
+// so does their sum
+func (cfg Config) resourceVersion() uint64 {
+	return cfg.Store.Version() + cfg.CIDRMap.Version()
+}
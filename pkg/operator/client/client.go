@@ -2,9 +2,11 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -17,7 +19,11 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-const defaultTimeout = 5 * time.Second
+const (
+	defaultTimeout = 5 * time.Second
+	// a watch is considered broken if nothing, not even a heartbeat, is received for this long
+	watchIdleTimeout = 3 * apiserver.WatchHeartbeatInterval
+)
 
 type Interface interface {
 	GetEndpointsAndCommunities() (apiserver.EndpointsAndCommunity, error)
@@ -25,12 +31,25 @@ type Interface interface {
 	UpdateEndpoints(endpoints []apis.Endpoint) error
 	SignCert(csr []byte) (Certificate, error)
 	GetClusterCIDRs() (map[string][]string, error)
+	// GetCACerts returns PEM data of CA certs trusted by host cluster
+	GetCACerts() ([]byte, error)
+	// RevokeCert revokes a certificate issued by host cluster, the certificate is in PEM form
+	RevokeCert(certPEM []byte) error
+	// GetCRL returns CRL of host cluster in PEM form, nil is returned if there is no CRL
+	GetCRL() ([]byte, error)
+	// Watch calls handler with endpoints, communities and CIDRs from host cluster when they're
+	// changed. If the watch API is unavailable, e.g. host cluster runs an old version, or the
+	// stream is broken, data is polled once and watch is retried after pollInterval.
+	// Errors are passed to onError if it's not nil. Watch returns only when ctx is done
+	Watch(ctx context.Context, pollInterval time.Duration, handler func(apiserver.WatchEvent), onError func(error))
 }
 
 type client struct {
 	clusterName string
 	baseURL     *url.URL
 	client      *http.Client
+	// streamClient has no timeout, it's used by long-lived requests
+	streamClient *http.Client
 }
 
 type Certificate struct {
@@ -52,6 +71,9 @@ func NewClient(apiServerAddr string, clusterName string, transport http.RoundTri
 			Timeout:   defaultTimeout,
 			Transport: transport,
 		},
+		streamClient: &http.Client{
+			Transport: transport,
+		},
 	}, nil
 }
 
@@ -81,6 +103,9 @@ func (c *client) UpdateCluster(cluster apis.Cluster) error {
 			CIDRs:     cluster.Spec.CIDRs,
 			EndPoints: cluster.Spec.EndPoints,
 		},
+		Status: apis.ClusterStatus{
+			Version: cluster.Status.Version,
+		},
 	}
 
 	data, err := json.Marshal(cluster)
@@ -163,6 +188,140 @@ func (c *client) GetClusterCIDRs() (cidrMap map[string][]string, err error) {
 	return cidrMap, err
 }
 
+func (c *client) GetCACerts() ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, join(c.baseURL, apiserver.URLGetCA), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(apiserver.HeaderClusterName, c.clusterName)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return handleResponse(resp)
+}
+
+func (c *client) RevokeCert(certPEM []byte) error {
+	req, err := http.NewRequest(http.MethodPost, join(c.baseURL, apiserver.URLRevokeCert), bytes.NewReader(certPEM))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set(apiserver.HeaderClusterName, c.clusterName)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	_, err = handleResponse(resp)
+	return err
+}
+
+func (c *client) GetCRL() ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, join(c.baseURL, apiserver.URLGetCRL), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(apiserver.HeaderClusterName, c.clusterName)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return handleResponse(resp)
+}
+
+func (c *client) Watch(ctx context.Context, pollInterval time.Duration, handler func(apiserver.WatchEvent), onError func(error)) {
+	if onError == nil {
+		onError = func(error) {}
+	}
+
+	for {
+		err := c.watch(ctx, handler)
+		if ctx.Err() != nil {
+			return
+		}
+		onError(err)
+
+		event, err := c.poll()
+		if err != nil {
+			onError(err)
+		} else {
+			handler(event)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// watch handles events from watch API until the stream is broken or ctx is done
+func (c *client) watch(ctx context.Context, handler func(apiserver.WatchEvent)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, join(c.baseURL, apiserver.URLWatch), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(apiserver.HeaderClusterName, c.clusterName)
+
+	idleTimer := time.AfterFunc(watchIdleTimeout, cancel)
+	defer idleTimer.Stop()
+
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if _, err = handleResponse(resp); err != nil {
+			return err
+		}
+		return fmt.Errorf("unexpected status code of watch: %d", resp.StatusCode)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event apiserver.WatchEvent
+		if err = decoder.Decode(&event); err != nil {
+			return fmt.Errorf("watch is broken: %w", err)
+		}
+		idleTimer.Reset(watchIdleTimeout)
+
+		if event.Type == apiserver.WatchEventChanged {
+			handler(event)
+		}
+	}
+}
+
+// poll gets endpoints, communities and CIDRs by non-streaming APIs and makes an event of them
+func (c *client) poll() (apiserver.WatchEvent, error) {
+	ea, err := c.GetEndpointsAndCommunities()
+	if err != nil {
+		return apiserver.WatchEvent{}, err
+	}
+
+	cidrs, err := c.GetClusterCIDRs()
+	if err != nil {
+		return apiserver.WatchEvent{}, err
+	}
+
+	return apiserver.WatchEvent{
+		Type:                  apiserver.WatchEventChanged,
+		EndpointsAndCommunity: &ea,
+		CIDRs:                 cidrs,
+	}, nil
+}
+
 func GetCertificate(apiServerAddr string) (cert Certificate, err error) {
 	baseURL, err := url.Parse(apiServerAddr)
 	if err != nil {
@@ -186,7 +345,9 @@ func GetCertificate(apiServerAddr string) (cert Certificate, err error) {
 	return readCertFromResponse(resp)
 }
 
-func SignCertByToken(apiServerAddr string, token string, csr []byte, certPool *x509.CertPool) (cert Certificate, err error) {
+// SignCertByToken requests a certificate with the join token of cluster, the token is
+// only accepted if it's issued for clusterName
+func SignCertByToken(apiServerAddr, clusterName, token string, csr []byte, certPool *x509.CertPool) (cert Certificate, err error) {
 	baseURL, err := url.Parse(apiServerAddr)
 	if err != nil {
 		return cert, err
@@ -206,6 +367,7 @@ func SignCertByToken(apiServerAddr string, token string, csr []byte, certPool *x
 		return cert, err
 	}
 	req.Header.Set(apiserver.HeaderAuthorization, "bearer "+token)
+	req.Header.Set(apiserver.HeaderClusterName, clusterName)
 	req.Header.Set("Content-Type", "text/html")
 
 	resp, err := cli.Do(req)
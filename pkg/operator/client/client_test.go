@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"crypto/x509"
 	"encoding/json"
 	"io/ioutil"
@@ -47,11 +48,12 @@ func TestSignCertByToken(t *testing.T) {
 	defer teardown()
 
 	var requestContent []byte
-	var receivedToken string
+	var receivedToken, receivedClusterName string
 	var req *http.Request
 	mux.HandleFunc(apiserver.URLSignCERT, func(w http.ResponseWriter, r *http.Request) {
 		req = r
 		receivedToken = r.Header.Get(apiserver.HeaderAuthorization)[7:]
+		receivedClusterName = r.Header.Get(apiserver.HeaderClusterName)
 		requestContent, _ = ioutil.ReadAll(r.Body)
 
 		csr, _ := certutil.DecodePEM(requestContent)
@@ -66,13 +68,14 @@ func TestSignCertByToken(t *testing.T) {
 	csrPEM := certutil.EncodeCertRequestPEM(csr)
 
 	token := "123456"
-	cert, err := SignCertByToken(url, token, csr, certPool)
+	cert, err := SignCertByToken(url, "beijing", token, csr, certPool)
 
 	g.Expect(err).Should(BeNil())
 	g.Expect(req.Method).Should(Equal(http.MethodPost))
 	g.Expect(cert.Raw.Subject.CommonName).Should(Equal("test"))
 	g.Expect(cert.Raw.PublicKey).Should(Equal(privateKey.Public()))
 	g.Expect(receivedToken).Should(Equal(token))
+	g.Expect(receivedClusterName).Should(Equal("beijing"))
 	g.Expect(requestContent).Should(Equal(csrPEM))
 }
 
@@ -263,6 +266,192 @@ func TestClient_GetClusterCIDRs(t *testing.T) {
 	g.Expect(req.Header.Get(apiserver.HeaderClusterName)).Should(Equal(clusterName))
 }
 
+func TestClient_GetCACerts(t *testing.T) {
+	g := NewGomegaWithT(t)
+	mux, url, teardown := newServer()
+	defer teardown()
+
+	certManager, _ := newCertManager()
+	var req *http.Request
+	mux.HandleFunc(apiserver.URLGetCA, func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		w.Write(certManager.GetCABundlePEM())
+	})
+
+	cli, err := NewClient(url, clusterName, NewTLSTransport(nil))
+	g.Expect(err).Should(BeNil())
+
+	bundlePEM, err := cli.GetCACerts()
+	g.Expect(err).Should(BeNil())
+	g.Expect(bundlePEM).Should(Equal(certManager.GetCABundlePEM()))
+	g.Expect(req.Method).Should(Equal(http.MethodGet))
+	g.Expect(req.Header.Get(apiserver.HeaderClusterName)).Should(Equal(clusterName))
+}
+
+func TestClient_RevokeCert(t *testing.T) {
+	g := NewGomegaWithT(t)
+	mux, url, teardown := newServer()
+	defer teardown()
+
+	var req *http.Request
+	var requestContent []byte
+	mux.HandleFunc(apiserver.URLRevokeCert, func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		requestContent, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	cli, err := NewClient(url, clusterName, nil)
+	g.Expect(err).Should(BeNil())
+
+	certManager, _ := newCertManager()
+	g.Expect(cli.RevokeCert(certManager.GetCACertPEM())).Should(Succeed())
+	g.Expect(req.Method).Should(Equal(http.MethodPost))
+	g.Expect(req.Header.Get(apiserver.HeaderClusterName)).Should(Equal(clusterName))
+	g.Expect(requestContent).Should(Equal(certManager.GetCACertPEM()))
+}
+
+func TestClient_GetCRL(t *testing.T) {
+	g := NewGomegaWithT(t)
+	mux, url, teardown := newServer()
+	defer teardown()
+
+	crlPEM := []byte("-----BEGIN X509 CRL-----\n-----END X509 CRL-----\n")
+	mux.HandleFunc(apiserver.URLGetCRL, func(w http.ResponseWriter, r *http.Request) {
+		if crlPEM == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write(crlPEM)
+	})
+
+	cli, err := NewClient(url, clusterName, nil)
+	g.Expect(err).Should(BeNil())
+
+	content, err := cli.GetCRL()
+	g.Expect(err).Should(BeNil())
+	g.Expect(content).Should(Equal(crlPEM))
+
+	crlPEM = nil
+	content, err = cli.GetCRL()
+	g.Expect(err).Should(BeNil())
+	g.Expect(content).Should(BeNil())
+}
+
+func TestClient_Watch(t *testing.T) {
+	g := NewGomegaWithT(t)
+	mux, url, teardown := newServer()
+	defer teardown()
+
+	events := []apiserver.WatchEvent{
+		{
+			Type:            apiserver.WatchEventChanged,
+			ResourceVersion: 1,
+			EndpointsAndCommunity: &apiserver.EndpointsAndCommunity{
+				Communities: map[string][]string{"connectors": {"cluster1.connector"}},
+			},
+			CIDRs: map[string][]string{"cluster1": {"192.168.0.0/18"}},
+		},
+		{
+			Type:            apiserver.WatchEventHeartbeat,
+			ResourceVersion: 1,
+		},
+		{
+			Type:            apiserver.WatchEventChanged,
+			ResourceVersion: 3,
+			EndpointsAndCommunity: &apiserver.EndpointsAndCommunity{
+				Communities: map[string][]string{"connectors": {"cluster1.connector", "cluster2.connector"}},
+			},
+			CIDRs: map[string][]string{"cluster1": {"192.168.0.0/18"}, "cluster2": {"192.168.64.0/18"}},
+		},
+	}
+
+	reqs := make(chan *http.Request, 10)
+	mux.HandleFunc(apiserver.URLWatch, func(w http.ResponseWriter, r *http.Request) {
+		reqs <- r
+		encoder := json.NewEncoder(w)
+		for _, event := range events {
+			_ = encoder.Encode(event)
+			w.(http.Flusher).Flush()
+		}
+		<-r.Context().Done()
+	})
+
+	cli, err := NewClient(url, clusterName, nil)
+	g.Expect(err).Should(BeNil())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan apiserver.WatchEvent, 10)
+	done := make(chan struct{})
+	go func() {
+		cli.Watch(ctx, time.Hour, func(event apiserver.WatchEvent) {
+			received <- event
+		}, nil)
+		close(done)
+	}()
+
+	g.Eventually(received).Should(Receive(Equal(events[0])))
+	g.Eventually(received).Should(Receive(Equal(events[2])))
+	g.Consistently(received, 100*time.Millisecond).ShouldNot(Receive())
+
+	req := <-reqs
+	g.Expect(req.Method).Should(Equal(http.MethodGet))
+	g.Expect(req.Header.Get(apiserver.HeaderClusterName)).Should(Equal(clusterName))
+
+	cancel()
+	g.Eventually(done).Should(BeClosed())
+}
+
+func TestClient_WatchFallsBackToPolling(t *testing.T) {
+	g := NewGomegaWithT(t)
+	mux, url, teardown := newServer()
+	defer teardown()
+
+	expectedEA := apiserver.EndpointsAndCommunity{
+		Communities: map[string][]string{"connectors": {"cluster1.connector"}},
+	}
+	expectedCIDRMap := map[string][]string{"cluster1": {"192.168.0.0/18"}}
+
+	// the watch API is not registered, just like an old host cluster
+	mux.HandleFunc(apiserver.URLGetEndpointsAndCommunities, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := json.Marshal(expectedEA)
+		w.Write(data)
+	})
+	mux.HandleFunc(apiserver.URLGetCIDRs, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := json.Marshal(expectedCIDRMap)
+		w.Write(data)
+	})
+
+	cli, err := NewClient(url, clusterName, nil)
+	g.Expect(err).Should(BeNil())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan apiserver.WatchEvent, 10)
+	errs := make(chan error, 10)
+	go cli.Watch(ctx, 10*time.Millisecond, func(event apiserver.WatchEvent) {
+		received <- event
+	}, func(err error) {
+		errs <- err
+	})
+
+	expectedEvent := apiserver.WatchEvent{
+		Type:                  apiserver.WatchEventChanged,
+		EndpointsAndCommunity: &expectedEA,
+		CIDRs:                 expectedCIDRMap,
+	}
+	g.Eventually(received).Should(Receive(Equal(expectedEvent)))
+	// polling is repeated
+	g.Eventually(received).Should(Receive(Equal(expectedEvent)))
+
+	var err2 error
+	g.Eventually(errs).Should(Receive(&err2))
+	httpErr, ok := err2.(*HttpError)
+	g.Expect(ok).Should(BeTrue())
+	g.Expect(httpErr.Response.StatusCode).Should(Equal(http.StatusNotFound))
+}
+
 func newServer() (mux *http.ServeMux, url string, close func()) {
 	mux = http.NewServeMux()
 	server := httptest.NewServer(mux)
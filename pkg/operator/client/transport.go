@@ -0,0 +1,45 @@
+package client
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+)
+
+var _ http.RoundTripper = &TLSTransport{}
+
+// TLSTransport is a http.RoundTripper whose TLS config can be replaced at runtime,
+// member clusters use it to switch client certificate and trusted CAs when CA is rotated
+type TLSTransport struct {
+	mu        sync.RWMutex
+	transport *http.Transport
+}
+
+func NewTLSTransport(cfg *tls.Config) *TLSTransport {
+	return &TLSTransport{
+		transport: &http.Transport{
+			TLSClientConfig: cfg,
+		},
+	}
+}
+
+// SetTLSConfig makes new requests use the TLS config, idle connections
+// created with previous TLS config are closed
+func (t *TLSTransport) SetTLSConfig(cfg *tls.Config) {
+	t.mu.Lock()
+	old := t.transport
+	t.transport = &http.Transport{
+		TLSClientConfig: cfg,
+	}
+	t.mu.Unlock()
+
+	old.CloseIdleConnections()
+}
+
+func (t *TLSTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.RLock()
+	transport := t.transport
+	t.mu.RUnlock()
+
+	return transport.RoundTrip(req)
+}
@@ -16,6 +16,7 @@ package operator
 
 import (
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	apisv1beta1 "github.com/fabedge/fabedge/pkg/apis/v1beta1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
 	"k8s.io/klog/v2/klogr"
@@ -28,6 +29,7 @@ var log = klogr.New().WithName("agent")
 
 func init() {
 	_ = apis.AddToScheme(scheme.Scheme)
+	_ = apisv1beta1.AddToScheme(scheme.Scheme)
 	_ = calicoapi.AddToScheme(scheme.Scheme)
 }
 
@@ -23,16 +23,20 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/fabedge/fabedge/pkg/common/constants"
+	"github.com/fabedge/fabedge/pkg/common/tunnelstatus"
+	operatormetrics "github.com/fabedge/fabedge/pkg/operator/metrics"
 	"github.com/fabedge/fabedge/pkg/operator/types"
 	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
 )
@@ -52,10 +56,13 @@ type agentPodHandler struct {
 	imagePullPolicy corev1.PullPolicy
 	argMap          types.AgentArgumentMap
 	args            []string
-	agentNameSet    *types.SafeStringSet
+	// mediationEnabled makes edge nodes use strongswan even if they ask for wireguard
+	mediationEnabled bool
+	agentNameSet     *types.SafeStringSet
 
-	client client.Client
-	log    logr.Logger
+	client   client.Client
+	recorder record.EventRecorder
+	log      logr.Logger
 }
 
 func (handler *agentPodHandler) Do(ctx context.Context, node corev1.Node) error {
@@ -70,13 +77,20 @@ func (handler *agentPodHandler) Do(ctx context.Context, node corev1.Node) error
 			return errRequeueRequest
 		}
 
-		needRestart := ctx.Value(keyRestartAgent) == errRestartAgent
-		if !needRestart {
+		reason := ""
+		if ctx.Value(keyRestartAgent) == errRestartAgent {
+			reason = "its certificate or keys changed"
+		} else {
 			newPod := handler.buildAgentPod(handler.namespace, agentName, node)
-			needRestart = newPod.Labels[constants.KeyPodHash] != oldPod.Labels[constants.KeyPodHash]
+			if newPod.Labels[constants.KeyPodHash] != oldPod.Labels[constants.KeyPodHash] {
+				reason = "its pod spec hash changed"
+			}
 		}
 
-		if !needRestart {
+		if reason == "" {
+			if handler.argMap.IsTunnelStatusReportEnabled() {
+				return handler.syncAgentRole(ctx, node, oldPod.Name)
+			}
 			return nil
 		}
 
@@ -87,6 +101,9 @@ func (handler *agentPodHandler) Do(ctx context.Context, node corev1.Node) error
 			log.Error(err, "failed to delete agent pod")
 			return err
 		}
+		operatormetrics.AgentPodRebuilds.WithLabelValues(node.Name).Inc()
+		handler.recorder.Eventf(&node, corev1.EventTypeNormal, types.EventReasonAgentPodRebuilt,
+			"agent pod %s is deleted to be rebuilt because %s", agentName, reason)
 
 		handler.agentNameSet.Delete(agentName)
 		return nil
@@ -109,17 +126,33 @@ func (handler *agentPodHandler) Do(ctx context.Context, node corev1.Node) error
 		log.V(5).Info("Agent pod is not found, create it now")
 		newPod := handler.buildAgentPod(handler.namespace, agentName, node)
 
+		requested, resolved := types.GetTunnelBackend(node, handler.argMap), types.ResolveTunnelBackend(node, handler.argMap, handler.mediationEnabled)
+		if requested != resolved {
+			handler.recorder.Eventf(&node, corev1.EventTypeWarning, types.EventReasonTunnelBackendNotSupported,
+				"tunnel backend %s doesn't support mediation, %s is used instead", requested, resolved)
+		}
+
 		if err = controllerutil.SetControllerReference(&node, newPod, scheme.Scheme); err != nil {
 			log.Error(err, "failed to set ownerReference to TLS secret")
 			return err
 		}
 
+		reportEnabled := handler.argMap.IsTunnelStatusReportEnabled()
+		if reportEnabled {
+			if err = handler.syncAgentServiceAccount(ctx, node); err != nil {
+				return err
+			}
+		}
+
 		if err = handler.client.Create(ctx, newPod); err != nil {
 			log.Error(err, "failed to create agent pod")
 			return err
 		}
 
 		handler.agentNameSet.Insert(agentName)
+		if reportEnabled {
+			return handler.syncAgentRole(ctx, node, newPod.Name)
+		}
 		return nil
 	default:
 		log.Error(err, "failed to get agent pod")
@@ -152,6 +185,7 @@ func (handler *agentPodHandler) buildAgentPod(namespace, podName string, node co
 	hostPathDirectoryOrCreate := corev1.HostPathDirectoryOrCreate
 	privileged := true
 	defaultMode := int32(420)
+	optional := true
 	automountServiceAccountToken := false
 
 	pod := &corev1.Pod{
@@ -235,6 +269,11 @@ func (handler *agentPodHandler) buildAgentPod(namespace, podName string, node co
 							MountPath: "/etc/ipsec.d",
 							ReadOnly:  true,
 						},
+						{
+							Name:      "psk",
+							MountPath: "/etc/fabedge-psk",
+							ReadOnly:  true,
+						},
 						{
 							Name:      "agent-workdir",
 							MountPath: "/var/lib/fabedge",
@@ -254,9 +293,12 @@ func (handler *agentPodHandler) buildAgentPod(namespace, podName string, node co
 							Name:      "var-run",
 							MountPath: "/var/run/",
 						},
+						// only private keys are mounted, CA certificates are loaded by agent through
+						// vici, so they can be cleared when CA is rotated
 						{
 							Name:      "ipsec-d",
-							MountPath: "/etc/ipsec.d",
+							MountPath: "/etc/ipsec.d/private",
+							SubPath:   "private",
 							ReadOnly:  true,
 						},
 						{
@@ -314,6 +356,10 @@ func (handler *agentPodHandler) buildAgentPod(namespace, podName string, node co
 									Key:  corev1.TLSPrivateKeyKey,
 									Path: "private/tls.key",
 								},
+								{
+									Key:  secretutil.KeyWireGuardKey,
+									Path: "private/wireguard.key",
+								},
 							},
 						},
 					},
@@ -333,6 +379,16 @@ func (handler *agentPodHandler) buildAgentPod(namespace, podName string, node co
 						},
 					},
 				},
+				{
+					Name: "psk",
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{
+							SecretName:  getAgentPSKSecretName(node.Name),
+							DefaultMode: &defaultMode,
+							Optional:    &optional,
+						},
+					},
+				},
 				{
 					Name: "cni-config",
 					VolumeSource: corev1.VolumeSource{
@@ -370,6 +426,9 @@ func (handler *agentPodHandler) buildAgentPod(namespace, podName string, node co
 		},
 	}
 
+	// strongswan container is kept even if wireguard is the tunnel backend of node, because connections
+	// to peers of other backends and the mediation connection are still set up by strongswan
+
 	if handler.argMap.IsProxyEnabled() {
 		xtablesHostType := corev1.HostPathFileOrCreate
 		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
@@ -418,6 +477,27 @@ func (handler *agentPodHandler) buildAgentPod(namespace, podName string, node co
 		}
 	}
 
+	// agent needs the permission to patch its own pod to report tunnel status,
+	// each agent has its own service account which is only allowed to patch its pod
+	if handler.argMap.IsTunnelStatusReportEnabled() {
+		automountServiceAccountToken = true
+		pod.Spec.ServiceAccountName = podName
+		pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env,
+			corev1.EnvVar{
+				Name: tunnelstatus.EnvPodName,
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+				},
+			},
+			corev1.EnvVar{
+				Name: tunnelstatus.EnvPodNamespace,
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+				},
+			},
+		)
+	}
+
 	pod.Labels[constants.KeyPodHash] = computePodHash(pod.Spec)
 	return pod
 }
@@ -431,6 +511,16 @@ func (handler *agentPodHandler) buildAgentArgs(node corev1.Node) []string {
 		}
 	}
 
+	// the backend of node's endpoint is also the default backend of its agent
+	if backend, ok := node.Annotations[constants.KeyTunnelBackend]; ok {
+		argMap.Set("tunnel-backend", backend)
+	}
+
+	// wireguard has no mediation, the default backend is guaranteed not to be wireguard by Options.Validate
+	if argMap.HasKey("tunnel-backend") {
+		argMap.Set("tunnel-backend", types.ResolveTunnelBackend(node, handler.argMap, handler.mediationEnabled))
+	}
+
 	if len(argMap) == 0 {
 		return handler.args
 	}
@@ -444,8 +534,101 @@ func (handler *agentPodHandler) buildAgentArgs(node corev1.Node) []string {
 	return argMap.ArgumentArray()
 }
 
+// syncAgentServiceAccount creates the service account used by agent of the node to report tunnel status,
+// the service account is named after the agent
+func (handler *agentPodHandler) syncAgentServiceAccount(ctx context.Context, node corev1.Node) error {
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getAgentName(node.Name),
+			Namespace: handler.namespace,
+		},
+	}
+
+	return handler.createOrUpdate(ctx, node, sa, func() {
+		// the token is mounted by agent pod only
+		automountServiceAccountToken := false
+		sa.AutomountServiceAccountToken = &automountServiceAccountToken
+	})
+}
+
+// syncAgentRole makes sure the service account of agent can only patch the agent pod,
+// a compromised edge node is not able to modify other pods with the token
+func (handler *agentPodHandler) syncAgentRole(ctx context.Context, node corev1.Node, podName string) error {
+	agentName := getAgentName(node.Name)
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      agentName,
+			Namespace: handler.namespace,
+		},
+	}
+	err := handler.createOrUpdate(ctx, node, role, func() {
+		role.Rules = []rbacv1.PolicyRule{
+			{
+				APIGroups:     []string{""},
+				Resources:     []string{"pods"},
+				ResourceNames: []string{podName},
+				Verbs:         []string{"patch"},
+			},
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	roleBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      agentName,
+			Namespace: handler.namespace,
+		},
+	}
+	return handler.createOrUpdate(ctx, node, roleBinding, func() {
+		roleBinding.RoleRef = rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     agentName,
+		}
+		roleBinding.Subjects = []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      agentName,
+				Namespace: handler.namespace,
+			},
+		}
+	})
+}
+
+func (handler *agentPodHandler) createOrUpdate(ctx context.Context, node corev1.Node, obj client.Object, mutate func()) error {
+	_, err := controllerutil.CreateOrUpdate(ctx, handler.client, obj, func() error {
+		mutate()
+		obj.SetLabels(map[string]string{
+			constants.KeyFabEdgeAPP: constants.AppAgent,
+			constants.KeyCreatedBy:  constants.AppOperator,
+		})
+		return controllerutil.SetControllerReference(&node, obj, scheme.Scheme)
+	})
+	if err != nil {
+		handler.log.Error(err, "failed to create or update object for agent", "kind", fmt.Sprintf("%T", obj), "name", obj.GetName(), "namespace", obj.GetNamespace())
+	}
+
+	return err
+}
+
 func (handler *agentPodHandler) Undo(ctx context.Context, nodeName string) error {
+	operatormetrics.AgentPodRebuilds.DeleteLabelValues(nodeName)
+
 	agentName := getAgentName(nodeName)
+	objectMeta := metav1.ObjectMeta{Name: agentName, Namespace: handler.namespace}
+	for _, obj := range []client.Object{
+		&rbacv1.RoleBinding{ObjectMeta: objectMeta},
+		&rbacv1.Role{ObjectMeta: objectMeta},
+		&corev1.ServiceAccount{ObjectMeta: objectMeta},
+	} {
+		if err := handler.client.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+			handler.log.Error(err, "failed to delete object of agent", "kind", fmt.Sprintf("%T", obj), "name", agentName, "namespace", handler.namespace)
+			return err
+		}
+	}
+
 	pod, err := handler.getAgentPod(ctx, agentName)
 	if err != nil {
 		if errors.IsNotFound(err) {
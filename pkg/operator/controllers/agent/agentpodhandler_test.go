@@ -19,12 +19,17 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/fabedge/fabedge/pkg/common/constants"
+	operatormetrics "github.com/fabedge/fabedge/pkg/operator/metrics"
 	"github.com/fabedge/fabedge/pkg/operator/types"
 	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
 	testutil "github.com/fabedge/fabedge/pkg/util/test"
@@ -63,6 +68,7 @@ var _ = Describe("AgentPodHandler", func() {
 			argMap:          argMap,
 			agentNameSet:    types.NewSafeStringSet(),
 			client:          k8sClient,
+			recorder:        record.NewFakeRecorder(10),
 			log:             klogr.New().WithName("agentPodHandler"),
 		}
 
@@ -96,6 +102,7 @@ var _ = Describe("AgentPodHandler", func() {
 		hostPathDirectory := corev1.HostPathDirectory
 		hostPathDirectoryOrCreate := corev1.HostPathDirectoryOrCreate
 		defaultMode := int32(420)
+		optional := true
 		edgeTunnelConfigMap := getAgentConfigMapName(node.Name)
 		expectedVolumes := []corev1.Volume{
 			{
@@ -143,6 +150,10 @@ var _ = Describe("AgentPodHandler", func() {
 								Key:  corev1.TLSPrivateKeyKey,
 								Path: "private/tls.key",
 							},
+							{
+								Key:  secretutil.KeyWireGuardKey,
+								Path: "private/wireguard.key",
+							},
 						},
 					},
 				},
@@ -162,6 +173,16 @@ var _ = Describe("AgentPodHandler", func() {
 					},
 				},
 			},
+			{
+				Name: "psk",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName:  getAgentPSKSecretName(node.Name),
+						DefaultMode: &defaultMode,
+						Optional:    &optional,
+					},
+				},
+			},
 			{
 				Name: "cni-config",
 				VolumeSource: corev1.VolumeSource{
@@ -263,6 +284,11 @@ var _ = Describe("AgentPodHandler", func() {
 				MountPath: "/etc/ipsec.d",
 				ReadOnly:  true,
 			},
+			{
+				Name:      "psk",
+				MountPath: "/etc/fabedge-psk",
+				ReadOnly:  true,
+			},
 			{
 				Name:      "agent-workdir",
 				MountPath: "/var/lib/fabedge",
@@ -281,7 +307,8 @@ var _ = Describe("AgentPodHandler", func() {
 			},
 			{
 				Name:      "ipsec-d",
-				MountPath: "/etc/ipsec.d",
+				MountPath: "/etc/ipsec.d/private",
+				SubPath:   "private",
 				ReadOnly:  true,
 			},
 			{
@@ -390,6 +417,85 @@ var _ = Describe("AgentPodHandler", func() {
 		}))
 	})
 
+	It("should mount service account token and pass pod info to agent container if report-tunnel-status is true", func() {
+		handler.argMap.Set("report-tunnel-status", "true")
+		handler.args = handler.argMap.ArgumentArray()
+
+		nodeName := getNodeName()
+		agentName := getAgentName(nodeName)
+		node = newNode(nodeName, "10.40.20.182", "2.2.2.3/26")
+		node.UID = "234567"
+
+		Expect(handler.Do(context.TODO(), node)).To(Succeed())
+
+		pod, err := handler.getAgentPod(context.Background(), agentName)
+		Expect(err).Should(BeNil())
+
+		Expect(*pod.Spec.AutomountServiceAccountToken).To(BeTrue())
+		Expect(pod.Spec.ServiceAccountName).To(Equal(agentName))
+
+		agentContainer := pod.Spec.Containers[0]
+		Expect(agentContainer.Args).To(ContainElement("--report-tunnel-status=true"))
+		Expect(agentContainer.Env).To(ConsistOf(
+			corev1.EnvVar{
+				Name: "POD_NAME",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "metadata.name"},
+				},
+			},
+			corev1.EnvVar{
+				Name: "POD_NAMESPACE",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "metadata.namespace"},
+				},
+			},
+		))
+	})
+
+	It("should only allow agent to patch its own pod if report-tunnel-status is true", func() {
+		handler.argMap.Set("report-tunnel-status", "true")
+		handler.args = handler.argMap.ArgumentArray()
+
+		nodeName := getNodeName()
+		agentName := getAgentName(nodeName)
+		node = newNode(nodeName, "10.40.20.186", "2.2.2.16/26")
+		node.UID = "345681"
+
+		Expect(handler.Do(context.TODO(), node)).To(Succeed())
+
+		pod, err := handler.getAgentPod(context.Background(), agentName)
+		Expect(err).Should(BeNil())
+
+		key := client.ObjectKey{Name: agentName, Namespace: namespace}
+		var sa corev1.ServiceAccount
+		Expect(k8sClient.Get(context.Background(), key, &sa)).To(Succeed())
+		Expect(*sa.AutomountServiceAccountToken).To(BeFalse())
+		expectOwnerReference(&sa, node)
+
+		var role rbacv1.Role
+		Expect(k8sClient.Get(context.Background(), key, &role)).To(Succeed())
+		Expect(role.Rules).To(ConsistOf(rbacv1.PolicyRule{
+			APIGroups:     []string{""},
+			Resources:     []string{"pods"},
+			ResourceNames: []string{pod.Name},
+			Verbs:         []string{"patch"},
+		}))
+
+		var roleBinding rbacv1.RoleBinding
+		Expect(k8sClient.Get(context.Background(), key, &roleBinding)).To(Succeed())
+		Expect(roleBinding.RoleRef.Name).To(Equal(agentName))
+		Expect(roleBinding.Subjects).To(ConsistOf(rbacv1.Subject{
+			Kind:      rbacv1.ServiceAccountKind,
+			Name:      agentName,
+			Namespace: namespace,
+		}))
+
+		Expect(handler.Undo(context.TODO(), nodeName)).To(Succeed())
+		Expect(errors.IsNotFound(k8sClient.Get(context.Background(), key, &sa))).To(BeTrue())
+		Expect(errors.IsNotFound(k8sClient.Get(context.Background(), key, &role))).To(BeTrue())
+		Expect(errors.IsNotFound(k8sClient.Get(context.Background(), key, &roleBinding))).To(BeTrue())
+	})
+
 	It("should use arguments from node's annotation and default arguments to build agent pod", func() {
 		nodeName := getNodeName()
 		agentName = getAgentName(nodeName)
@@ -414,6 +520,64 @@ var _ = Describe("AgentPodHandler", func() {
 		))
 	})
 
+	It("should keep strongswan container if wireguard is used as tunnel backend", func() {
+		nodeName := getNodeName()
+		agentName = getAgentName(nodeName)
+		node = newNode(nodeName, "10.40.20.183", "2.2.2.4/26")
+		node.UID = "345678"
+		node.Annotations = map[string]string{
+			"argument.fabedge.io/tunnel-backend": "wireguard",
+		}
+
+		Expect(handler.Do(context.TODO(), node)).To(Succeed())
+
+		pod, err := handler.getAgentPod(context.Background(), agentName)
+		Expect(err).Should(BeNil())
+		// peers using strongswan are still connected by strongswan
+		Expect(len(pod.Spec.Containers)).To(Equal(2))
+		Expect(pod.Spec.Containers[0].Name).To(Equal("agent"))
+		Expect(pod.Spec.Containers[1].Name).To(Equal("strongswan"))
+		Expect(pod.Spec.Containers[0].Args).To(ContainElement("--tunnel-backend=wireguard"))
+	})
+
+	It("should use tunnel backend of node's endpoint as agent's tunnel backend", func() {
+		nodeName := getNodeName()
+		agentName = getAgentName(nodeName)
+		node = newNode(nodeName, "10.40.20.184", "2.2.2.8/26")
+		node.UID = "345679"
+		node.Annotations = map[string]string{
+			constants.KeyTunnelBackend: "wireguard",
+		}
+
+		Expect(handler.Do(context.TODO(), node)).To(Succeed())
+
+		pod, err := handler.getAgentPod(context.Background(), agentName)
+		Expect(err).Should(BeNil())
+		Expect(len(pod.Spec.Containers)).To(Equal(2))
+		Expect(pod.Spec.Containers[0].Args).To(ContainElement("--tunnel-backend=wireguard"))
+	})
+
+	It("should use strongswan instead of wireguard and record an event if mediation is enabled", func() {
+		handler.mediationEnabled = true
+
+		nodeName := getNodeName()
+		agentName = getAgentName(nodeName)
+		node = newNode(nodeName, "10.40.20.185", "2.2.2.12/26")
+		node.UID = "345680"
+		node.Annotations = map[string]string{
+			constants.KeyTunnelBackend: "wireguard",
+		}
+
+		Expect(handler.Do(context.TODO(), node)).To(Succeed())
+
+		pod, err := handler.getAgentPod(context.Background(), agentName)
+		Expect(err).Should(BeNil())
+		Expect(pod.Spec.Containers[0].Args).To(ContainElement("--tunnel-backend=strongswan"))
+
+		recorder := handler.recorder.(*record.FakeRecorder)
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning TunnelBackendNotSupported")))
+	})
+
 	It("return errRequeueRequest if an agent pod is already created but agentPodHandler is not able to get it", func() {
 		nodeName := getNodeName()
 		agentName = getAgentName(nodeName)
@@ -445,13 +609,19 @@ var _ = Describe("AgentPodHandler", func() {
 		pod, err = handler.getAgentPod(context.Background(), agentName)
 		Expect(errors.IsNotFound(err) || pod.DeletionTimestamp != nil).Should(BeTrue())
 		Expect(handler.agentNameSet.Has(agentName)).To(BeFalse())
+		recorder := handler.recorder.(*record.FakeRecorder)
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal AgentPodRebuilt")))
 	})
 
 	It("is able to delete agent pod for specified node", func() {
+		operatormetrics.AgentPodRebuilds.WithLabelValues(node.Name).Inc()
+		count := promtestutil.CollectAndCount(operatormetrics.AgentPodRebuilds)
+
 		Expect(handler.Undo(context.TODO(), node.Name)).To(Succeed())
 
 		pod, err := handler.getAgentPod(context.Background(), agentName)
 		Expect(errors.IsNotFound(err) || pod.DeletionTimestamp != nil).Should(BeTrue())
 		Expect(handler.agentNameSet.Has(agentName)).To(BeFalse())
+		Expect(promtestutil.CollectAndCount(operatormetrics.AgentPodRebuilds)).To(Equal(count - 1))
 	})
 })
@@ -20,17 +20,19 @@ import (
 	"encoding/pem"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/fabedge/fabedge/pkg/common/constants"
+	"github.com/fabedge/fabedge/pkg/operator/crl"
 	"github.com/fabedge/fabedge/pkg/operator/types"
+	"github.com/fabedge/fabedge/pkg/tunnel/wireguard"
 	certutil "github.com/fabedge/fabedge/pkg/util/cert"
 	nodeutil "github.com/fabedge/fabedge/pkg/util/node"
 	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
@@ -44,19 +46,60 @@ type certHandler struct {
 	getEndpointName  types.GetNameFunc
 	certManager      certutil.Manager
 	certOrganization string
+	// renewalFraction is the fraction of certificate's lifetime after which the certificate is renewed
+	renewalFraction float64
+	// keyAlgorithm is the algorithm of private keys created for agents, existing
+	// keys are kept when certificates are renewed
+	keyAlgorithm certutil.KeyAlgorithm
+	// revoker revokes certificate of a node when the node is removed, it's optional
+	revoker crl.Revoker
 
-	client client.Client
-	log    logr.Logger
+	client   client.Client
+	recorder record.EventRecorder
+	log      logr.Logger
 }
 
 func (handler *certHandler) Do(ctx context.Context, node corev1.Node) error {
+	var secret corev1.Secret
+
+	err := handler.ensureTLSSecret(ctx, node, &secret)
+	if err != nil && err != errRestartAgent {
+		return err
+	}
+
+	if innerErr := handler.ensureWireGuardPublicKey(ctx, node, secret); innerErr != nil {
+		return innerErr
+	}
+	handler.scheduleRenewal(ctx, secret)
+
+	return err
+}
+
+// scheduleRenewal asks controller to reconcile the node again when the certificate
+// in secret needs renewal, so it's renewed on time even if the node never changes
+func (handler *certHandler) scheduleRenewal(ctx context.Context, secret corev1.Secret) {
+	cert, err := parseCertFromSecret(secret)
+	if err != nil {
+		return
+	}
+
+	renewalTime, ok := certutil.RenewalTime(cert, handler.renewalFraction)
+	if !ok {
+		return
+	}
+
+	requeueAfter(ctx, time.Until(renewalTime))
+}
+
+// ensureTLSSecret makes sure the TLS secret of agent is valid, errRestartAgent is
+// returned if the secret is created or changed
+func (handler *certHandler) ensureTLSSecret(ctx context.Context, node corev1.Node, secret *corev1.Secret) error {
 	secretName := getCertSecretName(node.Name)
 
 	log := handler.log.WithValues("nodeName", node.Name, "secretName", secretName, "namespace", handler.namespace)
 	log.V(5).Info("Sync agent tls secret")
 
-	var secret corev1.Secret
-	err := handler.client.Get(ctx, ObjectKey{Name: secretName, Namespace: handler.namespace}, &secret)
+	err := handler.client.Get(ctx, ObjectKey{Name: secretName, Namespace: handler.namespace}, secret)
 	if err != nil {
 		if !errors.IsNotFound(err) {
 			handler.log.Error(err, "failed to get secret")
@@ -64,18 +107,15 @@ func (handler *certHandler) Do(ctx context.Context, node corev1.Node) error {
 		}
 
 		log.V(5).Info("TLS secret for agent is not found, generate it now")
-		secret, err = handler.buildCertAndKeySecret(secretName, node)
+		// the secret is not owned by node, or it might be deleted by garbage collector
+		// before its certificate is revoked, it's deleted by Undo after revocation
+		*secret, err = handler.buildCertAndKeySecret(secretName, node, "")
 		if err != nil {
 			log.Error(err, "failed to create cert and key for agent")
 			return err
 		}
 
-		if err = controllerutil.SetControllerReference(&node, &secret, scheme.Scheme); err != nil {
-			log.Error(err, "failed to set ownerReference to TLS secret")
-			return err
-		}
-
-		err = handler.client.Create(ctx, &secret)
+		err = handler.client.Create(ctx, secret)
 		if err != nil {
 			log.Error(err, "failed to create secret")
 			return err
@@ -84,32 +124,136 @@ func (handler *certHandler) Do(ctx context.Context, node corev1.Node) error {
 		return errRestartAgent
 	}
 
-	err = handler.verifyCert(secret, node)
-	if err == nil {
+	// secrets created by previous versions are owned by node
+	if len(secret.OwnerReferences) != 0 {
+		secret.OwnerReferences = nil
+		if err = handler.client.Update(ctx, secret); err != nil {
+			log.Error(err, "failed to remove ownerReferences of TLS secret")
+			return err
+		}
+	}
+
+	verifyErr := handler.verifyCert(*secret, node)
+	if verifyErr == nil {
 		log.V(5).Info("cert is verified")
-		return nil
+		if err = handler.renewCertIfNeeded(ctx, node, secret); err != nil {
+			return err
+		}
+		return handler.ensureWireGuardKey(ctx, secret)
 	}
 
-	log.Error(err, "failed to verify cert, need to regenerate a cert to agent")
-	secret, err = handler.buildCertAndKeySecret(secretName, node)
+	log.Error(verifyErr, "failed to verify cert, need to regenerate a cert to agent")
+	// wireguard key is kept, or the public key of node is changed and peers are broken
+	*secret, err = handler.buildCertAndKeySecret(secretName, node, secretutil.GetWireGuardKey(*secret))
 	if err != nil {
 		log.Error(err, "failed to recreate cert and key for agent")
 		return err
 	}
 
-	if err = controllerutil.SetControllerReference(&node, &secret, scheme.Scheme); err != nil {
-		log.Error(err, "failed to set ownerReference to TLS secret")
+	if err = handler.client.Update(ctx, secret); err != nil {
+		log.Error(err, "failed to save secret")
 		return err
 	}
+	handler.recorder.Eventf(&node, corev1.EventTypeNormal, types.EventReasonCertRenewed,
+		"certificate in secret %s is renewed: %s", secretName, verifyErr)
 
-	if err = handler.client.Update(ctx, &secret); err != nil {
-		log.Error(err, "failed to save secret")
+	return errRestartAgent
+}
+
+// renewCertIfNeeded signs a new certificate with the private key in secret when the
+// certificate is near expiry. Agent pod is not restarted, because agent will
+// reload the certificate when secret volume is updated, existing tunnels are kept
+func (handler *certHandler) renewCertIfNeeded(ctx context.Context, node corev1.Node, secret *corev1.Secret) error {
+	cert, err := parseCertFromSecret(*secret)
+	if err != nil {
+		return err
+	}
+
+	if !certutil.NeedRenewal(cert, handler.renewalFraction) {
+		return nil
+	}
+
+	log := handler.log.WithValues("nodeName", node.Name, "secretName", secret.Name)
+	log.V(3).Info("certificate is near expiry, renew it", "notAfter", cert.NotAfter)
+
+	keyDER, err := certutil.DecodePEM(secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		log.Error(err, "failed to decode private key")
+		return err
+	}
+
+	csr, err := certutil.NewCertRequestWithKey(keyDER, handler.buildCertRequest(node))
+	if err != nil {
+		log.Error(err, "failed to create cert request")
+		return err
+	}
+
+	certDER, err := handler.certManager.SignCert(csr)
+	if err != nil {
+		log.Error(err, "failed to sign cert")
+		return err
+	}
+
+	secret.Data[corev1.TLSCertKey] = certutil.EncodeCertPEM(certDER)
+	if err = handler.client.Update(ctx, secret); err != nil {
+		log.Error(err, "failed to save renewed cert")
+		return err
+	}
+	handler.recorder.Eventf(&node, corev1.EventTypeNormal, types.EventReasonCertRenewed,
+		"certificate in secret %s is renewed before it expires at %s", secret.Name, cert.NotAfter.Format(time.RFC3339))
+
+	return nil
+}
+
+// ensureWireGuardKey adds a wireguard private key to TLS secret if it doesn't have one,
+// secrets created by old versions don't have it
+func (handler *certHandler) ensureWireGuardKey(ctx context.Context, secret *corev1.Secret) error {
+	if secretutil.GetWireGuardKey(*secret) != "" {
+		return nil
+	}
+
+	key, err := wireguard.GenerateKey()
+	if err != nil {
+		handler.log.Error(err, "failed to generate wireguard key")
+		return err
+	}
+
+	secret.Data[secretutil.KeyWireGuardKey] = []byte(key)
+	if err = handler.client.Update(ctx, secret); err != nil {
+		handler.log.Error(err, "failed to save wireguard key to secret", "secretName", secret.Name)
 		return err
 	}
 
 	return errRestartAgent
 }
 
+// ensureWireGuardPublicKey puts the public key of wireguard private key in TLS secret to
+// node's annotations, from which the public key is distributed with node's endpoint
+func (handler *certHandler) ensureWireGuardPublicKey(ctx context.Context, node corev1.Node, secret corev1.Secret) error {
+	publicKey, err := wireguard.PublicKey(secretutil.GetWireGuardKey(secret))
+	if err != nil {
+		handler.log.Error(err, "failed to compute wireguard public key", "nodeName", node.Name)
+		return err
+	}
+
+	if node.Annotations[constants.KeyWireGuardPublicKey] == publicKey {
+		return nil
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	node.Annotations[constants.KeyWireGuardPublicKey] = publicKey
+
+	if err = handler.client.Patch(ctx, &node, patch); err != nil {
+		handler.log.Error(err, "failed to save wireguard public key to node annotations", "nodeName", node.Name)
+		return err
+	}
+
+	return nil
+}
+
 func (handler *certHandler) verifyCert(secret corev1.Secret, node corev1.Node) error {
 	cert, err := parseCertFromSecret(secret)
 	if err != nil {
@@ -124,20 +268,27 @@ func (handler *certHandler) verifyCert(secret corev1.Secret, node corev1.Node) e
 	return handler.certManager.VerifyCert(cert, certutil.ExtKeyUsagesServerAndClient)
 }
 
-func (handler *certHandler) buildCertAndKeySecret(secretName string, node corev1.Node) (corev1.Secret, error) {
+func (handler *certHandler) buildCertRequest(node corev1.Node) certutil.Request {
 	var ips []net.IP
 	for _, ip := range nodeutil.GetInternalIPs(node) {
 		ips = append(ips, net.ParseIP(ip))
 	}
 
 	name := handler.getEndpointName(node.Name)
-	keyDER, csr, err := certutil.NewCertRequest(certutil.Request{
+	return certutil.Request{
 		CommonName:   name,
 		Organization: []string{handler.certOrganization},
 		// use DNS and IP as alias for mediation
-		DNSNames: []string{name},
-		IPs:      ips,
-	})
+		DNSNames:     []string{name},
+		IPs:          ips,
+		KeyAlgorithm: handler.keyAlgorithm,
+	}
+}
+
+// buildCertAndKeySecret creates a TLS secret with a new certificate and private key, wgKey
+// is put in the secret as wireguard private key, a new one is generated if it's empty
+func (handler *certHandler) buildCertAndKeySecret(secretName string, node corev1.Node, wgKey string) (corev1.Secret, error) {
+	keyDER, csr, err := certutil.NewCertRequest(handler.buildCertRequest(node))
 	if err != nil {
 		return corev1.Secret{}, err
 	}
@@ -147,12 +298,19 @@ func (handler *certHandler) buildCertAndKeySecret(secretName string, node corev1
 		return corev1.Secret{}, err
 	}
 
+	if wgKey == "" {
+		if wgKey, err = wireguard.GenerateKey(); err != nil {
+			return corev1.Secret{}, err
+		}
+	}
+
 	return secretutil.TLSSecret().
 		Name(secretName).
 		Namespace(handler.namespace).
 		EncodeCert(certDER).
 		EncodeKey(keyDER).
-		CACertPEM(handler.certManager.GetCACertPEM()).
+		CACertPEM(handler.certManager.GetCABundlePEM()).
+		WireGuardKey(wgKey).
 		Label(constants.KeyCreatedBy, constants.AppOperator).
 		Label(constants.KeyNode, node.Name).Build(), nil
 }
@@ -164,6 +322,11 @@ func (handler *certHandler) Undo(ctx context.Context, nodeName string) error {
 			Namespace: handler.namespace,
 		},
 	}
+
+	if err := handler.revokeCert(ctx, secret); err != nil {
+		return err
+	}
+
 	err := handler.client.Delete(ctx, &secret)
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -175,6 +338,51 @@ func (handler *certHandler) Undo(ctx context.Context, nodeName string) error {
 	return err
 }
 
+// revokeCert revokes the certificate of the removed node, so the node can't
+// establish tunnels or access API server with it anymore
+func (handler *certHandler) revokeCert(ctx context.Context, secret corev1.Secret) error {
+	if handler.revoker == nil {
+		return nil
+	}
+
+	err := handler.client.Get(ctx, client.ObjectKey{Name: secret.Name, Namespace: secret.Namespace}, &secret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		handler.log.Error(err, "failed to get secret", "name", secret.Name, "namespace", secret.Namespace)
+		return err
+	}
+
+	if len(secretutil.GetCert(secret)) == 0 {
+		return nil
+	}
+
+	cert, err := parseCertFromSecret(secret)
+	if err != nil {
+		// a certificate which can't be parsed can't be used either
+		handler.log.Error(err, "failed to parse certificate", "name", secret.Name, "namespace", secret.Namespace)
+		return nil
+	}
+
+	err = handler.revoker.Revoke(ctx, cert)
+	if err == crl.ErrNotSupported {
+		// retrying won't help, the secret is deleted without revoking its certificate
+		handler.log.Info("certificate revocation is not supported, skip revoking certificate", "name", secret.Name, "serialNumber", cert.SerialNumber)
+		handler.recorder.Eventf(&secret, corev1.EventTypeWarning, types.EventReasonCertNotRevoked,
+			"certificate with serial number %s is not revoked: %s", cert.SerialNumber, err)
+		return nil
+	}
+	if err != nil {
+		handler.log.Error(err, "failed to revoke certificate", "name", secret.Name, "serialNumber", cert.SerialNumber)
+		return err
+	}
+
+	handler.recorder.Eventf(&secret, corev1.EventTypeNormal, types.EventReasonCertRevoked,
+		"certificate with serial number %s is revoked", cert.SerialNumber)
+	return nil
+}
+
 func getCertSecretName(nodeName string) string {
 	return fmt.Sprintf("fabedge-agent-tls-%s", nodeName)
 }
@@ -25,8 +25,14 @@ import (
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	"github.com/fabedge/fabedge/pkg/common/constants"
+	"github.com/fabedge/fabedge/pkg/operator/crl"
+	"github.com/fabedge/fabedge/pkg/tunnel/wireguard"
 	certutil "github.com/fabedge/fabedge/pkg/util/cert"
 	nodeutil "github.com/fabedge/fabedge/pkg/util/node"
 	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
@@ -59,26 +65,32 @@ var _ = Describe("CertHandler", func() {
 			certManager:      certManager,
 			getEndpointName:  getEndpointName,
 			certOrganization: certutil.DefaultOrganization,
+			recorder:         record.NewFakeRecorder(10),
 			log:              klogr.New().WithName("configHandler"),
 		}
 
 		nodeName := getNodeName()
 		node = newNode(nodeName, "10.40.20.181", "2.2.1.128/26")
-		node.UID = "123456"
+		Expect(k8sClient.Create(context.Background(), &node)).Should(Succeed())
 
 		Expect(handler.Do(context.Background(), node)).Should(Equal(errRestartAgent))
 	})
 
+	AfterEach(func() {
+		Expect(k8sClient.Delete(context.Background(), &node)).Should(Succeed())
+	})
+
 	It("should ensure a valid certificate and a private key for specified node's agent", func() {
 		var secret corev1.Secret
 		secretName := getCertSecretName(node.Name)
 		Expect(k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)).Should(Succeed())
-		expectOwnerReference(&secret, node)
+		// certificate must be revoked before secret is deleted, so secret is not owned by node
+		Expect(secret.OwnerReferences).To(BeEmpty())
 
 		By("Checking TLS secret")
 		caCertPEM, certPEM := secretutil.GetCACert(secret), secretutil.GetCert(secret)
 		Expect(certManager.VerifyCertInPEM(certPEM, certutil.ExtKeyUsagesServerAndClient)).Should(Succeed())
-		Expect(caCertPEM).Should(Equal(certManager.GetCACertPEM()))
+		Expect(caCertPEM).Should(Equal(certManager.GetCABundlePEM()))
 		block, _ := pem.Decode(certPEM)
 
 		cert, err := x509.ParseCertificate(block.Bytes)
@@ -108,17 +120,106 @@ var _ = Describe("CertHandler", func() {
 		By("Checking if TLS secret updated")
 		secret = corev1.Secret{}
 		Expect(k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)).Should(Succeed())
-		expectOwnerReference(&secret, node)
+		Expect(secret.OwnerReferences).To(BeEmpty())
 
 		caCertPEM, certPEM = secretutil.GetCACert(secret), secretutil.GetCert(secret)
 		Expect(certManager.VerifyCertInPEM(certPEM, certutil.ExtKeyUsagesServerAndClient)).Should(Succeed())
-		Expect(caCertPEM).Should(Equal(certManager.GetCACertPEM()))
+		Expect(caCertPEM).Should(Equal(certManager.GetCABundlePEM()))
+	})
+
+	It("should remove ownerReferences of TLS secret created by previous versions", func() {
+		var secret corev1.Secret
+		secretName := getCertSecretName(node.Name)
+		Expect(k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)).Should(Succeed())
+		Expect(controllerutil.SetControllerReference(&node, &secret, scheme.Scheme)).To(Succeed())
+		Expect(k8sClient.Update(context.Background(), &secret)).To(Succeed())
+
+		Expect(handler.Do(context.Background(), node)).To(Succeed())
+
+		secret = corev1.Secret{}
+		Expect(k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)).Should(Succeed())
+		Expect(secret.OwnerReferences).To(BeEmpty())
+	})
+
+	It("should renew certificate with the same private key when it's near expiry", func() {
+		var secret corev1.Secret
+		secretName := getCertSecretName(node.Name)
+		Expect(k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)).Should(Succeed())
+
+		By("Changing TLS secret with a cert which is near expiry")
+		certDER, keyDER, _ := certManager.NewCertKey(certutil.Config{
+			CommonName:     getEndpointName(node.Name),
+			Usages:         certutil.ExtKeyUsagesServerAndClient,
+			ValidityPeriod: 4 * time.Second,
+		})
+		oldCertPEM, keyPEM := certutil.EncodeCertPEM(certDER), certutil.EncodePrivateKeyPEM(keyDER)
+		secret.Data[corev1.TLSCertKey] = oldCertPEM
+		secret.Data[corev1.TLSPrivateKeyKey] = keyPEM
+		Expect(k8sClient.Update(context.Background(), &secret)).Should(Succeed())
+
+		time.Sleep(2500 * time.Millisecond)
+
+		handler.renewalFraction = 0.5
+		Expect(handler.Do(context.Background(), node)).Should(Succeed())
+
+		By("Checking if certificate is renewed")
+		secret = corev1.Secret{}
+		Expect(k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)).Should(Succeed())
+		certPEM, newKeyPEM := secretutil.GetCertAndKey(secret)
+		Expect(certPEM).ShouldNot(Equal(oldCertPEM))
+		Expect(newKeyPEM).Should(Equal(keyPEM))
+		Expect(certManager.VerifyCertInPEM(certPEM, certutil.ExtKeyUsagesServerAndClient)).Should(Succeed())
+
+		recorder := handler.recorder.(*record.FakeRecorder)
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal CertificateRenewed")))
+	})
+
+	It("should ask controller to reconcile the node again when certificate needs renewal", func() {
+		handler.renewalFraction = 0.5
+
+		timer := &requeueTimer{}
+		ctx := context.WithValue(context.Background(), keyRequeueAfter, timer)
+		Expect(handler.Do(ctx, node)).Should(Succeed())
+
+		// certificates are valid for 365 days in this test
+		Expect(timer.after).Should(BeNumerically("~", timeutil.Days(365)/2, time.Minute))
+	})
+
+	It("should generate a wireguard key and put its public key to node's annotations", func() {
+		var secret corev1.Secret
+		secretName := getCertSecretName(node.Name)
+		Expect(k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)).Should(Succeed())
+
+		publicKey, err := wireguard.PublicKey(secretutil.GetWireGuardKey(secret))
+		Expect(err).Should(BeNil())
+
+		var savedNode corev1.Node
+		Expect(k8sClient.Get(context.Background(), ObjectKey{Name: node.Name}, &savedNode)).Should(Succeed())
+		Expect(savedNode.Annotations[constants.KeyWireGuardPublicKey]).Should(Equal(publicKey))
+
+		By("Removing wireguard key from TLS secret")
+		delete(secret.Data, secretutil.KeyWireGuardKey)
+		Expect(k8sClient.Update(context.Background(), &secret)).Should(Succeed())
+
+		Expect(handler.Do(context.Background(), savedNode)).Should(Equal(errRestartAgent))
+
+		secret = corev1.Secret{}
+		Expect(k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)).Should(Succeed())
+		newPublicKey, err := wireguard.PublicKey(secretutil.GetWireGuardKey(secret))
+		Expect(err).Should(BeNil())
+		Expect(newPublicKey).ShouldNot(Equal(publicKey))
+
+		savedNode = corev1.Node{}
+		Expect(k8sClient.Get(context.Background(), ObjectKey{Name: node.Name}, &savedNode)).Should(Succeed())
+		Expect(savedNode.Annotations[constants.KeyWireGuardPublicKey]).Should(Equal(newPublicKey))
 	})
 
-	It("should regenerate a certificate and private key if certificate's commonName is wrong", func() {
+	It("should regenerate a certificate and private key but keep wireguard key if certificate's commonName is wrong", func() {
 		var secret corev1.Secret
 		secretName := getCertSecretName(node.Name)
 		Expect(k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)).Should(Succeed())
+		wgKey := secretutil.GetWireGuardKey(secret)
+		Expect(wgKey).ShouldNot(BeEmpty())
 
 		By("Changing TLS secret with expired cert")
 		handler.getEndpointName = func(nodeName string) string {
@@ -130,12 +231,16 @@ var _ = Describe("CertHandler", func() {
 		By("Checking if TLS secret updated")
 		secret = corev1.Secret{}
 		Expect(k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)).Should(Succeed())
-		expectOwnerReference(&secret, node)
+		Expect(secret.OwnerReferences).To(BeEmpty())
 
 		cert, err := parseCertFromSecret(secret)
 		Expect(err).To(BeNil())
 		Expect(certManager.VerifyCert(cert, certutil.ExtKeyUsagesServerAndClient)).Should(Succeed())
 		Expect(cert.Subject.CommonName).To(Equal(handler.getEndpointName(node.Name)))
+		Expect(secretutil.GetWireGuardKey(secret)).Should(Equal(wgKey))
+
+		recorder := handler.recorder.(*record.FakeRecorder)
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal CertificateRenewed")))
 	})
 
 	It("should be able to delete cert secret created for specified node", func() {
@@ -146,4 +251,53 @@ var _ = Describe("CertHandler", func() {
 		err := k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)
 		Expect(errors.IsNotFound(err)).Should(BeTrue())
 	})
+
+	It("should revoke certificate of specified node before deleting cert secret if revoker is provided", func() {
+		var revokedCert *x509.Certificate
+		handler.revoker = crl.RevokeFunc(func(cert *x509.Certificate) error {
+			revokedCert = cert
+			return nil
+		})
+
+		var secret corev1.Secret
+		secretName := getCertSecretName(node.Name)
+		Expect(k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)).Should(Succeed())
+		cert, err := parseCertFromSecret(secret)
+		Expect(err).Should(BeNil())
+
+		Expect(handler.Undo(context.Background(), node.Name)).Should(Succeed())
+		Expect(revokedCert).ShouldNot(BeNil())
+		Expect(revokedCert.SerialNumber).Should(Equal(cert.SerialNumber))
+
+		err = k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)
+		Expect(errors.IsNotFound(err)).Should(BeTrue())
+	})
+
+	It("should keep cert secret if failed to revoke certificate", func() {
+		handler.revoker = crl.RevokeFunc(func(cert *x509.Certificate) error {
+			return fmt.Errorf("failed")
+		})
+
+		Expect(handler.Undo(context.Background(), node.Name)).ShouldNot(Succeed())
+
+		var secret corev1.Secret
+		secretName := getCertSecretName(node.Name)
+		Expect(k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)).Should(Succeed())
+	})
+
+	It("should delete cert secret and record an event if certificate revocation is not supported", func() {
+		handler.revoker = crl.RevokeFunc(func(cert *x509.Certificate) error {
+			return crl.ErrNotSupported
+		})
+
+		Expect(handler.Undo(context.Background(), node.Name)).Should(Succeed())
+
+		var secret corev1.Secret
+		secretName := getCertSecretName(node.Name)
+		err := k8sClient.Get(context.Background(), ObjectKey{Namespace: namespace, Name: secretName}, &secret)
+		Expect(errors.IsNotFound(err)).Should(BeTrue())
+
+		recorder := handler.recorder.(*record.FakeRecorder)
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning CertificateNotRevoked")))
+	})
 })
@@ -17,6 +17,7 @@ package agent
 import (
 	"context"
 	"fmt"
+	"reflect"
 
 	"github.com/go-logr/logr"
 	"gopkg.in/yaml.v3"
@@ -61,6 +62,11 @@ func (handler *configHandler) Do(ctx context.Context, node corev1.Node) error {
 	isConfigNotFound := errors.IsNotFound(err)
 
 	networkConf := handler.buildNetworkConf(node.Name)
+	// pre-shared keys are synchronized before config, so agent won't find new peers without keys
+	if err = handler.syncPSKSecret(ctx, node, networkConf); err != nil {
+		return err
+	}
+
 	configDataBytes, err := yaml.Marshal(networkConf)
 	if err != nil {
 		handler.log.Error(err, "not able to marshal NetworkConf")
@@ -138,7 +144,8 @@ func (handler *configHandler) getPeers(name string) []apis.Endpoint {
 	store := handler.store
 	nameSet := sets.NewString()
 
-	for _, community := range store.GetCommunitiesByEndpoint(name) {
+	communities := store.GetCommunitiesByEndpoint(name)
+	for _, community := range communities {
 		nameSet.Insert(community.Members.List()...)
 	}
 	nameSet.Delete(name)
@@ -147,11 +154,73 @@ func (handler *configHandler) getPeers(name string) []apis.Endpoint {
 	// always put connector endpoint first
 	endpoints = append(endpoints, handler.getConnectorEndpoint())
 	endpoints = append(endpoints, store.GetEndpoints(nameSet.List()...)...)
+	types.ApplyCommunityIPSecParameters(communities, name, endpoints)
 
 	return endpoints
 }
 
+// syncPSKSecret saves pre-shared keys of tunnels between node and its peers
+// in a secret owned by node, peers whose keys are not available are skipped
+func (handler *configHandler) syncPSKSecret(ctx context.Context, node corev1.Node, conf netconf.NetworkConf) error {
+	secretName := getAgentPSKSecretName(node.Name)
+	log := handler.log.WithValues("nodeName", node.Name, "secretName", secretName, "namespace", handler.namespace)
+
+	data, err := types.BuildPSKSecretData(ctx, handler.client, handler.namespace, conf.Endpoint, conf.Peers)
+	if err != nil {
+		log.Error(err, "some pre-shared keys are not available")
+	}
+
+	var secret corev1.Secret
+	err = handler.client.Get(ctx, ObjectKey{Name: secretName, Namespace: handler.namespace}, &secret)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "failed to get agent psk secret")
+		return err
+	}
+
+	if errors.IsNotFound(err) {
+		if len(data) == 0 {
+			return nil
+		}
+
+		log.V(5).Info("Agent psk secret is not found, create it now")
+		secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: handler.namespace,
+				Labels: map[string]string{
+					constants.KeyCreatedBy: constants.AppOperator,
+					constants.KeyNode:      node.Name,
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: data,
+		}
+
+		if err = controllerutil.SetControllerReference(&node, &secret, scheme.Scheme); err != nil {
+			log.Error(err, "failed to set ownerReference to secret")
+			return err
+		}
+
+		return handler.client.Create(ctx, &secret)
+	}
+
+	if reflect.DeepEqual(secret.Data, data) || (len(secret.Data) == 0 && len(data) == 0) {
+		return nil
+	}
+
+	secret.Data = data
+	if err = handler.client.Update(ctx, &secret); err != nil {
+		log.Error(err, "failed to update agent psk secret")
+	}
+
+	return err
+}
+
 func (handler *configHandler) Undo(ctx context.Context, nodeName string) error {
+	if err := handler.deletePSKSecret(ctx, nodeName); err != nil {
+		return err
+	}
+
 	config := corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      getAgentConfigMapName(nodeName),
@@ -169,6 +238,28 @@ func (handler *configHandler) Undo(ctx context.Context, nodeName string) error {
 	return err
 }
 
+func (handler *configHandler) deletePSKSecret(ctx context.Context, nodeName string) error {
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getAgentPSKSecretName(nodeName),
+			Namespace: handler.namespace,
+		},
+	}
+	err := handler.client.Delete(ctx, &secret)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			err = nil
+		} else {
+			handler.log.Error(err, "failed to delete secret", "name", secret.Name, "namespace", secret.Namespace)
+		}
+	}
+	return err
+}
+
 func getAgentConfigMapName(nodeName string) string {
 	return fmt.Sprintf("fabedge-agent-config-%s", nodeName)
 }
+
+func getAgentPSKSecretName(nodeName string) string {
+	return fmt.Sprintf("fabedge-agent-psk-%s", nodeName)
+}
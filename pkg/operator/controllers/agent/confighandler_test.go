@@ -23,6 +23,7 @@ import (
 	"gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2/klogr"
 
@@ -31,6 +32,7 @@ import (
 	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
 	"github.com/fabedge/fabedge/pkg/operator/types"
 	nodeutil "github.com/fabedge/fabedge/pkg/util/node"
+	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
 )
 
 var _ = Describe("ConfigHandler", func() {
@@ -186,6 +188,48 @@ var _ = Describe("ConfigHandler", func() {
 		Expect(conf).Should(Equal(expectedConf))
 	})
 
+	It("Do should save pre-shared keys of peers in psk secret", func() {
+		pskSecret := corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "edge2-psk",
+				Namespace: namespace,
+			},
+			Data: map[string][]byte{
+				secretutil.KeyPSK: []byte("edge2-secret"),
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), &pskSecret)).To(Succeed())
+		defer k8sClient.Delete(context.TODO(), &pskSecret)
+
+		edge2Endpoint.PSKSecretName = pskSecret.Name
+		store.SaveEndpoint(edge2Endpoint)
+
+		By("re-executing Do method")
+		Expect(handler.Do(context.TODO(), node)).To(Succeed())
+
+		var secret corev1.Secret
+		err := k8sClient.Get(context.Background(), ObjectKey{Name: getAgentPSKSecretName(node.Name), Namespace: namespace}, &secret)
+		Expect(err).ShouldNot(HaveOccurred())
+		expectOwnerReference(&secret, node)
+		Expect(secret.Data).To(Equal(map[string][]byte{
+			edge2Endpoint.Name: []byte("edge2-secret"),
+		}))
+
+		By("changing pre-shared key")
+		pskSecret.Data[secretutil.KeyPSK] = []byte("edge2-new-secret")
+		Expect(k8sClient.Update(context.TODO(), &pskSecret)).To(Succeed())
+		Expect(handler.Do(context.TODO(), node)).To(Succeed())
+
+		err = k8sClient.Get(context.Background(), ObjectKey{Name: getAgentPSKSecretName(node.Name), Namespace: namespace}, &secret)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(secret.Data[edge2Endpoint.Name]).To(Equal([]byte("edge2-new-secret")))
+
+		By("undo")
+		Expect(handler.Undo(context.TODO(), node.Name)).To(Succeed())
+		err = k8sClient.Get(context.Background(), ObjectKey{Name: getAgentPSKSecretName(node.Name), Namespace: namespace}, &secret)
+		Expect(errors.IsNotFound(err)).Should(BeTrue())
+	})
+
 	It("Undo should delete configmap created by Do method", func() {
 		Expect(handler.Undo(context.TODO(), node.Name)).To(Succeed())
 
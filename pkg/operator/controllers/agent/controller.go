@@ -23,6 +23,7 @@ import (
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	ctrlpkg "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -33,7 +34,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/common/constants"
 	"github.com/fabedge/fabedge/pkg/operator/allocator"
+	"github.com/fabedge/fabedge/pkg/operator/crl"
 	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
 	"github.com/fabedge/fabedge/pkg/operator/types"
 	certutil "github.com/fabedge/fabedge/pkg/util/cert"
@@ -49,8 +52,27 @@ const (
 	agentConfigTunnelFileName = "tunnels.yaml"
 
 	keyRestartAgent = "restartAgent"
+	keyRequeueAfter = "requeueAfter"
 )
 
+// requeueTimer is put in context by controller, handlers use it to ask controller
+// to reconcile a node again later, the earliest time wins
+type requeueTimer struct {
+	after time.Duration
+}
+
+// requeueAfter asks controller to reconcile the node again after d
+func requeueAfter(ctx context.Context, d time.Duration) {
+	timer, ok := ctx.Value(keyRequeueAfter).(*requeueTimer)
+	if !ok || d <= 0 {
+		return
+	}
+
+	if timer.after == 0 || d < timer.after {
+		timer.after = d
+	}
+}
+
 type ObjectKey = client.ObjectKey
 
 type Handler interface {
@@ -65,6 +87,10 @@ type agentController struct {
 	client      client.Client
 	log         logr.Logger
 	edgeNameSet *types.SafeStringSet
+
+	namespace            string
+	store                storepkg.Interface
+	getConnectorEndpoint types.EndpointGetter
 }
 
 type Config struct {
@@ -78,6 +104,8 @@ type Config struct {
 	StrongswanImage   string
 	ImagePullPolicy   string
 	AgentPodArguments types.AgentArgumentMap
+	// MediationEnabled tells if connector is used as mediator, edge nodes can't use wireguard then
+	MediationEnabled bool
 
 	GetConnectorEndpoint types.EndpointGetter
 	NewEndpoint          types.NewEndpointFunc
@@ -86,6 +114,12 @@ type Config struct {
 
 	CertManager      certutil.Manager
 	CertOrganization string
+	// CertRenewalFraction is the fraction of certificate's lifetime after which agent's certificate is renewed
+	CertRenewalFraction float64
+	// CertKeyAlgorithm is the algorithm of private keys created for agents
+	CertKeyAlgorithm certutil.KeyAlgorithm
+	// CertRevoker revokes certificate of a node when the node is removed, optional
+	CertRevoker crl.Revoker
 }
 
 func AddToManager(cnf Config) error {
@@ -93,12 +127,26 @@ func AddToManager(cnf Config) error {
 
 	log := mgr.GetLogger().WithName(controllerName)
 	cli := mgr.GetClient()
+	recorder := mgr.GetEventRecorderFor(types.EventSource)
 
 	reconciler := &agentController{
 		log:         log,
 		client:      cli,
 		edgeNameSet: types.NewSafeStringSet(),
-		handlers:    initHandlers(cnf, cli, log),
+		handlers:    initHandlers(cnf, cli, recorder, log),
+
+		namespace:            cnf.Namespace,
+		store:                cnf.Store,
+		getConnectorEndpoint: cnf.GetConnectorEndpoint,
+	}
+
+	removedNodeChan := make(chan event.GenericEvent)
+	err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		reconciler.enqueueRemovedNodes(ctx, removedNodeChan)
+		return nil
+	}))
+	if err != nil {
+		return err
 	}
 
 	return ctrlpkg.NewControllerManagedBy(mgr).
@@ -107,11 +155,14 @@ func AddToManager(cnf Config) error {
 		Owns(&corev1.Secret{}).
 		Owns(&corev1.Pod{}).
 		Watches(&source.Channel{Source: cnf.CommunityChan}, newCommunityEventHandler(cnf.ClusterName, mgr.GetLogger())).
+		Watches(&source.Channel{Source: removedNodeChan}, &handler.EnqueueRequestForObject{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(reconciler.mapPSKSecretToNodes)).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(reconciler.mapCertSecretToNode)).
 		Named(controllerName).
 		Complete(reconciler)
 }
 
-func initHandlers(cnf Config, cli client.Client, log logr.Logger) []Handler {
+func initHandlers(cnf Config, cli client.Client, recorder record.EventRecorder, log logr.Logger) []Handler {
 	var handlers []Handler
 	if len(cnf.Allocators) != 0 {
 		handlers = append(handlers, &allocatablePodCIDRsHandler{
@@ -120,6 +171,7 @@ func initHandlers(cnf Config, cli client.Client, log logr.Logger) []Handler {
 			newEndpoint:     cnf.NewEndpoint,
 			getEndpointName: cnf.GetEndpointName,
 			client:          cli,
+			recorder:        recorder,
 			log:             log.WithName("podCIDRsHandler"),
 		})
 	} else {
@@ -142,10 +194,14 @@ func initHandlers(cnf Config, cli client.Client, log logr.Logger) []Handler {
 	handlers = append(handlers, &certHandler{
 		namespace: cnf.Namespace,
 		client:    cli,
+		recorder:  recorder,
 
 		certManager:      cnf.CertManager,
 		getEndpointName:  cnf.GetEndpointName,
 		certOrganization: cnf.CertOrganization,
+		renewalFraction:  cnf.CertRenewalFraction,
+		keyAlgorithm:     cnf.CertKeyAlgorithm,
+		revoker:          cnf.CertRevoker,
 
 		log: log.WithName("certHandler"),
 	})
@@ -155,14 +211,16 @@ func initHandlers(cnf Config, cli client.Client, log logr.Logger) []Handler {
 	handlers = append(handlers, &agentPodHandler{
 		namespace: cnf.Namespace,
 		client:    cli,
+		recorder:  recorder,
 		log:       log.WithName("agentPodHandler"),
 
-		imagePullPolicy: corev1.PullPolicy(cnf.ImagePullPolicy),
-		agentImage:      cnf.AgentImage,
-		strongswanImage: cnf.StrongswanImage,
-		argMap:          cnf.AgentPodArguments,
-		args:            cnf.AgentPodArguments.ArgumentArray(),
-		agentNameSet:    types.NewSafeStringSet(),
+		imagePullPolicy:  corev1.PullPolicy(cnf.ImagePullPolicy),
+		agentImage:       cnf.AgentImage,
+		strongswanImage:  cnf.StrongswanImage,
+		argMap:           cnf.AgentPodArguments,
+		args:             cnf.AgentPodArguments.ArgumentArray(),
+		mediationEnabled: cnf.MediationEnabled,
+		agentNameSet:     types.NewSafeStringSet(),
 	})
 
 	return handlers
@@ -190,6 +248,9 @@ func (ctl *agentController) Reconcile(ctx context.Context, request reconcile.Req
 		return reconcile.Result{}, nil
 	}
 
+	timer := &requeueTimer{}
+	ctx = context.WithValue(ctx, keyRequeueAfter, timer)
+
 	ctl.edgeNameSet.Insert(node.Name)
 	for _, handler := range ctl.handlers {
 		if err := handler.Do(ctx, node); err != nil {
@@ -203,7 +264,7 @@ func (ctl *agentController) Reconcile(ctx context.Context, request reconcile.Req
 		}
 	}
 
-	return reconcile.Result{}, nil
+	return reconcile.Result{RequeueAfter: timer.after}, nil
 }
 
 func (ctl *agentController) shouldSkip(node corev1.Node) bool {
@@ -226,6 +287,90 @@ func (ctl *agentController) clearAllocatedResourcesForEdgeNode(ctx context.Conte
 	return nil
 }
 
+// enqueueRemovedNodes finds TLS secrets of nodes which are deleted or not edge nodes anymore,
+// e.g. nodes deleted when operator is not running. TLS secrets are not owned by nodes, so
+// these nodes are reconciled again to revoke their certificates and clear their resources
+func (ctl *agentController) enqueueRemovedNodes(ctx context.Context, ch chan<- event.GenericEvent) {
+	var secrets corev1.SecretList
+	err := ctl.client.List(ctx, &secrets,
+		client.InNamespace(ctl.namespace),
+		client.MatchingLabels{constants.KeyCreatedBy: constants.AppOperator},
+		client.HasLabels{constants.KeyNode})
+	if err != nil {
+		ctl.log.Error(err, "failed to list TLS secrets of agents")
+		return
+	}
+
+	for _, secret := range secrets.Items {
+		nodeName := secret.Labels[constants.KeyNode]
+		if secret.Name != getCertSecretName(nodeName) {
+			continue
+		}
+
+		var node corev1.Node
+		err = ctl.client.Get(ctx, ObjectKey{Name: nodeName}, &node)
+		switch {
+		case err == nil:
+			if node.DeletionTimestamp == nil && nodeutil.IsEdgeNode(node) {
+				continue
+			}
+		case errors.IsNotFound(err):
+			node.Name = nodeName
+		default:
+			ctl.log.Error(err, "failed to get node", "nodeName", nodeName)
+			continue
+		}
+
+		ctl.log.V(3).Info("node is removed, clear its resources", "nodeName", nodeName)
+		ctl.edgeNameSet.Insert(nodeName)
+		select {
+		case ch <- event.GenericEvent{Object: &node}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// mapCertSecretToNode enqueues the node whose TLS secret changes, TLS secrets are
+// not owned by nodes, so they are not watched by Owns
+func (ctl *agentController) mapCertSecretToNode(obj client.Object) []reconcile.Request {
+	nodeName := obj.GetLabels()[constants.KeyNode]
+	if obj.GetNamespace() != ctl.namespace || nodeName == "" || obj.GetName() != getCertSecretName(nodeName) {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: ObjectKey{Name: nodeName}}}
+}
+
+// mapPSKSecretToNodes enqueues all edge nodes when a secret referenced as
+// pre-shared key by any endpoint changes, so that agents get rotated keys
+func (ctl *agentController) mapPSKSecretToNodes(obj client.Object) []reconcile.Request {
+	if obj.GetNamespace() != ctl.namespace || !ctl.isPSKSecret(obj.GetName()) {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, name := range ctl.edgeNameSet.List() {
+		requests = append(requests, reconcile.Request{NamespacedName: ObjectKey{Name: name}})
+	}
+
+	return requests
+}
+
+func (ctl *agentController) isPSKSecret(name string) bool {
+	if ctl.getConnectorEndpoint().PSKSecretName == name {
+		return true
+	}
+
+	for _, endpoint := range ctl.store.GetEndpoints(ctl.store.GetAllEndpointNames().List()...) {
+		if endpoint.PSKSecretName == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 func newCommunityEventHandler(clusterName string, log logr.Logger) handler.EventHandler {
 	prefix := fmt.Sprintf("%s.", clusterName)
 	return handler.Funcs{
@@ -32,6 +32,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/common/constants"
 	"github.com/fabedge/fabedge/pkg/operator/types"
 	testutil "github.com/fabedge/fabedge/pkg/util/test"
 )
@@ -243,6 +244,69 @@ var _ = Describe("AgentController", func() {
 	})
 })
 
+var _ = Describe("enqueueRemovedNodes", func() {
+	var (
+		controller *agentController
+		edgeNode   corev1.Node
+		namespace  = "default"
+	)
+
+	newCertSecret := func(nodeName string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      getCertSecretName(nodeName),
+				Namespace: namespace,
+				Labels: map[string]string{
+					constants.KeyCreatedBy: constants.AppOperator,
+					constants.KeyNode:      nodeName,
+				},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		controller = &agentController{
+			namespace:   namespace,
+			edgeNameSet: types.NewSafeStringSet(),
+			client:      k8sClient,
+			log:         klogr.New().WithName(controllerName),
+		}
+
+		edgeNode = newNodePodCIDRsInAnnotations(getNodeName(), "10.40.20.181", "")
+		Expect(k8sClient.Create(context.Background(), &edgeNode)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(testutil.PurgeAllSecrets(k8sClient, client.InNamespace(namespace))).To(Succeed())
+		Expect(testutil.PurgeAllNodes(k8sClient)).To(Succeed())
+	})
+
+	It("should enqueue nodes which are removed but whose TLS secrets are left", func() {
+		removedNodeName := getNodeName()
+		Expect(k8sClient.Create(context.Background(), newCertSecret(removedNodeName))).To(Succeed())
+		Expect(k8sClient.Create(context.Background(), newCertSecret(edgeNode.Name))).To(Succeed())
+
+		ch := make(chan event.GenericEvent, 2)
+		controller.enqueueRemovedNodes(context.Background(), ch)
+
+		Expect(ch).To(HaveLen(1))
+		evt := <-ch
+		Expect(evt.Object.GetName()).To(Equal(removedNodeName))
+		Expect(controller.edgeNameSet.Has(removedNodeName)).To(BeTrue())
+		Expect(controller.edgeNameSet.Has(edgeNode.Name)).To(BeFalse())
+	})
+
+	It("should map TLS secret to its node", func() {
+		secret := newCertSecret(edgeNode.Name)
+		Expect(controller.mapCertSecretToNode(secret)).To(ConsistOf(reconcile.Request{
+			NamespacedName: ObjectKey{Name: edgeNode.Name},
+		}))
+
+		secret.Name = getAgentPSKSecretName(edgeNode.Name)
+		Expect(controller.mapCertSecretToNode(secret)).To(BeEmpty())
+	})
+})
+
 var _ = Describe("newCommunityEventHandler", func() {
 	var (
 		handler handler.EventHandler
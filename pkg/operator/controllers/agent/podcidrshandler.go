@@ -21,6 +21,7 @@ import (
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/fabedge/fabedge/pkg/common/constants"
@@ -33,6 +34,7 @@ var _ Handler = &allocatablePodCIDRsHandler{}
 
 type allocatablePodCIDRsHandler struct {
 	client          client.Client
+	recorder        record.EventRecorder
 	allocators      []allocator.Interface
 	store           storepkg.Interface
 	newEndpoint     types.NewEndpointFunc
@@ -47,7 +49,7 @@ func (handler *allocatablePodCIDRsHandler) Do(ctx context.Context, node corev1.N
 		if err := handler.allocateSubnet(ctx, node); err != nil {
 			return err
 		}
-		handler.reclaimPodCIDRs(currentEndpoint.Subnets)
+		handler.reclaimPodCIDRs(node, currentEndpoint.Subnets)
 	} else {
 		handler.store.SaveEndpointAsLocal(currentEndpoint)
 	}
@@ -94,6 +96,10 @@ func (handler *allocatablePodCIDRsHandler) allocateSubnet(ctx context.Context, n
 		subnet, err := alloc.GetFreeSubnetBlock(node.Name)
 		if err != nil {
 			log.Error(err, "failed to allocate subnet for node")
+			if err == allocator.ErrNoAvailableSubnet {
+				handler.recorder.Eventf(&node, corev1.EventTypeWarning, types.EventReasonSubnetPoolExhausted,
+					"no subnet is available in pool %s", alloc.Usage().Pool)
+			}
 
 			// reclaim allocated subnet if possible
 			if i > 0 {
@@ -133,7 +139,7 @@ func (handler *allocatablePodCIDRsHandler) allocateSubnet(ctx context.Context, n
 
 // reclaimPodCIDRs try its best to reclaim podCIDRs to corresponding
 // allocator, if a podCIDR is out of range of any allocators, it will just be discarded
-func (handler *allocatablePodCIDRsHandler) reclaimPodCIDRs(podCIDRs []string) {
+func (handler *allocatablePodCIDRsHandler) reclaimPodCIDRs(node corev1.Node, podCIDRs []string) {
 	for _, podCIDR := range podCIDRs {
 		_, ipNet, err := net.ParseCIDR(podCIDR)
 		if err != nil {
@@ -144,6 +150,8 @@ func (handler *allocatablePodCIDRsHandler) reclaimPodCIDRs(podCIDRs []string) {
 		for _, alloc := range handler.allocators {
 			if alloc.Contains(*ipNet) {
 				_ = alloc.Reclaim(*ipNet)
+				handler.recorder.Eventf(&node, corev1.EventTypeNormal, types.EventReasonPodCIDRReclaimed,
+					"pod CIDR %s is reclaimed", podCIDR)
 			}
 		}
 	}
@@ -161,6 +169,11 @@ func (handler *allocatablePodCIDRsHandler) Undo(ctx context.Context, nodeName st
 	handler.store.DeleteEndpoint(ep.Name)
 	log.V(5).Info("endpoint is delete from store", "endpoint", ep)
 
+	// the node may be deleted already or just stop being an edge node, events
+	// are recorded only when the node still exists
+	var node corev1.Node
+	nodeExists := handler.client.Get(ctx, client.ObjectKey{Name: nodeName}, &node) == nil
+
 	for _, sn := range ep.Subnets {
 		_, subnet, err := net.ParseCIDR(sn)
 		if err != nil {
@@ -172,6 +185,11 @@ func (handler *allocatablePodCIDRsHandler) Undo(ctx context.Context, nodeName st
 			if alloc.Contains(*subnet) {
 				_ = alloc.Reclaim(*subnet)
 				log.V(5).Info("subnet is reclaimed", "subnet", subnet)
+
+				if nodeExists {
+					handler.recorder.Eventf(&node, corev1.EventTypeNormal, types.EventReasonPodCIDRReclaimed,
+						"pod CIDR %s is reclaimed", sn)
+				}
 			}
 		}
 	}
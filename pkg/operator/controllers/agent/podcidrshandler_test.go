@@ -22,6 +22,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2/klogr"
 
 	"github.com/fabedge/fabedge/pkg/common/constants"
@@ -54,6 +55,7 @@ var _ = Describe("allocatablePodCIDRsHandler", func() {
 				getEndpointName: getEndpointName,
 				newEndpoint:     newEndpoint,
 				client:          k8sClient,
+				recorder:        record.NewFakeRecorder(10),
 				log:             klogr.New().WithName("podCIDRsHandler"),
 			}
 			return handler, nil
@@ -169,6 +171,22 @@ var _ = Describe("allocatablePodCIDRsHandler", func() {
 			Entry("[IPv6 Only] IPv4 PodCIDR is allocated", []string{"fd85:ee78:d8a6:8607::1:0000/112"}, []int{122}, "2.2.0.1/26"),
 			Entry("[IPv6 Only] IPv6 PodCIDR is out of range", []string{"fd85:ee78:d8a6:8607::1:0000/112"}, []int{122}, "fd85:ee79:d8a6:8607::1:0001/122"),
 		)
+
+		It("should record an event on node when subnet pool is exhausted", func() {
+			handler, err := newHandler([]string{"2.2.0.0/25"}, []int{26})
+			Expect(err).Should(BeNil())
+			for _, subnet := range []string{"2.2.0.0/26", "2.2.0.64/26"} {
+				_, ipNet, _ := net.ParseCIDR(subnet)
+				Expect(handler.allocators[0].Record(*ipNet)).To(Succeed())
+			}
+
+			node := newNode(getNodeName(), "10.40.20.181", "")
+			Expect(k8sClient.Create(context.Background(), &node)).Should(Succeed())
+			Expect(handler.Do(context.TODO(), node)).Should(Equal(allocator.ErrNoAvailableSubnet))
+
+			recorder := handler.recorder.(*record.FakeRecorder)
+			Expect(recorder.Events).To(Receive(Equal("Warning SubnetPoolExhausted no subnet is available in pool 2.2.0.0/25")))
+		})
 	})
 
 	Context("Undo method", func() {
@@ -191,11 +209,13 @@ var _ = Describe("allocatablePodCIDRsHandler", func() {
 				_, ok := handler.store.GetEndpoint(epName)
 				Expect(ok).Should(BeFalse())
 
+				recorder := handler.recorder.(*record.FakeRecorder)
 				for i, cidr := range podCIDRs {
 					_, ipNet, err := net.ParseCIDR(cidr)
 					Expect(err).Should(Succeed())
 
 					Expect(handler.allocators[i].IsAllocated(*ipNet)).Should(BeFalse())
+					Expect(recorder.Events).To(Receive(Equal("Normal PodCIDRReclaimed pod CIDR " + cidr + " is reclaimed")))
 				}
 			},
 			Entry("DualStack", []string{"2.2.0.0/16", "fd85:ee78:d8a6:8607::1:0000/112"}, []int{26, 122}),
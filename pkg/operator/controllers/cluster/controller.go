@@ -2,7 +2,7 @@ package cluster
 
 import (
 	"context"
-	"crypto/rsa"
+	"crypto"
 	"sync"
 	"time"
 
@@ -10,6 +10,7 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrlpkg "sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -18,12 +19,15 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/common/constants"
 	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
 	"github.com/fabedge/fabedge/pkg/operator/types"
 )
 
 const (
 	controllerName = "cluster-controller"
+
+	defaultHeartbeatTimeout = time.Minute
 )
 
 type EndpointNameSet = sets.String
@@ -40,13 +44,25 @@ type controller struct {
 type Config struct {
 	Cluster       string
 	TokenDuration time.Duration
-	PrivateKey    *rsa.PrivateKey
-	Store         storepkg.Interface
-	Manager       manager.Manager
-	CIDRMap       *types.ClusterCIDRsMap
+	// TokenSingleUse makes tokens of member clusters usable only once
+	TokenSingleUse bool
+	// a member cluster is considered not ready if it doesn't report in HeartbeatTimeout
+	HeartbeatTimeout time.Duration
+	// PrivateKey signs tokens of member clusters, it's a RSA, ECDSA or Ed25519 key
+	PrivateKey crypto.Signer
+	Store      storepkg.Interface
+	Manager    manager.Manager
+	CIDRMap    *types.ClusterCIDRsMap
+	// EdgePodCIDRs are CIDRs of edge pods of local cluster, CIDRs of member clusters
+	// should not overlap with them, just like cluster CIDRs of local cluster
+	EdgePodCIDRs []string
 }
 
 func AddToManager(config Config) error {
+	if config.HeartbeatTimeout <= 0 {
+		config.HeartbeatTimeout = defaultHeartbeatTimeout
+	}
+
 	mgr := config.Manager
 	ctl, err := ctrlpkg.New(
 		controllerName,
@@ -85,9 +101,9 @@ func (ctl *controller) Reconcile(ctx context.Context, request reconcile.Request)
 		log.Error(err, "failed to get cluster")
 		return reconcile.Result{}, err
 	}
-	ctl.syncClusterCIDRs(cluster)
 
 	if cluster.Name == ctl.Cluster {
+		ctl.syncClusterCIDRs(cluster)
 		log.V(5).Info("This cluster is local cluster, skip it")
 		return reconcile.Result{}, nil
 	}
@@ -98,25 +114,60 @@ func (ctl *controller) Reconcile(ctx context.Context, request reconcile.Request)
 		return reconcile.Result{}, nil
 	}
 
-	if err := ctl.generateTokenIfNeeded(ctx, cluster); err != nil {
+	conflicts, err := ctl.checkCIDRConflicts(ctx, cluster)
+	if err != nil {
+		log.Error(err, "failed to check CIDR conflicts")
+		return reconcile.Result{}, err
+	}
+
+	// CIDRs and endpoints of a quarantined cluster are not provided to any cluster, or
+	// traffic to the overlapped addresses might be routed to a wrong cluster
+	if conflicts.Quarantined {
+		log.Info("cluster is quarantined because of CIDR conflicts", "conflicts", conflicts.Spec)
+		ctl.pruneEndpoints(cluster.Name)
+		ctl.CIDRMap.Delete(cluster.Name)
+	} else {
+		ctl.syncClusterCIDRs(cluster)
+	}
+
+	if err := ctl.generateTokenIfNeeded(ctx, &cluster); err != nil {
 		ctl.log.Error(err, "failed to assign token for cluster", "cluster", cluster.Name)
 		return reconcile.Result{}, err
 	}
 
-	// for now, endpoints will contain only connector of every cluster
-	ctl.syncEndpoints(cluster)
+	if !conflicts.Quarantined {
+		// for now, endpoints will contain only connector of every cluster
+		ctl.syncEndpoints(cluster)
+	}
+
+	requeueAfter, err := ctl.updateStatus(ctx, &cluster, conflicts)
+	if err != nil {
+		log.Error(err, "failed to update cluster status")
+		return reconcile.Result{}, err
+	}
 
-	return reconcile.Result{}, nil
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
 }
 
-func (ctl *controller) generateTokenIfNeeded(ctx context.Context, cluster apis.Cluster) error {
+func (ctl *controller) generateTokenIfNeeded(ctx context.Context, cluster *apis.Cluster) error {
 	if len(cluster.Spec.Token) != 0 {
 		return nil
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.StandardClaims{
-		Subject:   cluster.Name,
-		ExpiresAt: time.Now().Add(ctl.TokenDuration).Unix(),
+	method, err := types.SigningMethodOf(ctl.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(method, types.ClusterTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        string(uuid.NewUUID()),
+			Subject:   cluster.Name,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ctl.TokenDuration)),
+		},
+		SingleUse: ctl.TokenSingleUse,
 	})
 
 	tokenString, err := token.SignedString(ctl.PrivateKey)
@@ -125,7 +176,9 @@ func (ctl *controller) generateTokenIfNeeded(ctx context.Context, cluster apis.C
 	}
 
 	cluster.Spec.Token = tokenString
-	return ctl.client.Update(ctx, &cluster)
+	// the used token is replaced, so is its record
+	delete(cluster.Annotations, constants.KeyUsedTokenID)
+	return ctl.client.Update(ctx, cluster)
 }
 
 func (ctl *controller) syncEndpoints(cluster apis.Cluster) {
@@ -136,6 +189,7 @@ func (ctl *controller) syncEndpoints(cluster apis.Cluster) {
 			continue
 		}
 
+		endpoint.IPSec = cluster.Spec.IPSec
 		ctl.Store.SaveEndpoint(endpoint)
 		nameSet.Insert(endpoint.Name)
 	}
@@ -8,6 +8,7 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrlpkg "sigs.k8s.io/controller-runtime/pkg/controller"
@@ -17,6 +18,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/common/constants"
 	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
 	"github.com/fabedge/fabedge/pkg/operator/types"
 	certutil "github.com/fabedge/fabedge/pkg/util/cert"
@@ -55,11 +57,12 @@ var _ = Describe("Controller", func() {
 
 		ctrl = &controller{
 			Config: Config{
-				Cluster:       "test",
-				Store:         storepkg.NewStore(),
-				PrivateKey:    privateKey,
-				TokenDuration: time.Hour,
-				CIDRMap:       types.NewClusterCIDRsMap(),
+				Cluster:          "test",
+				Store:            storepkg.NewStore(),
+				PrivateKey:       privateKey,
+				TokenDuration:    time.Hour,
+				HeartbeatTimeout: time.Minute,
+				CIDRMap:          types.NewClusterCIDRsMap(),
 			},
 			clusterCache: make(map[string]EndpointNameSet),
 			client:       mgr.GetClient(),
@@ -147,14 +150,199 @@ var _ = Describe("Controller", func() {
 
 		Expect(cluster.Spec.Token).ShouldNot(BeEmpty())
 
-		var claims jwt.StandardClaims
+		var claims types.ClusterTokenClaims
 		token, err := jwt.ParseWithClaims(cluster.Spec.Token, &claims, func(token *jwt.Token) (interface{}, error) {
 			return cert.PublicKey, nil
 		})
 		Expect(err).Should(BeNil())
 		Expect(token.Valid).Should(BeTrue())
 		Expect(claims.Subject).Should(Equal(cluster.Name))
-		Expect(claims.ExpiresAt).Should(BeNumerically(">", time.Now().Unix()))
+		Expect(claims.ID).ShouldNot(BeEmpty())
+		Expect(claims.SingleUse).Should(BeFalse())
+		Expect(claims.ExpiresAt.Unix()).Should(BeNumerically(">", time.Now().Unix()))
+	})
+
+	It("should mark token as expired if it's single-use and used", func() {
+		err := k8sClient.Get(context.Background(), client.ObjectKey{Name: cluster.Name}, &cluster)
+		Expect(err).Should(BeNil())
+
+		token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, types.ClusterTokenClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        "token1",
+				Subject:   cluster.Name,
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			SingleUse: true,
+		}).SignedString(ctrl.PrivateKey)
+		Expect(err).Should(BeNil())
+
+		cluster.Spec.Token = token
+		cluster.Annotations = map[string]string{constants.KeyUsedTokenID: "token1"}
+		Expect(k8sClient.Update(context.Background(), &cluster)).Should(Succeed())
+		Eventually(requests, 5*time.Second).Should(ReceiveKey(client.ObjectKey{
+			Name: cluster.Name,
+		}))
+
+		err = k8sClient.Get(context.Background(), client.ObjectKey{Name: cluster.Name}, &cluster)
+		Expect(err).Should(BeNil())
+
+		tokenExpired := cluster.Status.GetCondition(apis.ClusterTokenExpired)
+		Expect(tokenExpired).ShouldNot(BeNil())
+		Expect(tokenExpired.Status).Should(Equal(corev1.ConditionTrue))
+		Expect(tokenExpired.Reason).Should(Equal(ReasonTokenUsed))
+	})
+
+	It("should set conditions of cluster", func() {
+		err := k8sClient.Get(context.Background(), client.ObjectKey{Name: cluster.Name}, &cluster)
+		Expect(err).Should(BeNil())
+
+		ready := cluster.Status.GetCondition(apis.ClusterReady)
+		Expect(ready).ShouldNot(BeNil())
+		Expect(ready.Status).Should(Equal(corev1.ConditionFalse))
+		Expect(ready.Reason).Should(Equal(ReasonNeverReported))
+
+		tokenExpired := cluster.Status.GetCondition(apis.ClusterTokenExpired)
+		Expect(tokenExpired).ShouldNot(BeNil())
+		Expect(tokenExpired.Status).Should(Equal(corev1.ConditionFalse))
+
+		cidrConflict := cluster.Status.GetCondition(apis.ClusterCIDRConflict)
+		Expect(cidrConflict).ShouldNot(BeNil())
+		Expect(cidrConflict.Status).Should(Equal(corev1.ConditionFalse))
+	})
+
+	It("should mark cluster as ready if it reported connector endpoint recently", func() {
+		err := k8sClient.Get(context.Background(), client.ObjectKey{Name: cluster.Name}, &cluster)
+		Expect(err).Should(BeNil())
+
+		base := cluster.DeepCopy()
+		connector := cluster.Spec.EndPoints[0]
+		connector.Type = apis.Connector
+		now := metav1.Now()
+		cluster.Status.LastHeartbeatTime = &now
+		cluster.Status.Endpoints = []apis.Endpoint{connector}
+		Expect(k8sClient.Status().Patch(context.Background(), &cluster, client.MergeFrom(base))).Should(Succeed())
+		Eventually(requests, 5*time.Second).Should(ReceiveKey(client.ObjectKey{
+			Name: cluster.Name,
+		}))
+
+		err = k8sClient.Get(context.Background(), client.ObjectKey{Name: cluster.Name}, &cluster)
+		Expect(err).Should(BeNil())
+
+		ready := cluster.Status.GetCondition(apis.ClusterReady)
+		Expect(ready).ShouldNot(BeNil())
+		Expect(ready.Status).Should(Equal(corev1.ConditionTrue))
+		Expect(ready.Reason).Should(Equal(ReasonHeartbeatReceived))
+	})
+
+	It("should mark cluster as not ready if its heartbeat is too old", func() {
+		err := k8sClient.Get(context.Background(), client.ObjectKey{Name: cluster.Name}, &cluster)
+		Expect(err).Should(BeNil())
+
+		base := cluster.DeepCopy()
+		connector := cluster.Spec.EndPoints[0]
+		connector.Type = apis.Connector
+		lastHeartbeatTime := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+		cluster.Status.LastHeartbeatTime = &lastHeartbeatTime
+		cluster.Status.Endpoints = []apis.Endpoint{connector}
+		Expect(k8sClient.Status().Patch(context.Background(), &cluster, client.MergeFrom(base))).Should(Succeed())
+		Eventually(requests, 5*time.Second).Should(ReceiveKey(client.ObjectKey{
+			Name: cluster.Name,
+		}))
+
+		err = k8sClient.Get(context.Background(), client.ObjectKey{Name: cluster.Name}, &cluster)
+		Expect(err).Should(BeNil())
+
+		ready := cluster.Status.GetCondition(apis.ClusterReady)
+		Expect(ready).ShouldNot(BeNil())
+		Expect(ready.Status).Should(Equal(corev1.ConditionFalse))
+		Expect(ready.Reason).Should(Equal(ReasonHeartbeatTimeout))
+	})
+
+	It("should mark cluster as CIDR conflicted if its CIDRs overlap with those of other clusters", func() {
+		ctrl.CIDRMap.Set("other", []string{"2.2.1.0/24"})
+
+		err := k8sClient.Get(context.Background(), client.ObjectKey{Name: cluster.Name}, &cluster)
+		Expect(err).Should(BeNil())
+
+		cluster.Spec.CIDRs = []string{"2.2.0.0/16", "10.10.0.0/16"}
+		Expect(k8sClient.Update(context.Background(), &cluster)).Should(Succeed())
+		Eventually(requests, 5*time.Second).Should(ReceiveKey(client.ObjectKey{
+			Name: cluster.Name,
+		}))
+
+		err = k8sClient.Get(context.Background(), client.ObjectKey{Name: cluster.Name}, &cluster)
+		Expect(err).Should(BeNil())
+
+		cidrConflict := cluster.Status.GetCondition(apis.ClusterCIDRConflict)
+		Expect(cidrConflict).ShouldNot(BeNil())
+		Expect(cidrConflict.Status).Should(Equal(corev1.ConditionTrue))
+		Expect(cidrConflict.Message).Should(ContainSubstring("2.2.0.0/16 overlaps with 2.2.1.0/24 of cluster other"))
+	})
+
+	It("should quarantine cluster if its CIDRs overlap with those of a cluster created earlier", func() {
+		later := apis.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "later",
+			},
+			Spec: apis.ClusterSpec{
+				CIDRs: []string{"2.2.1.0/24"},
+				EndPoints: []apis.Endpoint{
+					{
+						Name:            "later.connector",
+						PublicAddresses: []string{"10.10.10.20"},
+						Subnets:         []string{"2.2.1.0/24"},
+						NodeSubnets:     []string{"192.168.2.1"},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(context.Background(), &later)).To(Succeed())
+		Eventually(requests, 5*time.Second).Should(ReceiveKey(client.ObjectKey{
+			Name: later.Name,
+		}))
+
+		err := k8sClient.Get(context.Background(), client.ObjectKey{Name: later.Name}, &later)
+		Expect(err).Should(BeNil())
+
+		cidrConflict := later.Status.GetCondition(apis.ClusterCIDRConflict)
+		Expect(cidrConflict).ShouldNot(BeNil())
+		Expect(cidrConflict.Status).Should(Equal(corev1.ConditionTrue))
+		Expect(cidrConflict.Reason).Should(Equal(ReasonQuarantined))
+		Expect(cidrConflict.Message).Should(ContainSubstring("2.2.1.0/24 overlaps with 2.2.0.0/16 of cluster root"))
+		Expect(later.Status.IsQuarantined()).Should(BeTrue())
+
+		_, ok := ctrl.CIDRMap.Get(later.Name)
+		Expect(ok).Should(BeFalse())
+		_, ok = ctrl.Store.GetEndpoint("later.connector")
+		Expect(ok).Should(BeFalse())
+
+		// the earlier cluster keeps working
+		cidrs, ok := ctrl.CIDRMap.Get(cluster.Name)
+		Expect(ok).Should(BeTrue())
+		Expect(cidrs).Should(Equal(cluster.Spec.CIDRs))
+	})
+
+	It("should quarantine cluster if its CIDRs overlap with edge pod CIDRs of local cluster", func() {
+		ctrl.EdgePodCIDRs = []string{"2.2.128.0/17"}
+
+		err := k8sClient.Get(context.Background(), client.ObjectKey{Name: cluster.Name}, &cluster)
+		Expect(err).Should(BeNil())
+
+		cluster.Labels = map[string]string{"test": "edge-pod-cidrs"}
+		Expect(k8sClient.Update(context.Background(), &cluster)).Should(Succeed())
+		Eventually(requests, 5*time.Second).Should(ReceiveKey(client.ObjectKey{
+			Name: cluster.Name,
+		}))
+
+		err = k8sClient.Get(context.Background(), client.ObjectKey{Name: cluster.Name}, &cluster)
+		Expect(err).Should(BeNil())
+		Expect(cluster.Status.IsQuarantined()).Should(BeTrue())
+
+		cidrConflict := cluster.Status.GetCondition(apis.ClusterCIDRConflict)
+		Expect(cidrConflict.Message).Should(ContainSubstring("2.2.0.0/16 overlaps with 2.2.128.0/17 of cluster test"))
+
+		_, ok := ctrl.CIDRMap.Get(cluster.Name)
+		Expect(ok).Should(BeFalse())
 	})
 
 	It("should save endpoints of cluster to store when a new cluster is created", func() {
@@ -212,6 +400,24 @@ var _ = Describe("Controller", func() {
 		Expect(ep2).Should(Equal(ep))
 	})
 
+	It("should save IPSec parameters of cluster in its endpoints, which are used by tunnels of local cluster to them", func() {
+		err := k8sClient.Get(context.Background(), client.ObjectKey{Name: cluster.Name}, &cluster)
+		Expect(err).Should(BeNil())
+
+		params := &apis.IPSecParameters{ESPProposals: []string{"aes256gcm16-ecp384"}}
+		cluster.Spec.IPSec = params
+		Expect(k8sClient.Update(context.Background(), &cluster)).Should(Succeed())
+		Eventually(requests, 5*time.Second).Should(ReceiveKey(client.ObjectKey{
+			Name: cluster.Name,
+		}))
+
+		for _, ep := range cluster.Spec.EndPoints {
+			ep2, ok := ctrl.Store.GetEndpoint(ep.Name)
+			Expect(ok).Should(BeTrue())
+			Expect(ep2.IPSec).Should(Equal(params))
+		}
+	})
+
 	It("should sync cluster CIDRs when cluster is updated", func() {
 		err := k8sClient.Get(context.Background(), client.ObjectKey{Name: cluster.Name}, &cluster)
 		Expect(err).Should(BeNil())
@@ -0,0 +1,290 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/common/constants"
+	"github.com/fabedge/fabedge/pkg/operator/types"
+)
+
+const (
+	ReasonHeartbeatReceived = "HeartbeatReceived"
+	ReasonHeartbeatTimeout  = "HeartbeatTimeout"
+	ReasonNeverReported     = "NeverReported"
+	ReasonNoConnector       = "NoConnector"
+	ReasonTokenExpired      = "TokenExpired"
+	ReasonTokenValid        = "TokenValid"
+	ReasonTokenUsed         = "TokenUsed"
+	ReasonInvalidToken      = "InvalidToken"
+	ReasonCIDRConflict      = "CIDRConflict"
+	ReasonNoCIDRConflict    = "NoCIDRConflict"
+	ReasonQuarantined       = apis.ClusterQuarantined
+)
+
+// conflicts may be resolved by changes of other clusters, so conflicted clusters are checked periodically
+const cidrConflictCheckInterval = 30 * time.Second
+
+// cidrConflicts are conflicts between CIDRs of a cluster and those of other clusters
+type cidrConflicts struct {
+	// Spec are conflicts of CIDRs in cluster spec
+	Spec []types.CIDRConflict
+	// Reported are conflicts of CIDRs which are reported by the cluster but refused by API server
+	Reported []types.CIDRConflict
+	// Quarantined means some CIDRs in cluster spec overlap with those of local cluster or of
+	// clusters created earlier, the cluster is excluded until conflicts are resolved
+	Quarantined bool
+}
+
+func (c cidrConflicts) Empty() bool {
+	return len(c.Spec) == 0 && len(c.Reported) == 0
+}
+
+// updateStatus computes conditions of cluster and save status if it's changed.
+// The returned duration tells when conditions should be checked again, because
+// conditions like Ready and TokenExpired are changed by time
+func (ctl *controller) updateStatus(ctx context.Context, cluster *apis.Cluster, conflicts cidrConflicts) (time.Duration, error) {
+	status := cluster.Status.DeepCopy()
+	now := time.Now()
+
+	readyCondition, readyCheckAfter := ctl.getReadyCondition(status, now)
+	status.SetCondition(readyCondition)
+
+	tokenCondition, tokenCheckAfter := getTokenExpiredCondition(cluster, now)
+	status.SetCondition(tokenCondition)
+
+	conflictCondition, conflictCheckAfter := getCIDRConflictCondition(conflicts)
+	status.SetCondition(conflictCondition)
+
+	if !reflect.DeepEqual(*status, cluster.Status) {
+		base := cluster.DeepCopy()
+		cluster.Status = *status
+		// heartbeat is updated by apiserver at the same time, use patch to avoid conflicts
+		if err := ctl.client.Status().Patch(ctx, cluster, client.MergeFrom(base)); err != nil {
+			return 0, err
+		}
+	}
+
+	return minPositiveDuration(readyCheckAfter, tokenCheckAfter, conflictCheckAfter), nil
+}
+
+func (ctl *controller) getReadyCondition(status *apis.ClusterStatus, now time.Time) (apis.ClusterCondition, time.Duration) {
+	condition := apis.ClusterCondition{
+		Type:   apis.ClusterReady,
+		Status: corev1.ConditionFalse,
+	}
+
+	if status.LastHeartbeatTime == nil {
+		condition.Reason = ReasonNeverReported
+		condition.Message = "cluster has not reported its endpoints yet"
+		return condition, 0
+	}
+
+	elapsed := now.Sub(status.LastHeartbeatTime.Time)
+	if elapsed > ctl.HeartbeatTimeout {
+		condition.Reason = ReasonHeartbeatTimeout
+		condition.Message = fmt.Sprintf("cluster has not reported its endpoints since %s", status.LastHeartbeatTime.Format(time.RFC3339))
+		return condition, 0
+	}
+
+	checkAfter := ctl.HeartbeatTimeout - elapsed + time.Second
+	if !hasConnector(status.Endpoints) {
+		condition.Reason = ReasonNoConnector
+		condition.Message = "no connector endpoint is reported"
+		return condition, checkAfter
+	}
+
+	condition.Status = corev1.ConditionTrue
+	condition.Reason = ReasonHeartbeatReceived
+	condition.Message = "cluster reported its endpoints recently"
+
+	return condition, checkAfter
+}
+
+// getTokenExpiredCondition checks if token of cluster is expired or used up, the token is
+// signed by this controller, so it is not verified here
+func getTokenExpiredCondition(cluster *apis.Cluster, now time.Time) (apis.ClusterCondition, time.Duration) {
+	condition := apis.ClusterCondition{
+		Type:   apis.ClusterTokenExpired,
+		Status: corev1.ConditionUnknown,
+	}
+
+	var claims types.ClusterTokenClaims
+	if _, _, err := new(jwt.Parser).ParseUnverified(cluster.Spec.Token, &claims); err != nil {
+		condition.Reason = ReasonInvalidToken
+		condition.Message = err.Error()
+		return condition, 0
+	}
+
+	if claims.SingleUse && claims.ID != "" && cluster.Annotations[constants.KeyUsedTokenID] == claims.ID {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = ReasonTokenUsed
+		condition.Message = "single-use token has been used"
+		return condition, 0
+	}
+
+	if claims.ExpiresAt == nil {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = ReasonTokenValid
+		condition.Message = "token never expires"
+		return condition, 0
+	}
+
+	expiresAt := claims.ExpiresAt.Time
+	if !now.Before(expiresAt) {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = ReasonTokenExpired
+		condition.Message = fmt.Sprintf("token expired at %s", expiresAt.Format(time.RFC3339))
+		return condition, 0
+	}
+
+	condition.Status = corev1.ConditionFalse
+	condition.Reason = ReasonTokenValid
+	condition.Message = fmt.Sprintf("token will expire at %s", expiresAt.Format(time.RFC3339))
+	return condition, expiresAt.Sub(now) + time.Second
+}
+
+// checkCIDRConflicts finds CIDRs of cluster which overlap with CIDRs of local cluster, including
+// edge pod CIDRs, and CIDRs of other clusters. When two clusters have overlapped CIDRs, the
+// cluster created earlier keeps working and the later one is quarantined, CIDRs of local cluster
+// and of clusters without cluster objects, e.g. those from upper level host clusters, always win
+func (ctl *controller) checkCIDRConflicts(ctx context.Context, cluster apis.Cluster) (cidrConflicts, error) {
+	var clusterList apis.ClusterList
+	if err := ctl.client.List(ctx, &clusterList); err != nil {
+		return cidrConflicts{}, err
+	}
+
+	// CIDRs of quarantined clusters are not in CIDRMap, so CIDRs of clusters are taken from
+	// their specs, otherwise a cluster may be quarantined or not depending on reconciling order
+	cidrsByName := make(map[string][]string)
+	for name, cidrs := range ctl.CIDRMap.GetCopy() {
+		cidrsByName[name] = cidrs
+	}
+
+	clusters := make(map[string]*apis.Cluster, len(clusterList.Items))
+	for i := range clusterList.Items {
+		other := &clusterList.Items[i]
+		if other.Name == ctl.Cluster || other.DeletionTimestamp != nil {
+			continue
+		}
+
+		clusters[other.Name] = other
+		cidrsByName[other.Name] = other.Spec.CIDRs
+	}
+
+	localCIDRs := append([]string{}, cidrsByName[ctl.Cluster]...)
+	cidrsByName[ctl.Cluster] = append(localCIDRs, ctl.EdgePodCIDRs...)
+
+	var conflicts cidrConflicts
+	conflicts.Spec = types.FindCIDRConflicts(cluster.Name, cluster.Spec.CIDRs, cidrsByName)
+	for _, c := range conflicts.Spec {
+		other, found := clusters[c.OtherCluster]
+		if !found || joinedEarlier(other, &cluster) {
+			conflicts.Quarantined = true
+			break
+		}
+	}
+
+	specCIDRs := sets.NewString(cluster.Spec.CIDRs...)
+	var refusedCIDRs []string
+	for _, cidr := range cluster.Status.CIDRs {
+		if !specCIDRs.Has(cidr) {
+			refusedCIDRs = append(refusedCIDRs, cidr)
+		}
+	}
+	conflicts.Reported = types.FindCIDRConflicts(cluster.Name, refusedCIDRs, cidrsByName)
+
+	return conflicts, nil
+}
+
+// joinedEarlier tells if cluster a is created before cluster b, names are compared
+// when they're created at the same time, so the result is always stable
+func joinedEarlier(a, b *apis.Cluster) bool {
+	ta, tb := a.CreationTimestamp, b.CreationTimestamp
+	if ta.Equal(&tb) {
+		return a.Name < b.Name
+	}
+
+	return ta.Before(&tb)
+}
+
+// getCIDRConflictCondition makes CIDRConflict condition from conflicts, the returned
+// duration tells when conflicts should be checked again
+func getCIDRConflictCondition(conflicts cidrConflicts) (apis.ClusterCondition, time.Duration) {
+	condition := apis.ClusterCondition{
+		Type:    apis.ClusterCIDRConflict,
+		Status:  corev1.ConditionFalse,
+		Reason:  ReasonNoCIDRConflict,
+		Message: "no CIDR conflict with other clusters",
+	}
+
+	if conflicts.Empty() {
+		return condition, 0
+	}
+
+	var messages []string
+	for _, c := range conflicts.Spec {
+		messages = append(messages, c.String())
+	}
+	for _, c := range conflicts.Reported {
+		messages = append(messages, fmt.Sprintf("reported %s", c))
+	}
+
+	condition.Status = corev1.ConditionTrue
+	condition.Reason = ReasonCIDRConflict
+	condition.Message = strings.Join(messages, "; ")
+	if conflicts.Quarantined {
+		condition.Reason = ReasonQuarantined
+		condition.Message = "cluster is quarantined: " + condition.Message
+	}
+
+	return condition, cidrConflictCheckInterval
+}
+
+func hasConnector(endpoints []apis.Endpoint) bool {
+	for _, endpoint := range endpoints {
+		if endpoint.Type == apis.Connector {
+			return true
+		}
+	}
+
+	return false
+}
+
+func minPositiveDuration(durations ...time.Duration) time.Duration {
+	var min time.Duration
+	for _, d := range durations {
+		if d <= 0 {
+			continue
+		}
+
+		if min == 0 || d < min {
+			min = d
+		}
+	}
+
+	return min
+}
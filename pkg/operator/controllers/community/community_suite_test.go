@@ -26,6 +26,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 
+	nodeutil "github.com/fabedge/fabedge/pkg/util/node"
 	testutil "github.com/fabedge/fabedge/pkg/util/test"
 )
 
@@ -43,6 +44,9 @@ func TestCommunity(t *testing.T) {
 
 var _ = BeforeSuite(func(done Done) {
 	testutil.SetupLogger()
+	nodeutil.SetEdgeNodeLabels(map[string]string{
+		"edge": "",
+	})
 
 	By("starting test environment")
 	var err error
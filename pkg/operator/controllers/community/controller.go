@@ -16,64 +16,115 @@ package community
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctlpkg "sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
 	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
 	"github.com/fabedge/fabedge/pkg/operator/types"
+	nodeutil "github.com/fabedge/fabedge/pkg/util/node"
 )
 
 const (
 	controllerName = "community-controller"
+
+	defaultStatusSyncInterval = 30 * time.Second
 )
 
 type ObjectKey = client.ObjectKey
 
 func AddToManager(config Config) error {
 	mgr := config.Manager
+	if config.StatusSyncInterval <= 0 {
+		config.StatusSyncInterval = defaultStatusSyncInterval
+	}
+
+	reconciler := &communityController{
+		store:              config.Store,
+		client:             mgr.GetClient(),
+		namespace:          config.Namespace,
+		statusSyncInterval: config.StatusSyncInterval,
+		getEndpointName:    config.GetEndpointName,
+		communityChan:      config.CommunityChan,
+		log:                mgr.GetLogger().WithName(controllerName),
+	}
 	ctl, err := ctlpkg.New(
 		controllerName,
 		mgr,
 		ctlpkg.Options{
-			Reconciler: &communityController{
-				store:         config.Store,
-				client:        mgr.GetClient(),
-				communityChan: config.CommunityChan,
-				log:           mgr.GetLogger().WithName(controllerName),
-			},
+			Reconciler: reconciler,
 		},
 	)
 	if err != nil {
 		return err
 	}
 
-	return ctl.Watch(
+	err = ctl.Watch(
 		&source.Kind{Type: &apis.Community{}},
 		&handler.EnqueueRequestForObject{},
 	)
+	if err != nil {
+		return err
+	}
+
+	// members selected by labels have to be re-evaluated when labels of nodes or clusters are changed
+	err = ctl.Watch(
+		&source.Kind{Type: &corev1.Node{}},
+		handler.EnqueueRequestsFromMapFunc(reconciler.communitiesWithNodeSelector),
+		predicate.LabelChangedPredicate{},
+	)
+	if err != nil {
+		return err
+	}
+
+	if !config.EnableClusterSelector {
+		return nil
+	}
+
+	return ctl.Watch(
+		&source.Kind{Type: &apis.Cluster{}},
+		handler.EnqueueRequestsFromMapFunc(reconciler.communitiesWithClusterSelector),
+		predicate.LabelChangedPredicate{},
+	)
 }
 
 type Config struct {
-	Manager       manager.Manager
-	Store         storepkg.Interface
-	CommunityChan chan<- event.GenericEvent
+	Manager manager.Manager
+	Store   storepkg.Interface
+	// Namespace is where agents and connector run, their pods
+	// are used to collect tunnel status
+	Namespace string
+	// StatusSyncInterval decides how often community status is updated,
+	// because tunnel status is changed without notifying community controller
+	StatusSyncInterval time.Duration
+	// GetEndpointName is used to get endpoint names of nodes selected by NodeSelector
+	GetEndpointName types.GetNameFunc
+	// EnableClusterSelector should only be true in host cluster where clusters are saved
+	EnableClusterSelector bool
+	CommunityChan         chan<- event.GenericEvent
 }
 
 type communityController struct {
-	client        client.Client
-	log           logr.Logger
-	store         storepkg.Interface
-	communityChan chan<- event.GenericEvent
+	client             client.Client
+	log                logr.Logger
+	store              storepkg.Interface
+	namespace          string
+	statusSyncInterval time.Duration
+	getEndpointName    types.GetNameFunc
+	communityChan      chan<- event.GenericEvent
 }
 
 func (ctl *communityController) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
@@ -101,16 +152,113 @@ func (ctl *communityController) Reconcile(ctx context.Context, request reconcile
 		return reconcile.Result{}, nil
 	}
 
+	members, err := ctl.getMembers(ctx, &community)
+	if err != nil {
+		ctl.log.Error(err, "failed to get members of community", "name", community.Name)
+		return reconcile.Result{}, err
+	}
+
+	// old members are needed to notify endpoints which are removed from community
+	affectedMembers := members
+	if cm, found := ctl.store.GetCommunity(community.Name); found {
+		affectedMembers = members.Union(cm.Members)
+	}
+
 	ctl.store.SaveCommunity(types.Community{
 		Name:    community.Name,
-		Members: sets.NewString(community.Spec.Members...),
+		Members: members,
+		IPSec:   community.Spec.IPSec,
 	})
 
 	// must send event after save community to store, otherwise
 	// when agentController might get incorrect data
+	evtCommunity := community.DeepCopy()
+	evtCommunity.Spec.Members = affectedMembers.List()
 	ctl.communityChan <- event.GenericEvent{
-		Object: &community,
+		Object: evtCommunity,
+	}
+
+	if err = ctl.updateStatus(ctx, &community, members); err != nil {
+		ctl.log.Error(err, "failed to update community status", "name", community.Name)
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{RequeueAfter: ctl.statusSyncInterval}, nil
+}
+
+// getMembers returns members listed in spec and members selected by selectors
+func (ctl *communityController) getMembers(ctx context.Context, community *apis.Community) (sets.String, error) {
+	members := sets.NewString(community.Spec.Members...)
+
+	if community.Spec.NodeSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(community.Spec.NodeSelector)
+		if err != nil {
+			return nil, err
+		}
+
+		var nodes corev1.NodeList
+		if err = ctl.client.List(ctx, &nodes, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, err
+		}
+
+		for _, node := range nodes.Items {
+			// only edge nodes have endpoints
+			if nodeutil.IsEdgeNode(node) {
+				members.Insert(ctl.getEndpointName(node.Name))
+			}
+		}
+	}
+
+	if community.Spec.ClusterSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(community.Spec.ClusterSelector)
+		if err != nil {
+			return nil, err
+		}
+
+		var clusters apis.ClusterList
+		if err = ctl.client.List(ctx, &clusters, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, err
+		}
+
+		for _, cluster := range clusters.Items {
+			for _, endpoint := range cluster.Spec.EndPoints {
+				if endpoint.Type == apis.Connector {
+					members.Insert(endpoint.Name)
+				}
+			}
+		}
+	}
+
+	return members, nil
+}
+
+func (ctl *communityController) communitiesWithNodeSelector(_ client.Object) []reconcile.Request {
+	return ctl.listCommunityRequests(func(community apis.Community) bool {
+		return community.Spec.NodeSelector != nil
+	})
+}
+
+func (ctl *communityController) communitiesWithClusterSelector(_ client.Object) []reconcile.Request {
+	return ctl.listCommunityRequests(func(community apis.Community) bool {
+		return community.Spec.ClusterSelector != nil
+	})
+}
+
+func (ctl *communityController) listCommunityRequests(filter func(community apis.Community) bool) []reconcile.Request {
+	var communities apis.CommunityList
+	if err := ctl.client.List(context.TODO(), &communities); err != nil {
+		ctl.log.Error(err, "failed to list communities")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, community := range communities.Items {
+		if filter(community) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: ObjectKey{Name: community.Name},
+			})
+		}
 	}
 
-	return reconcile.Result{}, nil
+	return requests
 }
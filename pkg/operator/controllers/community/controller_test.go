@@ -20,15 +20,19 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/common/constants"
 	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
 	testutil "github.com/fabedge/fabedge/pkg/util/test"
 )
@@ -53,13 +57,18 @@ var _ = Describe("Controller", func() {
 		})
 		Expect(err).ShouldNot(HaveOccurred())
 
-		reconciler := reconcile.Reconciler(&communityController{
+		ctl := &communityController{
 			client:        mgr.GetClient(),
 			communityChan: communityChan,
 			store:         store,
-			log:           mgr.GetLogger().WithName(controllerName),
-		})
-		reconciler, requests = testutil.WrapReconcile(reconciler)
+			namespace:     "default",
+			getEndpointName: func(nodeName string) string {
+				return "test." + nodeName
+			},
+			log: mgr.GetLogger().WithName(controllerName),
+		}
+		var reconciler reconcile.Reconciler
+		reconciler, requests = testutil.WrapReconcile(ctl)
 		c, err := controller.New(
 			controllerName,
 			mgr,
@@ -75,6 +84,13 @@ var _ = Describe("Controller", func() {
 		)
 		Expect(err).ShouldNot(HaveOccurred())
 
+		err = c.Watch(
+			&source.Kind{Type: &corev1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(ctl.communitiesWithNodeSelector),
+			predicate.LabelChangedPredicate{},
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+
 		go func() {
 			defer GinkgoRecover()
 			Expect(mgr.Start(ctx)).NotTo(HaveOccurred())
@@ -110,6 +126,103 @@ var _ = Describe("Controller", func() {
 		for _, cmm := range communities.Items {
 			Expect(k8sClient.Delete(context.Background(), &cmm))
 		}
+
+		Expect(k8sClient.DeleteAllOf(context.Background(), &corev1.Pod{}, client.InNamespace("default"), client.GracePeriodSeconds(0))).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(context.Background(), &corev1.Node{})).To(Succeed())
+	})
+
+	It("should add edge nodes selected by node selector to members and re-evaluate when node labels are changed", func() {
+		for _, node := range []corev1.Node{
+			newNode("node1", map[string]string{"edge": "", "group": "a"}),
+			newNode("node2", map[string]string{"edge": "", "group": "b"}),
+			newNode("node3", map[string]string{"group": "a"}),
+		} {
+			Expect(k8sClient.Create(context.Background(), &node)).To(Succeed())
+		}
+
+		community := apis.Community{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test",
+			},
+			Spec: apis.CommunitySpec{
+				Members: []string{"edge1"},
+				NodeSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"group": "a"},
+				},
+			},
+		}
+		Expect(k8sClient.Create(context.Background(), &community)).To(Succeed())
+
+		Eventually(requests, 5*time.Second).Should(Receive(Equal(reconcile.Request{
+			NamespacedName: ObjectKey{Name: community.Name},
+		})))
+
+		cmm, ok := store.GetCommunity(community.Name)
+		Expect(ok).Should(BeTrue())
+		Expect(cmm.Members.List()).To(ConsistOf("edge1", "test.node1"))
+
+		By("change labels of node2")
+		var node corev1.Node
+		Expect(k8sClient.Get(context.Background(), ObjectKey{Name: "node2"}, &node)).To(Succeed())
+		node.Labels["group"] = "a"
+		Expect(k8sClient.Update(context.Background(), &node)).To(Succeed())
+
+		Eventually(requests, 5*time.Second).Should(Receive(Equal(reconcile.Request{
+			NamespacedName: ObjectKey{Name: community.Name},
+		})))
+
+		cmm, ok = store.GetCommunity(community.Name)
+		Expect(ok).Should(BeTrue())
+		Expect(cmm.Members.List()).To(ConsistOf("edge1", "test.node1", "test.node2"))
+	})
+
+	It("should update status with resolved members, unresolved members and tunnel counts", func() {
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "agent-edge1",
+				Namespace: "default",
+				Annotations: map[string]string{
+					constants.KeyEndpointName:       "edge1",
+					constants.KeyEstablishedTunnels: "edge2,mediator",
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  "agent",
+						Image: "fabedge/agent",
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(context.Background(), &pod)).To(Succeed())
+
+		community := apis.Community{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test",
+			},
+			Spec: apis.CommunitySpec{
+				Members: []string{
+					"edge4",
+					"edge1",
+					"edge2",
+					"edge3",
+				},
+			},
+		}
+		Expect(k8sClient.Create(context.Background(), &community)).To(Succeed())
+
+		Eventually(requests, 5*time.Second).Should(Receive(Equal(reconcile.Request{
+			NamespacedName: ObjectKey{Name: community.Name},
+		})))
+
+		Expect(k8sClient.Get(context.Background(), ObjectKey{Name: community.Name}, &community)).To(Succeed())
+		Expect(community.Status).To(Equal(apis.CommunityStatus{
+			ResolvedMembers:    []string{"edge1", "edge2", "edge4"},
+			UnresolvedMembers:  []string{"edge3"},
+			ExpectedTunnels:    3,
+			EstablishedTunnels: 1,
+		}))
 	})
 
 	It("should save community and update communities of related endpoints in store", func() {
@@ -199,6 +312,15 @@ var _ = Describe("Controller", func() {
 	})
 })
 
+func newNode(name string, labels map[string]string) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
+
 func drainCommunityChan(ch chan event.GenericEvent, timeout time.Duration) *event.GenericEvent {
 	for {
 		select {
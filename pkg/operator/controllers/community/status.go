@@ -0,0 +1,105 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package community
+
+import (
+	"context"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/common/tunnelstatus"
+)
+
+// updateStatus computes status of community and save it if it's changed
+func (ctl *communityController) updateStatus(ctx context.Context, community *apis.Community, members sets.String) error {
+	status, err := ctl.computeStatus(ctx, members)
+	if err != nil {
+		return err
+	}
+
+	if reflect.DeepEqual(status, community.Status) {
+		return nil
+	}
+
+	base := community.DeepCopy()
+	community.Status = status
+	return ctl.client.Status().Patch(ctx, community, client.MergeFrom(base))
+}
+
+func (ctl *communityController) computeStatus(ctx context.Context, members sets.String) (apis.CommunityStatus, error) {
+	var status apis.CommunityStatus
+	for _, name := range members.List() {
+		if _, found := ctl.store.GetEndpoint(name); found {
+			status.ResolvedMembers = append(status.ResolvedMembers, name)
+		} else {
+			status.UnresolvedMembers = append(status.UnresolvedMembers, name)
+		}
+	}
+
+	resolved := status.ResolvedMembers
+	status.ExpectedTunnels = len(resolved) * (len(resolved) - 1) / 2
+	if status.ExpectedTunnels == 0 {
+		return status, nil
+	}
+
+	establishedTunnels, err := ctl.getEstablishedTunnels(ctx)
+	if err != nil {
+		return status, err
+	}
+
+	// a tunnel is established if either side of it reports so, because
+	// the other side may not be able to report, e.g. it's in another cluster
+	for i := 0; i < len(resolved); i++ {
+		for j := i + 1; j < len(resolved); j++ {
+			if establishedTunnels[resolved[i]].Has(resolved[j]) ||
+				establishedTunnels[resolved[j]].Has(resolved[i]) {
+				status.EstablishedTunnels++
+			}
+		}
+	}
+
+	return status, nil
+}
+
+// getEstablishedTunnels collects tunnel status reported by agents and connectors.
+// The key of result is an endpoint name and the value is names of peers which
+// that endpoint has established tunnels with
+func (ctl *communityController) getEstablishedTunnels(ctx context.Context) (map[string]sets.String, error) {
+	var pods corev1.PodList
+	if err := ctl.client.List(ctx, &pods, client.InNamespace(ctl.namespace)); err != nil {
+		return nil, err
+	}
+
+	establishedTunnels := make(map[string]sets.String)
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		endpointName, peers, ok := tunnelstatus.Parse(pod.Annotations)
+		if !ok {
+			continue
+		}
+
+		// there might be more than one connector pods, union their status
+		establishedTunnels[endpointName] = establishedTunnels[endpointName].Union(peers)
+	}
+
+	return establishedTunnels, nil
+}
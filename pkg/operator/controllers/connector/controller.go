@@ -19,6 +19,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"reflect"
 	"sync"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	controllerpkg "sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -40,6 +42,7 @@ import (
 	"github.com/fabedge/fabedge/pkg/common/netconf"
 	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
 	"github.com/fabedge/fabedge/pkg/operator/types"
+	"github.com/fabedge/fabedge/pkg/tunnel/wireguard"
 	certutil "github.com/fabedge/fabedge/pkg/util/cert"
 	nodeutil "github.com/fabedge/fabedge/pkg/util/node"
 	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
@@ -56,6 +59,7 @@ type Node struct {
 }
 
 type Config struct {
+	ClusterName     string
 	Namespace       string
 	Endpoint        apis.Endpoint
 	ProvidedSubnets []string
@@ -65,6 +69,10 @@ type Config struct {
 
 	CertOrganization string
 	SyncInterval     time.Duration
+	// CertRenewalFraction is the fraction of certificate's lifetime after which connector's certificate is renewed
+	CertRenewalFraction float64
+	// CertKeyAlgorithm is the algorithm of private key created for connector
+	CertKeyAlgorithm certutil.KeyAlgorithm
 
 	Store   storepkg.Interface
 	Manager manager.Manager
@@ -75,8 +83,9 @@ type Config struct {
 type controller struct {
 	Config
 
-	client client.Client
-	log    logr.Logger
+	client   client.Client
+	recorder record.EventRecorder
+	log      logr.Logger
 
 	nodeNameSet sets.String
 	nodeCache   map[string]Node
@@ -96,6 +105,7 @@ func AddToManager(cnf Config) (types.EndpointGetter, error) {
 		nodeNameSet: sets.NewString(),
 		nodeCache:   make(map[string]Node),
 		client:      mgr.GetClient(),
+		recorder:    mgr.GetEventRecorderFor(types.EventSource),
 		log:         mgr.GetLogger().WithName(controllerName),
 	}
 
@@ -128,24 +138,47 @@ func AddToManager(cnf Config) (types.EndpointGetter, error) {
 
 func (ctl *controller) SyncConnectorConfig(ctx context.Context) error {
 	tick := time.NewTicker(ctl.SyncInterval)
+	defer tick.Stop()
+
+	// renewal fires when connector's certificate needs renewal, so the certificate
+	// is renewed on time no matter how long the sync interval is. It's stopped when
+	// there is no renewal to wait for, failed renewals are retried by tick
+	renewal := time.NewTimer(time.Hour)
+	defer renewal.Stop()
+	resetRenewal := func(after time.Duration) {
+		if !renewal.Stop() {
+			select {
+			case <-renewal.C:
+			default:
+			}
+		}
+		if after > 0 {
+			renewal.Reset(after)
+		}
+	}
 
-	ctl.operateConnector()
+	resetRenewal(ctl.operateConnector())
 	for {
 		select {
 		case <-tick.C:
-			ctl.operateConnector()
+			resetRenewal(ctl.operateConnector())
+		case <-renewal.C:
+			resetRenewal(ctl.operateConnector())
 		case <-ctx.Done():
 			return nil
 		}
 	}
 }
 
-func (ctl *controller) operateConnector() {
+// operateConnector returns how long to wait before connector's certificate needs renewal
+func (ctl *controller) operateConnector() time.Duration {
 	ctl.updateConfigMapIfNeeded()
-	generated := ctl.generateCertIfNeeded()
+	generated, renewAfter := ctl.generateCertIfNeeded()
 	if generated {
 		ctl.restartConnectorPods()
 	}
+
+	return renewAfter
 }
 
 func (ctl *controller) updateConfigMapIfNeeded() {
@@ -168,6 +201,9 @@ func (ctl *controller) updateConfigMapIfNeeded() {
 		conf.Mediator = &mediator
 	}
 
+	// pre-shared keys are synchronized before config, so connector won't find new peers without keys
+	ctl.updatePSKSecretIfNeeded(ctx, conf)
+
 	confBytes, err := yaml.Marshal(conf)
 	if err != nil {
 		log.Error(err, "failed to marshal connector tunnels conf")
@@ -213,7 +249,65 @@ func (ctl *controller) updateConfigMapIfNeeded() {
 	}
 }
 
-func (ctl *controller) generateCertIfNeeded() bool {
+// updatePSKSecretIfNeeded saves pre-shared keys of tunnels between connector and
+// its peers in connector psk secret, connector reads keys from the mounted secret
+// periodically, so keys are rotated without restarting connector
+func (ctl *controller) updatePSKSecretIfNeeded(ctx context.Context, conf netconf.NetworkConf) {
+	key := client.ObjectKey{
+		Name:      constants.ConnectorPSKName,
+		Namespace: ctl.Namespace,
+	}
+	log := ctl.log.WithValues("key", key)
+
+	data, err := types.BuildPSKSecretData(ctx, ctl.client, ctl.Namespace, conf.Endpoint, conf.Peers)
+	if err != nil {
+		log.Error(err, "some pre-shared keys are not available")
+	}
+
+	var secret corev1.Secret
+	err = ctl.client.Get(ctx, key, &secret)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "failed to get connector psk secret")
+		return
+	}
+
+	if errors.IsNotFound(err) {
+		if len(data) == 0 {
+			return
+		}
+
+		log.V(5).Info("connector psk secret is not found, create it now")
+		secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+				Labels: map[string]string{
+					constants.KeyCreatedBy: constants.AppOperator,
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: data,
+		}
+		if err = ctl.client.Create(ctx, &secret); err != nil {
+			log.Error(err, "failed to create connector psk secret")
+		}
+		return
+	}
+
+	if reflect.DeepEqual(secret.Data, data) || (len(secret.Data) == 0 && len(data) == 0) {
+		return
+	}
+
+	log.V(5).Info("pre-shared keys are changed, update connector psk secret now")
+	secret.Data = data
+	if err = ctl.client.Update(ctx, &secret); err != nil {
+		log.Error(err, "failed to update connector psk secret")
+	}
+}
+
+// generateCertIfNeeded makes sure connector has a valid certificate, it returns true if
+// the certificate is regenerated and how long to wait before the certificate needs renewal
+func (ctl *controller) generateCertIfNeeded() (bool, time.Duration) {
 	key := client.ObjectKey{
 		Name:      constants.ConnectorTLSName,
 		Namespace: ctl.Namespace,
@@ -228,47 +322,163 @@ func (ctl *controller) generateCertIfNeeded() bool {
 	if err != nil {
 		if !errors.IsNotFound(err) {
 			ctl.log.Error(err, "failed to get secret")
-			return false
+			return false, 0
 		}
 
 		log.V(5).Info("TLS secret for connector is not found, generate it now")
-		secret, err = ctl.buildCertAndKeySecret(key)
+		secret, err = ctl.buildCertAndKeySecret(key, "")
 		if err != nil {
 			log.Error(err, "failed to create cert and key for connector")
-			return false
+			return false, 0
 		}
 
 		err = ctl.client.Create(ctx, &secret)
 		if err != nil {
 			log.Error(err, "failed to create secret")
-			return false
+			return false, 0
 		}
 
-		return true
+		ctl.updatePublicKey(secret)
+		return true, ctl.renewAfter(secret)
 	}
 
-	err = ctl.verifyCert(secret)
-	if err == nil {
+	verifyErr := ctl.verifyCert(secret)
+	if verifyErr == nil {
 		log.V(5).Info("connector's certificate is verified")
-		return false
+		ctl.renewCertIfNeeded(ctx, &secret)
+		added := ctl.addWireGuardKeyIfNeeded(ctx, &secret)
+		ctl.updatePublicKey(secret)
+		return added, ctl.renewAfter(secret)
 	}
 
-	log.Error(err, "failed to verify cert, need to regenerate a cert for connector")
-	secret, err = ctl.buildCertAndKeySecret(key)
+	log.Error(verifyErr, "failed to verify cert, need to regenerate a cert for connector")
+	// wireguard key is kept, or the public key of connector is changed and peers are broken
+	secret, err = ctl.buildCertAndKeySecret(key, secretutil.GetWireGuardKey(secret))
 	if err != nil {
 		log.Error(err, "failed to recreate cert and key for connector")
-		return false
+		return false, 0
 	}
 
 	err = ctl.client.Update(ctx, &secret)
 	if err != nil {
 		log.Error(err, "failed to save secret")
+		return false, 0
+	}
+	ctl.recordClusterEvent(ctx, corev1.EventTypeNormal, types.EventReasonCertRenewed,
+		"certificate in secret %s of connector is renewed: %s", key.Name, verifyErr)
+
+	ctl.updatePublicKey(secret)
+	return true, ctl.renewAfter(secret)
+}
+
+// renewAfter returns how long to wait before the certificate in secret needs renewal,
+// 0 is returned if it won't be renewed before it expires
+func (ctl *controller) renewAfter(secret corev1.Secret) time.Duration {
+	cert, err := parseCertFromSecret(secret)
+	if err != nil {
+		return 0
+	}
+
+	renewalTime, ok := certutil.RenewalTime(cert, ctl.CertRenewalFraction)
+	if !ok {
+		return 0
+	}
+
+	return time.Until(renewalTime)
+}
+
+// renewCertIfNeeded signs a new certificate with connector's private key when the
+// certificate is near expiry. Connector pods are not restarted, connector will
+// reload the certificate when secret volume is updated
+func (ctl *controller) renewCertIfNeeded(ctx context.Context, secret *corev1.Secret) {
+	cert, err := parseCertFromSecret(*secret)
+	if err != nil {
+		return
+	}
+
+	if !certutil.NeedRenewal(cert, ctl.CertRenewalFraction) {
+		return
+	}
+
+	log := ctl.log.WithValues("secretName", secret.Name)
+	log.V(3).Info("connector's certificate is near expiry, renew it", "notAfter", cert.NotAfter)
+
+	keyDER, err := certutil.DecodePEM(secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		log.Error(err, "failed to decode private key")
+		return
+	}
+
+	csr, err := certutil.NewCertRequestWithKey(keyDER, ctl.buildCertRequest())
+	if err != nil {
+		log.Error(err, "failed to create cert request")
+		return
+	}
+
+	certDER, err := ctl.CertManager.SignCert(csr)
+	if err != nil {
+		log.Error(err, "failed to sign cert")
+		return
+	}
+
+	secret.Data[corev1.TLSCertKey] = certutil.EncodeCertPEM(certDER)
+	if err = ctl.client.Update(ctx, secret); err != nil {
+		log.Error(err, "failed to save renewed cert")
+		return
+	}
+	ctl.recordClusterEvent(ctx, corev1.EventTypeNormal, types.EventReasonCertRenewed,
+		"certificate in secret %s of connector is renewed before it expires at %s",
+		secret.Name, cert.NotAfter.Format(time.RFC3339))
+}
+
+// addWireGuardKeyIfNeeded adds a wireguard private key to connector's TLS secret
+// if it doesn't have one, it returns true if the key is added
+func (ctl *controller) addWireGuardKeyIfNeeded(ctx context.Context, secret *corev1.Secret) bool {
+	if secretutil.GetWireGuardKey(*secret) != "" {
+		return false
+	}
+
+	key, err := wireguard.GenerateKey()
+	if err != nil {
+		ctl.log.Error(err, "failed to generate wireguard key")
+		return false
+	}
+
+	secret.Data[secretutil.KeyWireGuardKey] = []byte(key)
+	if err = ctl.client.Update(ctx, secret); err != nil {
+		ctl.log.Error(err, "failed to save wireguard key to secret")
+		delete(secret.Data, secretutil.KeyWireGuardKey)
 		return false
 	}
 
 	return true
 }
 
+// updatePublicKey sets public key of connector endpoint with the
+// public key of wireguard private key in connector's TLS secret
+func (ctl *controller) updatePublicKey(secret corev1.Secret) {
+	key := secretutil.GetWireGuardKey(secret)
+	if key == "" {
+		return
+	}
+
+	publicKey, err := wireguard.PublicKey(key)
+	if err != nil {
+		ctl.log.Error(err, "failed to compute wireguard public key")
+		return
+	}
+
+	ctl.mux.Lock()
+	defer ctl.mux.Unlock()
+
+	if ctl.Endpoint.PublicKey == publicKey {
+		return
+	}
+
+	ctl.Endpoint.PublicKey = publicKey
+	ctl.Store.SaveEndpointAsLocal(ctl.Endpoint)
+}
+
 func (ctl *controller) verifyCert(secret corev1.Secret) error {
 	cert, err := parseCertFromSecret(secret)
 	if err != nil {
@@ -282,6 +492,18 @@ func (ctl *controller) verifyCert(secret corev1.Secret) error {
 	return ctl.CertManager.VerifyCert(cert, certutil.ExtKeyUsagesServerAndClient)
 }
 
+// recordClusterEvent records an event on local cluster, connector has no kubernetes
+// object of its own
+func (ctl *controller) recordClusterEvent(ctx context.Context, eventType, reason, messageFmt string, args ...interface{}) {
+	var cluster apis.Cluster
+	if err := ctl.client.Get(ctx, client.ObjectKey{Name: ctl.ClusterName}, &cluster); err != nil {
+		ctl.log.Error(err, "failed to get local cluster, event is not recorded", "reason", reason)
+		return
+	}
+
+	ctl.recorder.Eventf(&cluster, eventType, reason, messageFmt, args...)
+}
+
 func (ctl *controller) restartConnectorPods() {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
@@ -299,11 +521,18 @@ func (ctl *controller) restartConnectorPods() {
 	}
 }
 
-func (ctl *controller) buildCertAndKeySecret(key client.ObjectKey) (corev1.Secret, error) {
-	keyDER, csr, err := certutil.NewCertRequest(certutil.Request{
+func (ctl *controller) buildCertRequest() certutil.Request {
+	return certutil.Request{
 		CommonName:   ctl.Endpoint.Name,
 		Organization: []string{ctl.CertOrganization},
-	})
+		KeyAlgorithm: ctl.CertKeyAlgorithm,
+	}
+}
+
+// buildCertAndKeySecret creates a TLS secret with a new certificate and private key, wgKey
+// is put in the secret as wireguard private key, a new one is generated if it's empty
+func (ctl *controller) buildCertAndKeySecret(key client.ObjectKey, wgKey string) (corev1.Secret, error) {
+	keyDER, csr, err := certutil.NewCertRequest(ctl.buildCertRequest())
 	if err != nil {
 		return corev1.Secret{}, err
 	}
@@ -313,12 +542,19 @@ func (ctl *controller) buildCertAndKeySecret(key client.ObjectKey) (corev1.Secre
 		return corev1.Secret{}, err
 	}
 
+	if wgKey == "" {
+		if wgKey, err = wireguard.GenerateKey(); err != nil {
+			return corev1.Secret{}, err
+		}
+	}
+
 	return secretutil.TLSSecret().
 		Name(key.Name).
 		Namespace(key.Namespace).
 		EncodeCert(certDER).
 		EncodeKey(keyDER).
-		CACertPEM(ctl.CertManager.GetCACertPEM()).
+		CACertPEM(ctl.CertManager.GetCABundlePEM()).
+		WireGuardKey(wgKey).
 		Label(constants.KeyCreatedBy, constants.AppOperator).Build(), nil
 }
 
@@ -326,7 +562,8 @@ func (ctl *controller) getPeers() []apis.Endpoint {
 	connectorName := ctl.Endpoint.Name
 
 	nameSet := ctl.Store.GetLocalEndpointNames()
-	for _, community := range ctl.Store.GetCommunitiesByEndpoint(connectorName) {
+	communities := ctl.Store.GetCommunitiesByEndpoint(connectorName)
+	for _, community := range communities {
 		for name := range community.Members {
 			nameSet.Insert(name)
 		}
@@ -334,6 +571,7 @@ func (ctl *controller) getPeers() []apis.Endpoint {
 	nameSet.Delete(connectorName)
 
 	endpoints := ctl.Store.GetEndpoints(nameSet.List()...)
+	types.ApplyCommunityIPSecParameters(communities, connectorName, endpoints)
 
 	peers := make([]apis.Endpoint, 0, len(endpoints))
 	peers = append(peers, endpoints...)
@@ -33,6 +33,7 @@ import (
 	"github.com/fabedge/fabedge/pkg/common/netconf"
 	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
 	"github.com/fabedge/fabedge/pkg/operator/types"
+	"github.com/fabedge/fabedge/pkg/tunnel/wireguard"
 	certutil "github.com/fabedge/fabedge/pkg/util/cert"
 	nodeutil "github.com/fabedge/fabedge/pkg/util/node"
 	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
@@ -261,7 +262,7 @@ var _ = Describe("Controller", func() {
 		By("Checking TLS secret")
 		caCertPEM, certPEM := secretutil.GetCACert(secret), secretutil.GetCert(secret)
 		Expect(certManager.VerifyCertInPEM(certPEM, certutil.ExtKeyUsagesServerAndClient)).Should(Succeed())
-		Expect(caCertPEM).Should(Equal(certManager.GetCACertPEM()))
+		Expect(caCertPEM).Should(Equal(certManager.GetCABundlePEM()))
 
 		certDER, err := certutil.DecodePEM(certPEM)
 		Expect(err).Should(BeNil())
@@ -271,6 +272,11 @@ var _ = Describe("Controller", func() {
 		Expect(cert.Subject.Organization[0]).To(Equal(config.CertOrganization))
 		Expect(cert.Subject.CommonName).To(Equal(getConnectorEndpoint().Name))
 
+		By("Checking wireguard public key of connector endpoint")
+		publicKey, err := wireguard.PublicKey(secretutil.GetWireGuardKey(secret))
+		Expect(err).Should(BeNil())
+		Expect(getConnectorEndpoint().PublicKey).Should(Equal(publicKey))
+
 		By("Changing TLS secret with expired cert")
 		certDER, keyDER, _ := certManager.NewCertKey(certutil.Config{
 			CommonName:     getConnectorEndpoint().Name,
@@ -287,10 +293,10 @@ var _ = Describe("Controller", func() {
 		Expect(k8sClient.Get(context.Background(), key, &secret)).Should(Succeed())
 		caCertPEM, certPEM = secretutil.GetCACert(secret), secretutil.GetCert(secret)
 		Expect(certManager.VerifyCertInPEM(certPEM, certutil.ExtKeyUsagesServerAndClient)).Should(Succeed())
-		Expect(caCertPEM).Should(Equal(certManager.GetCACertPEM()))
+		Expect(caCertPEM).Should(Equal(certManager.GetCABundlePEM()))
 	})
 
-	It("should recreate a tls secret for connector if commonName is wrong", func() {
+	It("should recreate a tls secret for connector but keep wireguard key if commonName is wrong", func() {
 		time.Sleep(interval + time.Second)
 
 		key := client.ObjectKey{
@@ -299,6 +305,7 @@ var _ = Describe("Controller", func() {
 		}
 		var secret corev1.Secret
 		Expect(k8sClient.Get(context.Background(), key, &secret)).Should(Succeed())
+		wgKey := secretutil.GetWireGuardKey(secret)
 
 		By("Changing TLS secret with wrong commonName")
 		certDER, keyDER, _ := certManager.NewCertKey(certutil.Config{
@@ -318,6 +325,7 @@ var _ = Describe("Controller", func() {
 		cert, err := parseCertFromSecret(secret)
 		Expect(err).Should(BeNil())
 		Expect(cert.Subject.CommonName).To(Equal(getConnectorEndpoint().Name))
+		Expect(secretutil.GetWireGuardKey(secret)).To(Equal(wgKey))
 	})
 
 	It("should delete connector pods if a tls secret is generated", func() {
@@ -344,6 +352,26 @@ var _ = Describe("Controller", func() {
 
 		expectConnectorDeleted(connectorPod, 2*interval)
 	})
+
+	It("should tell how long to wait before connector's certificate needs renewal", func() {
+		certDER, keyDER, _ := certManager.NewCertKey(certutil.Config{
+			CommonName:     config.Endpoint.Name,
+			ValidityPeriod: 10 * time.Hour,
+		})
+		secret := corev1.Secret{
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       certutil.EncodeCertPEM(certDER),
+				corev1.TLSPrivateKeyKey: certutil.EncodePrivateKeyPEM(keyDER),
+			},
+		}
+
+		ctl := &controller{Config: config}
+		ctl.CertRenewalFraction = 0.5
+		Expect(ctl.renewAfter(secret)).Should(BeNumerically("~", 5*time.Hour, time.Minute))
+
+		ctl.CertRenewalFraction = 0
+		Expect(ctl.renewAfter(secret)).Should(BeZero())
+	})
 })
 
 func newNormalNode(ip, subnets string) corev1.Node {
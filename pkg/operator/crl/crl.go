@@ -0,0 +1,257 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package crl maintains the certificate revocation list of certificates issued by
+// fabedge-operator, the CRL is saved in a configmap which is mounted by connectors
+package crl
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fabedge/fabedge/pkg/common/constants"
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+)
+
+const (
+	// ConfigMapName is the name of configmap which holds CRL
+	ConfigMapName = "fabedge-crl"
+	// KeyCRL is the key of CRL in configmap, CRL is saved in PEM form
+	KeyCRL = "crl.pem"
+
+	// DefaultValidPeriod is how long a CRL is valid, it's re-signed after half of it
+	DefaultValidPeriod = 7 * 24 * time.Hour
+)
+
+// ErrNotSupported is returned by revokers when certificates can't be revoked,
+// e.g. CRL can't be signed when certificates are issued by an external signer
+var ErrNotSupported = fmt.Errorf("certificate revocation is not supported")
+
+// Revoker revokes certificates
+type Revoker interface {
+	Revoke(ctx context.Context, cert *x509.Certificate) error
+}
+
+// Checker checks if a certificate is revoked
+type Checker interface {
+	IsRevoked(ctx context.Context, cert *x509.Certificate) (bool, error)
+}
+
+var _ Revoker = &LocalRevoker{}
+var _ Revoker = RevokeFunc(nil)
+var _ Checker = &ConfigMapChecker{}
+
+// RevokeFunc revokes certificates by other means, e.g. member clusters
+// revoke certificates through API server of host cluster
+type RevokeFunc func(cert *x509.Certificate) error
+
+func (fn RevokeFunc) Revoke(ctx context.Context, cert *x509.Certificate) error {
+	return fn(cert)
+}
+
+// LocalRevoker adds revoked certificates to CRL in configmap, the CRL is signed by
+// local CA, so it's only used in host cluster
+type LocalRevoker struct {
+	Namespace   string
+	Signer      certutil.CRLSigner
+	Client      client.Client
+	ValidPeriod time.Duration
+
+	mu sync.Mutex
+}
+
+func (r *LocalRevoker) Revoke(ctx context.Context, cert *x509.Certificate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revoked, err := r.getRevokedCertificates(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, rc := range revoked {
+		if rc.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return nil
+		}
+	}
+
+	revoked = append(revoked, pkix.RevokedCertificate{
+		SerialNumber:   cert.SerialNumber,
+		RevocationTime: time.Now().UTC(),
+	})
+
+	return r.save(ctx, revoked)
+}
+
+// Refresh re-signs CRL when half of its valid period has passed or it's
+// not signed by current CA, e.g. after CA is rotated
+func (r *LocalRevoker) Refresh(ctx context.Context, caCert *x509.Certificate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	crl, err := Get(ctx, r.Client, r.Namespace)
+	if err != nil || crl == nil {
+		return err
+	}
+
+	thisUpdate, nextUpdate := crl.TBSCertList.ThisUpdate, crl.TBSCertList.NextUpdate
+	halfLife := thisUpdate.Add(nextUpdate.Sub(thisUpdate) / 2)
+	if time.Now().Before(halfLife) && caCert.CheckCRLSignature(crl) == nil {
+		return nil
+	}
+
+	return r.save(ctx, crl.TBSCertList.RevokedCertificates)
+}
+
+func (r *LocalRevoker) getRevokedCertificates(ctx context.Context) ([]pkix.RevokedCertificate, error) {
+	crl, err := Get(ctx, r.Client, r.Namespace)
+	if err != nil || crl == nil {
+		return nil, err
+	}
+
+	return crl.TBSCertList.RevokedCertificates, nil
+}
+
+func (r *LocalRevoker) save(ctx context.Context, revoked []pkix.RevokedCertificate) error {
+	validPeriod := r.ValidPeriod
+	if validPeriod <= 0 {
+		validPeriod = DefaultValidPeriod
+	}
+
+	crlDER, err := r.Signer.SignCRL(revoked, validPeriod)
+	if err != nil {
+		return err
+	}
+
+	return Save(ctx, r.Client, r.Namespace, certutil.EncodeCRLPEM(crlDER))
+}
+
+// ConfigMapChecker checks certificates against CRL in configmap, the CRL
+// is parsed again only when configmap is changed
+type ConfigMapChecker struct {
+	Namespace string
+	Client    client.Reader
+
+	mu              sync.Mutex
+	resourceVersion string
+	crl             *pkix.CertificateList
+}
+
+func (c *ConfigMapChecker) IsRevoked(ctx context.Context, cert *x509.Certificate) (bool, error) {
+	crl, err := c.getCRL(ctx)
+	if err != nil || crl == nil {
+		return false, err
+	}
+
+	for _, rc := range crl.TBSCertList.RevokedCertificates {
+		if rc.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (c *ConfigMapChecker) getCRL(ctx context.Context) (*pkix.CertificateList, error) {
+	var cm corev1.ConfigMap
+	err := c.Client.Get(ctx, client.ObjectKey{Name: ConfigMapName, Namespace: c.Namespace}, &cm)
+	switch {
+	case err == nil:
+	case errors.IsNotFound(err):
+		return nil, nil
+	default:
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cm.ResourceVersion != c.resourceVersion {
+		crl, err := x509.ParseCRL([]byte(cm.Data[KeyCRL]))
+		if err != nil {
+			return nil, err
+		}
+
+		c.crl, c.resourceVersion = crl, cm.ResourceVersion
+	}
+
+	return c.crl, nil
+}
+
+// Get returns CRL in configmap, nil is returned if there is no CRL
+func Get(ctx context.Context, cli client.Reader, namespace string) (*pkix.CertificateList, error) {
+	crlPEM, err := GetPEM(ctx, cli, namespace)
+	if err != nil || len(crlPEM) == 0 {
+		return nil, err
+	}
+
+	return x509.ParseCRL(crlPEM)
+}
+
+// GetPEM returns CRL in configmap in PEM form
+func GetPEM(ctx context.Context, cli client.Reader, namespace string) ([]byte, error) {
+	var cm corev1.ConfigMap
+	err := cli.Get(ctx, client.ObjectKey{Name: ConfigMapName, Namespace: namespace}, &cm)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return []byte(cm.Data[KeyCRL]), nil
+}
+
+// Save creates or updates the configmap with CRL in PEM form
+func Save(ctx context.Context, cli client.Client, namespace string, crlPEM []byte) error {
+	var cm corev1.ConfigMap
+	err := cli.Get(ctx, client.ObjectKey{Name: ConfigMapName, Namespace: namespace}, &cm)
+	switch {
+	case err == nil:
+		if cm.Data[KeyCRL] == string(crlPEM) {
+			return nil
+		}
+
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[KeyCRL] = string(crlPEM)
+		return cli.Update(ctx, &cm)
+	case errors.IsNotFound(err):
+		cm = corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ConfigMapName,
+				Namespace: namespace,
+				Labels: map[string]string{
+					constants.KeyCreatedBy: constants.AppOperator,
+				},
+			},
+			Data: map[string]string{
+				KeyCRL: string(crlPEM),
+			},
+		}
+		return cli.Create(ctx, &cm)
+	default:
+		return err
+	}
+}
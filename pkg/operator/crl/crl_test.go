@@ -0,0 +1,170 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crl_test
+
+import (
+	"context"
+	"crypto/x509"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fabedge/fabedge/pkg/common/constants"
+	"github.com/fabedge/fabedge/pkg/operator/crl"
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+	timeutil "github.com/fabedge/fabedge/pkg/util/time"
+)
+
+var _ = Describe("CRL", func() {
+	const namespace = "fabedge"
+
+	var (
+		ctx         = context.Background()
+		cli         client.Client
+		certManager certutil.Manager
+		revoker     *crl.LocalRevoker
+		checker     *crl.ConfigMapChecker
+	)
+
+	newCertManager := func() certutil.Manager {
+		caDER, keyDER, err := certutil.NewSelfSignedCA(certutil.Config{
+			CommonName:     certutil.DefaultCAName,
+			Organization:   []string{certutil.DefaultOrganization},
+			IsCA:           true,
+			ValidityPeriod: timeutil.Days(365),
+		})
+		Expect(err).Should(BeNil())
+
+		m, err := certutil.NewManger(caDER, keyDER, timeutil.Days(365))
+		Expect(err).Should(BeNil())
+
+		return m
+	}
+
+	newCert := func(cn string) *x509.Certificate {
+		_, csr, err := certutil.NewCertRequest(certutil.Request{CommonName: cn})
+		Expect(err).Should(BeNil())
+
+		certDER, err := certManager.SignCert(csr)
+		Expect(err).Should(BeNil())
+
+		cert, err := x509.ParseCertificate(certDER)
+		Expect(err).Should(BeNil())
+
+		return cert
+	}
+
+	BeforeEach(func() {
+		cli = fake.NewClientBuilder().Build()
+		certManager = newCertManager()
+		revoker = &crl.LocalRevoker{
+			Namespace: namespace,
+			Signer:    certManager.(certutil.CRLSigner),
+			Client:    cli,
+		}
+		checker = &crl.ConfigMapChecker{
+			Namespace: namespace,
+			Client:    cli,
+		}
+	})
+
+	It("should treat certificates as not revoked if there is no CRL", func() {
+		revoked, err := checker.IsRevoked(ctx, newCert("edge1"))
+		Expect(err).Should(BeNil())
+		Expect(revoked).Should(BeFalse())
+
+		list, err := crl.Get(ctx, cli, namespace)
+		Expect(err).Should(BeNil())
+		Expect(list).Should(BeNil())
+	})
+
+	It("can revoke certificates and save CRL in configmap", func() {
+		edge1, edge2 := newCert("edge1"), newCert("edge2")
+
+		Expect(revoker.Revoke(ctx, edge1)).Should(Succeed())
+		// revoking the same certificate again changes nothing
+		Expect(revoker.Revoke(ctx, edge1)).Should(Succeed())
+
+		var cm corev1.ConfigMap
+		Expect(cli.Get(ctx, client.ObjectKey{Name: crl.ConfigMapName, Namespace: namespace}, &cm)).Should(Succeed())
+		Expect(cm.Labels[constants.KeyCreatedBy]).Should(Equal(constants.AppOperator))
+
+		list, err := crl.Get(ctx, cli, namespace)
+		Expect(err).Should(BeNil())
+		Expect(certManager.GetCACert().CheckCRLSignature(list)).Should(Succeed())
+		Expect(list.TBSCertList.RevokedCertificates).Should(HaveLen(1))
+		Expect(list.TBSCertList.RevokedCertificates[0].SerialNumber).Should(Equal(edge1.SerialNumber))
+
+		revoked, err := checker.IsRevoked(ctx, edge1)
+		Expect(err).Should(BeNil())
+		Expect(revoked).Should(BeTrue())
+
+		revoked, err = checker.IsRevoked(ctx, edge2)
+		Expect(err).Should(BeNil())
+		Expect(revoked).Should(BeFalse())
+
+		Expect(revoker.Revoke(ctx, edge2)).Should(Succeed())
+		revoked, err = checker.IsRevoked(ctx, edge2)
+		Expect(err).Should(BeNil())
+		Expect(revoked).Should(BeTrue())
+	})
+
+	It("should re-sign CRL if it's not signed by current CA", func() {
+		edge1 := newCert("edge1")
+		Expect(revoker.Revoke(ctx, edge1)).Should(Succeed())
+
+		certManager = newCertManager()
+		revoker.Signer = certManager.(certutil.CRLSigner)
+		Expect(revoker.Refresh(ctx, certManager.GetCACert())).Should(Succeed())
+
+		list, err := crl.Get(ctx, cli, namespace)
+		Expect(err).Should(BeNil())
+		Expect(certManager.GetCACert().CheckCRLSignature(list)).Should(Succeed())
+		Expect(list.TBSCertList.RevokedCertificates).Should(HaveLen(1))
+		Expect(list.TBSCertList.RevokedCertificates[0].SerialNumber).Should(Equal(edge1.SerialNumber))
+	})
+
+	It("should re-sign CRL if half of its valid period has passed", func() {
+		revoker.ValidPeriod = 4 * time.Second
+		Expect(revoker.Revoke(ctx, newCert("edge1"))).Should(Succeed())
+
+		oldCRL, err := crl.GetPEM(ctx, cli, namespace)
+		Expect(err).Should(BeNil())
+
+		Expect(revoker.Refresh(ctx, certManager.GetCACert())).Should(Succeed())
+		crlPEM, err := crl.GetPEM(ctx, cli, namespace)
+		Expect(err).Should(BeNil())
+		Expect(crlPEM).Should(Equal(oldCRL))
+
+		time.Sleep(3 * time.Second)
+		Expect(revoker.Refresh(ctx, certManager.GetCACert())).Should(Succeed())
+		crlPEM, err = crl.GetPEM(ctx, cli, namespace)
+		Expect(err).Should(BeNil())
+		Expect(crlPEM).ShouldNot(Equal(oldCRL))
+	})
+
+	It("can save CRL copied from other cluster", func() {
+		Expect(crl.Save(ctx, cli, namespace, []byte("crl"))).Should(Succeed())
+		Expect(crl.Save(ctx, cli, namespace, []byte("new crl"))).Should(Succeed())
+
+		crlPEM, err := crl.GetPEM(ctx, cli, namespace)
+		Expect(err).Should(BeNil())
+		Expect(crlPEM).Should(Equal([]byte("new crl")))
+	})
+})
@@ -0,0 +1,127 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/rand"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/operator/apiserver"
+	fclient "github.com/fabedge/fabedge/pkg/operator/client"
+)
+
+// HostCluster is a host cluster which a member cluster joins. A member cluster may join
+// several host clusters, each of them issues a separate client certificate for the member cluster
+type HostCluster struct {
+	// Address is the address of API server of host cluster
+	Address   string
+	InitToken string
+	// SecretName is the name of secret which keeps client certificate for this host cluster
+	SecretName string
+
+	lock sync.RWMutex
+	// client and transport are nil until the member cluster joins the host cluster. A host
+	// cluster which is not reachable when operator starts is joined later, see join
+	client fclient.Interface
+	// transport is used by client, its TLS config is updated when CA of host cluster is rotated
+	transport *fclient.TLSTransport
+}
+
+var errHostClusterNotJoined = fmt.Errorf("host cluster is not joined yet")
+
+func (host *HostCluster) join(client fclient.Interface, transport *fclient.TLSTransport) {
+	host.lock.Lock()
+	defer host.lock.Unlock()
+
+	host.client, host.transport = client, transport
+}
+
+func (host *HostCluster) joined() bool {
+	_, err := host.getClient()
+	return err == nil
+}
+
+func (host *HostCluster) getClient() (fclient.Interface, error) {
+	host.lock.RLock()
+	defer host.lock.RUnlock()
+
+	if host.client == nil {
+		return nil, errHostClusterNotJoined
+	}
+
+	return host.client, nil
+}
+
+// setTLSConfig updates TLS config of API client, it does nothing if the host cluster is not joined,
+// the TLS config is loaded from the secret of client certificate when it's joined
+func (host *HostCluster) setTLSConfig(config *tls.Config) {
+	host.lock.RLock()
+	defer host.lock.RUnlock()
+
+	if host.transport != nil {
+		host.transport.SetTLSConfig(config)
+	}
+}
+
+// UpdateCluster exports cluster to host cluster, it fails if the host cluster is not joined
+func (host *HostCluster) UpdateCluster(cluster apis.Cluster) error {
+	cli, err := host.getClient()
+	if err != nil {
+		return err
+	}
+
+	return cli.UpdateCluster(cluster)
+}
+
+// Watch waits until the host cluster is joined, then watches it, see fclient.Interface
+func (host *HostCluster) Watch(ctx context.Context, pollInterval time.Duration, handler func(apiserver.WatchEvent), onError func(error)) {
+	tick := time.NewTicker(pollInterval)
+	defer tick.Stop()
+
+	for {
+		if cli, err := host.getClient(); err == nil {
+			cli.Watch(ctx, pollInterval, handler, onError)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// clientTLSSecretName returns the name of secret which keeps client certificate for the host
+// cluster at address. The name is derived from the address, so reordering, adding or removing
+// host clusters doesn't make a member cluster present a certificate to a wrong host cluster.
+// A member cluster which joins only one host cluster uses the name of previous versions
+func clientTLSSecretName(address string, hostCount int) string {
+	if hostCount == 1 {
+		return ClientTLSSecretName
+	}
+
+	hasher := fnv.New32a()
+	_, _ = hasher.Write([]byte(address))
+
+	return fmt.Sprintf("%s-%s", ClientTLSSecretName, rand.SafeEncodeString(fmt.Sprint(hasher.Sum32())))
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics defines metrics of fabedge-operator, they are registered
+// in the registry of controller-runtime and served by controller manager
+package metrics
+
+import (
+	"context"
+	"crypto/x509"
+	"math/big"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/fabedge/fabedge/pkg/common/constants"
+	"github.com/fabedge/fabedge/pkg/operator/allocator"
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
+)
+
+const namespace = "fabedge_operator"
+
+var (
+	// ClusterLastReportTime is the time when member clusters reported their endpoints last time
+	ClusterLastReportTime = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cluster_last_report_timestamp_seconds",
+		Help:      "Unix time when member cluster reported its endpoints last time",
+	}, []string{"cluster"})
+
+	// AgentPodRebuilds is the number of times agent pods are deleted to be rebuilt
+	AgentPodRebuilds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "agent_pod_rebuilds_total",
+		Help:      "Number of times agent pod of edge node is deleted to be rebuilt",
+	}, []string{"node"})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(ClusterLastReportTime, AgentPodRebuilds)
+}
+
+// RegisterAllocators makes usage of allocators reported
+func RegisterAllocators(allocators []allocator.Interface) error {
+	return ctrlmetrics.Registry.Register(&allocatorCollector{
+		allocators: allocators,
+		blocks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "allocator_blocks"),
+			"Number of used and free subnet blocks of allocator pool",
+			[]string{"pool", "state"}, nil,
+		),
+	})
+}
+
+// RegisterCertSecrets makes expiry time of certificates in secrets created by operator reported
+func RegisterCertSecrets(cli client.Reader, secretNamespace string, log logr.Logger) error {
+	return ctrlmetrics.Registry.Register(&certCollector{
+		client:    cli,
+		namespace: secretNamespace,
+		log:       log,
+		expiry: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "cert_expiry_timestamp_seconds"),
+			"Unix time when certificate in secret expires",
+			[]string{"secret"}, nil,
+		),
+	})
+}
+
+type allocatorCollector struct {
+	allocators []allocator.Interface
+	blocks     *prometheus.Desc
+}
+
+func (c *allocatorCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.blocks
+}
+
+func (c *allocatorCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, alloc := range c.allocators {
+		usage := alloc.Usage()
+		free := new(big.Int).Sub(usage.Total, big.NewInt(int64(usage.Used)))
+		freeCount, _ := new(big.Float).SetInt(free).Float64()
+
+		ch <- prometheus.MustNewConstMetric(c.blocks, prometheus.GaugeValue, float64(usage.Used), usage.Pool, "used")
+		ch <- prometheus.MustNewConstMetric(c.blocks, prometheus.GaugeValue, freeCount, usage.Pool, "free")
+	}
+}
+
+// certCollector reads secrets from cache on every scrape, so
+// certificates rotated or removed are reflected immediately
+type certCollector struct {
+	client    client.Reader
+	namespace string
+	log       logr.Logger
+	expiry    *prometheus.Desc
+}
+
+func (c *certCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.expiry
+}
+
+func (c *certCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var secrets corev1.SecretList
+	err := c.client.List(ctx, &secrets,
+		client.InNamespace(c.namespace),
+		client.MatchingLabels{constants.KeyCreatedBy: constants.AppOperator},
+	)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.expiry, err)
+		return
+	}
+
+	for _, secret := range secrets.Items {
+		certPEM := secretutil.GetCert(secret)
+		if len(certPEM) == 0 {
+			continue
+		}
+
+		cert, err := parseCert(certPEM)
+		if err != nil {
+			c.log.Error(err, "failed to parse certificate", "secret", secret.Name)
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.expiry, prometheus.GaugeValue, float64(cert.NotAfter.Unix()), secret.Name)
+	}
+}
+
+func parseCert(certPEM []byte) (*x509.Certificate, error) {
+	certDER, err := certutil.DecodePEM(certPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParseCertificate(certDER)
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fabedge/fabedge/pkg/common/constants"
+	"github.com/fabedge/fabedge/pkg/operator/allocator"
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
+)
+
+func TestAllocatorCollector(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	alloc, err := allocator.New("2.2.0.0/16", 24)
+	g.Expect(err).Should(BeNil())
+	_, subnet, _ := net.ParseCIDR("2.2.1.0/24")
+	g.Expect(alloc.Record(*subnet)).Should(Succeed())
+
+	collector := &allocatorCollector{
+		allocators: []allocator.Interface{alloc},
+		blocks:     prometheus.NewDesc("fabedge_operator_allocator_blocks", "Number of blocks", []string{"pool", "state"}, nil),
+	}
+
+	expected := `
+# HELP fabedge_operator_allocator_blocks Number of blocks
+# TYPE fabedge_operator_allocator_blocks gauge
+fabedge_operator_allocator_blocks{pool="2.2.0.0/16",state="free"} 255
+fabedge_operator_allocator_blocks{pool="2.2.0.0/16",state="used"} 1
+`
+	g.Expect(testutil.CollectAndCompare(collector, strings.NewReader(expected))).Should(Succeed())
+}
+
+func TestCertCollector(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	certDER, keyDER, err := certutil.NewSelfSignedCA(certutil.Config{
+		CommonName:     "edge1",
+		ValidityPeriod: time.Hour,
+	})
+	g.Expect(err).Should(BeNil())
+	cert, err := parseCert(certutil.EncodeCertPEM(certDER))
+	g.Expect(err).Should(BeNil())
+
+	agentSecret := secretutil.TLSSecret().
+		Name("fabedge-agent-tls-edge1").
+		Namespace("fabedge").
+		Label(constants.KeyCreatedBy, constants.AppOperator).
+		EncodeCert(certDER).
+		EncodeKey(keyDER).
+		Build()
+	pskSecret := corev1.Secret{}
+	pskSecret.Name, pskSecret.Namespace = "fabedge-agent-psk-edge1", "fabedge"
+	pskSecret.Labels = map[string]string{constants.KeyCreatedBy: constants.AppOperator}
+	pskSecret.Data = map[string][]byte{secretutil.KeyPSK: []byte("secret")}
+
+	collector := &certCollector{
+		client:    fake.NewClientBuilder().WithObjects(&agentSecret, &pskSecret).Build(),
+		namespace: "fabedge",
+		log:       klogr.New(),
+		expiry:    prometheus.NewDesc("fabedge_operator_cert_expiry_timestamp_seconds", "Expiry time", []string{"secret"}, nil),
+	}
+
+	expected := fmt.Sprintf(`
+# HELP fabedge_operator_cert_expiry_timestamp_seconds Expiry time
+# TYPE fabedge_operator_cert_expiry_timestamp_seconds gauge
+fabedge_operator_cert_expiry_timestamp_seconds{secret="fabedge-agent-tls-edge1"} %d
+`, cert.NotAfter.Unix())
+	g.Expect(testutil.CollectAndCompare(collector, strings.NewReader(expected))).Should(Succeed())
+}
@@ -15,11 +15,13 @@
 package operator
 
 import (
+	"bytes"
 	"context"
-	"crypto/rsa"
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
@@ -35,6 +37,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 
@@ -48,9 +51,14 @@ import (
 	cmmctl "github.com/fabedge/fabedge/pkg/operator/controllers/community"
 	connectorctl "github.com/fabedge/fabedge/pkg/operator/controllers/connector"
 	"github.com/fabedge/fabedge/pkg/operator/controllers/ipamblockmonitor"
+	"github.com/fabedge/fabedge/pkg/operator/crl"
+	operatormetrics "github.com/fabedge/fabedge/pkg/operator/metrics"
 	"github.com/fabedge/fabedge/pkg/operator/routines"
+	"github.com/fabedge/fabedge/pkg/operator/signer"
 	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
 	"github.com/fabedge/fabedge/pkg/operator/types"
+	"github.com/fabedge/fabedge/pkg/operator/webhook"
+	"github.com/fabedge/fabedge/pkg/tunnel"
 	certutil "github.com/fabedge/fabedge/pkg/util/cert"
 	nodeutil "github.com/fabedge/fabedge/pkg/util/node"
 	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
@@ -63,8 +71,14 @@ const (
 	RoleMember = "member"
 
 	ClientTLSSecretName = "api-client-tls"
+
+	SignerLocal       = "local"
+	SignerCertManager = "cert-manager"
+	SignerHTTP        = "http"
 )
 
+var errNotLeader = fmt.Errorf("not the leader")
+
 var dns1123Reg, _ = regexp.Compile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
 
 type Options struct {
@@ -86,7 +100,25 @@ type Options struct {
 
 	CASecretName        string
 	CertValidPeriod     int64
+	CertRenewalFraction float64
 	CertOrganization    string
+	// CertKeyAlgorithm is the algorithm of private keys created for agents, connectors and API client
+	CertKeyAlgorithm string
+	// CAKeyAlgorithm is the algorithm of private key of new CA when CA is rotated,
+	// the algorithm of current CA is used if it's empty
+	CAKeyAlgorithm string
+	// CARenewalFraction decides when CA is rotated automatically, see certutil.NeedRenewal
+	CARenewalFraction     float64
+	CARotationGracePeriod time.Duration
+	// CASigner decides how certificates are signed in host cluster. If it's not local, the CA
+	// private key is kept by an external signer and only CA certs are needed in CA secret
+	CASigner          string
+	CertManagerIssuer signer.IssuerRef
+	HTTPSigner        struct {
+		URL       string
+		TokenFile string
+		CAFile    string
+	}
 	Agent               agentctl.Config
 	Connector           connectorctl.Config
 	ConnectorPublicPort uint
@@ -97,9 +129,21 @@ type Options struct {
 	APIServerCertFile      string
 	APIServerKeyFile       string
 	APIServerListenAddress string
-	APIServerAddress       string
-	TokenValidPeriod       time.Duration
-	InitToken              string
+	// APIServerAddresses are addresses of API servers of host clusters which a member cluster joins,
+	// certificates of agents and connectors are signed and revoked by the first reachable one of them.
+	// A host cluster with APIServerAddresses is an intermediate cluster, see joinsHostClusters
+	APIServerAddresses []string
+	TokenValidPeriod   time.Duration
+	// TokenSingleUse makes tokens of member clusters usable only once
+	TokenSingleUse bool
+	// InitTokens are tokens to join host clusters, one for each address in APIServerAddresses
+	InitTokens []string
+	// ClusterHeartbeatTimeout decides when a member cluster is considered not ready
+	ClusterHeartbeatTimeout time.Duration
+
+	// EnableWebhook makes operator serve admission webhooks for clusters and communities,
+	// the port and cert dir of webhook server are configured in ManagerOpts
+	EnableWebhook bool
 
 	Store           storepkg.Interface
 	ClusterCIDRsMap *types.ClusterCIDRsMap
@@ -107,8 +151,24 @@ type Options struct {
 	NewEndpoint     types.NewEndpointFunc
 	Manager         manager.Manager
 	APIServer       *http.Server
-	APIClient       fclient.Interface
-	PrivateKey      *rsa.PrivateKey
+	// HostClusters are host clusters which a member cluster joins
+	HostClusters []*HostCluster
+
+	// HostStateMirror fills APIServerStore and APIServerCIDRsMap in every replica of host operator,
+	// API server and webhooks of host cluster use them instead of Store and ClusterCIDRsMap
+	// which are filled by the leader only
+	HostStateMirror   *routines.HostStateMirror
+	APIServerStore    storepkg.Interface
+	APIServerCIDRsMap *types.ClusterCIDRsMap
+
+	// PrivateKey signs tokens of member clusters
+	PrivateKey  crypto.Signer
+	CertManager *certutil.DynamicManager
+	// CRLRevoker maintains CRL in host cluster, it's nil in member clusters
+	CRLRevoker *crl.LocalRevoker
+
+	// signCert signs certificates by external signer, it's nil if CA signer is local
+	signCert certutil.SignCertFunc
 }
 
 func (opts *Options) AddFlags(flag *pflag.FlagSet) {
@@ -127,6 +187,8 @@ func (opts *Options) AddFlags(flag *pflag.FlagSet) {
 	flag.StringToStringVar(&opts.Connector.ConnectorLabels, "connector-labels", map[string]string{"app": "fabedge-connector"}, "The labels used to find connector pods, e.g. key2=,key3=value3")
 	flag.StringSliceVar(&opts.Connector.Endpoint.PublicAddresses, "connector-public-addresses", nil, "The connector's public addresses which should be accessible for every edge node, comma separated. Takes single IPv4 addresses, DNS names")
 	flag.UintVar(&opts.ConnectorPublicPort, "connector-public-port", 500, "Public UDP port for IKE communication of connector")
+	flag.StringVar(&opts.Connector.Endpoint.PSKSecretName, "connector-psk-secret", "", "The name of secret which contains pre-shared key used by tunnels to connector, the key is read from the field \"psk\". If it's empty, certificates are used for authentication")
+	flag.StringVar(&opts.Connector.Endpoint.TunnelBackend, "connector-tunnel-backend", tunnel.BackendStrongSwan, "The tunnel backend of connector used by tunnels to connector, possible values are: strongswan, wireguard. It should be the same as the tunnel-backend of connector")
 	flag.BoolVar(&opts.ConnectorAsMediator, "connector-as-mediator", false, "Use connector as mediator for hole punching")
 	flag.StringSliceVar(&opts.Connector.ProvidedSubnets, "connector-subnets", nil, "The subnets of connector, mostly the CIDRs to assign pod IP and service ClusterIP")
 	flag.DurationVar(&opts.Connector.SyncInterval, "connector-config-sync-interval", 5*time.Second, "The interval to synchronize connector configmap")
@@ -138,16 +200,35 @@ func (opts *Options) AddFlags(flag *pflag.FlagSet) {
 	flag.StringVar(&opts.CASecretName, "ca-secret", "fabedge-ca", "The name of secret which contains CA's cert and key")
 	flag.StringVar(&opts.CertOrganization, "cert-organization", certutil.DefaultOrganization, "The organization name for agent's cert")
 	flag.Int64Var(&opts.CertValidPeriod, "cert-validity-period", 3650, "The validity period for agent's cert")
+	flag.Float64Var(&opts.CertRenewalFraction, "cert-renewal-fraction", 0.8, "Agent and connector certificates are renewed after this fraction of their validity period has passed, set it to 0 to renew them only when they are invalid")
+	flag.StringVar(&opts.CertKeyAlgorithm, "cert-key-algorithm", string(certutil.KeyAlgorithmRSA), "The algorithm of private keys created for agents, connectors and API client, possible values are: rsa, ecdsa, ed25519. Existing keys are kept when certificates are renewed")
+	flag.StringVar(&opts.CAKeyAlgorithm, "ca-key-algorithm", "", "The algorithm of private key of new CA when CA is rotated, possible values are: rsa, ecdsa, ed25519. The algorithm of current CA is used if it's empty. Only used by host cluster")
+	flag.Float64Var(&opts.CARenewalFraction, "ca-renewal-fraction", 0.8, "CA is rotated automatically after this fraction of its validity period has passed, set it to 0 to rotate CA only when it's requested. Only used by host cluster")
+	flag.StringVar(&opts.CASigner, "ca-signer", SignerLocal, "How certificates are signed in host cluster, possible values are: local, cert-manager, http. If it's not local, CA private key is not needed in CA secret")
+	flag.StringVar(&opts.CertManagerIssuer.Name, "cert-manager-issuer-name", "", "The name of cert-manager issuer which signs certificates, used when ca-signer is cert-manager")
+	flag.StringVar(&opts.CertManagerIssuer.Kind, "cert-manager-issuer-kind", "Issuer", "The kind of cert-manager issuer, e.g. Issuer, ClusterIssuer")
+	flag.StringVar(&opts.CertManagerIssuer.Group, "cert-manager-issuer-group", "cert-manager.io", "The API group of cert-manager issuer")
+	flag.StringVar(&opts.HTTPSigner.URL, "http-signer-url", "", "The URL to which CSRs are posted, used when ca-signer is http")
+	flag.StringVar(&opts.HTTPSigner.TokenFile, "http-signer-token-file", "", "The file which contains bearer token for HTTP signer")
+	flag.StringVar(&opts.HTTPSigner.CAFile, "http-signer-ca-file", "", "The CA cert file used to verify certificate of HTTP signer")
+	flag.DurationVar(&opts.CARotationGracePeriod, "ca-rotation-grace-period", 10*time.Minute, "The minimal duration of each phase of CA rotation, agents, connectors and member clusters should get the latest CA certs in this duration. Only used by host cluster")
 
 	flag.BoolVar(&opts.ManagerOpts.LeaderElection, "leader-election", false, "Determines whether or not to use leader election")
 	flag.StringVar(&opts.ManagerOpts.LeaderElectionID, "leader-election-id", "fabedge-operator-leader", "The name of the resource that leader election will use for holding the leader lock")
+	flag.BoolVar(&opts.EnableWebhook, "enable-webhook", false, "Serve admission webhooks to validate and mutate clusters and communities")
+	flag.IntVar(&opts.ManagerOpts.Port, "webhook-port", 9443, "The port on which webhook server listens")
+	flag.StringVar(&opts.ManagerOpts.CertDir, "webhook-cert-dir", "/etc/fabedge/webhook-certs", "The directory which contains tls.crt and tls.key for webhook server")
+	flag.StringVar(&opts.ManagerOpts.MetricsBindAddress, "metrics-bind-address", ":8080", "The address on which prometheus metrics are served, set it to \"0\" to disable metrics")
+	flag.StringVar(&opts.ManagerOpts.HealthProbeBindAddress, "health-probe-bind-address", ":8081", "The address on which /readyz is served, set it to \"0\" to disable it. In member clusters, only the leader is ready when webhooks are enabled")
 
 	flag.StringVar(&opts.APIServerListenAddress, "api-server-listen-address", "0.0.0.0:3030", "The address on which for API server to listen")
-	flag.StringVar(&opts.APIServerAddress, "api-server-address", "", "The address of host cluster's API server")
+	flag.StringSliceVar(&opts.APIServerAddresses, "api-server-address", nil, "The addresses of API servers of host clusters which member cluster joins, comma separated. Certificates of agents and connectors are signed by the first reachable host cluster. If it's provided to a host cluster, the host cluster becomes an intermediate cluster which joins these host clusters too")
 	flag.StringVar(&opts.APIServerCertFile, "api-server-cert-file", "", "The cert file path for api server")
 	flag.StringVar(&opts.APIServerKeyFile, "api-server-key-file", "", "The key file path for api server")
-	flag.StringVar(&opts.InitToken, "init-token", "", "The token used to initialize TLS cert for API client")
+	flag.StringSliceVar(&opts.InitTokens, "init-token", nil, "The tokens used to initialize TLS certs for API clients, comma separated, one for each address of api-server-address")
 	flag.DurationVar(&opts.TokenValidPeriod, "token-valid-period", 12*time.Hour, "The validity duration of token for child cluster to initialize")
+	flag.BoolVar(&opts.TokenSingleUse, "token-single-use", false, "Generate tokens which can be used only once for child clusters to initialize. Only used by host cluster")
+	flag.DurationVar(&opts.ClusterHeartbeatTimeout, "cluster-heartbeat-timeout", time.Minute, "A member cluster is considered not ready if it doesn't report its endpoints within this duration")
 }
 
 func (opts *Options) Complete() (err error) {
@@ -178,7 +259,15 @@ func (opts *Options) Complete() (err error) {
 	}
 
 	getEndpointName, getEndpointID, newEndpoint := types.NewEndpointFuncs(opts.Cluster, opts.EndpointIDFormat, getEdgePodCIDRs)
-	opts.NewEndpoint = newEndpoint
+	opts.NewEndpoint = func(node corev1.Node) apis.Endpoint {
+		ep := newEndpoint(node)
+		// both sides of a connection negotiate the backend by their endpoints, so the
+		// backend of an edge node's endpoint has to be the same as the one of its agent
+		if ep.Name != "" {
+			ep.TunnelBackend = types.ResolveTunnelBackend(node, opts.Agent.AgentPodArguments, opts.ConnectorAsMediator)
+		}
+		return ep
+	}
 
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -193,7 +282,6 @@ func (opts *Options) Complete() (err error) {
 	}
 
 	opts.ManagerOpts.LeaderElectionNamespace = opts.Namespace
-	opts.ManagerOpts.MetricsBindAddress = "0"
 	opts.ManagerOpts.Logger = klogr.New().WithName("fabedge-operator")
 	opts.Manager, err = manager.New(cfg, opts.ManagerOpts)
 	if err != nil {
@@ -202,7 +290,22 @@ func (opts *Options) Complete() (err error) {
 	}
 
 	var certManager certutil.Manager
-	if opts.ClusterRole == RoleHost {
+	if opts.joinsHostClusters() {
+		certManager, err = opts.joinHostClusters(kubeClient)
+		if err != nil {
+			return err
+		}
+
+		// certificates of child clusters of an intermediate cluster are signed through host clusters too,
+		// but tokens of child clusters are signed with the key of API server, like external signer does
+		if opts.ClusterRole == RoleHost {
+			opts.PrivateKey, err = loadPrivateKey(opts.APIServerKeyFile)
+			if err != nil {
+				log.Error(err, "failed to load private key of api server")
+				return err
+			}
+		}
+	} else if opts.CASigner == SignerLocal {
 		certManager, opts.PrivateKey, err = createCertManager(kubeClient, client.ObjectKey{
 			Name:      opts.CASecretName,
 			Namespace: opts.Namespace,
@@ -212,48 +315,89 @@ func (opts *Options) Complete() (err error) {
 			return err
 		}
 	} else {
-		cacert, err := fclient.GetCertificate(opts.APIServerAddress)
+		opts.signCert, err = opts.newExternalSignCertFunc(kubeClient)
 		if err != nil {
-			log.Error(err, "failed to get CA cert from host cluster")
+			log.Error(err, "failed to create external signer")
 			return err
 		}
 
-		if err = opts.initAPIClient(kubeClient, cacert); err != nil {
+		certManager, err = opts.createExternalCertManager(context.Background(), kubeClient)
+		if err != nil {
+			log.Error(err, "failed to create cert manager")
 			return err
 		}
 
-		certManager, err = certutil.NewRemoteManager(cacert.DER, func(csr []byte) ([]byte, error) {
-			cert, innerErr := opts.APIClient.SignCert(csr)
-			if innerErr != nil {
-				return nil, innerErr
-			}
-
-			return cert.DER, nil
-		})
+		// there is no CA private key, so tokens of member clusters are signed with the key of API server
+		opts.PrivateKey, err = loadPrivateKey(opts.APIServerKeyFile)
 		if err != nil {
-			log.Error(err, "failed to create certManager")
+			log.Error(err, "failed to load private key of api server")
 			return err
 		}
 	}
+	// CA may be rotated at runtime, so every holder of cert manager should share the same dynamic manager
+	opts.CertManager = certutil.NewDynamicManager(certManager)
+
+	if err = operatormetrics.RegisterAllocators(opts.Agent.Allocators); err != nil {
+		log.Error(err, "failed to register allocator metrics")
+		return err
+	}
+
+	if err = operatormetrics.RegisterCertSecrets(opts.Manager.GetClient(), opts.Namespace, log.WithName("metrics")); err != nil {
+		log.Error(err, "failed to register certificate metrics")
+		return err
+	}
 
 	opts.Store = storepkg.NewStore()
 	opts.ClusterCIDRsMap = types.NewClusterCIDRsMap()
 
 	opts.Agent.ClusterName = opts.Cluster
 	opts.Agent.Namespace = opts.Namespace
-	opts.Agent.CertManager = certManager
+	opts.Agent.CertManager = opts.CertManager
 	opts.Agent.Manager = opts.Manager
 	opts.Agent.Store = opts.Store
 	opts.Agent.NewEndpoint = opts.NewEndpoint
 	opts.Agent.GetEndpointName = getEndpointName
 	opts.Agent.CertOrganization = opts.CertOrganization
+	opts.Agent.MediationEnabled = opts.ConnectorAsMediator
+	opts.Agent.CertRenewalFraction = opts.CertRenewalFraction
+	opts.Agent.CertKeyAlgorithm = certutil.KeyAlgorithm(opts.CertKeyAlgorithm)
+	var hostRevoker crl.Revoker
+	if opts.joinsHostClusters() {
+		// certificates of member clusters are issued by host cluster, so are they revoked
+		hostRevoker = crl.RevokeFunc(func(cert *x509.Certificate) error {
+			return opts.tryHostClusters(func(cli fclient.Interface) error {
+				err := cli.RevokeCert(certutil.EncodeCertPEM(cert.Raw))
+				// host cluster can't revoke certificates if they are issued by an external signer
+				if httpErr, ok := err.(*fclient.HttpError); ok && httpErr.Response.StatusCode == http.StatusNotImplemented {
+					return crl.ErrNotSupported
+				}
+				return err
+			})
+		})
+		opts.Agent.CertRevoker = hostRevoker
+	} else if opts.ClusterRole == RoleHost && opts.CASigner == SignerLocal {
+		opts.CRLRevoker = &crl.LocalRevoker{
+			Namespace: opts.Namespace,
+			Signer:    opts.CertManager,
+			Client:    opts.Manager.GetClient(),
+		}
+		opts.Agent.CertRevoker = opts.CRLRevoker
+	} else if opts.ClusterRole == RoleHost {
+		// CRL can't be signed when certificates are issued by an external signer
+		opts.Agent.CertRevoker = crl.RevokeFunc(func(cert *x509.Certificate) error {
+			return crl.ErrNotSupported
+		})
+	}
 	if opts.Agent.AgentPodArguments.IsProxyEnabled() {
 		opts.Agent.AgentPodArguments.Set("proxy-cluster-cidr", strings.Join(opts.ClusterCIDRs, ","))
 	}
 
+	opts.Connector.ClusterName = opts.Cluster
 	opts.Connector.Namespace = opts.Namespace
 	opts.Connector.CertOrganization = opts.CertOrganization
-	opts.Connector.CertManager = certManager
+	opts.Connector.CertRenewalFraction = opts.CertRenewalFraction
+	opts.Connector.CertKeyAlgorithm = certutil.KeyAlgorithm(opts.CertKeyAlgorithm)
+	opts.Connector.CertManager = opts.CertManager
 	opts.Connector.Manager = opts.Manager
 	opts.Connector.Store = opts.Store
 	opts.Connector.GetPodCIDRs = getCloudPodCIDRs
@@ -262,6 +406,7 @@ func (opts *Options) Complete() (err error) {
 	if opts.ConnectorPublicPort != 500 {
 		opts.Connector.Endpoint.Port = &opts.ConnectorPublicPort
 	}
+	var extraEndpoints []apis.Endpoint
 	if opts.ConnectorAsMediator {
 		mediator := opts.Connector.Endpoint
 		mediator.Name = constants.DefaultMediatorName
@@ -272,40 +417,204 @@ func (opts *Options) Complete() (err error) {
 
 		mediator.Subnets = nil
 		mediator.NodeSubnets = nil
+		// mediation connections are always authenticated by certificates
+		mediator.PSKSecretName = ""
 		opts.Store.SaveEndpoint(mediator)
+		extraEndpoints = append(extraEndpoints, mediator)
 	}
 
 	if opts.ClusterRole == RoleHost {
-		opts.APIServer, err = apiserver.New(apiserver.Config{
-			CertManager: certManager,
-			Addr:        opts.APIServerListenAddress,
-			Store:       opts.Store,
-			CIDRMap:     opts.ClusterCIDRsMap,
-			Client:      opts.Manager.GetClient(),
-			Log:         log.WithName("apiserver"),
-		})
+		opts.APIServerStore = storepkg.NewStore()
+		opts.APIServerCIDRsMap = types.NewClusterCIDRsMap()
+		opts.HostStateMirror = &routines.HostStateMirror{
+			Store:          opts.APIServerStore,
+			CIDRMap:        opts.APIServerCIDRsMap,
+			NewEndpoint:    opts.NewEndpoint,
+			ExtraEndpoints: extraEndpoints,
+			Informers:      opts.Manager.GetCache(),
+			Client:         opts.Manager.GetClient(),
+			SyncInterval:   10 * time.Second,
+			Log:            opts.Manager.GetLogger().WithName("HostStateMirror"),
+		}
+
+		apiServerConfig := apiserver.Config{
+			PublicKey:    opts.PrivateKey.Public(),
+			CertManager:  opts.CertManager,
+			Addr:         opts.APIServerListenAddress,
+			Store:        opts.APIServerStore,
+			CIDRMap:      opts.APIServerCIDRsMap,
+			Cluster:      opts.Cluster,
+			EdgePodCIDRs: opts.edgePodCIDRs(),
+			Client:       opts.Manager.GetClient(),
+			Namespace:    opts.Namespace,
+			RevocationChecker: &crl.ConfigMapChecker{
+				Namespace: opts.Namespace,
+				Client:    opts.Manager.GetClient(),
+			},
+			Log: log.WithName("apiserver"),
+		}
+		// CRL can't be signed without CA private key, an intermediate cluster
+		// passes revocation requests of child clusters to its host cluster
+		if opts.CRLRevoker != nil {
+			apiServerConfig.Revoker = opts.CRLRevoker
+		} else if hostRevoker != nil {
+			apiServerConfig.Revoker = hostRevoker
+		}
+
+		opts.APIServer, err = apiserver.New(apiServerConfig)
 		if err != nil {
 			log.Error(err, "failed to create api server")
 			return err
 		}
 
-		certPool := x509.NewCertPool()
-		certPool.AddCert(certManager.GetCACert())
 		cert, err := tls.LoadX509KeyPair(opts.APIServerCertFile, opts.APIServerKeyFile)
 		if err != nil {
 			log.Error(err, "failed to load api server key pair")
 			return err
 		}
 		opts.APIServer.TLSConfig = &tls.Config{
-			ClientCAs:    certPool,
 			Certificates: []tls.Certificate{cert},
 			ClientAuth:   tls.RequestClientCert,
+			// trusted CAs are changed when CA is rotated, so client CAs are loaded for each connection,
+			// otherwise clients with certificates issued by a new CA won't send their certificates
+			GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+				certPool := x509.NewCertPool()
+				certPool.AppendCertsFromPEM(opts.CertManager.GetCABundlePEM())
+
+				return &tls.Config{
+					ClientCAs:    certPool,
+					Certificates: []tls.Certificate{cert},
+					ClientAuth:   tls.RequestClientCert,
+				}, nil
+			},
 		}
 	}
 
 	return nil
 }
 
+// joinHostClusters initializes API clients of host clusters and creates a cert manager which
+// signs certificates through host clusters. Host clusters which are not reachable are skipped
+// and joined later by retryJoiningHostClusters, but at least one of them has to be joined
+func (opts *Options) joinHostClusters(kubeClient client.Client) (certutil.Manager, error) {
+	bundles := make([][]byte, 0, len(opts.APIServerAddresses))
+	for i, address := range opts.APIServerAddresses {
+		host := &HostCluster{
+			Address:    address,
+			InitToken:  opts.InitTokens[i],
+			SecretName: clientTLSSecretName(address, len(opts.APIServerAddresses)),
+		}
+		opts.HostClusters = append(opts.HostClusters, host)
+
+		cacert, err := opts.joinHostCluster(kubeClient, host)
+		if err != nil {
+			log.Error(err, "failed to join host cluster, it will be retried later", "address", address)
+			continue
+		}
+
+		bundles = append(bundles, cacert.PEM)
+	}
+
+	if len(bundles) == 0 {
+		err := fmt.Errorf("none of host clusters is joined")
+		log.Error(err, "failed to join host clusters")
+		return nil, err
+	}
+
+	certManager, err := opts.newRemoteCertManager(bundles...)
+	if err != nil {
+		log.Error(err, "failed to create certManager")
+		return nil, err
+	}
+
+	return certManager, nil
+}
+
+// joinHostCluster gets CA cert of host cluster and initializes API client with it,
+// the CA cert is returned
+func (opts *Options) joinHostCluster(kubeClient client.Client, host *HostCluster) (fclient.Certificate, error) {
+	cacert, err := fclient.GetCertificate(host.Address)
+	if err != nil {
+		return cacert, err
+	}
+
+	return cacert, opts.initAPIClient(kubeClient, host, cacert)
+}
+
+// retryJoiningHostClusters joins host clusters which were not reachable when operator started,
+// it returns when all host clusters are joined. CA certs of them are trusted once cert manager
+// is reloaded. Every replica holds its own API clients, so it should be run by every replica
+func (opts Options) retryJoiningHostClusters(ctx context.Context) error {
+	tick := time.NewTicker(timeutil.Seconds(30))
+	defer tick.Stop()
+
+	for {
+		allJoined := true
+		for _, host := range opts.HostClusters {
+			if host.joined() {
+				continue
+			}
+
+			if _, err := opts.joinHostCluster(opts.Manager.GetClient(), host); err != nil {
+				log.Error(err, "failed to join host cluster", "address", host.Address)
+				allJoined = false
+				continue
+			}
+			log.V(3).Info("host cluster is joined", "address", host.Address)
+		}
+
+		if allJoined {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+		}
+	}
+}
+
+// tryHostClusters calls fn with API clients of host clusters in order until it succeeds, so
+// certificates are signed and revoked by the first reachable host cluster, e.g. the standby
+// one when the active one is down. The last error is returned if fn fails with all of them
+func (opts Options) tryHostClusters(fn func(cli fclient.Interface) error) error {
+	err := errHostClusterNotJoined
+	for _, host := range opts.HostClusters {
+		var cli fclient.Interface
+		if cli, err = host.getClient(); err != nil {
+			continue
+		}
+
+		if err = fn(cli); err == nil {
+			return nil
+		}
+		log.Error(err, "failed to request host cluster, try the next one", "address", host.Address)
+	}
+
+	return err
+}
+
+// joinsHostClusters tells if the cluster is a member of other host clusters. It's always true for
+// member clusters. A host cluster which joins host clusters is an intermediate cluster, its child
+// clusters are named "<cluster>.<child>" and their endpoints and CIDRs are exported to host clusters
+// along with its own, so a hierarchy of clusters can be built, e.g. global, regions and edge clusters
+func (opts Options) joinsHostClusters() bool {
+	return len(opts.APIServerAddresses) > 0
+}
+
+// edgePodCIDRs returns configured edge pod CIDRs, they're only used with calico
+func (opts Options) edgePodCIDRs() []string {
+	var cidrs []string
+	for _, cidr := range []string{opts.EdgePodCIDRv4, opts.EdgePodCIDRv6} {
+		if cidr != "" {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+
+	return cidrs
+}
+
 // normalizeCIDRs will normalize cluster cidrs and connector's subnets because
 // sometimes user may provide values that are correct but not abbreviated enough(mainly IPv6).
 // Example: fd96:ee88:0:1::0/116 and fd96:ee88:0:1::/116 are equal as CIDRs but are not equal as strings.
@@ -364,8 +673,17 @@ func (opts Options) Validate() (err error) {
 		return fmt.Errorf("unknown cluster role: %s", opts.ClusterRole)
 	}
 
-	if opts.ClusterRole == RoleMember && len(opts.InitToken) == 0 {
-		return fmt.Errorf("initialization token is needed when cluster role is member")
+	if opts.ClusterRole == RoleMember && len(opts.APIServerAddresses) == 0 {
+		return fmt.Errorf("api server address of host cluster is needed when cluster role is member")
+	}
+
+	if len(opts.InitTokens) != len(opts.APIServerAddresses) {
+		return fmt.Errorf("an initialization token is needed for each api server address")
+	}
+
+	// wireguard has no mediation, edge nodes asking for it by annotations use strongswan instead
+	if opts.ConnectorAsMediator && opts.Agent.AgentPodArguments.Get("tunnel-backend") == tunnel.BackendWireGuard {
+		return fmt.Errorf("wireguard can't be the tunnel backend of agents when connector is mediator")
 	}
 
 	if opts.ClusterRole == RoleHost {
@@ -376,6 +694,35 @@ func (opts Options) Validate() (err error) {
 		if !fileExists(opts.APIServerCertFile) {
 			return fmt.Errorf("api server certificate file doesnt' exist")
 		}
+
+		// certificates of an intermediate cluster and its child clusters are signed by its host cluster
+		if opts.joinsHostClusters() && opts.CASigner != SignerLocal {
+			return fmt.Errorf("ca-signer must be local when a host cluster joins other host clusters")
+		}
+
+		switch opts.CASigner {
+		case SignerLocal:
+		case SignerCertManager:
+			if len(opts.CertManagerIssuer.Name) == 0 {
+				return fmt.Errorf("cert-manager issuer name is needed when CA signer is cert-manager")
+			}
+		case SignerHTTP:
+			if len(opts.HTTPSigner.URL) == 0 {
+				return fmt.Errorf("http signer url is needed when CA signer is http")
+			}
+		default:
+			return fmt.Errorf("unknown CA signer: %s", opts.CASigner)
+		}
+	}
+
+	if _, err := certutil.ParseKeyAlgorithm(opts.CertKeyAlgorithm); err != nil {
+		return err
+	}
+
+	if len(opts.CAKeyAlgorithm) > 0 {
+		if _, err := certutil.ParseKeyAlgorithm(opts.CAKeyAlgorithm); err != nil {
+			return err
+		}
 	}
 
 	if len(opts.EdgeLabels) == 0 {
@@ -394,6 +741,12 @@ func (opts Options) Validate() (err error) {
 		return fmt.Errorf("connector public port is invalid")
 	}
 
+	switch opts.Connector.Endpoint.TunnelBackend {
+	case tunnel.BackendStrongSwan, tunnel.BackendWireGuard:
+	default:
+		return fmt.Errorf("unknown connector tunnel backend: %s", opts.Connector.Endpoint.TunnelBackend)
+	}
+
 	if len(opts.ClusterCIDRs) == 0 {
 		return fmt.Errorf("cluster-cidr is needed")
 	}
@@ -483,7 +836,7 @@ func (opts Options) isOverlappedWithProvidedSubnets(ipNet *net.IPNet) bool {
 	return false
 }
 
-func createCertManager(cli client.Client, key client.ObjectKey, validPeriod time.Duration) (certutil.Manager, *rsa.PrivateKey, error) {
+func createCertManager(cli client.Client, key client.ObjectKey, validPeriod time.Duration) (certutil.Manager, crypto.Signer, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -493,25 +846,334 @@ func createCertManager(cli client.Client, key client.ObjectKey, validPeriod time
 	if err != nil {
 		return nil, nil, err
 	}
-	certPEM, keyPEM := secretutil.GetCA(secret)
 
-	certDER, err := certutil.DecodePEM(certPEM)
+	keyDER, err := certutil.DecodePEM(secretutil.GetCAKey(secret))
 	if err != nil {
 		return nil, nil, err
 	}
 
-	keyDER, err := certutil.DecodePEM(keyPEM)
+	privateKey, err := certutil.ParsePrivateKey(keyDER)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	privateKey, err := x509.ParsePKCS1PrivateKey(keyDER)
+	certManager, err := routines.NewCertManagerFromSecret(secret, validPeriod)
+	return certManager, privateKey, err
+}
+
+// createExternalCertManager creates a cert manager which signs certificates by external signer,
+// ca.crt of CA secret may contain multiple CA certs, the first one is the CA of external signer
+// and all of them are trusted
+func (opts Options) createExternalCertManager(ctx context.Context, cli client.Reader) (certutil.Manager, error) {
+	var secret corev1.Secret
+	err := cli.Get(ctx, client.ObjectKey{Name: opts.CASecretName, Namespace: opts.Namespace}, &secret)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	certManager, err := certutil.NewManger(certDER, keyDER, validPeriod)
-	return certManager, privateKey, err
+	caCerts, err := certutil.DecodeCertsPEM(secretutil.GetCACert(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	return certutil.NewRemoteManager(caCerts[0], opts.signCert, caCerts[1:]...)
+}
+
+func (opts Options) newExternalSignCertFunc(cli client.Client) (certutil.SignCertFunc, error) {
+	switch opts.CASigner {
+	case SignerCertManager:
+		return signer.CertManagerSigner{
+			Client:    cli,
+			Namespace: opts.Namespace,
+			IssuerRef: opts.CertManagerIssuer,
+			Duration:  timeutil.Days(opts.CertValidPeriod),
+		}.Sign, nil
+	case SignerHTTP:
+		s := signer.HTTPSigner{
+			URL:    opts.HTTPSigner.URL,
+			Client: &http.Client{Timeout: signer.DefaultTimeout},
+		}
+
+		if opts.HTTPSigner.TokenFile != "" {
+			token, err := ioutil.ReadFile(opts.HTTPSigner.TokenFile)
+			if err != nil {
+				return nil, err
+			}
+			s.Token = strings.TrimSpace(string(token))
+		}
+
+		if opts.HTTPSigner.CAFile != "" {
+			caPEM, err := ioutil.ReadFile(opts.HTTPSigner.CAFile)
+			if err != nil {
+				return nil, err
+			}
+
+			certPool := x509.NewCertPool()
+			if !certPool.AppendCertsFromPEM(caPEM) {
+				return nil, fmt.Errorf("no CA cert found in %s", opts.HTTPSigner.CAFile)
+			}
+			s.Client.Transport = &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: certPool},
+			}
+		}
+
+		return s.Sign, nil
+	default:
+		return nil, fmt.Errorf("unknown CA signer: %s", opts.CASigner)
+	}
+}
+
+func loadPrivateKey(filename string) (crypto.Signer, error) {
+	keyDER, err := certutil.ReadPEMFileAndDecode(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return certutil.ParsePrivateKey(keyDER)
+}
+
+// newRemoteCertManager creates a cert manager which trusts all CA certs in bundles and signs
+// certificates through host clusters, bundles are CA certs of host clusters in order
+func (opts Options) newRemoteCertManager(bundles ...[]byte) (certutil.Manager, error) {
+	var caCerts [][]byte
+	for _, bundlePEM := range bundles {
+		certs, err := certutil.DecodeCertsPEM(bundlePEM)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cert := range certs {
+			if !containsCert(caCerts, cert) {
+				caCerts = append(caCerts, cert)
+			}
+		}
+	}
+
+	if len(caCerts) == 0 {
+		return nil, fmt.Errorf("no CA certs of host clusters")
+	}
+
+	return certutil.NewRemoteManager(caCerts[0], func(csr []byte) ([]byte, error) {
+		var certDER []byte
+		err := opts.tryHostClusters(func(cli fclient.Interface) error {
+			cert, err := cli.SignCert(csr)
+			certDER = cert.DER
+			return err
+		})
+
+		return certDER, err
+	}, caCerts[1:]...)
+}
+
+// loadCertManagerFromHost creates a cert manager with CA certs trusted by host clusters.
+// TLS certs of API clients are refreshed here too, because API clients have to keep
+// working with host clusters during CA rotation
+func (opts Options) loadCertManagerFromHost(ctx context.Context) (certutil.Manager, error) {
+	bundles := make([][]byte, 0, len(opts.HostClusters))
+	for _, host := range opts.HostClusters {
+		var bundlePEM []byte
+		cli, err := host.getClient()
+		if err == nil {
+			bundlePEM, err = cli.GetCACerts()
+		}
+		if err == nil {
+			err = opts.refreshAPIClientTLS(ctx, host, cli, bundlePEM)
+		}
+
+		if err != nil {
+			// an unreachable host cluster doesn't sign certificates, the last saved CA certs of it are used,
+			// otherwise connectors would stop trusting it whenever it's not reachable
+			log.Error(err, "failed to refresh CA certs of host cluster", "address", host.Address)
+			bundlePEM, err = opts.getSavedCABundle(ctx, host)
+			switch {
+			case err == nil:
+			case errors.IsNotFound(err):
+				// the host cluster has never been joined
+				continue
+			default:
+				return nil, err
+			}
+		}
+
+		bundles = append(bundles, bundlePEM)
+	}
+
+	return opts.newRemoteCertManager(bundles...)
+}
+
+func containsCert(certs [][]byte, cert []byte) bool {
+	for _, c := range certs {
+		if bytes.Equal(c, cert) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getSavedCABundle returns CA certs of host cluster saved in the secret of its client certificate
+func (opts Options) getSavedCABundle(ctx context.Context, host *HostCluster) ([]byte, error) {
+	var secret corev1.Secret
+	key := client.ObjectKey{Name: host.SecretName, Namespace: opts.Namespace}
+	if err := opts.Manager.GetClient().Get(ctx, key, &secret); err != nil {
+		return nil, err
+	}
+
+	return secretutil.GetCACert(secret), nil
+}
+
+// reloadAPIClientTLS updates TLS config of API client of host cluster with the certificate and
+// CA certs saved in its secret, and returns the CA certs
+func (opts Options) reloadAPIClientTLS(ctx context.Context, host *HostCluster) ([]byte, error) {
+	var secret corev1.Secret
+	key := client.ObjectKey{Name: host.SecretName, Namespace: opts.Namespace}
+	if err := opts.Manager.GetClient().Get(ctx, key, &secret); err != nil {
+		return nil, err
+	}
+
+	tlsCert, err := tls.X509KeyPair(secretutil.GetCertAndKey(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	bundlePEM := secretutil.GetCACert(secret)
+	certPool := x509.NewCertPool()
+	certPool.AppendCertsFromPEM(bundlePEM)
+	host.setTLSConfig(&tls.Config{
+		RootCAs:      certPool,
+		Certificates: []tls.Certificate{tlsCert},
+	})
+
+	return bundlePEM, nil
+}
+
+// refreshCRL keeps CRL in local cluster up to date, host cluster re-signs its CRL
+// when it's near expiry or CA is rotated, member and intermediate clusters copy CRL from host cluster
+func (opts Options) refreshCRL(ctx context.Context) {
+	if !opts.joinsHostClusters() {
+		if opts.CRLRevoker == nil {
+			return
+		}
+
+		if err := opts.CRLRevoker.Refresh(ctx, opts.CertManager.GetCACert()); err != nil {
+			log.Error(err, "failed to refresh CRL")
+		}
+		return
+	}
+
+	// certificates of member clusters are issued and revoked by the first reachable host cluster
+	var crlPEM []byte
+	err := opts.tryHostClusters(func(cli fclient.Interface) (err error) {
+		crlPEM, err = cli.GetCRL()
+		return err
+	})
+	if err != nil {
+		log.Error(err, "failed to get CRL from host cluster")
+		return
+	}
+
+	if len(crlPEM) == 0 {
+		return
+	}
+
+	if err = crl.Save(ctx, opts.Manager.GetClient(), opts.Namespace, crlPEM); err != nil {
+		log.Error(err, "failed to save CRL")
+	}
+}
+
+// refreshAPIClientTLS re-signs the certificate of API client of host cluster if it's not issued
+// by the current CA of host cluster, then updates TLS config of API client. bundlePEM is CA certs
+// of host cluster, the first one is the current CA, hostClient is the API client of host cluster
+func (opts Options) refreshAPIClientTLS(ctx context.Context, host *HostCluster, hostClient fclient.Interface, bundlePEM []byte) error {
+	caCertsDER, err := certutil.DecodeCertsPEM(bundlePEM)
+	if err != nil {
+		return err
+	}
+
+	caCert, err := x509.ParseCertificate(caCertsDER[0])
+	if err != nil {
+		return err
+	}
+
+	cli := opts.Manager.GetClient()
+	key := client.ObjectKey{
+		Name:      host.SecretName,
+		Namespace: opts.Namespace,
+	}
+
+	var secret corev1.Secret
+	if err := cli.Get(ctx, key, &secret); err != nil {
+		return err
+	}
+
+	certPEM, keyPEM := secretutil.GetCertAndKey(secret)
+	certDER, err := certutil.DecodePEM(certPEM)
+	if err != nil {
+		return err
+	}
+
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return err
+	}
+
+	isIssuedByCA := certutil.IsIssuedBy(cert, caCert)
+	if isIssuedByCA && bytes.Equal(secretutil.GetCACert(secret), bundlePEM) {
+		return nil
+	}
+
+	if !isIssuedByCA {
+		keyDER, err := certutil.DecodePEM(keyPEM)
+		if err != nil {
+			return err
+		}
+
+		csr, err := certutil.NewCertRequestWithKey(keyDER, certutil.Request{
+			CommonName:   cert.Subject.CommonName,
+			Organization: cert.Subject.Organization,
+		})
+		if err != nil {
+			return err
+		}
+
+		newCert, err := hostClient.SignCert(csr)
+		if err != nil {
+			return err
+		}
+		secret.Data[corev1.TLSCertKey] = newCert.PEM
+	}
+
+	secret.Data[secretutil.KeyCACert] = bundlePEM
+	if err = cli.Update(ctx, &secret); err != nil {
+		return err
+	}
+
+	tlsCert, err := tls.X509KeyPair(secretutil.GetCertAndKey(secret))
+	if err != nil {
+		return err
+	}
+
+	certPool := x509.NewCertPool()
+	certPool.AppendCertsFromPEM(bundlePEM)
+	host.setTLSConfig(&tls.Config{
+		RootCAs:      certPool,
+		Certificates: []tls.Certificate{tlsCert},
+	})
+	log.V(3).Info("TLS config of API client is refreshed", "address", host.Address, "certReSigned", !isIssuedByCA)
+
+	return nil
+}
+
+// leaderChecker fails until the instance is elected as the leader, it always succeeds if leader election is disabled
+func (opts Options) leaderChecker() healthz.Checker {
+	return func(_ *http.Request) error {
+		select {
+		case <-opts.Manager.Elected():
+			return nil
+		default:
+			return errNotLeader
+		}
+	}
 }
 
 func (opts Options) RunManager() error {
@@ -520,11 +1182,72 @@ func (opts Options) RunManager() error {
 		return err
 	}
 
+	// webhook server is run by every operator instance, but store and CIDRMap are filled by
+	// the leader only, so only the leader can make correct decisions in member clusters,
+	// other instances are not ready, so webhook requests are sent to the leader only.
+	// In host cluster, the store and CIDRMap filled by HostStateMirror in every instance are used
+	store, cidrMap := opts.Store, opts.ClusterCIDRsMap
+	if opts.ClusterRole == RoleHost {
+		store, cidrMap = opts.APIServerStore, opts.APIServerCIDRsMap
+	} else if opts.EnableWebhook {
+		if err := opts.Manager.AddReadyzCheck("leader", opts.leaderChecker()); err != nil {
+			log.Error(err, "failed to add readyz check")
+			return err
+		}
+	}
+	if opts.EnableWebhook {
+		if err := webhook.AddToManager(webhook.Config{
+			Manager:      opts.Manager,
+			Store:        store,
+			CIDRMap:      cidrMap,
+			EdgePodCIDRs: opts.edgePodCIDRs(),
+		}); err != nil {
+			log.Error(err, "failed to add webhooks to manager")
+			return err
+		}
+	}
+
+	// API server is run by every instance of host operator, so member clusters
+	// can reach any of them and aren't interrupted when leader changes
 	if opts.ClusterRole == RoleHost {
-		if err := opts.Manager.Add(manager.RunnableFunc(opts.runAPIServer)); err != nil {
+		if err := opts.Manager.Add(opts.HostStateMirror); err != nil {
+			log.Error(err, "failed to add host state mirror to manager")
+			return err
+		}
+
+		if err := opts.Manager.Add(routines.EveryReplica(manager.RunnableFunc(opts.runAPIServer))); err != nil {
 			log.Error(err, "failed to add api server runnable")
 			return err
 		}
+
+		if err := opts.Manager.Add(routines.EveryReplica(manager.RunnableFunc(opts.reloadCertManager))); err != nil {
+			log.Error(err, "failed to add cert manager reloader")
+			return err
+		}
+	}
+
+	// host clusters which are not reachable when operator starts are joined later
+	if opts.joinsHostClusters() {
+		if err := opts.Manager.Add(routines.EveryReplica(manager.RunnableFunc(opts.retryJoiningHostClusters))); err != nil {
+			log.Error(err, "failed to add host cluster joiner")
+			return err
+		}
+	}
+
+	// API server of an intermediate cluster serves data from its host clusters too,
+	// so every replica has to watch them
+	if opts.ClusterRole == RoleHost && opts.joinsHostClusters() {
+		if err := opts.Manager.Add(routines.EveryReplica(routines.WatchUpperHostClusters(
+			timeutil.Seconds(10),
+			opts.Cluster,
+			opts.Store,
+			opts.ClusterCIDRsMap,
+			opts.HostStateMirror,
+			opts.hostClusterWatches()...,
+		))); err != nil {
+			log.Error(err, "failed to add watchUpperHostClusters routine")
+			return err
+		}
 	}
 
 	err := opts.Manager.Start(signals.SetupSignalHandler())
@@ -535,6 +1258,66 @@ func (opts Options) RunManager() error {
 	return err
 }
 
+// reloadCertManager keeps cert manager of an instance which is not the leader up to date by
+// loading CA from CA secret, so its API server signs and verifies certificates with the current CA.
+// The leader rotates CA and updates cert manager by cert refresher, so it stops once elected
+func (opts Options) reloadCertManager(ctx context.Context) error {
+	tick := time.NewTicker(timeutil.Minutes(1))
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-opts.Manager.Elected():
+			return nil
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+		}
+
+		certManager, err := opts.loadCertManagerFromSecret(ctx)
+		if err != nil {
+			log.Error(err, "failed to reload CA from CA secret")
+			continue
+		}
+		opts.CertManager.SetManager(certManager)
+	}
+}
+
+// loadCertManagerFromSecret creates a cert manager from CA secret without changing it. An intermediate
+// cluster has no CA secret, CA certs of host clusters and certificates of API clients, which are refreshed
+// by the leader, are loaded from secrets of API clients instead
+func (opts Options) loadCertManagerFromSecret(ctx context.Context) (certutil.Manager, error) {
+	if opts.joinsHostClusters() {
+		bundles := make([][]byte, 0, len(opts.HostClusters))
+		for _, host := range opts.HostClusters {
+			bundlePEM, err := opts.reloadAPIClientTLS(ctx, host)
+			switch {
+			case err == nil:
+			case errors.IsNotFound(err):
+				// the host cluster has never been joined
+				continue
+			default:
+				return nil, err
+			}
+			bundles = append(bundles, bundlePEM)
+		}
+
+		return opts.newRemoteCertManager(bundles...)
+	}
+
+	if opts.CASigner != SignerLocal {
+		return opts.createExternalCertManager(ctx, opts.Manager.GetClient())
+	}
+
+	var secret corev1.Secret
+	key := client.ObjectKey{Name: opts.CASecretName, Namespace: opts.Namespace}
+	if err := opts.Manager.GetClient().Get(ctx, key, &secret); err != nil {
+		return nil, err
+	}
+
+	return routines.NewCertManagerFromSecret(secret, timeutil.Days(opts.CertValidPeriod))
+}
+
 func (opts Options) runAPIServer(ctx context.Context) error {
 	errChan := make(chan error)
 
@@ -580,18 +1363,15 @@ func (opts Options) initializeControllers(ctx context.Context) error {
 		if opts.AutoKeepIPPools {
 			opts.createIPPoolsForEdgePodCIDRs(ctx)
 
-			getClusterCIDRInfo := func() (map[string][]string, error) {
-				return opts.ClusterCIDRsMap.GetCopy(), nil
-			}
-			if opts.ClusterRole == RoleMember {
-				getClusterCIDRInfo = opts.APIClient.GetClusterCIDRs
-			}
-
+			// CIDRs of member clusters are recorded by cluster controller in host cluster,
+			// and are synchronized from host cluster in member clusters
 			if err := opts.Manager.Add(routines.NewIPPoolKeeper(
 				timeutil.Minutes(1),
 				opts.Cluster,
+				opts.edgePodCIDRs(),
 				opts.Manager.GetClient(),
-				getClusterCIDRInfo,
+				opts.Manager.GetEventRecorderFor(types.EventSource),
+				opts.ClusterCIDRsMap,
 			)); err != nil {
 				// IPPoolKeeper is used to save users from configuring a lot of ippool manually,
 				// but it's ok if it's registered successfully
@@ -606,6 +1386,50 @@ func (opts Options) initializeControllers(ctx context.Context) error {
 		return err
 	}
 
+	loadCertManager := opts.loadCertManagerFromHost
+	switch {
+	case opts.joinsHostClusters():
+		// CA certs are trusted by host clusters, intermediate clusters included
+	case opts.CASigner != SignerLocal:
+		// CA of external signer is rotated by users, by putting the new CA cert before
+		// the old one in CA secret, certificates are re-signed by cert refresher then
+		loadCertManager = func(ctx context.Context) (certutil.Manager, error) {
+			return opts.createExternalCertManager(ctx, opts.Manager.GetClient())
+		}
+	case opts.ClusterRole == RoleHost:
+		rotator := &routines.CARotator{
+			SecretKey: client.ObjectKey{
+				Name:      opts.CASecretName,
+				Namespace: opts.Namespace,
+			},
+			GracePeriod:     opts.CARotationGracePeriod,
+			RenewalFraction: opts.CARenewalFraction,
+			CertValidPeriod: timeutil.Days(opts.CertValidPeriod),
+			KeyAlgorithm:    certutil.KeyAlgorithm(opts.CAKeyAlgorithm),
+			Client:          opts.Manager.GetClient(),
+			Recorder:        opts.Manager.GetEventRecorderFor(types.EventSource),
+			Log:             opts.Manager.GetLogger().WithName("CARotator"),
+		}
+		loadCertManager = rotator.Rotate
+	}
+
+	if err = opts.Manager.Add(routines.NewCertRefresher(
+		timeutil.Minutes(1),
+		opts.Namespace,
+		opts.Manager.GetClient(),
+		opts.Manager.GetEventRecorderFor(types.EventSource),
+		opts.CertManager,
+		loadCertManager,
+	)); err != nil {
+		log.Error(err, "failed to add cert refresher to manager")
+		return err
+	}
+
+	if err = opts.Manager.Add(routines.Periodic(timeutil.Minutes(1), opts.refreshCRL)); err != nil {
+		log.Error(err, "failed to add CRL refresher to manager")
+		return err
+	}
+
 	// todo: ugly!!! try to move getConnectorEndpoint init in Complete
 	getConnectorEndpoint, err := connectorctl.AddToManager(opts.Connector)
 	if err != nil {
@@ -623,9 +1447,12 @@ func (opts Options) initializeControllers(ctx context.Context) error {
 	}
 
 	if err = cmmctl.AddToManager(cmmctl.Config{
-		Manager:       opts.Manager,
-		Store:         opts.Store,
-		CommunityChan: communityEventChan,
+		Manager:               opts.Manager,
+		Store:                 opts.Store,
+		Namespace:             opts.Namespace,
+		GetEndpointName:       opts.Agent.GetEndpointName,
+		EnableClusterSelector: opts.ClusterRole == RoleHost,
+		CommunityChan:         communityEventChan,
 	}); err != nil {
 		log.Error(err, "failed to add communities controller to manager")
 		return err
@@ -650,43 +1477,71 @@ func (opts Options) initializeControllers(ctx context.Context) error {
 		}
 
 		if err = clusterctl.AddToManager(clusterctl.Config{
-			Cluster:       opts.Cluster,
-			Manager:       opts.Manager,
-			PrivateKey:    opts.PrivateKey,
-			TokenDuration: opts.TokenValidPeriod,
-			Store:         opts.Store,
-			CIDRMap:       opts.ClusterCIDRsMap,
+			Cluster:          opts.Cluster,
+			Manager:          opts.Manager,
+			PrivateKey:       opts.PrivateKey,
+			TokenDuration:    opts.TokenValidPeriod,
+			TokenSingleUse:   opts.TokenSingleUse,
+			HeartbeatTimeout: opts.ClusterHeartbeatTimeout,
+			Store:            opts.Store,
+			CIDRMap:          opts.ClusterCIDRsMap,
+			EdgePodCIDRs:     opts.edgePodCIDRs(),
 		}); err != nil {
 			log.Error(err, "failed to add cluster controller to manager")
 			return err
 		}
-	} else {
-		err = opts.Manager.Add(routines.LoadEndpointsAndCommunities(
+	}
+
+	if opts.ClusterRole == RoleMember {
+		// changes are streamed from host clusters, polling is only used when watch is not available.
+		// Host clusters of an intermediate cluster are watched by every replica, see RunManager
+		err = opts.Manager.Add(routines.WatchHostClusters(
 			timeutil.Seconds(10),
 			opts.Store,
-			opts.APIClient.GetEndpointsAndCommunities,
+			opts.ClusterCIDRsMap,
+			opts.hostClusterWatches()...,
 		))
 		if err != nil {
-			log.Error(err, "failed to start loadEndpointsAndCommunities routine")
+			log.Error(err, "failed to start watchHostClusters routine")
 			return err
 		}
+	}
 
-		err = opts.Manager.Add(routines.ExportCluster(
-			timeutil.Seconds(10),
-			opts.Cluster,
-			clusterCIDRs,
-			getConnectorEndpoint,
-			opts.APIClient.UpdateCluster,
-		))
-		if err != nil {
-			log.Error(err, "failed to start exportCluster routine")
-			return err
+	if opts.joinsHostClusters() {
+		// an intermediate cluster exports its child clusters as a part of itself
+		var getChildren routines.GetChildClustersFunc
+		if opts.ClusterRole == RoleHost {
+			getChildren = routines.NewChildClustersGetter(opts.Manager.GetClient(), opts.Cluster)
+		}
+
+		for _, host := range opts.HostClusters {
+			err = opts.Manager.Add(routines.ExportCluster(
+				timeutil.Seconds(10),
+				opts.Cluster,
+				clusterCIDRs,
+				getConnectorEndpoint,
+				getChildren,
+				host.UpdateCluster,
+			))
+			if err != nil {
+				log.Error(err, "failed to start exportCluster routine", "address", host.Address)
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+func (opts Options) hostClusterWatches() []routines.WatchFunc {
+	watches := make([]routines.WatchFunc, 0, len(opts.HostClusters))
+	for _, host := range opts.HostClusters {
+		watches = append(watches, host.Watch)
+	}
+
+	return watches
+}
+
 func (opts Options) recordEndpoints(ctx context.Context) error {
 	cli := opts.Manager.GetClient()
 	store := opts.Store
@@ -762,23 +1617,23 @@ func (opts Options) recordIPAMBlocks(ctx context.Context) error {
 	return nil
 }
 
-func (opts *Options) initAPIClient(kubeClient client.Client, cacert fclient.Certificate) error {
+func (opts *Options) initAPIClient(kubeClient client.Client, host *HostCluster, cacert fclient.Certificate) error {
 	key := client.ObjectKey{
-		Name:      ClientTLSSecretName,
+		Name:      host.SecretName,
 		Namespace: opts.Namespace,
 	}
 
 	certPool := x509.NewCertPool()
-	certPool.AddCert(cacert.Raw)
+	certPool.AppendCertsFromPEM(cacert.PEM)
 
 	var secret corev1.Secret
 	err := kubeClient.Get(context.Background(), key, &secret)
 	switch {
 	case err == nil:
 	case errors.IsNotFound(err):
-		secret, err = opts.createTLSSecretForClient(kubeClient, certPool, cacert)
+		secret, err = opts.createTLSSecretForClient(kubeClient, host, certPool, cacert)
 		if err != nil {
-			log.Error(err, "failed to create tls secret for API client")
+			log.Error(err, "failed to create tls secret for API client", "address", host.Address)
 			return err
 		}
 	default:
@@ -793,38 +1648,39 @@ func (opts *Options) initAPIClient(kubeClient client.Client, cacert fclient.Cert
 		return err
 	}
 
-	opts.APIClient, err = fclient.NewClient(opts.APIServerAddress, opts.Cluster, &http.Transport{
-		TLSClientConfig: &tls.Config{
-			RootCAs:      certPool,
-			Certificates: []tls.Certificate{cert},
-		},
+	transport := fclient.NewTLSTransport(&tls.Config{
+		RootCAs:      certPool,
+		Certificates: []tls.Certificate{cert},
 	})
+	cli, err := fclient.NewClient(host.Address, opts.Cluster, transport)
 	if err != nil {
 		log.Error(err, "failed to create API client")
 		return err
 	}
+	host.join(cli, transport)
 
 	return nil
 }
 
-func (opts Options) createTLSSecretForClient(kubeClient client.Client, certPool *x509.CertPool, cacert fclient.Certificate) (secret corev1.Secret, err error) {
+func (opts Options) createTLSSecretForClient(kubeClient client.Client, host *HostCluster, certPool *x509.CertPool, cacert fclient.Certificate) (secret corev1.Secret, err error) {
 	keyDER, csrDER, err := certutil.NewCertRequest(certutil.Request{
-		CommonName:   fmt.Sprintf("%s.fabedge-client", opts.Cluster),
+		CommonName:   apiserver.ClientCommonName(opts.Cluster),
 		Organization: []string{opts.CertOrganization},
+		KeyAlgorithm: certutil.KeyAlgorithm(opts.CertKeyAlgorithm),
 	})
 	if err != nil {
 		log.Error(err, "failed to create certificate request")
 		return secret, err
 	}
 
-	cert, err := fclient.SignCertByToken(opts.APIServerAddress, opts.InitToken, csrDER, certPool)
+	cert, err := fclient.SignCertByToken(host.Address, opts.Cluster, host.InitToken, csrDER, certPool)
 	if err != nil {
 		log.Error(err, "failed to create certificate for API client")
 		return secret, err
 	}
 
 	secret = secretutil.TLSSecret().
-		Name(ClientTLSSecretName).
+		Name(host.SecretName).
 		Namespace(opts.Namespace).
 		EncodeKey(keyDER).
 		CertPEM(cert.PEM).
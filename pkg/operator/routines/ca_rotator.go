@@ -0,0 +1,253 @@
+package routines
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fabedge/fabedge/pkg/common/constants"
+	"github.com/fabedge/fabedge/pkg/operator/types"
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
+)
+
+// CARotator rotates the CA in CA secret, a rotation has three phases and the current
+// phase is decided by data of CA secret:
+//  1. A new CA is generated and saved as next-ca.crt/next-ca.key, certificates issued by
+//     either CA are trusted, the CA bundle is distributed to agents, connectors and member clusters.
+//  2. After GracePeriod, the new CA replaces ca.crt/ca.key and the old one is kept as
+//     previous-ca.crt, certificates are re-signed by the new CA.
+//  3. After another GracePeriod, the old CA is removed if all certificates in local
+//     cluster are re-signed.
+//
+// A rotation is started when CA secret is annotated with fabedge.io/rotate-ca=true or
+// when CA is near expiry.
+type CARotator struct {
+	SecretKey client.ObjectKey
+	// GracePeriod is how long a phase lasts at least, it should be long enough for agents,
+	// connectors and member clusters to get the latest CA bundle
+	GracePeriod time.Duration
+	// RenewalFraction decides when to rotate CA automatically, see certutil.NeedRenewal
+	RenewalFraction float64
+	CertValidPeriod time.Duration
+	// KeyAlgorithm is the key algorithm of new CA, the algorithm of current CA is used if it's empty
+	KeyAlgorithm certutil.KeyAlgorithm
+	Client       client.Client
+	Recorder     record.EventRecorder
+	Log          logr.Logger
+}
+
+// Rotate moves CA rotation forward when it's time, then returns a cert manager
+// built from CA secret
+func (r *CARotator) Rotate(ctx context.Context) (certutil.Manager, error) {
+	var secret corev1.Secret
+	if err := r.Client.Get(ctx, r.SecretKey, &secret); err != nil {
+		return nil, err
+	}
+
+	var err error
+	switch {
+	case len(secret.Data[secretutil.KeyNextCACert]) > 0:
+		if r.isGracePeriodPassed(secret) {
+			err = r.switchCA(ctx, &secret)
+		}
+	case len(secret.Data[secretutil.KeyPreviousCACert]) > 0:
+		if r.isGracePeriodPassed(secret) {
+			err = r.retirePreviousCA(ctx, &secret)
+		}
+	default:
+		err = r.startRotationIfNeeded(ctx, &secret)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCertManagerFromSecret(secret, r.CertValidPeriod)
+}
+
+func (r *CARotator) startRotationIfNeeded(ctx context.Context, secret *corev1.Secret) error {
+	caCert, err := parseCert(secretutil.GetCACert(*secret))
+	if err != nil {
+		return err
+	}
+
+	requested := secret.Annotations[constants.KeyRotateCA] == "true"
+	if !requested && !certutil.NeedRenewal(caCert, r.RenewalFraction) {
+		return nil
+	}
+
+	keyAlgorithm := r.KeyAlgorithm
+	if keyAlgorithm == "" {
+		keyAlgorithm = certutil.KeyAlgorithmOf(caCert.PublicKey)
+	}
+
+	r.Log.V(3).Info("start to rotate CA", "requested", requested, "notAfter", caCert.NotAfter, "keyAlgorithm", keyAlgorithm)
+	caDER, caKeyDER, err := certutil.NewSelfSignedCA(certutil.Config{
+		CommonName:     caCert.Subject.CommonName,
+		Organization:   caCert.Subject.Organization,
+		IsCA:           true,
+		ValidityPeriod: caCert.NotAfter.Sub(caCert.NotBefore),
+		KeyAlgorithm:   keyAlgorithm,
+	})
+	if err != nil {
+		r.Log.Error(err, "failed to create new CA")
+		return err
+	}
+
+	secret.Data[secretutil.KeyNextCACert] = certutil.EncodeCertPEM(caDER)
+	secret.Data[secretutil.KeyNextCAKey] = certutil.EncodePrivateKeyPEM(caKeyDER)
+	delete(secret.Annotations, constants.KeyRotateCA)
+	if err = r.updateSecret(ctx, secret); err != nil {
+		return err
+	}
+
+	r.Recorder.Eventf(secret, corev1.EventTypeNormal, types.EventReasonCARotationStarted,
+		"a new CA is generated and trusted, it will be used to sign certificates after %s", r.GracePeriod)
+	return nil
+}
+
+func (r *CARotator) switchCA(ctx context.Context, secret *corev1.Secret) error {
+	r.Log.V(3).Info("switch to new CA")
+
+	secret.Data[secretutil.KeyPreviousCACert] = secret.Data[secretutil.KeyCACert]
+	secret.Data[secretutil.KeyCACert] = secret.Data[secretutil.KeyNextCACert]
+	secret.Data[secretutil.KeyCAKey] = secret.Data[secretutil.KeyNextCAKey]
+	delete(secret.Data, secretutil.KeyNextCACert)
+	delete(secret.Data, secretutil.KeyNextCAKey)
+	if err := r.updateSecret(ctx, secret); err != nil {
+		return err
+	}
+
+	r.Recorder.Event(secret, corev1.EventTypeNormal, types.EventReasonCASwitched,
+		"new CA is used to sign certificates, certificates issued by previous CA will be re-signed")
+	return nil
+}
+
+func (r *CARotator) retirePreviousCA(ctx context.Context, secret *corev1.Secret) error {
+	caCert, err := parseCert(secretutil.GetCACert(*secret))
+	if err != nil {
+		return err
+	}
+
+	names, err := r.getSecretsNotIssuedBy(ctx, caCert)
+	if err != nil {
+		return err
+	}
+
+	if len(names) > 0 {
+		r.Log.V(3).Info("some certificates are not re-signed by new CA yet, wait for them", "secrets", names)
+		return nil
+	}
+
+	r.Log.V(3).Info("retire previous CA")
+	delete(secret.Data, secretutil.KeyPreviousCACert)
+	delete(secret.Annotations, constants.KeyCARotationTime)
+	if err = r.Client.Update(ctx, secret); err != nil {
+		r.Log.Error(err, "failed to update CA secret")
+		return err
+	}
+
+	r.Recorder.Event(secret, corev1.EventTypeNormal, types.EventReasonCARotationFinished,
+		"previous CA is not trusted anymore")
+	return nil
+}
+
+// getSecretsNotIssuedBy returns names of TLS secrets created by operator whose
+// certificates are not issued by caCert
+func (r *CARotator) getSecretsNotIssuedBy(ctx context.Context, caCert *x509.Certificate) ([]string, error) {
+	var secrets corev1.SecretList
+	err := r.Client.List(ctx, &secrets,
+		client.InNamespace(r.SecretKey.Namespace),
+		client.MatchingLabels{constants.KeyCreatedBy: constants.AppOperator},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, secret := range secrets.Items {
+		certPEM := secretutil.GetCert(secret)
+		if len(certPEM) == 0 {
+			continue
+		}
+
+		cert, err := parseCert(certPEM)
+		if err != nil || !certutil.IsIssuedBy(cert, caCert) {
+			names = append(names, secret.Name)
+		}
+	}
+
+	return names, nil
+}
+
+// isGracePeriodPassed checks if the current phase has lasted for GracePeriod, if the
+// time when the phase began is unknown, the grace period is considered passed
+func (r *CARotator) isGracePeriodPassed(secret corev1.Secret) bool {
+	rotationTime, err := time.Parse(time.RFC3339, secret.Annotations[constants.KeyCARotationTime])
+	if err != nil {
+		return true
+	}
+
+	return time.Since(rotationTime) >= r.GracePeriod
+}
+
+// updateSecret saves CA secret and marks the time when the current phase begins
+func (r *CARotator) updateSecret(ctx context.Context, secret *corev1.Secret) error {
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations[constants.KeyCARotationTime] = time.Now().Format(time.RFC3339)
+
+	err := r.Client.Update(ctx, secret)
+	if err != nil {
+		r.Log.Error(err, "failed to update CA secret")
+	}
+
+	return err
+}
+
+// NewCertManagerFromSecret creates a cert manager which signs certificates with
+// ca.crt/ca.key of CA secret, CAs being rotated are trusted too
+func NewCertManagerFromSecret(secret corev1.Secret, validPeriod time.Duration) (certutil.Manager, error) {
+	certPEM, keyPEM := secretutil.GetCA(secret)
+
+	certDER, err := certutil.DecodePEM(certPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := certutil.DecodePEM(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	var trustedCAs [][]byte
+	for _, key := range []string{secretutil.KeyNextCACert, secretutil.KeyPreviousCACert} {
+		if len(secret.Data[key]) == 0 {
+			continue
+		}
+
+		der, err := certutil.DecodePEM(secret.Data[key])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", key, err)
+		}
+		trustedCAs = append(trustedCAs, der)
+	}
+
+	return certutil.NewManger(certDER, keyDER, validPeriod, trustedCAs...)
+}
+
+func parseCert(certPEM []byte) (*x509.Certificate, error) {
+	der, err := certutil.DecodePEM(certPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParseCertificate(der)
+}
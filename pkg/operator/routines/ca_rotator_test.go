@@ -0,0 +1,200 @@
+package routines
+
+import (
+	"context"
+	"crypto/x509"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fabedge/fabedge/pkg/common/constants"
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
+)
+
+var _ = Describe("CARotator", func() {
+	const namespace = "fabedge"
+
+	var (
+		cli       client.Client
+		recorder  *record.FakeRecorder
+		rotator   *CARotator
+		refresher *certRefresher
+		caKey     = client.ObjectKey{Name: "fabedge-ca", Namespace: namespace}
+		leafKey   = client.ObjectKey{Name: "edge1-tls", Namespace: namespace}
+		oldCA     *x509.Certificate
+	)
+
+	getSecret := func(key client.ObjectKey) corev1.Secret {
+		var secret corev1.Secret
+		Expect(cli.Get(context.TODO(), key, &secret)).To(Succeed())
+		return secret
+	}
+
+	getLeafCert := func() *x509.Certificate {
+		cert, err := parseCert(secretutil.GetCert(getSecret(leafKey)))
+		Expect(err).To(BeNil())
+		return cert
+	}
+
+	rotate := func() certutil.Manager {
+		manager, err := rotator.Rotate(context.TODO())
+		Expect(err).To(BeNil())
+		return manager
+	}
+
+	BeforeEach(func() {
+		caDER, caKeyDER, err := certutil.NewSelfSignedCA(certutil.Config{
+			CommonName:     certutil.DefaultCAName,
+			Organization:   []string{certutil.DefaultOrganization},
+			IsCA:           true,
+			ValidityPeriod: 24 * time.Hour,
+		})
+		Expect(err).To(BeNil())
+		oldCA, _ = x509.ParseCertificate(caDER)
+
+		manager, err := certutil.NewManger(caDER, caKeyDER, time.Hour)
+		Expect(err).To(BeNil())
+		certDER, keyDER, err := manager.NewCertKey(certutil.Config{
+			CommonName:     "edge1",
+			Organization:   []string{certutil.DefaultOrganization},
+			ValidityPeriod: time.Hour,
+			Usages:         certutil.ExtKeyUsagesServerAndClient,
+		})
+		Expect(err).To(BeNil())
+
+		caSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: caKey.Name, Namespace: namespace},
+			Data: map[string][]byte{
+				secretutil.KeyCACert: certutil.EncodeCertPEM(caDER),
+				secretutil.KeyCAKey:  certutil.EncodePrivateKeyPEM(caKeyDER),
+			},
+		}
+		leafSecret := secretutil.TLSSecret().
+			Name(leafKey.Name).
+			Namespace(namespace).
+			EncodeCert(certDER).
+			EncodeKey(keyDER).
+			CACertPEM(manager.GetCABundlePEM()).
+			Label(constants.KeyCreatedBy, constants.AppOperator).
+			Build()
+		cli = fake.NewClientBuilder().WithObjects(caSecret, &leafSecret).Build()
+
+		recorder = record.NewFakeRecorder(10)
+		rotator = &CARotator{
+			SecretKey:       caKey,
+			RenewalFraction: 0.8,
+			CertValidPeriod: time.Hour,
+			Client:          cli,
+			Recorder:        recorder,
+			Log:             klogr.New(),
+		}
+		refresher = &certRefresher{
+			namespace:   namespace,
+			client:      cli,
+			recorder:    recorder,
+			certManager: certutil.NewDynamicManager(manager),
+			loadCertManager: func(ctx context.Context) (certutil.Manager, error) {
+				return rotator.Rotate(ctx)
+			},
+			log: klogr.New(),
+		}
+	})
+
+	It("should not rotate CA unless it's requested or CA is near expiry", func() {
+		manager := rotate()
+		Expect(manager.GetCACert()).To(Equal(oldCA))
+		Expect(getSecret(caKey).Data).NotTo(HaveKey(secretutil.KeyNextCACert))
+		Expect(drainEvents(recorder)).To(BeEmpty())
+	})
+
+	It("should rotate CA in phases and re-sign certificates with their own keys", func() {
+		caSecret := getSecret(caKey)
+		caSecret.Annotations = map[string]string{constants.KeyRotateCA: "true"}
+		Expect(cli.Update(context.TODO(), &caSecret)).To(Succeed())
+		oldLeaf := getLeafCert()
+
+		By("trusting the new CA")
+		refresher.refresh(context.TODO())
+		caSecret = getSecret(caKey)
+		Expect(caSecret.Annotations).NotTo(HaveKey(constants.KeyRotateCA))
+		Expect(caSecret.Annotations).To(HaveKey(constants.KeyCARotationTime))
+		Expect(caSecret.Data).To(HaveKey(secretutil.KeyNextCACert))
+		Expect(refresher.certManager.GetCACert()).To(Equal(oldCA))
+
+		bundle, err := certutil.DecodeCertsPEM(refresher.certManager.GetCABundlePEM())
+		Expect(err).To(BeNil())
+		Expect(bundle).To(HaveLen(2))
+		Expect(secretutil.GetCACert(getSecret(leafKey))).To(Equal(refresher.certManager.GetCABundlePEM()))
+		Expect(getLeafCert()).To(Equal(oldLeaf))
+		Expect(drainEvents(recorder)).To(ConsistOf(HavePrefix("Normal CARotationStarted")))
+
+		By("switching to the new CA")
+		refresher.refresh(context.TODO())
+		caSecret = getSecret(caKey)
+		Expect(caSecret.Data).NotTo(HaveKey(secretutil.KeyNextCACert))
+		Expect(caSecret.Data[secretutil.KeyPreviousCACert]).To(Equal(certutil.EncodeCertPEM(oldCA.Raw)))
+
+		newCA := refresher.certManager.GetCACert()
+		Expect(newCA).NotTo(Equal(oldCA))
+		newLeaf := getLeafCert()
+		Expect(certutil.IsIssuedBy(newLeaf, newCA)).To(BeTrue())
+		Expect(newLeaf.PublicKey).To(Equal(oldLeaf.PublicKey))
+		Expect(newLeaf.Subject.CommonName).To(Equal(oldLeaf.Subject.CommonName))
+		Expect(drainEvents(recorder)).To(ConsistOf(
+			HavePrefix("Normal CASwitched"),
+			HavePrefix("Normal CertificateRenewed"),
+		))
+
+		By("retiring the old CA")
+		refresher.refresh(context.TODO())
+		caSecret = getSecret(caKey)
+		Expect(caSecret.Data).NotTo(HaveKey(secretutil.KeyPreviousCACert))
+		Expect(caSecret.Annotations).NotTo(HaveKey(constants.KeyCARotationTime))
+		Expect(refresher.certManager.GetCABundlePEM()).To(Equal(certutil.EncodeCertPEM(newCA.Raw)))
+		Expect(secretutil.GetCACert(getSecret(leafKey))).To(Equal(certutil.EncodeCertPEM(newCA.Raw)))
+		Expect(refresher.certManager.VerifyCert(oldLeaf, certutil.ExtKeyUsagesServerAndClient)).NotTo(Succeed())
+		Expect(drainEvents(recorder)).To(ConsistOf(HavePrefix("Normal CARotationFinished")))
+	})
+
+	It("should not move to next phase until grace period passes", func() {
+		rotator.GracePeriod = time.Hour
+
+		caSecret := getSecret(caKey)
+		caSecret.Annotations = map[string]string{constants.KeyRotateCA: "true"}
+		Expect(cli.Update(context.TODO(), &caSecret)).To(Succeed())
+
+		rotate()
+		manager := rotate()
+		Expect(manager.GetCACert()).To(Equal(oldCA))
+		Expect(getSecret(caKey).Data).To(HaveKey(secretutil.KeyNextCACert))
+	})
+
+	It("should not re-sign certificates which are not trusted", func() {
+		otherCADER, otherCAKeyDER, err := certutil.NewSelfSignedCA(certutil.Config{
+			CommonName:     "other",
+			IsCA:           true,
+			ValidityPeriod: time.Hour,
+		})
+		Expect(err).To(BeNil())
+		certDER, _, err := certutil.NewCertFromCA2(otherCADER, otherCAKeyDER, certutil.Config{
+			CommonName:     "edge1",
+			ValidityPeriod: time.Hour,
+		})
+		Expect(err).To(BeNil())
+
+		leafSecret := getSecret(leafKey)
+		leafSecret.Data[corev1.TLSCertKey] = certutil.EncodeCertPEM(certDER)
+		Expect(cli.Update(context.TODO(), &leafSecret)).To(Succeed())
+
+		refresher.refresh(context.TODO())
+		Expect(secretutil.GetCert(getSecret(leafKey))).To(Equal(certutil.EncodeCertPEM(certDER)))
+	})
+})
@@ -0,0 +1,132 @@
+package routines
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/fabedge/fabedge/pkg/common/constants"
+	"github.com/fabedge/fabedge/pkg/operator/types"
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
+)
+
+// LoadCertManagerFunc returns a cert manager built from the latest CA certs
+type LoadCertManagerFunc func(ctx context.Context) (certutil.Manager, error)
+
+// NewCertRefresher creates a routine which keeps TLS secrets created by operator in
+// line with trusted CAs, it's what distributes CA bundle and re-signs certificates
+// when CA is rotated
+func NewCertRefresher(interval time.Duration, namespace string, cli client.Client, recorder record.EventRecorder,
+	certManager *certutil.DynamicManager, loadCertManager LoadCertManagerFunc) manager.Runnable {
+	r := &certRefresher{
+		namespace:       namespace,
+		client:          cli,
+		recorder:        recorder,
+		certManager:     certManager,
+		loadCertManager: loadCertManager,
+		log:             klogr.New().WithName("cert-refresher"),
+	}
+
+	return Periodic(interval, r.refresh)
+}
+
+type certRefresher struct {
+	namespace       string
+	client          client.Client
+	recorder        record.EventRecorder
+	certManager     *certutil.DynamicManager
+	loadCertManager LoadCertManagerFunc
+	log             logr.Logger
+}
+
+func (r *certRefresher) refresh(ctx context.Context) {
+	certManager, err := r.loadCertManager(ctx)
+	if err != nil {
+		r.log.Error(err, "failed to load cert manager")
+		return
+	}
+	r.certManager.SetManager(certManager)
+
+	var secrets corev1.SecretList
+	err = r.client.List(ctx, &secrets,
+		client.InNamespace(r.namespace),
+		client.MatchingLabels{constants.KeyCreatedBy: constants.AppOperator},
+	)
+	if err != nil {
+		r.log.Error(err, "failed to list TLS secrets")
+		return
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if err = r.refreshSecret(ctx, certManager, secret); err != nil {
+			r.log.Error(err, "failed to refresh TLS secret", "secretName", secret.Name)
+		}
+	}
+}
+
+// refreshSecret puts CA bundle in secret and re-signs its certificate with the same private key
+// if the certificate is issued by a CA being rotated. Certificates which are not trusted at all
+// are left to their owners, e.g. certHandler of agent controller, who will recreate them
+func (r *certRefresher) refreshSecret(ctx context.Context, certManager certutil.Manager, secret *corev1.Secret) error {
+	certPEM, keyPEM := secretutil.GetCertAndKey(*secret)
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		return nil
+	}
+
+	cert, err := parseCert(certPEM)
+	if err != nil {
+		return err
+	}
+
+	bundlePEM := certManager.GetCABundlePEM()
+	resign := !certutil.IsIssuedBy(cert, certManager.GetCACert()) &&
+		certManager.VerifyCert(cert, []x509.ExtKeyUsage{x509.ExtKeyUsageAny}) == nil
+	if !resign && bytes.Equal(secretutil.GetCACert(*secret), bundlePEM) {
+		return nil
+	}
+
+	if resign {
+		keyDER, err := certutil.DecodePEM(keyPEM)
+		if err != nil {
+			return err
+		}
+
+		csr, err := certutil.NewCertRequestWithKey(keyDER, certutil.Request{
+			CommonName:   cert.Subject.CommonName,
+			Organization: cert.Subject.Organization,
+			DNSNames:     cert.DNSNames,
+			IPs:          cert.IPAddresses,
+		})
+		if err != nil {
+			return err
+		}
+
+		certDER, err := certManager.SignCert(csr)
+		if err != nil {
+			return err
+		}
+		secret.Data[corev1.TLSCertKey] = certutil.EncodeCertPEM(certDER)
+	}
+
+	secret.Data[secretutil.KeyCACert] = bundlePEM
+	if err = r.client.Update(ctx, secret); err != nil {
+		return err
+	}
+
+	if resign {
+		r.recorder.Event(secret, corev1.EventTypeNormal, types.EventReasonCertRenewed,
+			"certificate is re-signed by the current CA")
+	}
+
+	return nil
+}
@@ -2,14 +2,17 @@ package routines
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/common/about"
 	"github.com/fabedge/fabedge/pkg/operator/apiserver"
 	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
 	"github.com/fabedge/fabedge/pkg/operator/types"
@@ -18,9 +21,14 @@ import (
 type UpdateCluster func(cluster apis.Cluster) error
 type UpdateEndpointsFunc func(endpoints []apis.Endpoint) error
 type GetEndpointsAndCommunitiesFunc func() (apiserver.EndpointsAndCommunity, error)
+type WatchFunc func(ctx context.Context, pollInterval time.Duration, handler func(apiserver.WatchEvent), onError func(error))
 
-// ExportCluster is used to export cluster CIDRs and endpoints to host cluster
-func ExportCluster(interval time.Duration, clusterName string, clusterCIDRs []string, getConnector types.EndpointGetter, updateCluster UpdateCluster) manager.Runnable {
+// GetChildClustersFunc returns endpoints and CIDRs of child clusters of an intermediate cluster
+type GetChildClustersFunc func(ctx context.Context) ([]apis.Endpoint, []string, error)
+
+// ExportCluster is used to export cluster CIDRs and endpoints to host cluster. If getChildren is not nil,
+// endpoints and CIDRs of child clusters are exported too, as if they belong to this cluster
+func ExportCluster(interval time.Duration, clusterName string, clusterCIDRs []string, getConnector types.EndpointGetter, getChildren GetChildClustersFunc, updateCluster UpdateCluster) manager.Runnable {
 	log := klogr.New().WithName("exportEndpoints")
 
 	fn := func(ctx context.Context) {
@@ -34,6 +42,21 @@ func ExportCluster(interval time.Duration, clusterName string, clusterCIDRs []st
 					getConnector(),
 				},
 			},
+			Status: apis.ClusterStatus{
+				Version: about.GetVersion(),
+			},
+		}
+
+		if getChildren != nil {
+			endpoints, cidrs, err := getChildren(ctx)
+			if err != nil {
+				// exporting without child clusters would make host cluster delete their endpoints
+				log.Error(err, "failed to get child clusters")
+				return
+			}
+
+			cluster.Spec.CIDRs = append(append([]string{}, clusterCIDRs...), cidrs...)
+			cluster.Spec.EndPoints = append(cluster.Spec.EndPoints, endpoints...)
 		}
 
 		if err := updateCluster(cluster); err != nil {
@@ -44,6 +67,37 @@ func ExportCluster(interval time.Duration, clusterName string, clusterCIDRs []st
 	return Periodic(interval, fn)
 }
 
+// NewChildClustersGetter returns a function which collects endpoints and CIDRs of child clusters
+// of an intermediate cluster. Only clusters named "<clusterName>.<child>" are child clusters, others
+// are ignored, because host clusters won't accept their endpoints from this cluster
+func NewChildClustersGetter(cli client.Reader, clusterName string) GetChildClustersFunc {
+	return func(ctx context.Context) ([]apis.Endpoint, []string, error) {
+		var clusters apis.ClusterList
+		if err := cli.List(ctx, &clusters); err != nil {
+			return nil, nil, err
+		}
+
+		var (
+			endpoints []apis.Endpoint
+			cidrs     []string
+		)
+		for _, cluster := range clusters.Items {
+			if cluster.DeletionTimestamp != nil || !apiserver.BelongsToCluster(cluster.Name, clusterName) {
+				continue
+			}
+
+			cidrs = append(cidrs, cluster.Spec.CIDRs...)
+			for _, endpoint := range cluster.Spec.EndPoints {
+				if apiserver.BelongsToCluster(endpoint.Name, clusterName) {
+					endpoints = append(endpoints, endpoint)
+				}
+			}
+		}
+
+		return endpoints, cidrs, nil
+	}
+}
+
 func ExportEndpoints(interval time.Duration, getConnector types.EndpointGetter, updateEndpoints UpdateEndpointsFunc) manager.Runnable {
 	log := klogr.New().WithName("exportEndpoints")
 
@@ -62,9 +116,7 @@ func ExportEndpoints(interval time.Duration, getConnector types.EndpointGetter,
 
 func LoadEndpointsAndCommunities(interval time.Duration, store storepkg.Interface, getEndpointsAndCommunities GetEndpointsAndCommunitiesFunc) manager.Runnable {
 	log := klogr.New().WithName("loadEndpointsAndCommunities")
-
-	communitySet := sets.NewString()
-	endpointSet := sets.NewString()
+	load := newEndpointsAndCommunityLoader(store)
 
 	fn := func(ctx context.Context) {
 		ec, err := getEndpointsAndCommunities()
@@ -73,12 +125,168 @@ func LoadEndpointsAndCommunities(interval time.Duration, store storepkg.Interfac
 			return
 		}
 
+		load(ec)
+	}
+
+	return Periodic(interval, fn)
+}
+
+// WatchHostClusters saves endpoints, communities and CIDRs from host clusters into store and cidrMap
+// once they're changed. Each watch is run in its own goroutine and is expected to poll data every
+// pollInterval if watching is not possible. Data of host clusters are merged, see mergeWatchEvents
+func WatchHostClusters(pollInterval time.Duration, store storepkg.Interface, cidrMap *types.ClusterCIDRsMap, watches ...WatchFunc) manager.Runnable {
+	load := newEndpointsAndCommunityLoader(store)
+
+	return watchHostClusters(pollInterval, func(ea apiserver.EndpointsAndCommunity, cidrs map[string][]string) {
+		load(ea)
+		cidrMap.Replace(cidrs)
+	}, watches...)
+}
+
+// WatchUpperHostClusters is like WatchHostClusters, but it's used by an intermediate cluster, which is the host
+// cluster of its child clusters at the same time. CIDRs of child clusters are saved in cidrMap too, so CIDRs from
+// host clusters are merged into cidrMap instead of replacing it. Data from host clusters are also passed to mirror,
+// so they're served to child clusters. It should be run by every replica, because mirror is used by every replica
+func WatchUpperHostClusters(pollInterval time.Duration, clusterName string, store storepkg.Interface, cidrMap *types.ClusterCIDRsMap, mirror *HostStateMirror, watches ...WatchFunc) manager.Runnable {
+	load := newEndpointsAndCommunityLoader(store)
+	loadCIDRs := newCIDRsLoader(cidrMap)
+
+	return watchHostClusters(pollInterval, func(ea apiserver.EndpointsAndCommunity, allCIDRs map[string][]string) {
+		// CIDRs of this cluster and its child clusters are exported to host clusters as a whole,
+		// they're already known and are kept by cluster controller separately
+		cidrs := make(map[string][]string, len(allCIDRs))
+		for name, clusterCIDRs := range allCIDRs {
+			if name != clusterName && !apiserver.BelongsToCluster(name, clusterName) {
+				cidrs[name] = clusterCIDRs
+			}
+		}
+
+		load(ea)
+		loadCIDRs(cidrs)
+		mirror.SetUpstream(ea, cidrs)
+	}, watches...)
+}
+
+func watchHostClusters(pollInterval time.Duration, save func(ea apiserver.EndpointsAndCommunity, cidrs map[string][]string), watches ...WatchFunc) manager.Runnable {
+	log := klogr.New().WithName("watchHostClusters")
+
+	var (
+		lock sync.Mutex
+		// snapshots keeps the latest event of each host cluster, from the oldest to the newest
+		snapshots []hostSnapshot
+	)
+	newHandler := func(host int) func(event apiserver.WatchEvent) {
+		return func(event apiserver.WatchEvent) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			for i := range snapshots {
+				if snapshots[i].host == host {
+					snapshots = append(snapshots[:i], snapshots[i+1:]...)
+					break
+				}
+			}
+			snapshots = append(snapshots, hostSnapshot{host: host, event: event})
+
+			save(mergeWatchEvents(snapshots))
+
+			log.V(5).Info("data from host cluster is saved", "host", host, "resourceVersion", event.ResourceVersion)
+		}
+	}
+
+	newErrorHandler := func(host int) func(err error) {
+		return func(err error) {
+			log.Error(err, "failed to watch or poll data from host cluster", "host", host)
+		}
+	}
+
+	return manager.RunnableFunc(func(ctx context.Context) error {
+		var wg sync.WaitGroup
+		for i, watch := range watches {
+			wg.Add(1)
+			go func(host int, watch WatchFunc) {
+				defer wg.Done()
+				watch(ctx, pollInterval, newHandler(host), newErrorHandler(host))
+			}(i, watch)
+		}
+		wg.Wait()
+
+		return nil
+	})
+}
+
+type hostSnapshot struct {
+	host  int
+	event apiserver.WatchEvent
+}
+
+// mergeWatchEvents merges data of host clusters, snapshots are ordered from the oldest to the newest.
+// Endpoints, communities and CIDRs of all host clusters are kept and members of a community are merged,
+// if an endpoint, IPSec parameters of a community or CIDRs of a cluster are provided by more than one host
+// cluster, the newest one wins, because it reflects the latest changes
+func mergeWatchEvents(snapshots []hostSnapshot) (apiserver.EndpointsAndCommunity, map[string][]string) {
+	var (
+		endpoints   = make(map[string]apis.Endpoint)
+		names       []string
+		communities = make(map[string]sets.String)
+		ipsec       = make(map[string]*apis.IPSecParameters)
+		cidrs       = make(map[string][]string)
+	)
+
+	for _, snapshot := range snapshots {
+		if ea := snapshot.event.EndpointsAndCommunity; ea != nil {
+			for _, endpoint := range ea.Endpoints {
+				if _, found := endpoints[endpoint.Name]; !found {
+					names = append(names, endpoint.Name)
+				}
+				endpoints[endpoint.Name] = endpoint
+			}
+
+			for name, members := range ea.Communities {
+				if _, found := communities[name]; !found {
+					communities[name] = sets.NewString()
+				}
+				communities[name].Insert(members...)
+			}
+
+			for name, params := range ea.IPSec {
+				ipsec[name] = params
+			}
+		}
+
+		for name, clusterCIDRs := range snapshot.event.CIDRs {
+			cidrs[name] = clusterCIDRs
+		}
+	}
+
+	ea := apiserver.EndpointsAndCommunity{
+		Communities: make(map[string][]string, len(communities)),
+		IPSec:       ipsec,
+	}
+	for _, name := range names {
+		ea.Endpoints = append(ea.Endpoints, endpoints[name])
+	}
+	for name, members := range communities {
+		ea.Communities[name] = members.List()
+	}
+
+	return ea, cidrs
+}
+
+// newEndpointsAndCommunityLoader returns a function which saves endpoints and communities into store
+// and deletes those which are saved by previous calls but missing this time
+func newEndpointsAndCommunityLoader(store storepkg.Interface) func(ec apiserver.EndpointsAndCommunity) {
+	communitySet := sets.NewString()
+	endpointSet := sets.NewString()
+
+	return func(ec apiserver.EndpointsAndCommunity) {
 		currentCommunitySet, currentEndpointSet := sets.NewString(), sets.NewString()
 		for name, members := range ec.Communities {
 			currentCommunitySet.Insert(name)
 			store.SaveCommunity(types.Community{
 				Name:    name,
 				Members: sets.NewString(members...),
+				IPSec:   ec.IPSec[name],
 			})
 		}
 
@@ -98,6 +306,24 @@ func LoadEndpointsAndCommunities(interval time.Duration, store storepkg.Interfac
 		communitySet = currentCommunitySet
 		endpointSet = currentEndpointSet
 	}
+}
 
-	return Periodic(interval, fn)
+// newCIDRsLoader returns a function which saves CIDRs into cidrMap and deletes those
+// which are saved by previous calls but missing this time, others are kept
+func newCIDRsLoader(cidrMap *types.ClusterCIDRsMap) func(cidrs map[string][]string) {
+	nameSet := sets.NewString()
+
+	return func(cidrs map[string][]string) {
+		currentNameSet := sets.NewString()
+		for name, clusterCIDRs := range cidrs {
+			currentNameSet.Insert(name)
+			cidrMap.Set(name, clusterCIDRs)
+		}
+
+		for name := range nameSet.Difference(currentNameSet) {
+			cidrMap.Delete(name)
+		}
+
+		nameSet = currentNameSet
+	}
 }
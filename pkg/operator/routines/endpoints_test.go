@@ -7,10 +7,12 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
 	"github.com/fabedge/fabedge/pkg/operator/apiserver"
 	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
+	"github.com/fabedge/fabedge/pkg/operator/types"
 )
 
 var _ = Describe("LoadEndpointsAndCommunities", func() {
@@ -98,3 +100,273 @@ var _ = Describe("LoadEndpointsAndCommunities", func() {
 		Expect(ok).Should(BeFalse())
 	})
 })
+
+var _ = Describe("WatchHostClusters", func() {
+	It("should save data of watch events into store and CIDR map", func() {
+		e1 := apis.Endpoint{
+			Name:            "cluster1.connector",
+			PublicAddresses: []string{"cluster1"},
+			Subnets:         []string{"2.2.2.0/24"},
+			NodeSubnets:     []string{"10.10.0.1/32"},
+		}
+		e2 := apis.Endpoint{
+			Name:            "cluster2.connector",
+			PublicAddresses: []string{"cluster2"},
+			Subnets:         []string{"192.168.1.0/24"},
+			NodeSubnets:     []string{"192.168.1.1/32"},
+		}
+
+		events := make(chan apiserver.WatchEvent)
+		var pollInterval time.Duration
+		watch := func(ctx context.Context, interval time.Duration, handler func(apiserver.WatchEvent), onError func(error)) {
+			pollInterval = interval
+			for {
+				select {
+				case event := <-events:
+					handler(event)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		store := storepkg.NewStore()
+		cidrMap := types.NewClusterCIDRsMap()
+		cidrMap.Set("stale", []string{"10.0.0.0/16"})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go WatchHostClusters(time.Minute, store, cidrMap, watch).Start(ctx)
+
+		events <- apiserver.WatchEvent{
+			Type:            apiserver.WatchEventChanged,
+			ResourceVersion: 1,
+			EndpointsAndCommunity: &apiserver.EndpointsAndCommunity{
+				Communities: map[string][]string{"connectors": {e1.Name, e2.Name}},
+				Endpoints:   []apis.Endpoint{e1, e2},
+			},
+			CIDRs: map[string][]string{"cluster1": {"2.2.2.0/24"}},
+		}
+		// the second event is received only after the first one is handled
+		events <- apiserver.WatchEvent{
+			Type:            apiserver.WatchEventChanged,
+			ResourceVersion: 2,
+			EndpointsAndCommunity: &apiserver.EndpointsAndCommunity{
+				Communities: map[string][]string{"connectors": {e1.Name}},
+				Endpoints:   []apis.Endpoint{e1},
+			},
+			CIDRs: map[string][]string{"cluster1": {"2.2.2.0/24"}, "cluster2": {"192.168.1.0/24"}},
+		}
+		cancel()
+
+		Eventually(func() bool {
+			_, ok := store.GetEndpoint(e2.Name)
+			return ok
+		}).Should(BeFalse())
+		Expect(pollInterval).Should(Equal(time.Minute))
+
+		community, _ := store.GetCommunity("connectors")
+		Expect(community.Members.List()).Should(ConsistOf(e1.Name))
+
+		endpoint, _ := store.GetEndpoint(e1.Name)
+		Expect(endpoint).Should(Equal(e1))
+
+		Expect(cidrMap.GetCopy()).Should(Equal(map[string][]string{
+			"cluster1": {"2.2.2.0/24"},
+			"cluster2": {"192.168.1.0/24"},
+		}))
+	})
+
+	It("should merge data of host clusters", func() {
+		e1 := apis.Endpoint{
+			Name:            "cluster1.connector",
+			PublicAddresses: []string{"cluster1"},
+			Subnets:         []string{"2.2.2.0/24"},
+		}
+		e2 := apis.Endpoint{
+			Name:            "cluster2.connector",
+			PublicAddresses: []string{"cluster2"},
+			Subnets:         []string{"192.168.1.0/24"},
+		}
+		e2New := e2
+		e2New.PublicAddresses = []string{"cluster2.new"}
+		e3 := apis.Endpoint{
+			Name:            "cluster3.connector",
+			PublicAddresses: []string{"cluster3"},
+			Subnets:         []string{"192.168.2.0/24"},
+		}
+
+		snapshots := []hostSnapshot{
+			{
+				host: 1,
+				event: apiserver.WatchEvent{
+					EndpointsAndCommunity: &apiserver.EndpointsAndCommunity{
+						Communities: map[string][]string{"connectors": {e2.Name, e3.Name}},
+						Endpoints:   []apis.Endpoint{e2, e3},
+						IPSec:       map[string]*apis.IPSecParameters{"connectors": {IKEProposals: []string{"aes128-sha256-modp2048"}}},
+					},
+					CIDRs: map[string][]string{"cluster2": {"192.168.1.0/24"}, "cluster3": {"192.168.2.0/24"}},
+				},
+			},
+			{
+				host: 0,
+				event: apiserver.WatchEvent{
+					EndpointsAndCommunity: &apiserver.EndpointsAndCommunity{
+						Communities: map[string][]string{"connectors": {e1.Name, e2.Name}},
+						Endpoints:   []apis.Endpoint{e1, e2New},
+						IPSec:       map[string]*apis.IPSecParameters{"connectors": {IKEProposals: []string{"aes256gcm16-prfsha384-ecp384"}}},
+					},
+					CIDRs: map[string][]string{"cluster1": {"2.2.2.0/24"}, "cluster2": {"192.168.3.0/24"}},
+				},
+			},
+		}
+
+		ea, cidrs := mergeWatchEvents(snapshots)
+		Expect(ea.Endpoints).Should(ConsistOf(e1, e2New, e3))
+		Expect(ea.Communities).Should(HaveLen(1))
+		Expect(ea.Communities["connectors"]).Should(ConsistOf(e1.Name, e2.Name, e3.Name))
+		Expect(ea.IPSec["connectors"].IKEProposals).Should(ConsistOf("aes256gcm16-prfsha384-ecp384"))
+		Expect(cidrs).Should(Equal(map[string][]string{
+			"cluster1": {"2.2.2.0/24"},
+			"cluster2": {"192.168.3.0/24"},
+			"cluster3": {"192.168.2.0/24"},
+		}))
+	})
+})
+
+var _ = Describe("WatchUpperHostClusters", func() {
+	It("should merge CIDRs from host clusters and pass data to mirror", func() {
+		global := apis.Endpoint{
+			Name:            "global.connector",
+			PublicAddresses: []string{"global"},
+			Subnets:         []string{"10.233.0.0/18"},
+		}
+
+		events := make(chan apiserver.WatchEvent)
+		watch := func(ctx context.Context, interval time.Duration, handler func(apiserver.WatchEvent), onError func(error)) {
+			for {
+				select {
+				case event := <-events:
+					handler(event)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		store := storepkg.NewStore()
+		cidrMap := types.NewClusterCIDRsMap()
+		// CIDRs of the cluster itself and its child clusters are kept by cluster controller
+		cidrMap.Set("region", []string{"10.234.0.0/16"})
+		cidrMap.Set("region.child", []string{"10.235.0.0/16"})
+		mirror := &HostStateMirror{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go WatchUpperHostClusters(time.Minute, "region", store, cidrMap, mirror, watch).Start(ctx)
+
+		ea := apiserver.EndpointsAndCommunity{
+			Communities: map[string][]string{"connectors": {global.Name, "region.connector"}},
+			Endpoints:   []apis.Endpoint{global},
+		}
+		events <- apiserver.WatchEvent{
+			Type:                  apiserver.WatchEventChanged,
+			EndpointsAndCommunity: &ea,
+			CIDRs: map[string][]string{
+				"global":       {"10.233.0.0/16"},
+				"beijing":      {"10.236.0.0/16"},
+				"region":       {"10.234.0.0/16", "10.235.0.0/16"},
+				"region.child": {"10.235.0.0/16"},
+			},
+		}
+		// the second event is received only after the first one is handled
+		events <- apiserver.WatchEvent{
+			Type:                  apiserver.WatchEventChanged,
+			EndpointsAndCommunity: &ea,
+			CIDRs:                 map[string][]string{"global": {"10.233.0.0/16"}},
+		}
+		cancel()
+
+		Eventually(cidrMap.GetCopy).Should(Equal(map[string][]string{
+			"global":       {"10.233.0.0/16"},
+			"region":       {"10.234.0.0/16"},
+			"region.child": {"10.235.0.0/16"},
+		}))
+
+		endpoint, _ := store.GetEndpoint(global.Name)
+		Expect(endpoint).Should(Equal(global))
+
+		var upstream apiserver.EndpointsAndCommunity
+		Eventually(func() map[string][]string {
+			upstream = apiserver.EndpointsAndCommunity{Communities: map[string][]string{}}
+			cidrs := map[string][]string{}
+			mirror.mergeUpstream(&upstream, cidrs)
+			return cidrs
+		}).Should(Equal(map[string][]string{"global": {"10.233.0.0/16"}}))
+		Expect(upstream.Endpoints).Should(ConsistOf(global))
+		Expect(upstream.Communities["connectors"]).Should(ConsistOf(global.Name, "region.connector"))
+	})
+})
+
+var _ = Describe("ExportCluster", func() {
+	It("should export endpoints and CIDRs of child clusters with its own", func() {
+		ctx := context.Background()
+		connector := apis.Endpoint{
+			Name:            "export-region.connector",
+			PublicAddresses: []string{"10.10.10.10"},
+			Subnets:         []string{"10.233.0.0/18"},
+		}
+		childConnector := apis.Endpoint{
+			Name:            "export-region.child.connector",
+			PublicAddresses: []string{"10.10.10.20"},
+			Subnets:         []string{"10.234.0.0/18"},
+		}
+
+		child := apis.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "export-region.child"},
+			Spec: apis.ClusterSpec{
+				CIDRs:     []string{"10.234.0.0/16"},
+				EndPoints: []apis.Endpoint{childConnector},
+			},
+		}
+		// clusters which are not named after the cluster can't be exported
+		other := apis.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "export-other"},
+			Spec: apis.ClusterSpec{
+				CIDRs: []string{"10.235.0.0/16"},
+				EndPoints: []apis.Endpoint{
+					{Name: "export-other.connector", PublicAddresses: []string{"10.10.10.30"}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, &child)).Should(Succeed())
+		Expect(k8sClient.Create(ctx, &other)).Should(Succeed())
+		defer func() {
+			Expect(k8sClient.Delete(ctx, &child)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, &other)).Should(Succeed())
+		}()
+
+		exported := make(chan apis.Cluster, 1)
+		runnable := ExportCluster(
+			time.Minute,
+			"export-region",
+			[]string{"10.233.0.0/16"},
+			func() apis.Endpoint { return connector },
+			NewChildClustersGetter(k8sClient, "export-region"),
+			func(cluster apis.Cluster) error {
+				exported <- cluster
+				return nil
+			},
+		)
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go runnable.Start(ctx)
+
+		var cluster apis.Cluster
+		Eventually(exported).Should(Receive(&cluster))
+		Expect(cluster.Name).Should(Equal("export-region"))
+		Expect(cluster.Spec.CIDRs).Should(Equal([]string{"10.233.0.0/16", "10.234.0.0/16"}))
+		Expect(cluster.Spec.EndPoints).Should(ConsistOf(connector, childConnector))
+	})
+})
@@ -0,0 +1,249 @@
+package routines
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/operator/apiserver"
+	operatormetrics "github.com/fabedge/fabedge/pkg/operator/metrics"
+	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
+	"github.com/fabedge/fabedge/pkg/operator/types"
+	nodeutil "github.com/fabedge/fabedge/pkg/util/node"
+)
+
+var _ manager.LeaderElectionRunnable = &HostStateMirror{}
+
+// HostStateMirror fills Store and CIDRMap with endpoints, communities and CIDRs built from
+// clusters, communities and edge nodes in informer caches. Unlike the store filled by controllers,
+// it runs in every replica of host operator, so that API server can be served by every replica
+type HostStateMirror struct {
+	Store   storepkg.Interface
+	CIDRMap *types.ClusterCIDRsMap
+	// NewEndpoint builds endpoints of edge nodes of local cluster
+	NewEndpoint types.NewEndpointFunc
+	// ExtraEndpoints are endpoints which can't be built from objects, e.g. mediator
+	ExtraEndpoints []apis.Endpoint
+	// Informers is used to get notified when objects are changed, Client should read from it
+	Informers    cache.Informers
+	Client       client.Reader
+	SyncInterval time.Duration
+	Log          logr.Logger
+
+	load func(ec apiserver.EndpointsAndCommunity)
+
+	// data from upper level host clusters, only used by intermediate clusters, see SetUpstream
+	upstreamLock     sync.Mutex
+	upstream         apiserver.EndpointsAndCommunity
+	upstreamCIDRs    map[string][]string
+	upstreamNotifier types.ChangeNotifier
+}
+
+func (m *HostStateMirror) NeedLeaderElection() bool {
+	return false
+}
+
+func (m *HostStateMirror) Start(ctx context.Context) error {
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+
+	handler := toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { notify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { notify() },
+		DeleteFunc: func(obj interface{}) { notify() },
+	}
+	for _, obj := range []client.Object{&apis.Cluster{}, &apis.Community{}, &corev1.Node{}} {
+		informer, err := m.Informers.GetInformer(ctx, obj)
+		if err != nil {
+			m.Log.Error(err, "failed to get informer")
+			return err
+		}
+		informer.AddEventHandler(handler)
+	}
+
+	// last report time of a cluster is recorded by API server of each replica,
+	// so it's deleted here instead of by cluster controller which runs in leader only
+	informer, err := m.Informers.GetInformer(ctx, &apis.Cluster{})
+	if err != nil {
+		m.Log.Error(err, "failed to get informer")
+		return err
+	}
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		DeleteFunc: deleteClusterMetrics,
+	})
+
+	tick := time.NewTicker(m.SyncInterval)
+	defer tick.Stop()
+
+	for {
+		upstreamChanged := m.upstreamNotifier.Changed()
+		m.sync(ctx)
+
+		select {
+		case <-changed:
+		case <-upstreamChanged:
+		case <-tick.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// deleteClusterMetrics deletes metrics of a deleted cluster
+func deleteClusterMetrics(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	if cluster, ok := obj.(*apis.Cluster); ok {
+		operatormetrics.ClusterLastReportTime.DeleteLabelValues(cluster.Name)
+	}
+}
+
+// SetUpstream saves endpoints, communities and CIDRs from upper level host clusters of an
+// intermediate cluster, they're merged with local data, so child clusters can get them
+func (m *HostStateMirror) SetUpstream(ea apiserver.EndpointsAndCommunity, cidrs map[string][]string) {
+	m.upstreamLock.Lock()
+	m.upstream, m.upstreamCIDRs = ea, cidrs
+	m.upstreamLock.Unlock()
+
+	m.upstreamNotifier.Notify()
+}
+
+func (m *HostStateMirror) sync(ctx context.Context) {
+	if m.load == nil {
+		m.load = newEndpointsAndCommunityLoader(m.Store)
+	}
+
+	ea, cidrs, err := m.build(ctx)
+	if err != nil {
+		m.Log.Error(err, "failed to build endpoints and communities")
+		return
+	}
+
+	m.load(ea)
+	m.CIDRMap.Replace(cidrs)
+}
+
+// build collects data in the same way as controllers do: endpoints and CIDRs of clusters come
+// from cluster specs, endpoints of local edge nodes are built from nodes, and members of communities
+// are those resolved by community controller, plus members in spec which may be not resolved yet
+func (m *HostStateMirror) build(ctx context.Context) (apiserver.EndpointsAndCommunity, map[string][]string, error) {
+	var clusters apis.ClusterList
+	if err := m.Client.List(ctx, &clusters); err != nil {
+		return apiserver.EndpointsAndCommunity{}, nil, err
+	}
+
+	var communities apis.CommunityList
+	if err := m.Client.List(ctx, &communities); err != nil {
+		return apiserver.EndpointsAndCommunity{}, nil, err
+	}
+
+	var nodes corev1.NodeList
+	if err := m.Client.List(ctx, &nodes, client.MatchingLabels(nodeutil.GetEdgeNodeLabels())); err != nil {
+		return apiserver.EndpointsAndCommunity{}, nil, err
+	}
+
+	ea := apiserver.EndpointsAndCommunity{
+		Communities: make(map[string][]string),
+		IPSec:       make(map[string]*apis.IPSecParameters),
+	}
+	cidrs := make(map[string][]string)
+
+	for _, cluster := range clusters.Items {
+		// a quarantined cluster is excluded by cluster controller because of CIDR conflicts
+		if cluster.DeletionTimestamp != nil || cluster.Status.IsQuarantined() {
+			continue
+		}
+
+		if len(cluster.Spec.CIDRs) > 0 {
+			cidrs[cluster.Name] = cluster.Spec.CIDRs
+		}
+
+		for _, endpoint := range cluster.Spec.EndPoints {
+			if len(endpoint.PublicAddresses) == 0 || len(endpoint.Subnets) == 0 || len(endpoint.NodeSubnets) == 0 {
+				continue
+			}
+
+			endpoint.IPSec = cluster.Spec.IPSec
+			ea.Endpoints = append(ea.Endpoints, endpoint)
+		}
+	}
+
+	for _, node := range nodes.Items {
+		if node.DeletionTimestamp != nil {
+			continue
+		}
+
+		endpoint := m.NewEndpoint(node)
+		if len(endpoint.Subnets) == 0 || len(endpoint.NodeSubnets) == 0 {
+			continue
+		}
+		ea.Endpoints = append(ea.Endpoints, endpoint)
+	}
+	ea.Endpoints = append(ea.Endpoints, m.ExtraEndpoints...)
+
+	for _, community := range communities.Items {
+		if community.DeletionTimestamp != nil {
+			continue
+		}
+
+		members := sets.NewString(community.Spec.Members...)
+		members.Insert(community.Status.ResolvedMembers...)
+		members.Insert(community.Status.UnresolvedMembers...)
+		ea.Communities[community.Name] = members.List()
+
+		if community.Spec.IPSec != nil {
+			ea.IPSec[community.Name] = community.Spec.IPSec
+		}
+	}
+
+	m.mergeUpstream(&ea, cidrs)
+
+	return ea, cidrs, nil
+}
+
+// mergeUpstream adds data from upper level host clusters to ea and cidrs. Local data wins if an endpoint,
+// IPSec parameters of a community or CIDRs of a cluster are provided by both, members of communities are merged
+func (m *HostStateMirror) mergeUpstream(ea *apiserver.EndpointsAndCommunity, cidrs map[string][]string) {
+	m.upstreamLock.Lock()
+	defer m.upstreamLock.Unlock()
+
+	names := sets.NewString()
+	for _, endpoint := range ea.Endpoints {
+		names.Insert(endpoint.Name)
+	}
+	for _, endpoint := range m.upstream.Endpoints {
+		if !names.Has(endpoint.Name) {
+			ea.Endpoints = append(ea.Endpoints, endpoint)
+		}
+	}
+
+	for name, members := range m.upstream.Communities {
+		ea.Communities[name] = sets.NewString(ea.Communities[name]...).Insert(members...).List()
+
+		if _, found := ea.IPSec[name]; !found && m.upstream.IPSec[name] != nil {
+			ea.IPSec[name] = m.upstream.IPSec[name]
+		}
+	}
+
+	for name, clusterCIDRs := range m.upstreamCIDRs {
+		if _, found := cidrs[name]; !found {
+			cidrs[name] = clusterCIDRs
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package routines
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2/klogr"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	operatormetrics "github.com/fabedge/fabedge/pkg/operator/metrics"
+	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
+	"github.com/fabedge/fabedge/pkg/operator/types"
+	nodeutil "github.com/fabedge/fabedge/pkg/util/node"
+)
+
+var _ = Describe("HostStateMirror", func() {
+	It("should delete last report time of a deleted cluster", func() {
+		operatormetrics.ClusterLastReportTime.WithLabelValues("mirror-deleted1").SetToCurrentTime()
+		operatormetrics.ClusterLastReportTime.WithLabelValues("mirror-deleted2").SetToCurrentTime()
+		count := promtestutil.CollectAndCount(operatormetrics.ClusterLastReportTime)
+
+		deleteClusterMetrics(&apis.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "mirror-deleted1"}})
+		Expect(promtestutil.CollectAndCount(operatormetrics.ClusterLastReportTime)).To(Equal(count - 1))
+
+		deleteClusterMetrics(toolscache.DeletedFinalStateUnknown{
+			Obj: &apis.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "mirror-deleted2"}},
+		})
+		Expect(promtestutil.CollectAndCount(operatormetrics.ClusterLastReportTime)).To(Equal(count - 2))
+	})
+
+	It("should build endpoints, communities and CIDRs from objects", func() {
+		nodeutil.SetEdgeNodeLabels(map[string]string{"node-role.kubernetes.io/edge": ""})
+		ctx := context.Background()
+
+		hostConnector := apis.Endpoint{
+			Name:            "mirror-host.connector",
+			PublicAddresses: []string{"10.10.10.10"},
+			Subnets:         []string{"10.233.0.0/18"},
+			NodeSubnets:     []string{"192.168.1.1"},
+		}
+		memberConnector := apis.Endpoint{
+			Name:            "mirror-member.connector",
+			PublicAddresses: []string{"10.10.10.20"},
+			Subnets:         []string{"10.234.0.0/18"},
+			NodeSubnets:     []string{"192.168.2.1"},
+		}
+		ipsec := &apis.IPSecParameters{IKEProposals: []string{"aes256gcm16-prfsha384-ecp384"}}
+
+		hostCluster := apis.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "mirror-host"},
+			Spec: apis.ClusterSpec{
+				CIDRs:     []string{"10.233.0.0/16"},
+				EndPoints: []apis.Endpoint{hostConnector},
+			},
+		}
+		memberCluster := apis.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "mirror-member"},
+			Spec: apis.ClusterSpec{
+				CIDRs: []string{"10.234.0.0/16"},
+				IPSec: ipsec,
+				EndPoints: []apis.Endpoint{
+					memberConnector,
+					// endpoints without subnets are not ready
+					{Name: "mirror-member.edge1", PublicAddresses: []string{"10.10.10.21"}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, &hostCluster)).Should(Succeed())
+		Expect(k8sClient.Create(ctx, &memberCluster)).Should(Succeed())
+
+		node := corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "mirror-edge1",
+				Labels: map[string]string{"node-role.kubernetes.io/edge": ""},
+			},
+		}
+		Expect(k8sClient.Create(ctx, &node)).Should(Succeed())
+		edgeEndpoint := apis.Endpoint{
+			Name:            "mirror-host.mirror-edge1",
+			PublicAddresses: []string{"10.10.10.30"},
+			Subnets:         []string{"2.2.2.0/24"},
+			NodeSubnets:     []string{"10.10.10.30"},
+		}
+
+		community := apis.Community{
+			ObjectMeta: metav1.ObjectMeta{Name: "mirror-connectors"},
+			Spec: apis.CommunitySpec{
+				Members: []string{hostConnector.Name},
+				IPSec:   ipsec,
+			},
+		}
+		Expect(k8sClient.Create(ctx, &community)).Should(Succeed())
+		// members selected by selectors are only found in status
+		community.Status.ResolvedMembers = []string{hostConnector.Name, memberConnector.Name}
+		community.Status.UnresolvedMembers = []string{"mirror-other.connector"}
+		Expect(k8sClient.Status().Update(ctx, &community)).Should(Succeed())
+
+		mediator := apis.Endpoint{Name: "fabedge-mediator", PublicAddresses: []string{"10.10.10.10"}}
+		store, cidrMap := storepkg.NewStore(), types.NewClusterCIDRsMap()
+		mirror := &HostStateMirror{
+			Store:   store,
+			CIDRMap: cidrMap,
+			NewEndpoint: func(node corev1.Node) apis.Endpoint {
+				return edgeEndpoint
+			},
+			ExtraEndpoints: []apis.Endpoint{mediator},
+			Client:         k8sClient,
+			Log:            klogr.New(),
+		}
+		mirror.sync(ctx)
+
+		memberConnector.IPSec = ipsec
+		// clusters created by other specs may be found too
+		Expect(store.GetEndpoints(store.GetAllEndpointNames().List()...)).Should(ContainElements(
+			hostConnector, memberConnector, edgeEndpoint, mediator,
+		))
+		_, found := store.GetEndpoint("mirror-member.edge1")
+		Expect(found).Should(BeFalse())
+
+		cmm, found := store.GetCommunity(community.Name)
+		Expect(found).Should(BeTrue())
+		Expect(cmm.Members.List()).Should(ConsistOf(hostConnector.Name, memberConnector.Name, "mirror-other.connector"))
+		Expect(cmm.IPSec).Should(Equal(ipsec))
+
+		Expect(cidrMap.GetCopy()).Should(HaveKeyWithValue(hostCluster.Name, hostCluster.Spec.CIDRs))
+		Expect(cidrMap.GetCopy()).Should(HaveKeyWithValue(memberCluster.Name, memberCluster.Spec.CIDRs))
+
+		By("quarantining member cluster")
+		memberCluster.Status.SetCondition(apis.ClusterCondition{
+			Type:   apis.ClusterCIDRConflict,
+			Status: corev1.ConditionTrue,
+			Reason: apis.ClusterQuarantined,
+		})
+		Expect(k8sClient.Status().Update(ctx, &memberCluster)).Should(Succeed())
+		mirror.sync(ctx)
+
+		_, found = store.GetEndpoint(memberConnector.Name)
+		Expect(found).Should(BeFalse())
+		Expect(cidrMap.GetCopy()).ShouldNot(HaveKey(memberCluster.Name))
+
+		By("deleting member cluster and community")
+		Expect(k8sClient.Delete(ctx, &memberCluster)).Should(Succeed())
+		Expect(k8sClient.Delete(ctx, &community)).Should(Succeed())
+		mirror.sync(ctx)
+
+		_, found = store.GetEndpoint(memberConnector.Name)
+		Expect(found).Should(BeFalse())
+		_, found = store.GetCommunity(community.Name)
+		Expect(found).Should(BeFalse())
+		Expect(cidrMap.GetCopy()).ShouldNot(HaveKey(memberCluster.Name))
+
+		Expect(k8sClient.Delete(ctx, &hostCluster)).Should(Succeed())
+		Expect(k8sClient.Delete(ctx, &node)).Should(Succeed())
+	})
+})
@@ -7,25 +7,45 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2/klogr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
 	"github.com/fabedge/fabedge/pkg/common/constants"
 	"github.com/fabedge/fabedge/pkg/operator/types"
 	"github.com/fabedge/fabedge/third_party/calicoapi"
 )
 
-func NewIPPoolKeeper(interval time.Duration, localClusterName string, cli client.Client, getClusterCIDRInfo types.GetClusterCIDRInfo) manager.Runnable {
-	return Periodic(interval, newIPPoolKeeperFunc(localClusterName, cli, getClusterCIDRInfo))
+// NewIPPoolKeeper keeps ippools for CIDRs of other clusters in cidrMap, ippools are
+// checked when cidrMap is changed and every interval. CIDRs which overlap with CIDRs of
+// local cluster or edgePodCIDRs are skipped, ippools of them would break local pods
+func NewIPPoolKeeper(interval time.Duration, localClusterName string, edgePodCIDRs []string, cli client.Client, recorder record.EventRecorder, cidrMap *types.ClusterCIDRsMap) manager.Runnable {
+	getClusterCIDRInfo := func() (map[string][]string, error) {
+		return cidrMap.GetCopy(), nil
+	}
+
+	return PeriodicOrChanged(interval, cidrMap.Changed, newIPPoolKeeperFunc(localClusterName, edgePodCIDRs, cli, recorder, getClusterCIDRInfo))
 }
 
-func newIPPoolKeeperFunc(localClusterName string, cli client.Client, getClusterCIDRInfo types.GetClusterCIDRInfo) func(ctx context.Context) {
+func newIPPoolKeeperFunc(localClusterName string, edgePodCIDRs []string, cli client.Client, recorder record.EventRecorder, getClusterCIDRInfo types.GetClusterCIDRInfo) func(ctx context.Context) {
 	log := klogr.New().WithName("ippool-keeper")
+	events := clusterEventRecorder{
+		localClusterName: localClusterName,
+		cli:              cli,
+		recorder:         recorder,
+		log:              log,
+	}
 
 	oldClusterSet := sets.NewString()
+	// conflicts found in the last pass, a conflict is recorded only when it's found for the first time,
+	// otherwise the same warning would be recorded in every pass until the conflict is resolved
+	oldConflictSet := sets.NewString()
 	return func(ctx context.Context) {
 		cidrsByCluster, err := getClusterCIDRInfo()
 		if err != nil {
@@ -33,15 +53,30 @@ func newIPPoolKeeperFunc(localClusterName string, cli client.Client, getClusterC
 			return
 		}
 
-		newClusterSet := sets.NewString()
+		localCIDRs := map[string][]string{
+			localClusterName: append(append([]string{}, cidrsByCluster[localClusterName]...), edgePodCIDRs...),
+		}
+
+		newClusterSet, newConflictSet := sets.NewString(), sets.NewString()
 		for name, cidrs := range cidrsByCluster {
 			if name == localClusterName {
 				continue
 			}
 			newClusterSet.Insert(name)
 
-			keepIPPoolForCluster(ctx, name, cidrs, cli, log)
+			var conflicts []types.CIDRConflict
+			cidrs, conflicts = skipConflictedCIDRs(name, cidrs, localCIDRs)
+			for _, c := range conflicts {
+				newConflictSet.Insert(c.String())
+				if !oldConflictSet.Has(c.String()) {
+					events.Eventf(ctx, name, corev1.EventTypeWarning, types.EventReasonCIDRConflict,
+						"ippool is not kept: %s", c)
+				}
+			}
+
+			keepIPPoolForCluster(ctx, name, cidrs, cli, events, log)
 		}
+		oldConflictSet = newConflictSet
 
 		noError := true
 		for clusterName := range oldClusterSet.Difference(newClusterSet) {
@@ -55,7 +90,10 @@ func newIPPoolKeeperFunc(localClusterName string, cli client.Client, getClusterC
 				if err = cli.Delete(ctx, &pool); err != nil {
 					log.Error(err, "failed to delete ippool")
 					noError = false
+					continue
 				}
+				events.Eventf(ctx, clusterName, corev1.EventTypeNormal, types.EventReasonIPPoolDeleted,
+					"ippool %s for CIDR %s is deleted", pool.Name, pool.Spec.CIDR)
 			}
 		}
 
@@ -65,7 +103,7 @@ func newIPPoolKeeperFunc(localClusterName string, cli client.Client, getClusterC
 	}
 }
 
-func keepIPPoolForCluster(ctx context.Context, clusterName string, cidrs []string, cli client.Client, log logr.Logger) {
+func keepIPPoolForCluster(ctx context.Context, clusterName string, cidrs []string, cli client.Client, events clusterEventRecorder, log logr.Logger) {
 	var pools calicoapi.IPPoolList
 	if err := cli.List(ctx, &pools, client.MatchingLabels{constants.KeyCluster: clusterName}); err != nil {
 		log.Error(err, "failed to get ippool list", "cluster", clusterName)
@@ -85,7 +123,10 @@ func keepIPPoolForCluster(ctx context.Context, clusterName string, cidrs []strin
 		pool := NewIPPool(clusterName, cidr)
 		if err := cli.Create(ctx, &pool); err != nil {
 			log.Error(err, "failed to create ippool", "cidr", cidr, "cluster", clusterName)
+			continue
 		}
+		events.Eventf(ctx, clusterName, corev1.EventTypeNormal, types.EventReasonIPPoolCreated,
+			"ippool %s for CIDR %s is created", pool.Name, cidr)
 	}
 
 	for cidr := range oldCIDRSet.Difference(newCIDRSet) {
@@ -97,8 +138,61 @@ func keepIPPoolForCluster(ctx context.Context, clusterName string, cidrs []strin
 		}
 		if err := cli.Delete(ctx, &pool); err != nil {
 			log.Error(err, "failed to delete ippool", "cidr", cidr, "cluster", clusterName)
+			continue
+		}
+		events.Eventf(ctx, clusterName, corev1.EventTypeNormal, types.EventReasonIPPoolDeleted,
+			"ippool %s for CIDR %s is deleted", poolName, cidr)
+	}
+}
+
+// skipConflictedCIDRs returns CIDRs of cluster which don't overlap with localCIDRs
+// and the conflicts of the skipped CIDRs
+func skipConflictedCIDRs(clusterName string, cidrs []string, localCIDRs map[string][]string) ([]string, []types.CIDRConflict) {
+	conflicts := types.FindCIDRConflicts(clusterName, cidrs, localCIDRs)
+	if len(conflicts) == 0 {
+		return cidrs, nil
+	}
+
+	conflictedCIDRs := sets.NewString()
+	for _, c := range conflicts {
+		conflictedCIDRs.Insert(c.CIDR)
+	}
+
+	var result []string
+	for _, cidr := range cidrs {
+		if !conflictedCIDRs.Has(cidr) {
+			result = append(result, cidr)
 		}
 	}
+
+	return result, conflicts
+}
+
+// clusterEventRecorder records events on cluster objects. Other clusters may have no
+// objects in local cluster, e.g. in a member cluster, their events are recorded on
+// the object of local cluster instead
+type clusterEventRecorder struct {
+	localClusterName string
+	cli              client.Client
+	recorder         record.EventRecorder
+	log              logr.Logger
+}
+
+func (r clusterEventRecorder) Eventf(ctx context.Context, clusterName, eventType, reason, messageFmt string, args ...interface{}) {
+	for _, name := range []string{clusterName, r.localClusterName} {
+		var cluster apis.Cluster
+		err := r.cli.Get(ctx, client.ObjectKey{Name: name}, &cluster)
+		switch {
+		case err == nil:
+			r.recorder.Eventf(&cluster, eventType, reason, messageFmt, args...)
+			return
+		case !errors.IsNotFound(err):
+			r.log.Error(err, "failed to get cluster", "cluster", name)
+			return
+		}
+	}
+
+	r.log.V(3).Info("no cluster object to record event on", "cluster", clusterName, "reason", reason)
 }
 
 func NewIPPool(clusterName, cidr string) calicoapi.IPPool {
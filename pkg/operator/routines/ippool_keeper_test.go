@@ -5,9 +5,12 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
 	"github.com/fabedge/fabedge/pkg/common/constants"
 	"github.com/fabedge/fabedge/third_party/calicoapi"
 )
@@ -27,16 +30,25 @@ var _ = Describe("IPPoolKeeper", func() {
 			clusterSuZhou:    cidrsSuZhou,
 		}
 		keepCIDRs   func(ctx context.Context)
+		recorder    *record.FakeRecorder
 		getCIDRInfo = func() (map[string][]string, error) {
 			return cidrsByCluster, nil
 		}
 	)
 
 	BeforeEach(func() {
-		keepCIDRs = newIPPoolKeeperFunc(localClusterName, k8sClient, getCIDRInfo)
+		recorder = record.NewFakeRecorder(10)
+		keepCIDRs = newIPPoolKeeperFunc(localClusterName, nil, k8sClient, recorder, getCIDRInfo)
+
+		// events of other clusters are recorded on local cluster when they have no objects here
+		localCluster := apis.Cluster{ObjectMeta: metav1.ObjectMeta{Name: localClusterName}}
+		Expect(k8sClient.Create(context.Background(), &localCluster)).To(Succeed())
 	})
 
 	AfterEach(func() {
+		localCluster := apis.Cluster{ObjectMeta: metav1.ObjectMeta{Name: localClusterName}}
+		Expect(k8sClient.Delete(context.Background(), &localCluster)).To(Succeed())
+
 		var pools calicoapi.IPPoolList
 		Expect(k8sClient.List(context.Background(), &pools)).To(Succeed())
 
@@ -57,6 +69,11 @@ var _ = Describe("IPPoolKeeper", func() {
 		// external cluster will have ip pools
 		expectPoolsFromClusterCIDRs(clusterShanghai, cidrsShanghai)
 		expectPoolsFromClusterCIDRs(clusterSuZhou, cidrsSuZhou)
+		Expect(drainEvents(recorder)).To(ConsistOf(
+			HavePrefix("Normal IPPoolCreated"),
+			HavePrefix("Normal IPPoolCreated"),
+			HavePrefix("Normal IPPoolCreated"),
+		))
 
 		By("change external cluster cidrs")
 		cidrsShanghai = []string{"2.2.0.0/16"}
@@ -66,9 +83,57 @@ var _ = Describe("IPPoolKeeper", func() {
 
 		expectPoolsFromClusterCIDRs(clusterShanghai, cidrsShanghai)
 		expectPoolsFromClusterCIDRs(clusterSuZhou, nil)
+		Expect(drainEvents(recorder)).To(ConsistOf(
+			HavePrefix("Normal IPPoolCreated"),
+			HavePrefix("Normal IPPoolDeleted"),
+			HavePrefix("Normal IPPoolDeleted"),
+			HavePrefix("Normal IPPoolDeleted"),
+		))
+	})
+
+	It("will skip CIDRs which overlap with CIDRs of local cluster or edge pod CIDRs", func() {
+		wuxiCIDRs := []string{"192.168.1.0/24", "10.60.0.0/16", "10.50.1.0/24"}
+		getCIDRInfo := func() (map[string][]string, error) {
+			return map[string][]string{
+				localClusterName: localClusterCIDRs,
+				"wuxi":           wuxiCIDRs,
+			}, nil
+		}
+		keepCIDRs := newIPPoolKeeperFunc(localClusterName, []string{"10.50.0.0/16"}, k8sClient, recorder, getCIDRInfo)
+		keepCIDRs(context.Background())
+
+		expectPoolsFromClusterCIDRs("wuxi", []string{"10.60.0.0/16"})
+		Expect(drainEvents(recorder)).To(ConsistOf(
+			"Warning CIDRConflict ippool is not kept: 10.50.1.0/24 overlaps with 10.50.0.0/16 of cluster beijing",
+			"Warning CIDRConflict ippool is not kept: 192.168.1.0/24 overlaps with 192.168.0.0/16 of cluster beijing",
+			HavePrefix("Normal IPPoolCreated"),
+		))
+
+		By("checking the same conflicts again")
+		keepCIDRs(context.Background())
+		Expect(drainEvents(recorder)).To(BeEmpty())
+
+		By("resolving a conflict and bringing it back")
+		wuxiCIDRs = []string{"10.60.0.0/16", "10.50.1.0/24"}
+		keepCIDRs(context.Background())
+		Expect(drainEvents(recorder)).To(BeEmpty())
+
+		wuxiCIDRs = []string{"192.168.1.0/24", "10.60.0.0/16", "10.50.1.0/24"}
+		keepCIDRs(context.Background())
+		Expect(drainEvents(recorder)).To(ConsistOf(
+			"Warning CIDRConflict ippool is not kept: 192.168.1.0/24 overlaps with 192.168.0.0/16 of cluster beijing",
+		))
 	})
 })
 
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	return events
+}
+
 func listIPPools(name string) (pools calicoapi.IPPoolList, err error) {
 	err = k8sClient.List(context.Background(), &pools, client.MatchingLabels{constants.KeyCluster: name})
 	return pools, err
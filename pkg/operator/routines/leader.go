@@ -0,0 +1,18 @@
+package routines
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// EveryReplica makes runnable run in every replica, not only in the leader
+func EveryReplica(runnable manager.Runnable) manager.Runnable {
+	return everyReplicaRunnable{Runnable: runnable}
+}
+
+type everyReplicaRunnable struct {
+	manager.Runnable
+}
+
+func (everyReplicaRunnable) NeedLeaderElection() bool {
+	return false
+}
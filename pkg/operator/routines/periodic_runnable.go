@@ -22,3 +22,24 @@ func Periodic(interval time.Duration, fn func(ctx context.Context)) manager.Runn
 		}
 	})
 }
+
+// PeriodicOrChanged works like Periodic, but fn is also called when the channel
+// returned by changed is closed. changed is called before each call of fn
+func PeriodicOrChanged(interval time.Duration, changed func() <-chan struct{}, fn func(ctx context.Context)) manager.Runnable {
+	return manager.RunnableFunc(func(ctx context.Context) error {
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+
+		for {
+			ch := changed()
+			fn(ctx)
+
+			select {
+			case <-tick.C:
+			case <-ch:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+}
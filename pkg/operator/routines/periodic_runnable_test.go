@@ -7,6 +7,8 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+
+	"github.com/fabedge/fabedge/pkg/operator/types"
 )
 
 var _ = Describe("PeriodicRunnable", func() {
@@ -25,4 +27,24 @@ var _ = Describe("PeriodicRunnable", func() {
 
 		Expect(counter).Should(BeNumerically(">=", 10))
 	})
+
+	It("should execute specified function when changed channel is closed", func() {
+		var notifier types.ChangeNotifier
+		counter := int32(0)
+		fn := func(ctx context.Context) {
+			atomic.AddInt32(&counter, 1)
+		}
+
+		runnable := PeriodicOrChanged(time.Hour, notifier.Changed, fn)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go runnable.Start(ctx)
+
+		Eventually(func() int32 { return atomic.LoadInt32(&counter) }).Should(Equal(int32(1)))
+
+		notifier.Notify()
+		Eventually(func() int32 { return atomic.LoadInt32(&counter) }).Should(Equal(int32(2)))
+		Consistently(func() int32 { return atomic.LoadInt32(&counter) }, 50*time.Millisecond).Should(Equal(int32(2)))
+	})
 })
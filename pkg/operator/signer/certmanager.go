@@ -0,0 +1,166 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package signer provides signers which sign certificates with a CA whose private key
+// is kept out of fabedge, their Sign methods are used as certutil.SignCertFunc
+package signer
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/fabedge/fabedge/pkg/common/constants"
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+)
+
+var certificateRequestGVK = schema.GroupVersionKind{
+	Group:   "cert-manager.io",
+	Version: "v1",
+	Kind:    "CertificateRequest",
+}
+
+const (
+	DefaultTimeout      = 30 * time.Second
+	DefaultPollInterval = time.Second
+)
+
+// IssuerRef references an issuer of cert-manager
+type IssuerRef struct {
+	Name string
+	// Kind is Issuer or ClusterIssuer, or a kind of external issuer
+	Kind  string
+	Group string
+}
+
+// CertManagerSigner signs certificates by creating CertificateRequests of cert-manager,
+// the CA private key is kept by the issuer, e.g. a Vault issuer or an external issuer.
+// A CertificateRequest is deleted after it's issued or failed
+type CertManagerSigner struct {
+	Client    client.Client
+	Namespace string
+	IssuerRef IssuerRef
+	// Duration is the validity period requested, the issuer may ignore it
+	Duration time.Duration
+	// Timeout is how long to wait for a CertificateRequest to be issued
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+func (s CertManagerSigner) Sign(csr []byte) ([]byte, error) {
+	timeout, interval := s.Timeout, s.PollInterval
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cr := s.buildCertificateRequest(csr)
+	if err := s.Client.Create(ctx, cr); err != nil {
+		return nil, fmt.Errorf("failed to create CertificateRequest: %w", err)
+	}
+	defer func() {
+		_ = s.Client.Delete(context.Background(), cr)
+	}()
+
+	var certDER []byte
+	err := wait.PollImmediateUntil(interval, func() (bool, error) {
+		if err := s.Client.Get(ctx, client.ObjectKey{Name: cr.GetName(), Namespace: cr.GetNamespace()}, cr); err != nil {
+			return false, err
+		}
+
+		var (
+			done bool
+			err  error
+		)
+		certDER, done, err = getCertificate(cr)
+		return done, err
+	}, ctx.Done())
+	if err == wait.ErrWaitTimeout {
+		return nil, fmt.Errorf("CertificateRequest %s/%s is not issued in %s", cr.GetNamespace(), cr.GetName(), timeout)
+	}
+
+	return certDER, err
+}
+
+func (s CertManagerSigner) buildCertificateRequest(csr []byte) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"request": base64.StdEncoding.EncodeToString(certutil.EncodeCertRequestPEM(csr)),
+		"issuerRef": map[string]interface{}{
+			"name":  s.IssuerRef.Name,
+			"kind":  s.IssuerRef.Kind,
+			"group": s.IssuerRef.Group,
+		},
+		// the same usages as certificates signed by local CA
+		"usages": []interface{}{"digital signature", "key encipherment", "server auth", "client auth"},
+	}
+	if s.Duration > 0 {
+		spec["duration"] = s.Duration.String()
+	}
+
+	cr := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	cr.SetGroupVersionKind(certificateRequestGVK)
+	cr.SetName(fmt.Sprintf("fabedge-%s", utilrand.String(8)))
+	cr.SetNamespace(s.Namespace)
+	cr.SetLabels(map[string]string{
+		constants.KeyCreatedBy: constants.AppOperator,
+	})
+
+	return cr
+}
+
+// getCertificate returns the certificate in DER form when CertificateRequest is issued,
+// an error is returned if the request is denied or failed
+func getCertificate(cr *unstructured.Unstructured) ([]byte, bool, error) {
+	conditions, _, _ := unstructured.NestedSlice(cr.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		conditionType, _, _ := unstructured.NestedString(condition, "type")
+		status, _, _ := unstructured.NestedString(condition, "status")
+		reason, _, _ := unstructured.NestedString(condition, "reason")
+		message, _, _ := unstructured.NestedString(condition, "message")
+
+		switch {
+		case (conditionType == "Denied" || conditionType == "InvalidRequest") && status == "True",
+			conditionType == "Ready" && status == "False" && reason == "Failed":
+			return nil, true, fmt.Errorf("CertificateRequest %s/%s is not issued: %s %s", cr.GetNamespace(), cr.GetName(), reason, message)
+		case conditionType == "Ready" && status == "True":
+			encoded, _, _ := unstructured.NestedString(cr.Object, "status", "certificate")
+			certPEM, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, true, fmt.Errorf("failed to decode certificate of CertificateRequest: %w", err)
+			}
+
+			certDER, err := certutil.DecodePEM(certPEM)
+			return certDER, true, err
+		}
+	}
+
+	return nil, false, nil
+}
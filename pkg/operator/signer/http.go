@@ -0,0 +1,73 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+)
+
+// HTTPSigner signs certificates through an HTTP service which keeps the CA private key,
+// e.g. a service in front of a PKCS#11 device or Vault. A CSR in PEM form is posted to URL
+// and a certificate in PEM form is expected in response body, if the response contains
+// a certificate chain, the first certificate is used
+type HTTPSigner struct {
+	URL    string
+	Client *http.Client
+	// Token is sent as a bearer token if it's not empty
+	Token string
+}
+
+func (s HTTPSigner) Sign(csr []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(certutil.EncodeCertRequestPEM(csr)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	cli := s.Client
+	if cli == nil {
+		cli = http.DefaultClient
+	}
+
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("signer responded with %d: %s", resp.StatusCode, content)
+	}
+
+	certs, err := certutil.DecodeCertsPEM(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return certs[0], nil
+}
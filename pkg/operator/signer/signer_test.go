@@ -0,0 +1,238 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer_test
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/fabedge/fabedge/pkg/operator/signer"
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+	timeutil "github.com/fabedge/fabedge/pkg/util/time"
+)
+
+var certificateRequestListGVK = schema.GroupVersionKind{
+	Group:   "cert-manager.io",
+	Version: "v1",
+	Kind:    "CertificateRequestList",
+}
+
+// newCA creates a cert manager which plays the role of an external CA
+func newCA() certutil.Manager {
+	caDER, keyDER, err := certutil.NewSelfSignedCA(certutil.Config{
+		CommonName:     certutil.DefaultCAName,
+		Organization:   []string{certutil.DefaultOrganization},
+		IsCA:           true,
+		ValidityPeriod: timeutil.Days(365),
+	})
+	Expect(err).Should(BeNil())
+
+	ca, err := certutil.NewManger(caDER, keyDER, timeutil.Days(365))
+	Expect(err).Should(BeNil())
+
+	return ca
+}
+
+func newCSR() []byte {
+	_, csr, err := certutil.NewCertRequest(certutil.Request{CommonName: "edge1"})
+	Expect(err).Should(BeNil())
+
+	return csr
+}
+
+var _ = Describe("CertManagerSigner", func() {
+	const namespace = "fabedge"
+
+	var (
+		ca  certutil.Manager
+		cli client.Client
+		s   signer.CertManagerSigner
+	)
+
+	// processRequests plays the role of cert-manager, it updates status of
+	// CertificateRequests with the result of fn
+	processRequests := func(fn func(cr *unstructured.Unstructured) map[string]interface{}) {
+		go func() {
+			defer GinkgoRecover()
+
+			Eventually(func() int {
+				var list unstructured.UnstructuredList
+				list.SetGroupVersionKind(certificateRequestListGVK)
+				Expect(cli.List(context.Background(), &list, client.InNamespace(namespace))).Should(Succeed())
+
+				for i := range list.Items {
+					cr := &list.Items[i]
+					cr.Object["status"] = fn(cr)
+					Expect(cli.Update(context.Background(), cr)).Should(Succeed())
+				}
+
+				return len(list.Items)
+			}, 5*time.Second, 100*time.Millisecond).Should(BeNumerically(">", 0))
+		}()
+	}
+
+	BeforeEach(func() {
+		ca = newCA()
+		cli = fake.NewClientBuilder().Build()
+		s = signer.CertManagerSigner{
+			Client:    cli,
+			Namespace: namespace,
+			IssuerRef: signer.IssuerRef{
+				Name:  "vault-issuer",
+				Kind:  "Issuer",
+				Group: "cert-manager.io",
+			},
+			Duration:     timeutil.Days(30),
+			Timeout:      5 * time.Second,
+			PollInterval: 100 * time.Millisecond,
+		}
+	})
+
+	It("should return certificate issued by cert-manager and delete CertificateRequest", func() {
+		processRequests(func(cr *unstructured.Unstructured) map[string]interface{} {
+			issuerName, _, _ := unstructured.NestedString(cr.Object, "spec", "issuerRef", "name")
+			Expect(issuerName).Should(Equal("vault-issuer"))
+			duration, _, _ := unstructured.NestedString(cr.Object, "spec", "duration")
+			Expect(duration).Should(Equal("720h0m0s"))
+
+			encoded, _, _ := unstructured.NestedString(cr.Object, "spec", "request")
+			csrPEM, err := base64.StdEncoding.DecodeString(encoded)
+			Expect(err).Should(BeNil())
+
+			csr, err := certutil.DecodePEM(csrPEM)
+			Expect(err).Should(BeNil())
+
+			certDER, err := ca.SignCert(csr)
+			Expect(err).Should(BeNil())
+
+			return map[string]interface{}{
+				"certificate": base64.StdEncoding.EncodeToString(certutil.EncodeCertPEM(certDER)),
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Approved", "status": "True"},
+					map[string]interface{}{"type": "Ready", "status": "True", "reason": "Issued"},
+				},
+			}
+		})
+
+		certDER, err := s.Sign(newCSR())
+		Expect(err).Should(BeNil())
+
+		cert, err := x509.ParseCertificate(certDER)
+		Expect(err).Should(BeNil())
+		Expect(cert.Subject.CommonName).Should(Equal("edge1"))
+		Expect(ca.VerifyCert(cert, certutil.ExtKeyUsagesServerAndClient)).Should(Succeed())
+
+		var list unstructured.UnstructuredList
+		list.SetGroupVersionKind(certificateRequestListGVK)
+		Expect(cli.List(context.Background(), &list, client.InNamespace(namespace))).Should(Succeed())
+		Expect(list.Items).Should(BeEmpty())
+	})
+
+	It("should return an error if CertificateRequest is denied", func() {
+		processRequests(func(cr *unstructured.Unstructured) map[string]interface{} {
+			return map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Denied", "status": "True", "reason": "Policy", "message": "not allowed"},
+				},
+			}
+		})
+
+		_, err := s.Sign(newCSR())
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("not allowed"))
+	})
+
+	It("should return an error if CertificateRequest is not issued in time", func() {
+		s.Timeout = 500 * time.Millisecond
+
+		_, err := s.Sign(newCSR())
+		Expect(err).Should(HaveOccurred())
+	})
+})
+
+var _ = Describe("HTTPSigner", func() {
+	var (
+		ca     certutil.Manager
+		server *httptest.Server
+		token  string
+	)
+
+	BeforeEach(func() {
+		ca = newCA()
+		token = "secret"
+
+		// a stub of external signing service
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+token {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte("invalid token"))
+				return
+			}
+
+			content, _ := ioutil.ReadAll(r.Body)
+			csr, err := certutil.DecodePEM(content)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			certDER, err := ca.SignCert(csr)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			// respond with the certificate chain
+			_, _ = w.Write(append(certutil.EncodeCertPEM(certDER), ca.GetCACertPEM()...))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should return certificate signed by external signing service", func() {
+		s := signer.HTTPSigner{URL: server.URL, Token: token}
+
+		certDER, err := s.Sign(newCSR())
+		Expect(err).Should(BeNil())
+
+		cert, err := x509.ParseCertificate(certDER)
+		Expect(err).Should(BeNil())
+		Expect(cert.IsCA).Should(BeFalse())
+		Expect(cert.Subject.CommonName).Should(Equal("edge1"))
+		Expect(ca.VerifyCert(cert, certutil.ExtKeyUsagesServerAndClient)).Should(Succeed())
+	})
+
+	It("should return an error if signing service rejects the request", func() {
+		s := signer.HTTPSigner{URL: server.URL, Token: "wrong"}
+
+		_, err := s.Sign(newCSR())
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("invalid token"))
+	})
+})
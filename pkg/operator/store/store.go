@@ -15,6 +15,7 @@
 package store
 
 import (
+	"reflect"
 	"sync"
 
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
@@ -35,6 +36,11 @@ type Interface interface {
 	GetCommunity(name string) (types.Community, bool)
 	GetCommunitiesByEndpoint(name string) []types.Community
 	DeleteCommunity(name string)
+
+	// Version returns resource version of store, it's increased when endpoints or communities are changed
+	Version() uint64
+	// Changed returns a channel which is closed when store is changed next time
+	Changed() <-chan struct{}
 }
 
 var _ Interface = &store{}
@@ -44,6 +50,7 @@ type store struct {
 	endpoints             map[string]apis.Endpoint
 	communities           map[string]types.Community
 	endpointToCommunities map[string]sets.String
+	notifier              types.ChangeNotifier
 
 	mux sync.RWMutex
 }
@@ -61,17 +68,28 @@ func (s *store) SaveEndpoint(ep apis.Endpoint) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
-	s.endpoints[ep.Name] = ep
+	s.saveEndpoint(ep)
 }
 
 func (s *store) SaveEndpointAsLocal(ep apis.Endpoint) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
-	s.endpoints[ep.Name] = ep
+	s.saveEndpoint(ep)
 	s.localNameSet.Insert(ep.Name)
 }
 
+// saveEndpoint saves ep and notifies watchers if ep is changed, endpoints are saved
+// periodically, so it's necessary to avoid notifying watchers for nothing
+func (s *store) saveEndpoint(ep apis.Endpoint) {
+	if old, ok := s.endpoints[ep.Name]; ok && reflect.DeepEqual(old, ep) {
+		return
+	}
+
+	s.endpoints[ep.Name] = ep
+	s.notifier.Notify()
+}
+
 func (s *store) GetEndpoint(name string) (apis.Endpoint, bool) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
@@ -124,6 +142,10 @@ func (s *store) DeleteEndpoint(name string) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
+	if _, ok := s.endpoints[name]; ok {
+		s.notifier.Notify()
+	}
+
 	delete(s.endpoints, name)
 	s.localNameSet.Delete(name)
 }
@@ -132,13 +154,16 @@ func (s *store) SaveCommunity(c types.Community) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
-	oldCommunity := s.communities[c.Name]
+	oldCommunity, found := s.communities[c.Name]
+	s.communities[c.Name] = c
+	if !found || !reflect.DeepEqual(oldCommunity.IPSec, c.IPSec) || !oldCommunity.Members.Equal(c.Members) {
+		s.notifier.Notify()
+	}
+
 	if oldCommunity.Members.Equal(c.Members) {
 		return
 	}
 
-	s.communities[c.Name] = c
-
 	// add new member to communities index
 	for member := range c.Members {
 		cs := s.endpointToCommunities[member]
@@ -198,8 +223,12 @@ func (s *store) DeleteCommunity(name string) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
+	cmm, found := s.communities[name]
+	if found {
+		s.notifier.Notify()
+	}
+
 	// remove this community from endpointToCommunity
-	cmm := s.communities[name]
 	for member := range cmm.Members {
 		cs := s.endpointToCommunities[member]
 		cs.Delete(name)
@@ -210,3 +239,11 @@ func (s *store) DeleteCommunity(name string) {
 
 	delete(s.communities, name)
 }
+
+func (s *store) Version() uint64 {
+	return s.notifier.Version()
+}
+
+func (s *store) Changed() <-chan struct{} {
+	return s.notifier.Changed()
+}
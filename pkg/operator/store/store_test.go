@@ -117,4 +117,41 @@ var _ = Describe("Store", func() {
 		Expect(ok).To(BeFalse())
 		Expect(c).NotTo(Equal(c1))
 	})
+
+	It("should increase version only when endpoints or communities are changed", func() {
+		e1 := apis.Endpoint{
+			ID:              "edge1",
+			Name:            "edge1",
+			PublicAddresses: []string{"10.40.20.181"},
+			Subnets:         []string{"2.2.0.0/26"},
+		}
+		c1 := types.Community{
+			Name:    "test",
+			Members: sets.NewString("edge1", "edge2"),
+		}
+
+		version, changed := store.Version(), store.Changed()
+		store.SaveEndpoint(e1)
+		Expect(store.Version()).To(BeNumerically(">", version))
+		Expect(changed).To(BeClosed())
+
+		By("saving the same endpoint and community again")
+		store.SaveCommunity(c1)
+		version, changed = store.Version(), store.Changed()
+		store.SaveEndpoint(e1)
+		store.SaveCommunity(c1)
+		store.DeleteEndpoint("unknown")
+		store.DeleteCommunity("unknown")
+		Expect(store.Version()).To(Equal(version))
+		Expect(changed).NotTo(BeClosed())
+
+		By("deleting endpoint and community")
+		store.DeleteEndpoint(e1.Name)
+		Expect(changed).To(BeClosed())
+
+		version, changed = store.Version(), store.Changed()
+		store.DeleteCommunity(c1.Name)
+		Expect(store.Version()).To(BeNumerically(">", version))
+		Expect(changed).To(BeClosed())
+	})
 })
@@ -78,6 +78,10 @@ func (argMap AgentArgumentMap) IsDNSProbeEnabled() bool {
 	return argMap.isTrue("dns-probe")
 }
 
+func (argMap AgentArgumentMap) IsTunnelStatusReportEnabled() bool {
+	return argMap.isTrue("report-tunnel-status")
+}
+
 func (argMap AgentArgumentMap) isTrue(name string) bool {
 	return argMap[name] == "true"
 }
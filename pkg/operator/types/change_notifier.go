@@ -0,0 +1,59 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import "sync"
+
+// ChangeNotifier tells watchers that data is changed. Each change increases the
+// version and closes the channel returned by Changed, so a watcher should get the
+// channel before reading data, then wait for the channel to be closed.
+// The zero value is ready to use
+type ChangeNotifier struct {
+	lock    sync.Mutex
+	version uint64
+	changed chan struct{}
+}
+
+// Notify increases version and wakes up all watchers
+func (n *ChangeNotifier) Notify() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.version++
+	if n.changed != nil {
+		close(n.changed)
+		n.changed = nil
+	}
+}
+
+// Version returns how many times data is changed
+func (n *ChangeNotifier) Version() uint64 {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return n.version
+}
+
+// Changed returns a channel which is closed when data is changed next time
+func (n *ChangeNotifier) Changed() <-chan struct{} {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.changed == nil {
+		n.changed = make(chan struct{})
+	}
+
+	return n.changed
+}
@@ -1,12 +1,21 @@
 package types
 
-import "sync"
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+
+	netutil "github.com/fabedge/fabedge/pkg/util/net"
+)
 
 type ClusterCIDRsMap struct {
 	lock        sync.RWMutex
 	cidrsByName map[string][]string
 
 	readonlyCopy map[string][]string
+	notifier     ChangeNotifier
 }
 
 func NewClusterCIDRsMap() *ClusterCIDRsMap {
@@ -19,8 +28,30 @@ func (m *ClusterCIDRsMap) Set(name string, cidrs []string) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
+	if old, found := m.cidrsByName[name]; found && reflect.DeepEqual(old, cidrs) {
+		return
+	}
+
 	m.cidrsByName[name] = cidrs
 	m.readonlyCopy = nil
+	m.notifier.Notify()
+}
+
+// Replace replaces all CIDRs with cidrsByName
+func (m *ClusterCIDRsMap) Replace(cidrsByName map[string][]string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if reflect.DeepEqual(m.cidrsByName, cidrsByName) {
+		return
+	}
+
+	m.cidrsByName = make(map[string][]string, len(cidrsByName))
+	for name, cidrs := range cidrsByName {
+		m.cidrsByName[name] = cidrs
+	}
+	m.readonlyCopy = nil
+	m.notifier.Notify()
 }
 
 func (m *ClusterCIDRsMap) Get(name string) ([]string, bool) {
@@ -41,6 +72,17 @@ func (m *ClusterCIDRsMap) Delete(name string) {
 
 	m.readonlyCopy = nil
 	delete(m.cidrsByName, name)
+	m.notifier.Notify()
+}
+
+// Version returns resource version of CIDRs, it's increased when CIDRs are changed
+func (m *ClusterCIDRsMap) Version() uint64 {
+	return m.notifier.Version()
+}
+
+// Changed returns a channel which is closed when CIDRs are changed next time
+func (m *ClusterCIDRsMap) Changed() <-chan struct{} {
+	return m.notifier.Changed()
 }
 
 // GetCopy return a copy of inner data, the returned data should not be changed
@@ -68,3 +110,54 @@ func (m *ClusterCIDRsMap) GetCopy() map[string][]string {
 
 	return cp
 }
+
+// CIDRConflict means CIDR of a cluster overlaps with OtherCIDR of OtherCluster
+type CIDRConflict struct {
+	CIDR         string
+	OtherCIDR    string
+	OtherCluster string
+}
+
+func (c CIDRConflict) String() string {
+	return fmt.Sprintf("%s overlaps with %s of cluster %s", c.CIDR, c.OtherCIDR, c.OtherCluster)
+}
+
+// FindCIDRConflicts returns conflicts between cidrs of cluster clusterName and CIDRs of other
+// clusters in cidrsByName, invalid CIDRs are ignored. The result is sorted, so messages made
+// from it are stable
+func FindCIDRConflicts(clusterName string, cidrs []string, cidrsByName map[string][]string) []CIDRConflict {
+	var conflicts []CIDRConflict
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+
+		for otherCluster, otherCIDRs := range cidrsByName {
+			if otherCluster == clusterName {
+				continue
+			}
+
+			for _, otherCIDR := range otherCIDRs {
+				_, otherIPNet, err := net.ParseCIDR(otherCIDR)
+				if err != nil {
+					continue
+				}
+
+				if netutil.IsCIDROverlapped(ipNet, otherIPNet) {
+					conflicts = append(conflicts, CIDRConflict{
+						CIDR:         cidr,
+						OtherCIDR:    otherCIDR,
+						OtherCluster: otherCluster,
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].String() < conflicts[j].String()
+	})
+
+	return conflicts
+}
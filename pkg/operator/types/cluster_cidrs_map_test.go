@@ -41,4 +41,48 @@ var _ = Describe("ClusterCIDRsMap", func() {
 		cp3 := cidrMap.GetCopy()
 		Expect(cp2).To(Equal(cp3))
 	})
+
+	It("should increase version and close changed channel only when data is changed", func() {
+		cidrMap := types.NewClusterCIDRsMap()
+		version, changed := cidrMap.Version(), cidrMap.Changed()
+
+		cidrMap.Set("beijing", []string{"192.168.0.0/18"})
+		Expect(cidrMap.Version()).To(BeNumerically(">", version))
+		Expect(changed).To(BeClosed())
+
+		version, changed = cidrMap.Version(), cidrMap.Changed()
+		cidrMap.Set("beijing", []string{"192.168.0.0/18"})
+		cidrMap.Replace(map[string][]string{"beijing": {"192.168.0.0/18"}})
+		Expect(cidrMap.Version()).To(Equal(version))
+		Expect(changed).NotTo(BeClosed())
+
+		cidrMap.Replace(map[string][]string{"shanghai": {"10.10.0.0/18"}})
+		Expect(cidrMap.Version()).To(BeNumerically(">", version))
+		Expect(changed).To(BeClosed())
+		Expect(cidrMap.GetCopy()).To(Equal(map[string][]string{"shanghai": {"10.10.0.0/18"}}))
+	})
+})
+
+var _ = Describe("FindCIDRConflicts", func() {
+	It("should return sorted conflicts with CIDRs of other clusters", func() {
+		conflicts := types.FindCIDRConflicts("beijing", []string{"10.10.0.0/16", "192.168.0.0/18", "invalid"}, map[string][]string{
+			"beijing":  {"192.168.0.0/18"},
+			"shanghai": {"192.168.0.0/16", "10.20.0.0/16"},
+			"suzhou":   {"10.10.1.0/24", "fd96:ee88:2::/48"},
+		})
+
+		Expect(conflicts).To(Equal([]types.CIDRConflict{
+			{CIDR: "10.10.0.0/16", OtherCIDR: "10.10.1.0/24", OtherCluster: "suzhou"},
+			{CIDR: "192.168.0.0/18", OtherCIDR: "192.168.0.0/16", OtherCluster: "shanghai"},
+		}))
+		Expect(conflicts[0].String()).To(Equal("10.10.0.0/16 overlaps with 10.10.1.0/24 of cluster suzhou"))
+	})
+
+	It("should return nothing if there is no conflict", func() {
+		conflicts := types.FindCIDRConflicts("beijing", []string{"10.10.0.0/16"}, map[string][]string{
+			"beijing":  {"10.10.0.0/16"},
+			"shanghai": {"10.20.0.0/16"},
+		})
+		Expect(conflicts).To(BeEmpty())
+	})
 })
@@ -15,10 +15,64 @@
 package types
 
 import (
+	"sort"
+
 	"k8s.io/apimachinery/pkg/util/sets"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
 )
 
 type Community struct {
 	Name    string
 	Members sets.String
+	IPSec   *apis.IPSecParameters
+}
+
+// FindIPSecParameters returns IPSec parameters of the first community which has
+// both endpoints as members, communities are checked in name order so that both
+// sides of a tunnel get the same parameters
+func FindIPSecParameters(communities []Community, name1, name2 string) *apis.IPSecParameters {
+	sorted := make([]Community, len(communities))
+	copy(sorted, communities)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	for _, community := range sorted {
+		if community.IPSec == nil {
+			continue
+		}
+
+		if community.Members.Has(name1) && community.Members.Has(name2) {
+			return community.IPSec
+		}
+	}
+
+	return nil
+}
+
+// ApplyCommunityIPSecParameters sets IPSec parameters of each peer with those
+// found in communities of endpoint, peers not found keep their own parameters
+func ApplyCommunityIPSecParameters(communities []Community, endpointName string, peers []apis.Endpoint) {
+	for i := range peers {
+		if params := FindIPSecParameters(communities, endpointName, peers[i].Name); params != nil {
+			peers[i].IPSec = params
+		}
+	}
+}
+
+// ResolveClusterIPSecParameters returns IPSec parameters of tunnels between endpoints of two clusters.
+// If only one cluster has parameters, they are used. If both clusters have parameters, those of
+// the cluster whose name is smaller are used, so both sides of a tunnel get the same parameters
+func ResolveClusterIPSecParameters(cluster1 string, params1 *apis.IPSecParameters, cluster2 string, params2 *apis.IPSecParameters) *apis.IPSecParameters {
+	switch {
+	case params1 == nil:
+		return params2
+	case params2 == nil:
+		return params1
+	case cluster1 < cluster2:
+		return params1
+	default:
+		return params2
+	}
 }
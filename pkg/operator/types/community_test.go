@@ -0,0 +1,49 @@
+package types_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/operator/types"
+)
+
+var _ = Describe("Community", func() {
+	gcm := &apis.IPSecParameters{ESPProposals: []string{"aes256gcm16-ecp384"}}
+	cbc := &apis.IPSecParameters{ESPProposals: []string{"aes256-sha256-modp2048"}}
+
+	communities := []types.Community{
+		{Name: "c", Members: sets.NewString("edge1", "edge2", "edge3"), IPSec: cbc},
+		{Name: "b", Members: sets.NewString("edge1", "edge2"), IPSec: gcm},
+		{Name: "a", Members: sets.NewString("edge1", "edge2")},
+	}
+
+	It("FindIPSecParameters returns parameters of first community which has both endpoints in name order", func() {
+		Expect(types.FindIPSecParameters(communities, "edge1", "edge2")).To(Equal(gcm))
+		Expect(types.FindIPSecParameters(communities, "edge2", "edge3")).To(Equal(cbc))
+		Expect(types.FindIPSecParameters(communities, "edge1", "edge4")).To(BeNil())
+	})
+
+	It("ApplyCommunityIPSecParameters only overwrites parameters of peers found in communities", func() {
+		clusterParams := &apis.IPSecParameters{IKEProposals: []string{"aes256-sha256-modp2048"}}
+		peers := []apis.Endpoint{
+			{Name: "edge2"},
+			{Name: "edge4", IPSec: clusterParams},
+		}
+
+		types.ApplyCommunityIPSecParameters(communities, "edge1", peers)
+		Expect(peers[0].IPSec).To(Equal(gcm))
+		Expect(peers[1].IPSec).To(Equal(clusterParams))
+	})
+
+	It("ResolveClusterIPSecParameters returns the same parameters for both sides of a tunnel", func() {
+		Expect(types.ResolveClusterIPSecParameters("beijing", nil, "shanghai", nil)).To(BeNil())
+
+		Expect(types.ResolveClusterIPSecParameters("beijing", gcm, "shanghai", nil)).To(Equal(gcm))
+		Expect(types.ResolveClusterIPSecParameters("shanghai", nil, "beijing", gcm)).To(Equal(gcm))
+
+		Expect(types.ResolveClusterIPSecParameters("beijing", gcm, "shanghai", cbc)).To(Equal(gcm))
+		Expect(types.ResolveClusterIPSecParameters("shanghai", cbc, "beijing", gcm)).To(Equal(gcm))
+	})
+})
@@ -0,0 +1,36 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+// EventSource is the component name of events emitted by fabedge-operator
+const EventSource = "fabedge-operator"
+
+// reasons of events emitted by fabedge-operator
+const (
+	EventReasonAgentPodRebuilt     = "AgentPodRebuilt"
+	EventReasonCertRenewed         = "CertificateRenewed"
+	EventReasonSubnetPoolExhausted = "SubnetPoolExhausted"
+	EventReasonPodCIDRReclaimed    = "PodCIDRReclaimed"
+	EventReasonIPPoolCreated       = "IPPoolCreated"
+	EventReasonIPPoolDeleted       = "IPPoolDeleted"
+	EventReasonCARotationStarted   = "CARotationStarted"
+	EventReasonCASwitched          = "CASwitched"
+	EventReasonCARotationFinished  = "CARotationFinished"
+	EventReasonCertRevoked         = "CertificateRevoked"
+	EventReasonCertNotRevoked      = "CertificateNotRevoked"
+	EventReasonCIDRConflict        = "CIDRConflict"
+	// EventReasonTunnelBackendNotSupported means the tunnel backend asked by an edge node can't be used
+	EventReasonTunnelBackendNotSupported = "TunnelBackendNotSupported"
+)
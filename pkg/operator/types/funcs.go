@@ -22,6 +22,7 @@ import (
 
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
 	"github.com/fabedge/fabedge/pkg/common/constants"
+	"github.com/fabedge/fabedge/pkg/tunnel"
 )
 
 type GetIDFunc func(nodeName string) string
@@ -64,12 +65,45 @@ func NewEndpointFuncs(namePrefix, idFormat string, getPodCIDRs PodCIDRsGetter) (
 			Subnets:         getPodCIDRs(node),
 			NodeSubnets:     nodeSubnets,
 			Type:            apis.EdgeNode,
+			PublicKey:       node.Annotations[constants.KeyWireGuardPublicKey],
+			TunnelBackend:   node.Annotations[constants.KeyTunnelBackend],
+			PSKSecretName:   node.Annotations[constants.KeyPSKSecretName],
 		}
 	}
 
 	return getName, getID, newEndpoint
 }
 
+// GetTunnelBackend returns the tunnel backend of an edge node. The annotation "fabedge.io/tunnel-backend"
+// of node is checked first, then the tunnel-backend argument of agent from node's annotations
+// and the one from argMap, strongswan is used if none of them is specified
+func GetTunnelBackend(node corev1.Node, argMap AgentArgumentMap) string {
+	if backend := node.Annotations[constants.KeyTunnelBackend]; backend != "" {
+		return backend
+	}
+
+	if backend := node.Annotations["argument.fabedge.io/tunnel-backend"]; backend != "" {
+		return backend
+	}
+
+	if backend := argMap.Get("tunnel-backend"); backend != "" {
+		return backend
+	}
+
+	return tunnel.BackendStrongSwan
+}
+
+// ResolveTunnelBackend returns the tunnel backend which is actually used by an edge node. Wireguard
+// has no mediation, so strongswan is used instead if mediation is enabled, e.g. connector is mediator
+func ResolveTunnelBackend(node corev1.Node, argMap AgentArgumentMap, mediationEnabled bool) string {
+	backend := GetTunnelBackend(node, argMap)
+	if mediationEnabled && backend == tunnel.BackendWireGuard {
+		return tunnel.BackendStrongSwan
+	}
+
+	return backend
+}
+
 func getPublicAddressesFromAnnotations(node corev1.Node) []string {
 	if len(node.Annotations) == 0 {
 		return nil
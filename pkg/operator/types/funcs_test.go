@@ -92,4 +92,70 @@ var _ = Describe("EndpointFuncs", func() {
 
 		Expect(endpoint.PublicAddresses).Should(ConsistOf("www.example.com", "10.0.0.1"))
 	})
+
+	It("should read wireguard public key from annotation if it exists", func() {
+		node.Annotations = map[string]string{
+			constants.KeyPodSubnets:         "2.2.0.1/26,2.2.0.128/26",
+			constants.KeyWireGuardPublicKey: "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg=",
+		}
+
+		endpoint = newEndpoint(node)
+
+		Expect(endpoint.PublicKey).Should(Equal("xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="))
+	})
+
+	It("should read tunnel backend from annotation if it exists", func() {
+		node.Annotations = map[string]string{
+			constants.KeyPodSubnets:    "2.2.0.1/26,2.2.0.128/26",
+			constants.KeyTunnelBackend: "wireguard",
+		}
+
+		endpoint = newEndpoint(node)
+
+		Expect(endpoint.TunnelBackend).Should(Equal("wireguard"))
+	})
+})
+
+var _ = Describe("GetTunnelBackend", func() {
+	It("should return strongswan if tunnel backend is not specified", func() {
+		Expect(types.GetTunnelBackend(corev1.Node{}, types.NewAgentArgumentMap())).To(Equal("strongswan"))
+	})
+
+	It("should return tunnel-backend argument if node has no annotations about it", func() {
+		argMap := types.AgentArgumentMap{"tunnel-backend": "wireguard"}
+		Expect(types.GetTunnelBackend(corev1.Node{}, argMap)).To(Equal("wireguard"))
+	})
+
+	It("should prefer annotations of node", func() {
+		argMap := types.AgentArgumentMap{"tunnel-backend": "strongswan"}
+		node := corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					"argument.fabedge.io/tunnel-backend": "wireguard",
+				},
+			},
+		}
+		Expect(types.GetTunnelBackend(node, argMap)).To(Equal("wireguard"))
+
+		node.Annotations[constants.KeyTunnelBackend] = "strongswan"
+		Expect(types.GetTunnelBackend(node, argMap)).To(Equal("strongswan"))
+	})
+})
+
+var _ = Describe("ResolveTunnelBackend", func() {
+	node := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				constants.KeyTunnelBackend: "wireguard",
+			},
+		},
+	}
+
+	It("should return the tunnel backend of node if mediation is not enabled", func() {
+		Expect(types.ResolveTunnelBackend(node, types.NewAgentArgumentMap(), false)).To(Equal("wireguard"))
+	})
+
+	It("should return strongswan instead of wireguard if mediation is enabled", func() {
+		Expect(types.ResolveTunnelBackend(node, types.NewAgentArgumentMap(), true)).To(Equal("strongswan"))
+	})
 })
@@ -0,0 +1,78 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
+)
+
+// GetPSKSecretName returns the name of secret which contains the pre-shared key
+// of tunnel between local and peer. If both endpoints have one, the secret of
+// the endpoint whose name is smaller is chosen so that both sides get the same key
+func GetPSKSecretName(local, peer apis.Endpoint) string {
+	switch {
+	case local.PSKSecretName == "":
+		return peer.PSKSecretName
+	case peer.PSKSecretName == "":
+		return local.PSKSecretName
+	case local.Name < peer.Name:
+		return local.PSKSecretName
+	default:
+		return peer.PSKSecretName
+	}
+}
+
+// BuildPSKSecretData collects pre-shared keys of tunnels between local and peers,
+// the keys of result are peer names. Peers whose secrets are not available are
+// skipped and the reasons are returned as an aggregated error
+func BuildPSKSecretData(ctx context.Context, cli client.Reader, namespace string, local apis.Endpoint, peers []apis.Endpoint) (map[string][]byte, error) {
+	data := make(map[string][]byte)
+	secrets := make(map[string]corev1.Secret)
+
+	var errs []error
+	for _, peer := range peers {
+		name := GetPSKSecretName(local, peer)
+		if name == "" {
+			continue
+		}
+
+		secret, found := secrets[name]
+		if !found {
+			if err := cli.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, &secret); err != nil {
+				errs = append(errs, fmt.Errorf("failed to get psk secret %s for peer %s: %w", name, peer.Name, err))
+				continue
+			}
+			secrets[name] = secret
+		}
+
+		psk := secretutil.GetPSK(secret)
+		if len(psk) == 0 {
+			errs = append(errs, fmt.Errorf("psk secret %s for peer %s has no psk", name, peer.Name))
+			continue
+		}
+
+		data[peer.Name] = psk
+	}
+
+	return data, utilerrors.NewAggregate(errs)
+}
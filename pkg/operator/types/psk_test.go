@@ -0,0 +1,59 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/operator/types"
+	secretutil "github.com/fabedge/fabedge/pkg/util/secret"
+)
+
+var _ = Describe("PSK", func() {
+	connector := apis.Endpoint{Name: "cluster.connector", PSKSecretName: "connector-psk"}
+	edge1 := apis.Endpoint{Name: "cluster.edge1", PSKSecretName: "edge1-psk"}
+	edge2 := apis.Endpoint{Name: "cluster.edge2"}
+	edge3 := apis.Endpoint{Name: "cluster.edge3", PSKSecretName: "edge3-psk"}
+
+	It("should return the same secret name for both sides of a tunnel", func() {
+		Expect(types.GetPSKSecretName(connector, edge1)).To(Equal("connector-psk"))
+		Expect(types.GetPSKSecretName(edge1, connector)).To(Equal("connector-psk"))
+
+		Expect(types.GetPSKSecretName(edge1, edge2)).To(Equal("edge1-psk"))
+		Expect(types.GetPSKSecretName(edge2, edge1)).To(Equal("edge1-psk"))
+
+		Expect(types.GetPSKSecretName(edge2, apis.Endpoint{Name: "cluster.edge4"})).To(BeEmpty())
+	})
+
+	It("should collect psk of peers and skip those not available", func() {
+		cli := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "edge1-psk", Namespace: "fabedge"},
+			Data:       map[string][]byte{secretutil.KeyPSK: []byte("secret1")},
+		}).Build()
+
+		data, err := types.BuildPSKSecretData(context.TODO(), cli, "fabedge", edge2, []apis.Endpoint{edge1, edge3, {Name: "cluster.edge4"}})
+		Expect(err).To(HaveOccurred())
+		Expect(data).To(Equal(map[string][]byte{
+			"cluster.edge1": []byte("secret1"),
+		}))
+	})
+})
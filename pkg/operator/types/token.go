@@ -0,0 +1,59 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ClusterTokenClaims are claims of tokens used by member clusters to join host cluster,
+// the subject is the name of member cluster
+type ClusterTokenClaims struct {
+	jwt.RegisteredClaims
+	// SingleUse means the token can be used only once, API server tracks used tokens by their IDs
+	SingleUse bool `json:"singleUse,omitempty"`
+}
+
+// SigningMethodOf returns the signing method of tokens signed or verified with key,
+// key can be a private key or a public key of RSA, ECDSA or Ed25519
+func SigningMethodOf(key interface{}) (jwt.SigningMethod, error) {
+	if signer, ok := key.(crypto.Signer); ok {
+		key = signer.Public()
+	}
+
+	switch publicKey := key.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch publicKey.Curve.Params().BitSize {
+		case 256:
+			return jwt.SigningMethodES256, nil
+		case 384:
+			return jwt.SigningMethodES384, nil
+		case 521:
+			return jwt.SigningMethodES512, nil
+		}
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type: %T", key)
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/fabedge/fabedge/pkg/operator/types"
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+)
+
+var _ = Describe("SigningMethodOf", func() {
+	table.DescribeTable("should return the signing method matching key algorithm",
+		func(algorithm certutil.KeyAlgorithm, expected jwt.SigningMethod) {
+			key, err := certutil.GenerateKey(algorithm, 2048)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			method, err := types.SigningMethodOf(key)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(method).To(Equal(expected))
+
+			method, err = types.SigningMethodOf(key.Public())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(method).To(Equal(expected))
+
+			token, err := jwt.NewWithClaims(method, jwt.RegisteredClaims{Subject: "test"}).SignedString(key)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+				return key.Public(), nil
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+		},
+		table.Entry("RSA", certutil.KeyAlgorithmRSA, jwt.SigningMethodRS256),
+		table.Entry("ECDSA", certutil.KeyAlgorithmECDSA, jwt.SigningMethodES256),
+		table.Entry("Ed25519", certutil.KeyAlgorithmEd25519, jwt.SigningMethodEdDSA),
+	)
+
+	It("should choose ECDSA signing method by curve", func() {
+		key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		Expect(types.SigningMethodOf(key)).To(Equal(jwt.SigningMethodES384))
+	})
+
+	It("should return error for unsupported keys", func() {
+		_, err := types.SigningMethodOf([]byte("secret"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,200 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/operator/types"
+	netutil "github.com/fabedge/fabedge/pkg/util/net"
+)
+
+var _ admission.Handler = &clusterMutator{}
+var _ admission.DecoderInjector = &clusterMutator{}
+var _ admission.Handler = &clusterValidator{}
+var _ admission.DecoderInjector = &clusterValidator{}
+
+// clusterMutator normalizes CIDRs and subnets of cluster
+type clusterMutator struct {
+	decoder *admission.Decoder
+	log     logr.Logger
+}
+
+func (m *clusterMutator) InjectDecoder(decoder *admission.Decoder) error {
+	m.decoder = decoder
+	return nil
+}
+
+func (m *clusterMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var cluster apis.Cluster
+	if err := m.decoder.Decode(req, &cluster); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	cluster.Spec.CIDRs = normalizeCIDRs(cluster.Spec.CIDRs)
+	for i := range cluster.Spec.EndPoints {
+		endpoint := &cluster.Spec.EndPoints[i]
+		endpoint.Subnets = normalizeCIDRs(endpoint.Subnets)
+		endpoint.NodeSubnets = normalizeCIDRs(endpoint.NodeSubnets)
+	}
+
+	data, err := json.Marshal(&cluster)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	return admission.PatchResponseFromRaw(req.Object.Raw, data)
+}
+
+// clusterValidator checks CIDRs and endpoints of cluster
+type clusterValidator struct {
+	client       client.Client
+	cidrMap      *types.ClusterCIDRsMap
+	edgePodCIDRs []string
+	decoder      *admission.Decoder
+	log          logr.Logger
+}
+
+func (v *clusterValidator) InjectDecoder(decoder *admission.Decoder) error {
+	v.decoder = decoder
+	return nil
+}
+
+func (v *clusterValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var cluster, oldCluster apis.Cluster
+	if err := v.decoder.Decode(req, &cluster); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if req.Operation == admissionv1.Update {
+		if err := v.decoder.DecodeRaw(req.OldObject, &oldCluster); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+
+	allErrs, err := v.validate(ctx, &cluster, &oldCluster)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if len(allErrs) > 0 {
+		logDenied(v.log, cluster.Name, allErrs)
+		return admission.Denied(allErrs.ToAggregate().Error())
+	}
+
+	return admission.Allowed("")
+}
+
+// validate checks cluster, oldCluster is empty unless it's an update request.
+// Only CIDRs added by update are checked for overlapping, otherwise a cluster which
+// has conflicts with others could not be updated anymore, e.g. refreshing its token
+func (v *clusterValidator) validate(ctx context.Context, cluster, oldCluster *apis.Cluster) (field.ErrorList, error) {
+	specPath := field.NewPath("spec")
+	cidrsPath := specPath.Child("cidrs")
+
+	allErrs := validateCIDRs(cluster.Spec.CIDRs, cidrsPath)
+	if len(allErrs) == 0 {
+		oldCIDRs := sets.NewString(oldCluster.Spec.CIDRs...)
+		for i, cidr := range cluster.Spec.CIDRs {
+			if oldCIDRs.Has(cidr) {
+				continue
+			}
+
+			if msg := v.findCIDRConflict(cluster.Name, cidr); msg != "" {
+				allErrs = append(allErrs, field.Invalid(cidrsPath.Index(i), cidr, msg))
+			}
+		}
+	}
+
+	otherEndpointNames, err := v.getEndpointNamesOfOtherClusters(ctx, cluster.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	endpointsPath := specPath.Child("endpoints")
+	names := sets.NewString()
+	for i, endpoint := range cluster.Spec.EndPoints {
+		fldPath := endpointsPath.Index(i)
+		allErrs = append(allErrs, validateCIDRs(endpoint.Subnets, fldPath.Child("subnets"))...)
+		allErrs = append(allErrs, validateNodeSubnets(endpoint.NodeSubnets, fldPath.Child("nodeSubnets"))...)
+
+		namePath := fldPath.Child("name")
+		switch {
+		case endpoint.Name == "":
+			allErrs = append(allErrs, field.Required(namePath, ""))
+		case names.Has(endpoint.Name), otherEndpointNames.Has(endpoint.Name):
+			allErrs = append(allErrs, field.Duplicate(namePath, endpoint.Name))
+		}
+		names.Insert(endpoint.Name)
+	}
+
+	allErrs = append(allErrs, validateIPSecParameters(cluster.Spec.IPSec, specPath.Child("ipsec"))...)
+
+	return allErrs, nil
+}
+
+// findCIDRConflict returns a message about which cluster's CIDR or which edge pod CIDR
+// overlaps with the given CIDR, an empty string means there is no conflict
+func (v *clusterValidator) findCIDRConflict(clusterName, cidr string) string {
+	if conflicts := types.FindCIDRConflicts(clusterName, []string{cidr}, v.cidrMap.GetCopy()); len(conflicts) > 0 {
+		return fmt.Sprintf("overlaps with %s of cluster %s", conflicts[0].OtherCIDR, conflicts[0].OtherCluster)
+	}
+
+	_, ipNet, _ := net.ParseCIDR(cidr)
+	for _, edgePodCIDR := range v.edgePodCIDRs {
+		_, edgePodIPNet, err := net.ParseCIDR(edgePodCIDR)
+		if err != nil {
+			continue
+		}
+
+		if netutil.IsCIDROverlapped(ipNet, edgePodIPNet) {
+			return fmt.Sprintf("overlaps with edge pod CIDR %s", edgePodCIDR)
+		}
+	}
+
+	return ""
+}
+
+func (v *clusterValidator) getEndpointNamesOfOtherClusters(ctx context.Context, clusterName string) (sets.String, error) {
+	var clusters apis.ClusterList
+	if err := v.client.List(ctx, &clusters); err != nil {
+		return nil, err
+	}
+
+	names := sets.NewString()
+	for _, cluster := range clusters.Items {
+		if cluster.Name == clusterName {
+			continue
+		}
+
+		for _, endpoint := range cluster.Spec.EndPoints {
+			names.Insert(endpoint.Name)
+		}
+	}
+
+	return names, nil
+}
@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gomodules.xyz/jsonpatch/v2"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/klogr"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	"github.com/fabedge/fabedge/pkg/operator/types"
+)
+
+var _ = Describe("ClusterMutator", func() {
+	var mutator *clusterMutator
+
+	BeforeEach(func() {
+		mutator = &clusterMutator{log: klogr.New()}
+		Expect(mutator.InjectDecoder(decoder)).To(Succeed())
+	})
+
+	It("should normalize CIDRs and subnets of endpoints", func() {
+		cluster := newCluster("beijing", []string{"fd96:ee88:0:1::0/116"})
+		cluster.Spec.EndPoints = []apis.Endpoint{
+			{
+				Name:        "beijing.connector",
+				Subnets:     []string{"fd96:ee88:0:2::0/64"},
+				NodeSubnets: []string{"10.10.0.1/32"},
+			},
+		}
+
+		resp := mutator.Handle(context.TODO(), newRequest(admissionv1.Create, &cluster, nil))
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Patches).To(ConsistOf(
+			jsonpatch.JsonPatchOperation{Operation: "replace", Path: "/spec/cidrs/0", Value: "fd96:ee88:0:1::/116"},
+			jsonpatch.JsonPatchOperation{Operation: "replace", Path: "/spec/endpoints/0/subnets/0", Value: "fd96:ee88:0:2::/64"},
+		))
+	})
+
+	It("should keep invalid CIDRs as they are", func() {
+		cluster := newCluster("beijing", []string{"10.10.0.0/33"})
+
+		resp := mutator.Handle(context.TODO(), newRequest(admissionv1.Create, &cluster, nil))
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Patches).To(BeEmpty())
+	})
+})
+
+var _ = Describe("ClusterValidator", func() {
+	var (
+		validator *clusterValidator
+		cidrMap   *types.ClusterCIDRsMap
+	)
+
+	BeforeEach(func() {
+		cidrMap = types.NewClusterCIDRsMap()
+		cidrMap.Set("shanghai", []string{"10.20.0.0/16"})
+		cidrMap.Set("beijing", []string{"10.40.0.0/16"})
+
+		validator = &clusterValidator{
+			client:       k8sClient,
+			cidrMap:      cidrMap,
+			edgePodCIDRs: []string{"2.2.0.0/16"},
+			log:          klogr.New(),
+		}
+		Expect(validator.InjectDecoder(decoder)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.DeleteAllOf(context.Background(), &apis.Cluster{})).To(Succeed())
+	})
+
+	It("should allow cluster with valid CIDRs and endpoints", func() {
+		cluster := newCluster("beijing", []string{"10.40.0.0/16"})
+		cluster.Spec.EndPoints = []apis.Endpoint{
+			{Name: "beijing.connector", Subnets: []string{"10.40.0.0/18"}},
+			{Name: "beijing.edge1", Subnets: []string{"10.41.0.0/24"}},
+		}
+
+		resp := validator.Handle(context.TODO(), newRequest(admissionv1.Create, &cluster, nil))
+		Expect(resp.Allowed).To(BeTrue())
+	})
+
+	It("should allow node subnets which are IPs or CIDRs", func() {
+		cluster := newCluster("beijing", []string{"10.40.0.0/16"})
+		cluster.Spec.EndPoints = []apis.Endpoint{
+			{Name: "beijing.connector", Subnets: []string{"10.40.0.0/18"}, NodeSubnets: []string{"192.168.1.1", "192.168.2.0/24", "fd00::1"}},
+		}
+
+		resp := validator.Handle(context.TODO(), newRequest(admissionv1.Create, &cluster, nil))
+		Expect(resp.Allowed).To(BeTrue())
+	})
+
+	It("should reject invalid CIDRs and subnets", func() {
+		cluster := newCluster("beijing", []string{"10.40.0.0/33"})
+		cluster.Spec.EndPoints = []apis.Endpoint{
+			{Name: "beijing.connector", Subnets: []string{"abc"}, NodeSubnets: []string{"10.40.0.1/33"}},
+		}
+
+		resp := validator.Handle(context.TODO(), newRequest(admissionv1.Create, &cluster, nil))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(string(resp.Result.Reason)).To(ContainSubstring("spec.cidrs[0]"))
+		Expect(string(resp.Result.Reason)).To(ContainSubstring("spec.endpoints[0].subnets[0]"))
+		Expect(string(resp.Result.Reason)).To(ContainSubstring("spec.endpoints[0].nodeSubnets[0]"))
+	})
+
+	It("should reject CIDRs which overlap with CIDRs of other clusters", func() {
+		cluster := newCluster("beijing", []string{"10.20.1.0/24"})
+
+		resp := validator.Handle(context.TODO(), newRequest(admissionv1.Create, &cluster, nil))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(string(resp.Result.Reason)).To(ContainSubstring("overlaps with 10.20.0.0/16 of cluster shanghai"))
+	})
+
+	It("should reject CIDRs which overlap with edge pod CIDRs", func() {
+		cluster := newCluster("beijing", []string{"2.2.1.0/24"})
+
+		resp := validator.Handle(context.TODO(), newRequest(admissionv1.Create, &cluster, nil))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(string(resp.Result.Reason)).To(ContainSubstring("overlaps with edge pod CIDR 2.2.0.0/16"))
+	})
+
+	It("should not check overlapping of CIDRs which are not changed by update", func() {
+		oldCluster := newCluster("beijing", []string{"10.20.1.0/24"})
+		cluster := newCluster("beijing", []string{"10.20.1.0/24"})
+		cluster.Spec.Token = "new-token"
+
+		resp := validator.Handle(context.TODO(), newRequest(admissionv1.Update, &cluster, &oldCluster))
+		Expect(resp.Allowed).To(BeTrue())
+	})
+
+	It("should reject duplicate endpoint names", func() {
+		other := newCluster("shanghai", nil)
+		other.Spec.EndPoints = []apis.Endpoint{
+			{Name: "shanghai.connector"},
+		}
+		Expect(k8sClient.Create(context.Background(), &other)).To(Succeed())
+
+		cluster := newCluster("beijing", nil)
+		cluster.Spec.EndPoints = []apis.Endpoint{
+			{Name: "beijing.connector"},
+			{Name: "beijing.connector"},
+			{Name: "shanghai.connector"},
+			{Name: ""},
+		}
+
+		resp := validator.Handle(context.TODO(), newRequest(admissionv1.Create, &cluster, nil))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(string(resp.Result.Reason)).To(ContainSubstring(`spec.endpoints[1].name: Duplicate value: "beijing.connector"`))
+		Expect(string(resp.Result.Reason)).To(ContainSubstring(`spec.endpoints[2].name: Duplicate value: "shanghai.connector"`))
+		Expect(string(resp.Result.Reason)).To(ContainSubstring(`spec.endpoints[3].name: Required value`))
+	})
+})
+
+func newCluster(name string, cidrs []string) apis.Cluster {
+	return apis.Cluster{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apis.SchemeGroupVersion.String(),
+			Kind:       "Cluster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: apis.ClusterSpec{
+			CIDRs: cidrs,
+		},
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
+)
+
+var _ admission.Handler = &communityValidator{}
+var _ admission.DecoderInjector = &communityValidator{}
+
+// communityValidator checks members and selectors of community
+type communityValidator struct {
+	store   storepkg.Interface
+	decoder *admission.Decoder
+	log     logr.Logger
+}
+
+func (v *communityValidator) InjectDecoder(decoder *admission.Decoder) error {
+	v.decoder = decoder
+	return nil
+}
+
+func (v *communityValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var community, oldCommunity apis.Community
+	if err := v.decoder.Decode(req, &community); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if req.Operation == admissionv1.Update {
+		if err := v.decoder.DecodeRaw(req.OldObject, &oldCommunity); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+
+	if allErrs := v.validate(&community, &oldCommunity); len(allErrs) > 0 {
+		logDenied(v.log, community.Name, allErrs)
+		return admission.Denied(allErrs.ToAggregate().Error())
+	}
+
+	return admission.Allowed("")
+}
+
+// validate checks community, oldCommunity is empty unless it's an update request.
+// Only members added by update are checked for existence, because endpoints
+// of existing members may be removed at any time
+func (v *communityValidator) validate(community, oldCommunity *apis.Community) field.ErrorList {
+	var allErrs field.ErrorList
+
+	specPath := field.NewPath("spec")
+	membersPath := specPath.Child("members")
+
+	endpointNames := v.store.GetAllEndpointNames()
+	oldMembers := sets.NewString(oldCommunity.Spec.Members...)
+	members := sets.NewString()
+	for i, member := range community.Spec.Members {
+		switch {
+		case members.Has(member):
+			allErrs = append(allErrs, field.Duplicate(membersPath.Index(i), member))
+		case !oldMembers.Has(member) && !endpointNames.Has(member):
+			allErrs = append(allErrs, field.NotFound(membersPath.Index(i), member))
+		}
+		members.Insert(member)
+	}
+
+	if community.Spec.NodeSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(community.Spec.NodeSelector, specPath.Child("nodeSelector"))...)
+	}
+
+	if community.Spec.ClusterSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(community.Spec.ClusterSelector, specPath.Child("clusterSelector"))...)
+	}
+
+	allErrs = append(allErrs, validateIPSecParameters(community.Spec.IPSec, specPath.Child("ipsec"))...)
+
+	return allErrs
+}
@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2/klogr"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
+)
+
+var _ = Describe("CommunityValidator", func() {
+	var validator *communityValidator
+
+	BeforeEach(func() {
+		store := storepkg.NewStore()
+		store.SaveEndpoint(apis.Endpoint{Name: "edge1"})
+		store.SaveEndpoint(apis.Endpoint{Name: "edge2"})
+
+		validator = &communityValidator{
+			store: store,
+			log:   klogr.New(),
+		}
+		Expect(validator.InjectDecoder(decoder)).To(Succeed())
+	})
+
+	It("should allow community whose members exist", func() {
+		community := newCommunity("test", "edge1", "edge2")
+
+		resp := validator.Handle(context.TODO(), newRequest(admissionv1.Create, &community, nil))
+		Expect(resp.Allowed).To(BeTrue())
+	})
+
+	It("should reject duplicate or unknown members", func() {
+		community := newCommunity("test", "edge1", "edge1", "edge3")
+
+		resp := validator.Handle(context.TODO(), newRequest(admissionv1.Create, &community, nil))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(string(resp.Result.Reason)).To(ContainSubstring(`spec.members[1]: Duplicate value: "edge1"`))
+		Expect(string(resp.Result.Reason)).To(ContainSubstring(`spec.members[2]: Not found: "edge3"`))
+	})
+
+	It("should only check existence of members added by update", func() {
+		oldCommunity := newCommunity("test", "edge1", "edge3")
+		community := newCommunity("test", "edge1", "edge2", "edge3")
+
+		resp := validator.Handle(context.TODO(), newRequest(admissionv1.Update, &community, &oldCommunity))
+		Expect(resp.Allowed).To(BeTrue())
+	})
+
+	It("should reject invalid selectors", func() {
+		community := newCommunity("test")
+		community.Spec.NodeSelector = &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "group", Operator: "Bad"},
+			},
+		}
+
+		resp := validator.Handle(context.TODO(), newRequest(admissionv1.Create, &community, nil))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(string(resp.Result.Reason)).To(ContainSubstring("spec.nodeSelector.matchExpressions[0].operator"))
+	})
+
+	It("should reject invalid IPSec parameters", func() {
+		rekeyTime, lifeTime := int64(3600), int64(1800)
+		community := newCommunity("test", "edge1", "edge2")
+		community.Spec.IPSec = &apis.IPSecParameters{
+			ESPProposals:     []string{"aes256gcm16-ecp384", ""},
+			RekeyTimeSeconds: &rekeyTime,
+			LifeTimeSeconds:  &lifeTime,
+		}
+
+		resp := validator.Handle(context.TODO(), newRequest(admissionv1.Create, &community, nil))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(string(resp.Result.Reason)).To(ContainSubstring("spec.ipsec.espProposals[1]: Required value"))
+		Expect(string(resp.Result.Reason)).To(ContainSubstring("spec.ipsec.lifeTimeSeconds: Invalid value: 1800: must be greater than rekeyTimeSeconds"))
+	})
+})
+
+func newCommunity(name string, members ...string) apis.Community {
+	return apis.Community{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apis.SchemeGroupVersion.String(),
+			Kind:       "Community",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: apis.CommunitySpec{
+			Members: members,
+		},
+	}
+}
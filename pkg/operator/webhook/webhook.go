@@ -0,0 +1,173 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"net"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	storepkg "github.com/fabedge/fabedge/pkg/operator/store"
+	"github.com/fabedge/fabedge/pkg/operator/types"
+)
+
+const (
+	PathMutateCluster     = "/mutate-fabedge-io-v1alpha1-cluster"
+	PathValidateCluster   = "/validate-fabedge-io-v1alpha1-cluster"
+	PathValidateCommunity = "/validate-fabedge-io-v1alpha1-community"
+	PathConvert           = "/convert"
+)
+
+type Config struct {
+	Manager manager.Manager
+	// Store is used to check if members of community exist
+	Store storepkg.Interface
+	// CIDRMap is used to check if CIDRs of a cluster overlap with those of other clusters
+	CIDRMap *types.ClusterCIDRsMap
+	// EdgePodCIDRs are CIDRs of edge pods of local cluster, CIDRs of other clusters should not overlap with them
+	EdgePodCIDRs []string
+}
+
+// AddToManager registers admission webhooks and conversion webhook of clusters
+// and communities to webhook server of manager, the server will be started along with manager.
+// The conversion webhook relies on the scheme of manager, which should have
+// both v1alpha1 and v1beta1 registered. v1beta1 is not served by default CRDs,
+// conversion webhook is used only if CRDs are changed to serve it
+func AddToManager(cnf Config) error {
+	mgr := cnf.Manager
+	log := mgr.GetLogger().WithName("webhook")
+	server := mgr.GetWebhookServer()
+
+	server.Register(PathMutateCluster, &webhook.Admission{
+		Handler: &clusterMutator{
+			log: log.WithName("clusterMutator"),
+		},
+	})
+	server.Register(PathValidateCluster, &webhook.Admission{
+		Handler: &clusterValidator{
+			client:       mgr.GetClient(),
+			cidrMap:      cnf.CIDRMap,
+			edgePodCIDRs: cnf.EdgePodCIDRs,
+			log:          log.WithName("clusterValidator"),
+		},
+	})
+	server.Register(PathValidateCommunity, &webhook.Admission{
+		Handler: &communityValidator{
+			store: cnf.Store,
+			log:   log.WithName("communityValidator"),
+		},
+	})
+	server.Register(PathConvert, &conversion.Webhook{})
+
+	return nil
+}
+
+// normalizeCIDRs normalizes CIDRs like Options.normalizeCIDRs does, e.g.
+// fd96:ee88:0:1::0/116 will be changed to fd96:ee88:0:1::/116.
+// Invalid CIDRs are kept as they are, validator will reject them
+func normalizeCIDRs(cidrs []string) []string {
+	if cidrs == nil {
+		return nil
+	}
+
+	results := make([]string, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+			cidr = ipNet.String()
+		}
+		results = append(results, cidr)
+	}
+
+	return results
+}
+
+func validateCIDRs(cidrs []string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, cidr := range cidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), cidr, "invalid CIDR"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateNodeSubnets checks node subnets of an endpoint, a node subnet
+// may be a CIDR or a single IP, e.g. the internal IP of a node
+func validateNodeSubnets(subnets []string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, subnet := range subnets {
+		if net.ParseIP(subnet) != nil {
+			continue
+		}
+
+		if _, _, err := net.ParseCIDR(subnet); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), subnet, "invalid IP or CIDR"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateIPSecParameters checks IPSec parameters, proposals are not parsed
+// here, strongswan will report invalid proposals when loading connections
+func validateIPSecParameters(params *apis.IPSecParameters, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if params == nil {
+		return allErrs
+	}
+
+	allErrs = append(allErrs, validateProposals(params.IKEProposals, fldPath.Child("ikeProposals"))...)
+	allErrs = append(allErrs, validateProposals(params.ESPProposals, fldPath.Child("espProposals"))...)
+	allErrs = append(allErrs, validateSeconds(params.IKERekeyTimeSeconds, fldPath.Child("ikeRekeyTimeSeconds"))...)
+	allErrs = append(allErrs, validateSeconds(params.RekeyTimeSeconds, fldPath.Child("rekeyTimeSeconds"))...)
+	allErrs = append(allErrs, validateSeconds(params.LifeTimeSeconds, fldPath.Child("lifeTimeSeconds"))...)
+
+	if params.RekeyTimeSeconds != nil && params.LifeTimeSeconds != nil &&
+		*params.LifeTimeSeconds <= *params.RekeyTimeSeconds {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("lifeTimeSeconds"), *params.LifeTimeSeconds, "must be greater than rekeyTimeSeconds"))
+	}
+
+	return allErrs
+}
+
+func validateProposals(proposals []string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, proposal := range proposals {
+		if proposal == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Index(i), ""))
+		}
+	}
+
+	return allErrs
+}
+
+func validateSeconds(seconds *int64, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if seconds != nil && *seconds <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, *seconds, "must be greater than 0"))
+	}
+
+	return allErrs
+}
+
+func logDenied(log logr.Logger, name string, errs field.ErrorList) {
+	log.V(3).Info("request is denied", "name", name, "reason", errs.ToAggregate().Error())
+}
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
+	testutil "github.com/fabedge/fabedge/pkg/util/test"
+)
+
+var cfg *rest.Config
+var k8sClient client.Client
+var decoder *admission.Decoder
+
+// envtest provide a api server which has some differences from real environments,
+// read https://book.kubebuilder.io/reference/envtest.html#testing-considerations
+var testEnv *envtest.Environment
+
+func TestWebhook(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Webhook Suite")
+}
+
+var _ = BeforeSuite(func(done Done) {
+	testutil.SetupLogger()
+
+	By("starting test environment")
+	var err error
+	testEnv, cfg, k8sClient, err = testutil.StartTestEnvWithCRD(
+		[]string{filepath.Join("..", "..", "..", "deploy", "crds")},
+	)
+	Expect(err).ToNot(HaveOccurred())
+
+	_ = apis.AddToScheme(scheme.Scheme)
+
+	decoder, err = admission.NewDecoder(scheme.Scheme)
+	Expect(err).ToNot(HaveOccurred())
+
+	close(done)
+}, 60)
+
+var _ = AfterSuite(func() {
+	By("tearing down the test environment")
+	err := testEnv.Stop()
+	Expect(err).ShouldNot(HaveOccurred())
+})
+
+func newRequest(operation admissionv1.Operation, obj, oldObj runtime.Object) admission.Request {
+	req := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: toJSON(obj)},
+		},
+	}
+
+	if oldObj != nil {
+		req.OldObject = runtime.RawExtension{Raw: toJSON(oldObj)}
+	}
+
+	return req
+}
+
+func toJSON(obj runtime.Object) []byte {
+	data, err := json.Marshal(obj)
+	ExpectWithOffset(2, err).ShouldNot(HaveOccurred())
+	return data
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ prometheus.Collector = &collector{}
+
+// collector queries tunnel manager on every scrape, so the
+// metrics always reflect current connections
+type collector struct {
+	tm Manager
+
+	loaded      *prometheus.Desc
+	established *prometheus.Desc
+	state       *prometheus.Desc
+	bytes       *prometheus.Desc
+	packets     *prometheus.Desc
+}
+
+// NewCollector creates a prometheus collector which reports connections of tm,
+// namespace is used as prefix of metric names, e.g. fabedge_agent
+func NewCollector(namespace string, tm Manager) prometheus.Collector {
+	return &collector{
+		tm: tm,
+		loaded: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "tunnels_loaded"),
+			"Number of connections loaded into tunnel backends",
+			nil, nil,
+		),
+		established: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "tunnels_established"),
+			"Number of connections which are established",
+			nil, nil,
+		),
+		state: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "tunnel_state"),
+			"State of connection to peer, the value is always 1",
+			[]string{"peer", "state"}, nil,
+		),
+		bytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "tunnel_bytes_total"),
+			"Bytes transferred by SAs of connection to peer, reset when SAs are rekeyed",
+			[]string{"peer", "direction"}, nil,
+		),
+		packets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "tunnel_packets_total"),
+			"Packets transferred by SAs of connection to peer, reset when SAs are rekeyed",
+			[]string{"peer", "direction"}, nil,
+		),
+	}
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.loaded
+	ch <- c.established
+	ch <- c.state
+	ch <- c.bytes
+	ch <- c.packets
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	names, err := c.tm.ListConnNames()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.loaded, err)
+		return
+	}
+
+	statuses, err := c.tm.ListConnStatuses()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.state, err)
+		return
+	}
+
+	statusByName := make(map[string]ConnStatus, len(statuses))
+	for _, status := range statuses {
+		statusByName[status.Name] = status
+	}
+
+	established := 0
+	for _, name := range names {
+		status, found := statusByName[name]
+		if !found {
+			status = ConnStatus{Name: name, State: ConnStateDown}
+		}
+
+		if status.State == ConnStateEstablished {
+			established++
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, 1, name, status.State)
+		ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.CounterValue, float64(status.BytesIn), name, "in")
+		ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.CounterValue, float64(status.BytesOut), name, "out")
+		ch <- prometheus.MustNewConstMetric(c.packets, prometheus.CounterValue, float64(status.PacketsIn), name, "in")
+		ch <- prometheus.MustNewConstMetric(c.packets, prometheus.CounterValue, float64(status.PacketsOut), name, "out")
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.loaded, prometheus.GaugeValue, float64(len(names)))
+	ch <- prometheus.MustNewConstMetric(c.established, prometheus.GaugeValue, float64(established))
+}
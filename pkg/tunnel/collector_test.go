@@ -0,0 +1,55 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/fabedge/fabedge/pkg/tunnel"
+)
+
+var _ = Describe("Collector", func() {
+	It("should report loaded and established connections", func() {
+		manager := newFakeManager()
+		manager.active = true
+		Expect(manager.LoadConn(tunnel.ConnConfig{Name: "edge1"})).To(Succeed())
+		Expect(manager.LoadConn(tunnel.ConnConfig{Name: "edge2"})).To(Succeed())
+
+		collector := tunnel.NewCollector("fabedge_test", manager)
+		expected := `
+# HELP fabedge_test_tunnels_established Number of connections which are established
+# TYPE fabedge_test_tunnels_established gauge
+fabedge_test_tunnels_established 2
+# HELP fabedge_test_tunnels_loaded Number of connections loaded into tunnel backends
+# TYPE fabedge_test_tunnels_loaded gauge
+fabedge_test_tunnels_loaded 2
+`
+		err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "fabedge_test_tunnels_established", "fabedge_test_tunnels_loaded")
+		Expect(err).NotTo(HaveOccurred())
+
+		expected = `
+# HELP fabedge_test_tunnel_state State of connection to peer, the value is always 1
+# TYPE fabedge_test_tunnel_state gauge
+fabedge_test_tunnel_state{peer="edge1",state="ESTABLISHED"} 1
+fabedge_test_tunnel_state{peer="edge2",state="ESTABLISHED"} 1
+`
+		err = testutil.CollectAndCompare(collector, strings.NewReader(expected), "fabedge_test_tunnel_state")
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
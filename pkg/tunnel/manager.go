@@ -18,6 +18,16 @@ import (
 	apis "github.com/fabedge/fabedge/pkg/apis/v1alpha1"
 )
 
+const (
+	BackendStrongSwan = "strongswan"
+	BackendWireGuard  = "wireguard"
+)
+
+const (
+	AuthPubKey = "pubkey"
+	AuthPSK    = "psk"
+)
+
 type Manager interface {
 	IsRunning() bool
 	ListConnNames() ([]string, error)
@@ -25,10 +35,43 @@ type Manager interface {
 	InitiateConn(name string) error
 	UnloadConn(name string) error
 	IsActive() (bool, error)
+	// ListEstablishedConnNames returns names of connections which are established now
+	ListEstablishedConnNames() ([]string, error)
+	// ListConnStatuses returns state and traffic of connections which have SAs or peers now
+	ListConnStatuses() ([]ConnStatus, error)
+}
+
+// CertReloader is implemented by managers whose connections are authenticated
+// by certificates, agent and connector use it to pick up renewed certificates
+type CertReloader interface {
+	// ReloadCerts loads certificates which are changed since they were loaded,
+	// established tunnels should be kept
+	ReloadCerts() error
+}
+
+const (
+	ConnStateEstablished = "ESTABLISHED"
+	ConnStateDown        = "DOWN"
+)
+
+// ConnStatus is the runtime status of a connection, traffic counters are
+// reset when SAs are rekeyed or the tunnel is rebuilt
+type ConnStatus struct {
+	Name string
+	// State of connection, for strongswan it's the state of IKE SA, e.g. ESTABLISHED, CONNECTING;
+	// for wireguard it's ESTABLISHED or DOWN decided by latest handshake
+	State      string
+	BytesIn    uint64
+	BytesOut   uint64
+	PacketsIn  uint64
+	PacketsOut uint64
 }
 
 type ConnConfig struct {
 	Name string // must be unique
+	// Backend is the name of tunnel backend used by this connection,
+	// empty value means the default backend. Only used by MixedManager
+	Backend string
 
 	LocalID          string
 	LocalAddress     []string
@@ -43,6 +86,14 @@ type ConnConfig struct {
 	RemoteNodeSubnets []string
 	RemoteType        apis.EndpointType
 	RemotePort        *uint
+	// RemotePublicKey is only used by wireguard backend
+	RemotePublicKey string
+
+	// AuthMethod is the authentication method of this connection: pubkey or psk,
+	// empty value means pubkey. Only used by strongswan backend. EAP is not supported
+	AuthMethod string
+	// PSK is the pre-shared key used when AuthMethod is psk
+	PSK PSK
 
 	// Whether this connection is used for mediation
 	Mediation bool
@@ -53,4 +104,31 @@ type ConnConfig struct {
 	// for detailed explanation for MediatedBy and MediationPeer
 	MediatedBy    string
 	MediationPeer string
+
+	// IPsec parameters, only used by strongswan backend, empty values mean strongswan defaults
+	IKEProposals []string
+	ESPProposals []string
+	// IKERekeyTime, RekeyTime and LifeTime are in seconds
+	IKERekeyTime int64
+	RekeyTime    int64
+	LifeTime     int64
+}
+
+// SetIPSecParameters copies IPsec parameters to connection config, nil params are ignored
+func (c *ConnConfig) SetIPSecParameters(params *apis.IPSecParameters) {
+	if params == nil {
+		return
+	}
+
+	c.IKEProposals = params.IKEProposals
+	c.ESPProposals = params.ESPProposals
+	if params.IKERekeyTimeSeconds != nil {
+		c.IKERekeyTime = *params.IKERekeyTimeSeconds
+	}
+	if params.RekeyTimeSeconds != nil {
+		c.RekeyTime = *params.RekeyTimeSeconds
+	}
+	if params.LifeTimeSeconds != nil {
+		c.LifeTime = *params.LifeTimeSeconds
+	}
 }
@@ -0,0 +1,273 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"fmt"
+	"sync"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+var _ Manager = &MixedManager{}
+var _ CertReloader = &MixedManager{}
+
+// MixedManager dispatches each connection to the manager of its backend,
+// which makes it possible to run several backends at the same time.
+// The manager of default backend is created at once, others are created
+// when they are used by some connection for the first time.
+type MixedManager struct {
+	registry       *Registry
+	defaultBackend string
+
+	managers      map[string]Manager
+	backendByConn map[string]string
+	backendsInUse []string
+	mu            sync.Mutex
+}
+
+func NewMixedManager(registry *Registry, defaultBackend string) (*MixedManager, error) {
+	m := &MixedManager{
+		registry:       registry,
+		defaultBackend: defaultBackend,
+		managers:       make(map[string]Manager),
+		backendByConn:  make(map[string]string),
+	}
+
+	if _, err := m.getManager(defaultBackend); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// DefaultBackend returns the backend used by connections which don't specify one
+func (m *MixedManager) DefaultBackend() string {
+	return m.defaultBackend
+}
+
+// BackendOf returns the backend to be used by a connection config
+func (m *MixedManager) BackendOf(conn ConnConfig) string {
+	if conn.Backend == "" {
+		return m.defaultBackend
+	}
+
+	return conn.Backend
+}
+
+func (m *MixedManager) IsRunning() bool {
+	for _, manager := range m.listManagers() {
+		if !manager.IsRunning() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m *MixedManager) ListConnNames() ([]string, error) {
+	names := sets.NewString()
+	for _, manager := range m.listManagers() {
+		connNames, err := manager.ListConnNames()
+		if err != nil {
+			return nil, err
+		}
+		names.Insert(connNames...)
+	}
+
+	return names.List(), nil
+}
+
+func (m *MixedManager) LoadConn(conn ConnConfig) error {
+	backend := m.BackendOf(conn)
+	manager, err := m.getManager(backend)
+	if err != nil {
+		return err
+	}
+
+	// the backend of a connection is changed, it has to be unloaded from old backend
+	if oldBackend, found := m.getBackendOfConn(conn.Name); found && oldBackend != backend {
+		oldManager, err := m.getManager(oldBackend)
+		if err != nil {
+			return err
+		}
+
+		if err = oldManager.UnloadConn(conn.Name); err != nil {
+			return err
+		}
+	}
+
+	if err = manager.LoadConn(conn); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.backendByConn[conn.Name] = backend
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *MixedManager) InitiateConn(name string) error {
+	backend, found := m.getBackendOfConn(name)
+	if !found {
+		return fmt.Errorf("connection %s not found", name)
+	}
+
+	manager, err := m.getManager(backend)
+	if err != nil {
+		return err
+	}
+
+	return manager.InitiateConn(name)
+}
+
+// UnloadConn unloads the connection from the manager which has it. Connections
+// left by last run are unknown to MixedManager, so every manager will be checked.
+func (m *MixedManager) UnloadConn(name string) error {
+	if backend, found := m.getBackendOfConn(name); found {
+		manager, err := m.getManager(backend)
+		if err != nil {
+			return err
+		}
+
+		if err = manager.UnloadConn(name); err != nil {
+			return err
+		}
+
+		m.mu.Lock()
+		delete(m.backendByConn, name)
+		m.mu.Unlock()
+
+		return nil
+	}
+
+	var errors []error
+	for _, manager := range m.listManagers() {
+		names, err := manager.ListConnNames()
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+
+		if !sets.NewString(names...).Has(name) {
+			continue
+		}
+
+		if err = manager.UnloadConn(name); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(errors)
+}
+
+// IsActive returns true if any backend is active
+func (m *MixedManager) IsActive() (bool, error) {
+	var errors []error
+	for _, manager := range m.listManagers() {
+		active, err := manager.IsActive()
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+
+		if active {
+			return true, nil
+		}
+	}
+
+	return false, utilerrors.NewAggregate(errors)
+}
+
+func (m *MixedManager) ListEstablishedConnNames() ([]string, error) {
+	names := sets.NewString()
+	for _, manager := range m.listManagers() {
+		connNames, err := manager.ListEstablishedConnNames()
+		if err != nil {
+			return nil, err
+		}
+		names.Insert(connNames...)
+	}
+
+	return names.List(), nil
+}
+
+func (m *MixedManager) ListConnStatuses() ([]ConnStatus, error) {
+	var statuses []ConnStatus
+	for _, manager := range m.listManagers() {
+		connStatuses, err := manager.ListConnStatuses()
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, connStatuses...)
+	}
+
+	return statuses, nil
+}
+
+// ReloadCerts reloads certificates of backends which support it
+func (m *MixedManager) ReloadCerts() error {
+	var errs []error
+	for _, manager := range m.listManagers() {
+		if reloader, ok := manager.(CertReloader); ok {
+			if err := reloader.ReloadCerts(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func (m *MixedManager) getManager(backend string) (Manager, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if manager, found := m.managers[backend]; found {
+		return manager, nil
+	}
+
+	manager, err := m.registry.New(backend)
+	if err != nil {
+		return nil, err
+	}
+	m.managers[backend] = manager
+	m.backendsInUse = append(m.backendsInUse, backend)
+
+	return manager, nil
+}
+
+func (m *MixedManager) getBackendOfConn(name string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	backend, found := m.backendByConn[name]
+	return backend, found
+}
+
+// listManagers returns created managers in the order of their creation
+func (m *MixedManager) listManagers() []Manager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	managers := make([]Manager, 0, len(m.backendsInUse))
+	for _, backend := range m.backendsInUse {
+		managers = append(managers, m.managers[backend])
+	}
+
+	return managers
+}
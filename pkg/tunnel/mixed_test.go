@@ -0,0 +1,250 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/fabedge/fabedge/pkg/tunnel"
+)
+
+type fakeManager struct {
+	connections map[string]tunnel.ConnConfig
+	active      bool
+}
+
+func newFakeManager() *fakeManager {
+	return &fakeManager{
+		connections: make(map[string]tunnel.ConnConfig),
+	}
+}
+
+func (m *fakeManager) IsRunning() bool {
+	return true
+}
+
+func (m *fakeManager) ListConnNames() ([]string, error) {
+	var names []string
+	for name := range m.connections {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (m *fakeManager) LoadConn(conn tunnel.ConnConfig) error {
+	m.connections[conn.Name] = conn
+	return nil
+}
+
+func (m *fakeManager) InitiateConn(name string) error {
+	if _, found := m.connections[name]; !found {
+		return fmt.Errorf("connection %s not found", name)
+	}
+	return nil
+}
+
+func (m *fakeManager) UnloadConn(name string) error {
+	delete(m.connections, name)
+	return nil
+}
+
+func (m *fakeManager) IsActive() (bool, error) {
+	return m.active, nil
+}
+
+func (m *fakeManager) ListEstablishedConnNames() ([]string, error) {
+	if !m.active {
+		return nil, nil
+	}
+	return m.ListConnNames()
+}
+
+func (m *fakeManager) ListConnStatuses() ([]tunnel.ConnStatus, error) {
+	var statuses []tunnel.ConnStatus
+	for name := range m.connections {
+		state := tunnel.ConnStateDown
+		if m.active {
+			state = tunnel.ConnStateEstablished
+		}
+		statuses = append(statuses, tunnel.ConnStatus{Name: name, State: state})
+	}
+	return statuses, nil
+}
+
+// fakeCertReloader is a fakeManager which supports reloading certificates
+type fakeCertReloader struct {
+	*fakeManager
+	reloaded int
+}
+
+func (m *fakeCertReloader) ReloadCerts() error {
+	m.reloaded++
+	return nil
+}
+
+var _ = Describe("NegotiateBackend", func() {
+	It("should return the same result on both sides", func() {
+		backends := []string{"", tunnel.BackendStrongSwan, tunnel.BackendWireGuard}
+		for _, local := range backends {
+			for _, remote := range backends {
+				Expect(tunnel.NegotiateBackend(local, remote)).To(Equal(tunnel.NegotiateBackend(remote, local)))
+			}
+		}
+	})
+
+	It("should use the backend if both sides specify the same one", func() {
+		Expect(tunnel.NegotiateBackend(tunnel.BackendWireGuard, tunnel.BackendWireGuard)).To(Equal(tunnel.BackendWireGuard))
+		Expect(tunnel.NegotiateBackend(tunnel.BackendStrongSwan, tunnel.BackendStrongSwan)).To(Equal(tunnel.BackendStrongSwan))
+	})
+
+	It("should take empty backend as strongswan", func() {
+		Expect(tunnel.NegotiateBackend("", "")).To(Equal(tunnel.BackendStrongSwan))
+		Expect(tunnel.NegotiateBackend("", tunnel.BackendStrongSwan)).To(Equal(tunnel.BackendStrongSwan))
+		Expect(tunnel.NegotiateBackend("", tunnel.BackendWireGuard)).To(Equal(tunnel.BackendStrongSwan))
+		Expect(tunnel.NegotiateBackend(tunnel.BackendWireGuard, "")).To(Equal(tunnel.BackendStrongSwan))
+	})
+
+	It("should use strongswan if both sides specify different backends", func() {
+		Expect(tunnel.NegotiateBackend(tunnel.BackendWireGuard, "other")).To(Equal(tunnel.BackendStrongSwan))
+	})
+})
+
+var _ = Describe("MixedManager", func() {
+	var (
+		registry   *tunnel.Registry
+		strongswan *fakeManager
+		wireguard  *fakeManager
+		created    map[string]int
+		manager    *tunnel.MixedManager
+	)
+
+	BeforeEach(func() {
+		strongswan, wireguard = newFakeManager(), newFakeManager()
+		created = make(map[string]int)
+
+		registry = tunnel.NewRegistry()
+		registry.Register(tunnel.BackendStrongSwan, func() (tunnel.Manager, error) {
+			created[tunnel.BackendStrongSwan]++
+			return strongswan, nil
+		})
+		registry.Register(tunnel.BackendWireGuard, func() (tunnel.Manager, error) {
+			created[tunnel.BackendWireGuard]++
+			return wireguard, nil
+		})
+
+		var err error
+		manager, err = tunnel.NewMixedManager(registry, tunnel.BackendStrongSwan)
+		Expect(err).Should(BeNil())
+	})
+
+	It("should fail to create if default backend is unknown", func() {
+		_, err := tunnel.NewMixedManager(registry, "unknown")
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("should only create manager of default backend at first", func() {
+		Expect(created[tunnel.BackendStrongSwan]).To(Equal(1))
+		Expect(created[tunnel.BackendWireGuard]).To(Equal(0))
+		Expect(registry.Names()).To(Equal([]string{tunnel.BackendStrongSwan, tunnel.BackendWireGuard}))
+	})
+
+	It("should load connections into managers of their backends", func() {
+		Expect(manager.LoadConn(tunnel.ConnConfig{Name: "a"})).To(Succeed())
+		Expect(manager.LoadConn(tunnel.ConnConfig{Name: "b", Backend: tunnel.BackendWireGuard})).To(Succeed())
+
+		Expect(strongswan.connections).To(HaveKey("a"))
+		Expect(wireguard.connections).To(HaveKey("b"))
+		Expect(created[tunnel.BackendWireGuard]).To(Equal(1))
+
+		names, err := manager.ListConnNames()
+		Expect(err).Should(BeNil())
+		Expect(names).To(ConsistOf("a", "b"))
+
+		Expect(manager.InitiateConn("a")).To(Succeed())
+		Expect(manager.InitiateConn("b")).To(Succeed())
+		Expect(manager.InitiateConn("c")).NotTo(Succeed())
+	})
+
+	It("should connect a wireguard node to a strongswan connector by strongswan", func() {
+		manager, err := tunnel.NewMixedManager(registry, tunnel.BackendWireGuard)
+		Expect(err).Should(BeNil())
+
+		backend := tunnel.NegotiateBackend(tunnel.BackendWireGuard, tunnel.BackendStrongSwan)
+		Expect(manager.LoadConn(tunnel.ConnConfig{Name: "connector", Backend: backend})).To(Succeed())
+		Expect(manager.LoadConn(tunnel.ConnConfig{Name: "mediator", Backend: tunnel.BackendStrongSwan, Mediation: true})).To(Succeed())
+		Expect(manager.LoadConn(tunnel.ConnConfig{Name: "edge2", Backend: tunnel.BackendWireGuard})).To(Succeed())
+
+		Expect(strongswan.connections).To(HaveKey("connector"))
+		Expect(strongswan.connections).To(HaveKey("mediator"))
+		Expect(wireguard.connections).To(HaveKey("edge2"))
+		Expect(wireguard.connections).NotTo(HaveKey("connector"))
+	})
+
+	It("should move connection to another manager when its backend is changed", func() {
+		Expect(manager.LoadConn(tunnel.ConnConfig{Name: "a"})).To(Succeed())
+		Expect(manager.LoadConn(tunnel.ConnConfig{Name: "a", Backend: tunnel.BackendWireGuard})).To(Succeed())
+
+		Expect(strongswan.connections).NotTo(HaveKey("a"))
+		Expect(wireguard.connections).To(HaveKey("a"))
+	})
+
+	It("should unload connections from managers which have them", func() {
+		// a connection left by last run
+		strongswan.connections["old"] = tunnel.ConnConfig{Name: "old"}
+		Expect(manager.LoadConn(tunnel.ConnConfig{Name: "b", Backend: tunnel.BackendWireGuard})).To(Succeed())
+
+		Expect(manager.UnloadConn("old")).To(Succeed())
+		Expect(manager.UnloadConn("b")).To(Succeed())
+
+		Expect(strongswan.connections).To(BeEmpty())
+		Expect(wireguard.connections).To(BeEmpty())
+	})
+
+	It("should be active if any backend is active", func() {
+		Expect(manager.LoadConn(tunnel.ConnConfig{Name: "b", Backend: tunnel.BackendWireGuard})).To(Succeed())
+
+		active, err := manager.IsActive()
+		Expect(err).Should(BeNil())
+		Expect(active).To(BeFalse())
+
+		wireguard.active = true
+		active, err = manager.IsActive()
+		Expect(err).Should(BeNil())
+		Expect(active).To(BeTrue())
+	})
+
+	It("should reload certificates of backends which support it", func() {
+		reloader := &fakeCertReloader{fakeManager: newFakeManager()}
+		registry = tunnel.NewRegistry()
+		registry.Register(tunnel.BackendStrongSwan, func() (tunnel.Manager, error) {
+			return reloader, nil
+		})
+		registry.Register(tunnel.BackendWireGuard, func() (tunnel.Manager, error) {
+			return wireguard, nil
+		})
+
+		manager, err := tunnel.NewMixedManager(registry, tunnel.BackendWireGuard)
+		Expect(err).Should(BeNil())
+		Expect(manager.ReloadCerts()).To(Succeed())
+		Expect(reloader.reloaded).To(Equal(0))
+
+		Expect(manager.LoadConn(tunnel.ConnConfig{Name: "a", Backend: tunnel.BackendStrongSwan})).To(Succeed())
+		Expect(manager.ReloadCerts()).To(Succeed())
+		Expect(reloader.reloaded).To(Equal(1))
+	})
+})
@@ -0,0 +1,87 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PSK is a pre-shared key, it's redacted when printed so that
+// connection configs can be logged safely
+type PSK string
+
+func (PSK) String() string {
+	return "<redacted>"
+}
+
+func (PSK) MarshalJSON() ([]byte, error) {
+	return []byte(`"<redacted>"`), nil
+}
+
+// LoadPSKs reads pre-shared keys from dir, the name of each file is the name of
+// peer endpoint and its content is the key. The dir is usually a mounted secret volume,
+// so hidden files created by kubelet are skipped. An empty map is returned if dir doesn't exist
+func LoadPSKs(dir string) (map[string]PSK, error) {
+	psks := make(map[string]PSK)
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return psks, nil
+		}
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		// files of secret volume are symlinks, so stat them again
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		psk := strings.TrimSpace(string(data))
+		if psk != "" {
+			psks[name] = PSK(psk)
+		}
+	}
+
+	return psks, nil
+}
+
+// SetPSK makes connection use PSK authentication if psk is not empty
+func (c *ConnConfig) SetPSK(psk PSK) {
+	if psk == "" {
+		return
+	}
+
+	c.AuthMethod = AuthPSK
+	c.PSK = psk
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/fabedge/fabedge/pkg/tunnel"
+)
+
+var _ = Describe("PSK", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "psk")
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	It("LoadPSKs should read keys from files named after peers and skip hidden files", func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "beijing.edge1"), []byte("secret1\n"), 0600)).To(Succeed())
+		Expect(os.Mkdir(filepath.Join(dir, "..data"), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "..data", "beijing.edge2"), []byte("secret2"), 0600)).To(Succeed())
+		Expect(os.Symlink(filepath.Join(dir, "..data", "beijing.edge2"), filepath.Join(dir, "beijing.edge2"))).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "beijing.edge3"), []byte(""), 0600)).To(Succeed())
+
+		psks, err := tunnel.LoadPSKs(dir)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(psks).To(Equal(map[string]tunnel.PSK{
+			"beijing.edge1": "secret1",
+			"beijing.edge2": "secret2",
+		}))
+	})
+
+	It("LoadPSKs should return empty map if dir does not exist", func() {
+		psks, err := tunnel.LoadPSKs(filepath.Join(dir, "not-exist"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(psks).To(BeEmpty())
+	})
+
+	It("should not print PSK of connection", func() {
+		conn := tunnel.ConnConfig{Name: "beijing.edge1"}
+		conn.SetPSK("secret1")
+
+		Expect(conn.AuthMethod).To(Equal(tunnel.AuthPSK))
+		Expect(string(conn.PSK)).To(Equal("secret1"))
+		Expect(fmt.Sprintf("%+v", conn)).NotTo(ContainSubstring("secret1"))
+	})
+})
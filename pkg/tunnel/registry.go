@@ -0,0 +1,101 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory creates a manager of a tunnel backend
+type Factory func() (Manager, error)
+
+// Registry keeps factories of tunnel backends by their names
+type Registry struct {
+	factories map[string]Factory
+	mu        sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[string]Factory),
+	}
+}
+
+// Register adds the factory of a backend, the factory registered
+// earlier with the same name will be replaced
+func (r *Registry) Register(name string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.factories[name] = factory
+}
+
+func (r *Registry) Has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, found := r.factories[name]
+	return found
+}
+
+// Names returns sorted names of registered backends
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// New creates a manager of specified backend
+func (r *Registry) New(name string) (Manager, error) {
+	r.mu.RLock()
+	factory, found := r.factories[name]
+	r.mu.RUnlock()
+
+	if !found {
+		return nil, fmt.Errorf("unknown tunnel backend: %s", name)
+	}
+
+	return factory()
+}
+
+// NegotiateBackend decides the backend of a connection by the backends
+// of both endpoints. The result is the same no matter which side calls it,
+// so both sides of a connection will use the same backend. An endpoint without
+// backend is taken as strongswan, and if endpoints ask for different backends,
+// strongswan is used too because it's supported by every endpoint.
+func NegotiateBackend(local, remote string) string {
+	if local == "" {
+		local = BackendStrongSwan
+	}
+
+	if remote == "" {
+		remote = BackendStrongSwan
+	}
+
+	if local == remote {
+		return local
+	}
+
+	return BackendStrongSwan
+}
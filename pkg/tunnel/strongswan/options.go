@@ -29,6 +29,19 @@ func CertsDir(path string) option {
 	}
 }
 
+func CACertsDir(path string) option {
+	return func(m *StrongSwanManager) {
+		m.caCertsPath = path
+	}
+}
+
+// CRLsDir sets the directory of CRLs, CRLs are not loaded if it's empty
+func CRLsDir(path string) option {
+	return func(m *StrongSwanManager) {
+		m.crlsPath = path
+	}
+}
+
 func StartAction(startAction string) option {
 	return func(m *StrongSwanManager) {
 		m.startAction = startAction
@@ -18,6 +18,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"reflect"
 	"strconv"
@@ -32,10 +33,14 @@ import (
 )
 
 var _ tunnel.Manager = &StrongSwanManager{}
+var _ tunnel.CertReloader = &StrongSwanManager{}
 
 type StrongSwanManager struct {
-	socketPath string
-	certsPath  string
+	socketPath  string
+	certsPath   string
+	caCertsPath string
+	// crlsPath is the directory of CRLs, CRLs are not loaded if it's empty
+	crlsPath string
 
 	// https://wiki.strongswan.org/projects/strongswan/wiki/Swanctlconf
 	// The default of none loads the connection only, which then can be manually initiated or used as a responder configuration.
@@ -50,7 +55,13 @@ type StrongSwanManager struct {
 	initTimeout uint
 
 	connectionByName map[string]tunnel.ConnConfig
-	mu               *sync.RWMutex
+	// certByFilename holds content of certificate files when they are loaded
+	certByFilename map[string]string
+	// loadedCACerts holds CA certificates loaded by load-cert
+	loadedCACerts sets.String
+	// loadedCRLs holds CRLs loaded by load-cert
+	loadedCRLs sets.String
+	mu         *sync.RWMutex
 }
 
 type connection struct {
@@ -67,6 +78,8 @@ type connection struct {
 	Mediation     string                 `vici:"mediation"`
 	MediatedBy    string                 `vici:"mediated_by"`
 	MediationPeer string                 `vici:"mediation_peer"`
+	Proposals     []string               `vici:"proposals,omitempty"`
+	RekeyTime     string                 `vici:"rekey_time,omitempty"`
 }
 
 type authConf struct {
@@ -82,14 +95,20 @@ type childSAConf struct {
 	CloseAction  string   `vici:"close_action"`         //none,clear,hold,restart
 	DpdAction    string   `vici:"dpd_action,omitempty"` //none,clear,hold,restart
 	ESPProposals []string `vici:"esp_proposals,omitempty"`
+	RekeyTime    string   `vici:"rekey_time,omitempty"`
+	LifeTime     string   `vici:"life_time,omitempty"`
 }
 
 func New(opts ...option) (*StrongSwanManager, error) {
 	manager := &StrongSwanManager{
 		socketPath:       "/var/run/charon.vici",
 		certsPath:        filepath.Join("/etc/ipsec.d", "certs"),
+		caCertsPath:      filepath.Join("/etc/ipsec.d", "cacerts"),
 		startAction:      "none",
 		connectionByName: make(map[string]tunnel.ConnConfig),
+		certByFilename:   make(map[string]string),
+		loadedCACerts:    sets.NewString(),
+		loadedCRLs:       sets.NewString(),
 		mu:               &sync.RWMutex{},
 	}
 
@@ -239,6 +258,100 @@ func (m StrongSwanManager) listSANames(ike string) (sets.String, error) {
 	return names, err
 }
 
+// ListEstablishedConnNames returns names of connections whose IKE SAs are established
+func (m StrongSwanManager) ListEstablishedConnNames() ([]string, error) {
+	names := sets.NewString()
+
+	err := m.do(func(session *vici.Session) error {
+		ms, err := session.StreamedCommandRequest("list-sas", "list-sa", vici.NewMessage())
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range ms.Messages() {
+			if err = msg.Err(); err != nil {
+				return err
+			}
+
+			for _, name := range msg.Keys() {
+				sa, ok := msg.Get(name).(*vici.Message)
+				if !ok {
+					continue
+				}
+
+				if state, _ := sa.Get("state").(string); state == "ESTABLISHED" {
+					names.Insert(name)
+				}
+			}
+		}
+		return nil
+	})
+
+	return names.List(), err
+}
+
+// ListConnStatuses returns states of IKE SAs and traffic of their CHILD_SAs, if there
+// are duplicate IKE SAs of a connection, their traffic is summed up and ESTABLISHED wins
+func (m StrongSwanManager) ListConnStatuses() ([]tunnel.ConnStatus, error) {
+	statusByName := make(map[string]*tunnel.ConnStatus)
+	var names []string
+
+	err := m.do(func(session *vici.Session) error {
+		ms, err := session.StreamedCommandRequest("list-sas", "list-sa", vici.NewMessage())
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range ms.Messages() {
+			if err = msg.Err(); err != nil {
+				return err
+			}
+
+			for _, name := range msg.Keys() {
+				sa, ok := msg.Get(name).(*vici.Message)
+				if !ok {
+					continue
+				}
+
+				status, found := statusByName[name]
+				if !found {
+					status = &tunnel.ConnStatus{Name: name}
+					statusByName[name] = status
+					names = append(names, name)
+				}
+
+				if state, _ := sa.Get("state").(string); status.State != tunnel.ConnStateEstablished {
+					status.State = state
+				}
+
+				children, ok := sa.Get("child-sas").(*vici.Message)
+				if !ok {
+					continue
+				}
+				for _, key := range children.Keys() {
+					child, ok := children.Get(key).(*vici.Message)
+					if !ok {
+						continue
+					}
+
+					status.BytesIn += getUint64(child, "bytes-in")
+					status.BytesOut += getUint64(child, "bytes-out")
+					status.PacketsIn += getUint64(child, "packets-in")
+					status.PacketsOut += getUint64(child, "packets-out")
+				}
+			}
+		}
+		return nil
+	})
+
+	statuses := make([]tunnel.ConnStatus, 0, len(names))
+	for _, name := range names {
+		statuses = append(statuses, *statusByName[name])
+	}
+
+	return statuses, err
+}
+
 func (m StrongSwanManager) initiateChildSA(child string) error {
 	return m.do(func(session *vici.Session) error {
 		msg := vici.NewMessage()
@@ -288,11 +401,305 @@ func (m StrongSwanManager) IsActive() (bool, error) {
 }
 
 func (m StrongSwanManager) LoadConn(cnf tunnel.ConnConfig) error {
-	certs, err := m.getCerts(cnf.LocalCerts)
+	conn, certs, err := m.buildConnection(cnf)
 	if err != nil {
 		return err
 	}
 
+	oldConn, found := m.getConnection(cnf.Name)
+	if found {
+		if reflect.DeepEqual(cnf, oldConn) {
+			return nil
+		}
+
+		// when only pre-shared key is rotated, replacing the shared key is enough,
+		// existing SAs are kept and new key will be used in next authentication
+		if isOnlyPSKChanged(cnf, oldConn) {
+			if err := m.loadSharedKey(cnf); err != nil {
+				return err
+			}
+			m.rememberConn(cnf)
+			return nil
+		}
+
+		// we call UnloadConn to remove old Connection in strongswan, but if it failed, we ignore it
+		// because the failure won't cause trouble for loadConn
+		_ = m.UnloadConn(cnf.Name)
+	}
+
+	if conn.LocalAuth.AuthMethod == tunnel.AuthPSK {
+		if err := m.loadSharedKey(cnf); err != nil {
+			return err
+		}
+	}
+
+	err = m.loadConn(cnf.Name, conn)
+	if err == nil {
+		m.rememberConn(cnf)
+		m.rememberCerts(cnf.LocalCerts, certs)
+	}
+	return err
+}
+
+// ReloadCerts loads certificates changed since they were loaded by load-cert, then replaces
+// connections using them. Connections are replaced in place, so established SAs are kept
+// and new certificates are used in next authentication.
+// CA certificates in CA certs directory are loaded too, so certificates issued by a new CA
+// can be trusted when CA is rotated. When a loaded CA certificate is removed from the
+// directory, e.g. the previous CA is retired, credentials loaded by vici are cleared and
+// current ones are loaded again, so charon stops trusting the removed CA certificate.
+// CRLs in CRLs directory are loaded too, they are enforced in next authentication.
+func (m StrongSwanManager) ReloadCerts() error {
+	caCerts, err := m.readCACerts()
+	if err != nil {
+		return err
+	}
+
+	crls, err := m.readCRLs()
+	if err != nil {
+		return err
+	}
+
+	if m.isAnyCACertRemoved(caCerts) {
+		return m.reloadCreds(caCerts, crls)
+	}
+
+	if err = m.loadCACerts(caCerts); err != nil {
+		return err
+	}
+
+	if err = m.loadCRLs(crls); err != nil {
+		return err
+	}
+
+	changed := make(map[string]string)
+	for filename, oldCert := range m.getLoadedCerts() {
+		cert, err := m.getCert(filename)
+		if err != nil {
+			return err
+		}
+
+		if cert != oldCert {
+			changed[filename] = cert
+		}
+	}
+
+	for _, cert := range changed {
+		if err := m.loadCert(cert); err != nil {
+			return err
+		}
+	}
+
+	for _, cnf := range m.getConnections() {
+		if !usesAnyCert(cnf, changed) {
+			continue
+		}
+
+		conn, _, err := m.buildConnection(cnf)
+		if err != nil {
+			return err
+		}
+
+		if err = m.loadConn(cnf.Name, conn); err != nil {
+			return err
+		}
+	}
+
+	for filename, cert := range changed {
+		m.rememberCerts([]string{filename}, []string{cert})
+	}
+
+	return nil
+}
+
+// reloadCreds clears credentials loaded by vici, then loads CA certificates, CRLs,
+// certificates and shared keys in use again. Connections are replaced in place, so
+// established SAs are kept. Private keys are not loaded by vici, they are not cleared
+func (m StrongSwanManager) reloadCreds(caCerts, crls []string) error {
+	err := m.do(func(session *vici.Session) error {
+		_, err := session.CommandRequest("clear-creds", vici.NewMessage())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	m.forgetCACertsAndCRLs()
+
+	if err = m.loadCACerts(caCerts); err != nil {
+		return err
+	}
+
+	if err = m.loadCRLs(crls); err != nil {
+		return err
+	}
+
+	for _, cnf := range m.getConnections() {
+		conn, certs, err := m.buildConnection(cnf)
+		if err != nil {
+			return err
+		}
+
+		if conn.LocalAuth.AuthMethod == tunnel.AuthPSK {
+			if err = m.loadSharedKey(cnf); err != nil {
+				return err
+			}
+		}
+
+		for _, cert := range certs {
+			if err = m.loadCert(cert); err != nil {
+				return err
+			}
+		}
+
+		if err = m.loadConn(cnf.Name, conn); err != nil {
+			return err
+		}
+		m.rememberCerts(cnf.LocalCerts, certs)
+	}
+
+	return nil
+}
+
+func (m StrongSwanManager) loadCert(cert string) error {
+	return m.doLoadCert(cert, "NONE")
+}
+
+// loadCACerts loads CA certificates which are not loaded yet
+func (m StrongSwanManager) loadCACerts(caCerts []string) error {
+	for _, cert := range caCerts {
+		if m.isCACertLoaded(cert) {
+			continue
+		}
+
+		if err := m.doLoadCert(cert, "CA"); err != nil {
+			return err
+		}
+		m.rememberCACert(cert)
+	}
+
+	return nil
+}
+
+// loadCRLs loads CRLs which are not loaded yet
+func (m StrongSwanManager) loadCRLs(crls []string) error {
+	for _, crl := range crls {
+		if m.isCRLLoaded(crl) {
+			continue
+		}
+
+		if err := m.loadCRL(crl); err != nil {
+			return err
+		}
+		m.rememberCRL(crl)
+	}
+
+	return nil
+}
+
+// readCACerts returns CA certificates in CA certs directory, a file may contain
+// multiple CA certificates
+func (m StrongSwanManager) readCACerts() ([]string, error) {
+	return readPEMBlocks(m.caCertsPath, "CERTIFICATE", false)
+}
+
+// readCRLs returns CRLs in CRLs directory, hidden files are skipped because they
+// are symlinks created by kubelet for configmap volumes
+func (m StrongSwanManager) readCRLs() ([]string, error) {
+	if m.crlsPath == "" {
+		return nil, nil
+	}
+
+	return readPEMBlocks(m.crlsPath, "X509 CRL", true)
+}
+
+// readPEMBlocks returns PEM blocks of specified type in files of dir, nothing is
+// returned if dir doesn't exist
+func readPEMBlocks(dir, blockType string, skipHidden bool) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var blocks []string
+	for _, file := range files {
+		if file.IsDir() || (skipHidden && strings.HasPrefix(file.Name(), ".")) {
+			continue
+		}
+
+		raw, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		for block, rest := pem.Decode(raw); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != blockType {
+				continue
+			}
+
+			blocks = append(blocks, string(pem.EncodeToMemory(block)))
+		}
+	}
+
+	return blocks, nil
+}
+
+func (m StrongSwanManager) loadCRL(crl string) error {
+	return m.do(func(session *vici.Session) error {
+		msg := vici.NewMessage()
+		_ = msg.Set("type", "X509_CRL")
+		_ = msg.Set("data", crl)
+
+		_, err := session.CommandRequest("load-cert", msg)
+		return err
+	})
+}
+
+func (m StrongSwanManager) doLoadCert(cert, flag string) error {
+	return m.do(func(session *vici.Session) error {
+		msg := vici.NewMessage()
+		_ = msg.Set("type", "X509")
+		_ = msg.Set("flag", flag)
+		_ = msg.Set("data", cert)
+
+		_, err := session.CommandRequest("load-cert", msg)
+		return err
+	})
+}
+
+func usesAnyCert(cnf tunnel.ConnConfig, certs map[string]string) bool {
+	if cnf.AuthMethod == tunnel.AuthPSK {
+		return false
+	}
+
+	for _, filename := range cnf.LocalCerts {
+		if _, ok := certs[filename]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// buildConnection translates connection config to vici connection, certificates
+// used by the connection are returned too
+func (m StrongSwanManager) buildConnection(cnf tunnel.ConnConfig) (connection, []string, error) {
+	authMethod := tunnel.AuthPubKey
+	if cnf.AuthMethod == tunnel.AuthPSK {
+		authMethod = tunnel.AuthPSK
+	}
+
+	var certs []string
+	if authMethod == tunnel.AuthPubKey {
+		var err error
+		certs, err = m.getCerts(cnf.LocalCerts)
+		if err != nil {
+			return connection{}, nil, err
+		}
+	}
+
 	conn := connection{
 		LocalAddrs:  cnf.LocalAddress,
 		RemoteAddrs: cnf.RemoteAddress,
@@ -300,17 +707,19 @@ func (m StrongSwanManager) LoadConn(cnf tunnel.ConnConfig) error {
 		IF_ID_OUT:   m.interfaceID,
 		LocalAuth: authConf{
 			ID:         cnf.LocalID,
-			AuthMethod: "pubkey",
+			AuthMethod: authMethod,
 			Certs:      certs,
 		},
 
-		DpdDelay: m.dpdDelay,
+		DpdDelay:  m.dpdDelay,
+		Proposals: cnf.IKEProposals,
+		RekeyTime: formatSeconds(cnf.IKERekeyTime),
 	}
 
 	if cnf.RemoteID != "" {
 		conn.RemoteAuth = authConf{
 			ID:         cnf.RemoteID,
-			AuthMethod: "pubkey",
+			AuthMethod: authMethod,
 		}
 	}
 
@@ -333,22 +742,31 @@ func (m StrongSwanManager) LoadConn(cnf tunnel.ConnConfig) error {
 
 		conn.Children = map[string]childSAConf{
 			fmt.Sprintf("%s-p2p", cnf.Name): {
-				LocalTS:     cnf.LocalSubnets,
-				RemoteTS:    cnf.RemoteSubnets,
-				StartAction: m.startAction,
-				DpdAction:   m.dpdAction,
+				LocalTS:      cnf.LocalSubnets,
+				RemoteTS:     cnf.RemoteSubnets,
+				StartAction:  m.startAction,
+				DpdAction:    m.dpdAction,
+				ESPProposals: cnf.ESPProposals,
+				RekeyTime:    formatSeconds(cnf.RekeyTime),
+				LifeTime:     formatSeconds(cnf.LifeTime),
 			},
 			fmt.Sprintf("%s-n2p", cnf.Name): {
-				LocalTS:     cnf.LocalNodeSubnets,
-				RemoteTS:    cnf.RemoteSubnets,
-				StartAction: m.startAction,
-				DpdAction:   m.dpdAction,
+				LocalTS:      cnf.LocalNodeSubnets,
+				RemoteTS:     cnf.RemoteSubnets,
+				StartAction:  m.startAction,
+				DpdAction:    m.dpdAction,
+				ESPProposals: cnf.ESPProposals,
+				RekeyTime:    formatSeconds(cnf.RekeyTime),
+				LifeTime:     formatSeconds(cnf.LifeTime),
 			},
 			fmt.Sprintf("%s-p2n", cnf.Name): {
-				LocalTS:     cnf.LocalSubnets,
-				RemoteTS:    cnf.RemoteNodeSubnets,
-				StartAction: m.startAction,
-				DpdAction:   m.dpdAction,
+				LocalTS:      cnf.LocalSubnets,
+				RemoteTS:     cnf.RemoteNodeSubnets,
+				StartAction:  m.startAction,
+				DpdAction:    m.dpdAction,
+				ESPProposals: cnf.ESPProposals,
+				RekeyTime:    formatSeconds(cnf.RekeyTime),
+				LifeTime:     formatSeconds(cnf.LifeTime),
 			},
 		}
 	}
@@ -363,22 +781,37 @@ func (m StrongSwanManager) LoadConn(cnf tunnel.ConnConfig) error {
 		conn.LocalPort = &localPort
 	}
 
-	oldConn, found := m.getConnection(cnf.Name)
-	if found {
-		if reflect.DeepEqual(cnf, oldConn) {
-			return nil
-		}
+	return conn, certs, nil
+}
 
-		// we call UnloadConn to remove old Connection in strongswan, but if it failed, we ignore it
-		// because the failure won't cause trouble for loadConn
-		_ = m.UnloadConn(cnf.Name)
+// loadSharedKey loads pre-shared key of connection, the key is owned by
+// local ID and remote ID, so it won't be used by other connections
+func (m StrongSwanManager) loadSharedKey(cnf tunnel.ConnConfig) error {
+	owners := []string{cnf.LocalID}
+	if cnf.RemoteID != "" {
+		owners = append(owners, cnf.RemoteID)
 	}
 
-	err = m.loadConn(cnf.Name, conn)
-	if err == nil {
-		m.rememberConn(cnf)
-	}
-	return err
+	return m.do(func(session *vici.Session) error {
+		msg := vici.NewMessage()
+		_ = msg.Set("id", getSharedKeyID(cnf.Name))
+		_ = msg.Set("type", "IKE")
+		_ = msg.Set("data", string(cnf.PSK))
+		_ = msg.Set("owners", owners)
+
+		_, err := session.CommandRequest("load-shared", msg)
+		return err
+	})
+}
+
+func (m StrongSwanManager) unloadSharedKey(name string) error {
+	return m.do(func(session *vici.Session) error {
+		msg := vici.NewMessage()
+		_ = msg.Set("id", getSharedKeyID(name))
+
+		_, err := session.CommandRequest("unload-shared", msg)
+		return err
+	})
 }
 
 func (m StrongSwanManager) loadConn(name string, conn connection) error {
@@ -407,8 +840,14 @@ func (m StrongSwanManager) terminateSA(name string) error {
 }
 
 func (m StrongSwanManager) UnloadConn(name string) error {
+	oldConn, found := m.getConnection(name)
 	m.forgetConn(name)
 
+	if found && oldConn.AuthMethod == tunnel.AuthPSK {
+		// failure of unloading shared key won't affect other connections, so just ignore it
+		_ = m.unloadSharedKey(name)
+	}
+
 	err := m.do(func(session *vici.Session) error {
 		msg := vici.NewMessage()
 		_ = msg.Set("name", name)
@@ -505,3 +944,108 @@ func (m StrongSwanManager) getConnection(name string) (tunnel.ConnConfig, bool)
 	conn, found := m.connectionByName[name]
 	return conn, found
 }
+
+func (m StrongSwanManager) getConnections() []tunnel.ConnConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	conns := make([]tunnel.ConnConfig, 0, len(m.connectionByName))
+	for _, conn := range m.connectionByName {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+func (m StrongSwanManager) rememberCerts(filenames, certs []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range certs {
+		m.certByFilename[filenames[i]] = certs[i]
+	}
+}
+
+func (m StrongSwanManager) getLoadedCerts() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	certs := make(map[string]string, len(m.certByFilename))
+	for filename, cert := range m.certByFilename {
+		certs[filename] = cert
+	}
+	return certs
+}
+
+func (m StrongSwanManager) isCACertLoaded(cert string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.loadedCACerts.Has(cert)
+}
+
+func (m StrongSwanManager) rememberCACert(cert string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.loadedCACerts.Insert(cert)
+}
+
+// isAnyCACertRemoved checks if any loaded CA certificate is not in caCerts
+func (m StrongSwanManager) isAnyCACertRemoved(caCerts []string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return !sets.NewString(caCerts...).IsSuperset(m.loadedCACerts)
+}
+
+func (m StrongSwanManager) forgetCACertsAndCRLs() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// loadedCACerts and loadedCRLs are shared by copies of manager, they are cleared in place
+	m.loadedCACerts.Delete(m.loadedCACerts.UnsortedList()...)
+	m.loadedCRLs.Delete(m.loadedCRLs.UnsortedList()...)
+}
+
+func (m StrongSwanManager) isCRLLoaded(crl string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.loadedCRLs.Has(crl)
+}
+
+func (m StrongSwanManager) rememberCRL(crl string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.loadedCRLs.Insert(crl)
+}
+
+// formatSeconds returns time value used by swanctl, empty string
+// will be returned if seconds is not positive
+func formatSeconds(seconds int64) string {
+	if seconds <= 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%ds", seconds)
+}
+
+func getSharedKeyID(name string) string {
+	return fmt.Sprintf("psk-%s", name)
+}
+
+func isOnlyPSKChanged(cnf, oldConn tunnel.ConnConfig) bool {
+	if cnf.AuthMethod != tunnel.AuthPSK || oldConn.AuthMethod != tunnel.AuthPSK {
+		return false
+	}
+
+	oldConn.PSK = cnf.PSK
+	return reflect.DeepEqual(cnf, oldConn)
+}
+
+func getUint64(msg *vici.Message, key string) uint64 {
+	value, _ := msg.Get(key).(string)
+	n, _ := strconv.ParseUint(value, 10, 64)
+	return n
+}
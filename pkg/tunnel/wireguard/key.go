@@ -0,0 +1,61 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+const keyLen = 32
+
+// GenerateKey generates a WireGuard private key, the key is base64 encoded
+// which is the same as the output of `wg genkey`
+func GenerateKey() (string, error) {
+	key := make([]byte, keyLen)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	// https://cr.yp.to/ecdh.html
+	key[0] &= 248
+	key[31] = (key[31] & 127) | 64
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// PublicKey computes the public key of a base64 encoded private key,
+// it works like `wg pubkey`
+func PublicKey(privateKey string) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(privateKey))
+	if err != nil {
+		return "", err
+	}
+
+	if len(key) != keyLen {
+		return "", fmt.Errorf("invalid wireguard key length: %d", len(key))
+	}
+
+	publicKey, err := curve25519.X25519(key, curve25519.Basepoint)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(publicKey), nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"encoding/base64"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Key", func() {
+	It("should generate clamped private keys and compute their public keys", func() {
+		privateKey, err := GenerateKey()
+		Expect(err).Should(BeNil())
+
+		key, err := base64.StdEncoding.DecodeString(privateKey)
+		Expect(err).Should(BeNil())
+		Expect(key).Should(HaveLen(keyLen))
+		Expect(key[0] & 7).Should(BeZero())
+		Expect(key[31] & 128).Should(BeZero())
+		Expect(key[31] & 64).ShouldNot(BeZero())
+
+		publicKey, err := PublicKey(privateKey + "\n")
+		Expect(err).Should(BeNil())
+		Expect(publicKey).ShouldNot(Equal(privateKey))
+
+		publicKey2, err := PublicKey(privateKey)
+		Expect(err).Should(BeNil())
+		Expect(publicKey2).Should(Equal(publicKey))
+
+		otherKey, err := GenerateKey()
+		Expect(err).Should(BeNil())
+		Expect(otherKey).ShouldNot(Equal(privateKey))
+	})
+
+	It("should reject invalid private keys", func() {
+		_, err := PublicKey("not base64")
+		Expect(err).Should(HaveOccurred())
+
+		_, err = PublicKey(base64.StdEncoding.EncodeToString([]byte("short")))
+		Expect(err).Should(HaveOccurred())
+	})
+})
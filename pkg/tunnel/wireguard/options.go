@@ -0,0 +1,65 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"time"
+
+	"k8s.io/utils/exec"
+)
+
+type Options []option
+type option func(manager *WireGuardManager)
+
+func InterfaceName(name string) option {
+	return func(m *WireGuardManager) {
+		m.interfaceName = name
+	}
+}
+
+func ListenPort(port uint) option {
+	return func(m *WireGuardManager) {
+		m.listenPort = port
+	}
+}
+
+// PrivateKeyFile set the path of private key file, if it's a relative path,
+// the file should be put under /etc/ipsec.d/private
+func PrivateKeyFile(path string) option {
+	return func(m *WireGuardManager) {
+		m.privateKeyFile = path
+	}
+}
+
+func MTU(mtu int) option {
+	return func(m *WireGuardManager) {
+		m.mtu = mtu
+	}
+}
+
+// PersistentKeepalive set the interval to send keepalive packets to peers,
+// it also makes a handshake initiated as soon as a peer is configured.
+// 0 means disable.
+func PersistentKeepalive(interval time.Duration) option {
+	return func(m *WireGuardManager) {
+		m.keepalive = interval
+	}
+}
+
+func Exec(exec exec.Interface) option {
+	return func(m *WireGuardManager) {
+		m.exec = exec
+	}
+}
@@ -0,0 +1,522 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/exec"
+
+	"github.com/fabedge/fabedge/pkg/tunnel"
+)
+
+var _ tunnel.Manager = &WireGuardManager{}
+
+const (
+	DefaultInterfaceName = "fabedge-wg0"
+	DefaultListenPort    = 51820
+
+	// a handshake is considered stale if it happened more than 3 minutes ago,
+	// by then wireguard will have given up the current session
+	handshakeTimeout = 180 * time.Second
+)
+
+// WireGuardManager implements tunnel.Manager with kernel wireguard.
+// Every connection is mapped to a wireguard peer, the remote subnets and
+// remote node subnets of a connection are used as the allowed IPs of that peer.
+// Wireguard has no concept of mediation, so mediation connections are ignored.
+type WireGuardManager struct {
+	interfaceName  string
+	listenPort     uint
+	privateKeyFile string
+	mtu            int
+	keepalive      time.Duration
+
+	exec exec.Interface
+
+	connectionByName map[string]tunnel.ConnConfig
+	mu               *sync.RWMutex
+}
+
+func New(opts ...option) (*WireGuardManager, error) {
+	manager := &WireGuardManager{
+		interfaceName:    DefaultInterfaceName,
+		listenPort:       DefaultListenPort,
+		privateKeyFile:   filepath.Join("/etc/ipsec.d", "private", "wireguard.key"),
+		mtu:              1420,
+		keepalive:        25 * time.Second,
+		exec:             exec.New(),
+		connectionByName: make(map[string]tunnel.ConnConfig),
+		mu:               &sync.RWMutex{},
+	}
+
+	for _, opt := range opts {
+		opt(manager)
+	}
+
+	if err := manager.ensureInterface(); err != nil {
+		return nil, err
+	}
+
+	// connections are kept in memory, peers left by last run are unknown
+	// to manager, so we have to remove them and start from a clean state
+	if err := manager.removeAllPeers(); err != nil {
+		return nil, err
+	}
+
+	return manager, nil
+}
+
+func (m *WireGuardManager) IsRunning() bool {
+	if _, err := netlink.LinkByName(m.interfaceName); err != nil {
+		return false
+	}
+
+	_, err := m.wg("show", m.interfaceName, "public-key")
+	return err == nil
+}
+
+func (m *WireGuardManager) ListConnNames() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.connectionByName))
+	for name := range m.connectionByName {
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+func (m *WireGuardManager) LoadConn(cnf tunnel.ConnConfig) error {
+	// mediation connections are only remembered, so they
+	// can be listed, initiated and unloaded like other connections
+	if cnf.Mediation {
+		m.rememberConn(cnf)
+		return nil
+	}
+
+	if cnf.RemotePublicKey == "" {
+		return fmt.Errorf("public key of connection %s is not provided", cnf.Name)
+	}
+
+	oldConn, found := m.getConnection(cnf.Name)
+	if found {
+		if reflect.DeepEqual(cnf, oldConn) {
+			return nil
+		}
+
+		if !oldConn.Mediation && oldConn.RemotePublicKey != cnf.RemotePublicKey {
+			if err := m.removePeer(oldConn.RemotePublicKey); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := m.ensureInterface(); err != nil {
+		return err
+	}
+
+	args := []string{
+		"set", m.interfaceName,
+		"peer", cnf.RemotePublicKey,
+		"allowed-ips", strings.Join(getAllowedIPs(cnf), ","),
+	}
+
+	if endpoint := m.getEndpoint(cnf); endpoint != "" {
+		args = append(args, "endpoint", endpoint)
+	}
+
+	if m.keepalive > 0 {
+		args = append(args, "persistent-keepalive", strconv.Itoa(int(m.keepalive.Seconds())))
+	}
+
+	if _, err := m.wg(args...); err != nil {
+		return err
+	}
+
+	m.rememberConn(cnf)
+	return m.syncRoutes()
+}
+
+// InitiateConn does nothing but check if connection exists, because wireguard
+// initiates handshakes on demand and persistent keepalive will trigger one immediately
+func (m *WireGuardManager) InitiateConn(name string) error {
+	if _, found := m.getConnection(name); !found {
+		return fmt.Errorf("connection %s not found", name)
+	}
+
+	return nil
+}
+
+func (m *WireGuardManager) UnloadConn(name string) error {
+	conn, found := m.getConnection(name)
+	if !found {
+		return nil
+	}
+
+	if conn.Mediation {
+		m.forgetConn(name)
+		return nil
+	}
+
+	if err := m.removePeer(conn.RemotePublicKey); err != nil {
+		return err
+	}
+
+	m.forgetConn(name)
+	return m.syncRoutes()
+}
+
+// IsActive returns true if any peer has a recent handshake
+func (m *WireGuardManager) IsActive() (bool, error) {
+	handshakes, err := m.getLatestHandshakes()
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	for _, t := range handshakes {
+		if now.Sub(t) < handshakeTimeout {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ListEstablishedConnNames returns names of connections whose peers have recent handshakes
+func (m *WireGuardManager) ListEstablishedConnNames() ([]string, error) {
+	handshakes, err := m.getLatestHandshakes()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	var names []string
+	for name, conn := range m.connectionByName {
+		if conn.Mediation {
+			continue
+		}
+
+		t, found := handshakes[conn.RemotePublicKey]
+		if found && now.Sub(t) < handshakeTimeout {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+// ListConnStatuses returns states and traffic of connections, wireguard doesn't
+// count packets, so only bytes are reported
+func (m *WireGuardManager) ListConnStatuses() ([]tunnel.ConnStatus, error) {
+	handshakes, err := m.getLatestHandshakes()
+	if err != nil {
+		return nil, err
+	}
+
+	transfers, err := m.getTransfers()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	var statuses []tunnel.ConnStatus
+	for name, conn := range m.connectionByName {
+		if conn.Mediation {
+			continue
+		}
+
+		status := tunnel.ConnStatus{
+			Name:  name,
+			State: tunnel.ConnStateDown,
+		}
+
+		if t, found := handshakes[conn.RemotePublicKey]; found && now.Sub(t) < handshakeTimeout {
+			status.State = tunnel.ConnStateEstablished
+		}
+
+		if transfer, found := transfers[conn.RemotePublicKey]; found {
+			status.BytesIn, status.BytesOut = transfer[0], transfer[1]
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// getTransfers returns received and sent bytes of every peer
+func (m *WireGuardManager) getTransfers() (map[string][2]uint64, error) {
+	out, err := m.wg("show", m.interfaceName, "transfer")
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := make(map[string][2]uint64)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+
+		received, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		sent, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		transfers[fields[0]] = [2]uint64{received, sent}
+	}
+
+	return transfers, nil
+}
+
+// getLatestHandshakes returns the time of latest handshake of every peer,
+// peers which never finished a handshake are not included
+func (m *WireGuardManager) getLatestHandshakes() (map[string]time.Time, error) {
+	out, err := m.wg("show", m.interfaceName, "latest-handshakes")
+	if err != nil {
+		return nil, err
+	}
+
+	handshakes := make(map[string]time.Time)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		timestamp, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || timestamp == 0 {
+			continue
+		}
+
+		handshakes[fields[0]] = time.Unix(timestamp, 0)
+	}
+
+	return handshakes, nil
+}
+
+func (m *WireGuardManager) ensureInterface() error {
+	link, err := netlink.LinkByName(m.interfaceName)
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); !ok {
+			return err
+		}
+
+		link = &netlink.GenericLink{
+			LinkAttrs: netlink.LinkAttrs{
+				Name: m.interfaceName,
+				MTU:  m.mtu,
+			},
+			LinkType: "wireguard",
+		}
+		if err = netlink.LinkAdd(link); err != nil {
+			return fmt.Errorf("failed to create wireguard interface %s: %w", m.interfaceName, err)
+		}
+
+		if link, err = netlink.LinkByName(m.interfaceName); err != nil {
+			return err
+		}
+	}
+
+	_, err = m.wg("set", m.interfaceName,
+		"listen-port", strconv.Itoa(int(m.listenPort)),
+		"private-key", m.getPrivateKeyFile(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return netlink.LinkSetUp(link)
+}
+
+func (m *WireGuardManager) removeAllPeers() error {
+	out, err := m.wg("show", m.interfaceName, "peers")
+	if err != nil {
+		return err
+	}
+
+	var errors []error
+	for _, key := range strings.Fields(string(out)) {
+		if err = m.removePeer(key); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(errors)
+}
+
+func (m *WireGuardManager) removePeer(publicKey string) error {
+	_, err := m.wg("set", m.interfaceName, "peer", publicKey, "remove")
+	return err
+}
+
+// syncRoutes makes sure remote subnets of every connection are routed to wireguard
+// interface and removes those routes which are not needed anymore. Remote node subnets
+// are not routed, because they are usually the addresses of peers too, routing them
+// to wireguard interface would make encrypted packets to peers come back.
+func (m *WireGuardManager) syncRoutes() error {
+	link, err := netlink.LinkByName(m.interfaceName)
+	if err != nil {
+		return err
+	}
+
+	dstSet := sets.NewString()
+	m.mu.RLock()
+	for _, conn := range m.connectionByName {
+		dstSet.Insert(conn.RemoteSubnets...)
+	}
+	m.mu.RUnlock()
+
+	routes, err := netlink.RouteList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return err
+	}
+
+	var errors []error
+	for _, route := range routes {
+		if route.Dst == nil || dstSet.Has(route.Dst.String()) {
+			continue
+		}
+
+		if err = netlink.RouteDel(&route); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	for _, dst := range dstSet.List() {
+		ipNet, err := netlink.ParseIPNet(dst)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+
+		err = netlink.RouteReplace(&netlink.Route{
+			LinkIndex: link.Attrs().Index,
+			Dst:       ipNet,
+			Scope:     netlink.SCOPE_LINK,
+		})
+		if err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	return utilerrors.NewAggregate(errors)
+}
+
+func (m *WireGuardManager) getEndpoint(cnf tunnel.ConnConfig) string {
+	if len(cnf.RemoteAddress) == 0 {
+		return ""
+	}
+
+	port := m.listenPort
+	if cnf.RemotePort != nil {
+		port = *cnf.RemotePort
+	}
+
+	return net.JoinHostPort(cnf.RemoteAddress[0], strconv.Itoa(int(port)))
+}
+
+func (m *WireGuardManager) getPrivateKeyFile() string {
+	if strings.HasPrefix(m.privateKeyFile, "/") {
+		return m.privateKeyFile
+	}
+
+	return filepath.Join("/etc/ipsec.d", "private", m.privateKeyFile)
+}
+
+func (m *WireGuardManager) wg(args ...string) ([]byte, error) {
+	out, err := m.exec.Command("wg", args...).CombinedOutput()
+	if err != nil {
+		return out, fmt.Errorf("failed to execute wg %s: %s, %w", strings.Join(args, " "), strings.TrimSpace(string(out)), err)
+	}
+
+	return out, nil
+}
+
+func (m *WireGuardManager) rememberConn(conn tunnel.ConnConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.connectionByName[conn.Name] = conn
+}
+
+func (m *WireGuardManager) forgetConn(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.connectionByName, name)
+}
+
+func (m *WireGuardManager) getConnection(name string) (tunnel.ConnConfig, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	conn, found := m.connectionByName[name]
+	return conn, found
+}
+
+// getAllowedIPs returns the CIDRs from which traffic of peer is accepted
+func getAllowedIPs(cnf tunnel.ConnConfig) []string {
+	ips := make([]string, 0, len(cnf.RemoteSubnets)+len(cnf.RemoteNodeSubnets))
+	ips = append(ips, cnf.RemoteSubnets...)
+	for _, subnet := range cnf.RemoteNodeSubnets {
+		ips = append(ips, toCIDR(subnet))
+	}
+
+	return ips
+}
+
+// toCIDR converts an IP address into a host CIDR, node subnets
+// are usually IP addresses instead of CIDRs
+func toCIDR(addr string) string {
+	if strings.Contains(addr, "/") {
+		return addr
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return addr
+	}
+
+	if ip.To4() != nil {
+		return fmt.Sprintf("%s/32", ip)
+	}
+
+	return fmt.Sprintf("%s/128", ip)
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+
+	"github.com/fabedge/fabedge/pkg/tunnel"
+)
+
+var _ = Describe("WireGuardManager", func() {
+	var (
+		fakeExec *testingexec.FakeExec
+		commands []string
+		manager  *WireGuardManager
+	)
+
+	// expectWG makes next wg command output out
+	expectWG := func(out string) {
+		fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+			commands = append(commands, strings.Join(append([]string{cmd}, args...), " "))
+			return testingexec.InitFakeCmd(&testingexec.FakeCmd{
+				CombinedOutputScript: []testingexec.FakeAction{
+					func() ([]byte, []byte, error) { return []byte(out), nil, nil },
+				},
+			}, cmd, args...)
+		})
+	}
+
+	BeforeEach(func() {
+		fakeExec, commands = &testingexec.FakeExec{}, nil
+		// New is not used because it needs to create wireguard interface
+		manager = &WireGuardManager{
+			interfaceName:    DefaultInterfaceName,
+			listenPort:       DefaultListenPort,
+			privateKeyFile:   "wireguard.key",
+			exec:             fakeExec,
+			connectionByName: make(map[string]tunnel.ConnConfig),
+			mu:               &sync.RWMutex{},
+		}
+	})
+
+	It("should reject connections without public key", func() {
+		err := manager.LoadConn(tunnel.ConnConfig{Name: "edge1", RemoteAddress: []string{"10.10.10.1"}})
+		Expect(err).Should(HaveOccurred())
+
+		names, _ := manager.ListConnNames()
+		Expect(names).Should(BeEmpty())
+	})
+
+	It("should only remember mediation connections", func() {
+		Expect(manager.LoadConn(tunnel.ConnConfig{Name: "mediator", Mediation: true})).Should(Succeed())
+		Expect(manager.InitiateConn("mediator")).Should(Succeed())
+		Expect(manager.ListConnNames()).Should(ConsistOf("mediator"))
+
+		Expect(manager.UnloadConn("mediator")).Should(Succeed())
+		Expect(manager.ListConnNames()).Should(BeEmpty())
+		Expect(manager.InitiateConn("mediator")).Should(HaveOccurred())
+		Expect(commands).Should(BeEmpty())
+	})
+
+	It("should report connections whose peers have recent handshakes as established", func() {
+		manager.rememberConn(tunnel.ConnConfig{Name: "edge1", RemotePublicKey: "key1"})
+		manager.rememberConn(tunnel.ConnConfig{Name: "edge2", RemotePublicKey: "key2"})
+		manager.rememberConn(tunnel.ConnConfig{Name: "edge3", RemotePublicKey: "key3"})
+		manager.rememberConn(tunnel.ConnConfig{Name: "mediator", Mediation: true})
+
+		now := time.Now()
+		handshakes := fmt.Sprintf("key1\t%d\nkey2\t%d\nkey3\t0\n", now.Unix(), now.Add(-handshakeTimeout-time.Minute).Unix())
+
+		expectWG(handshakes)
+		Expect(manager.ListEstablishedConnNames()).Should(ConsistOf("edge1"))
+
+		expectWG(handshakes)
+		Expect(manager.IsActive()).Should(BeTrue())
+
+		expectWG(handshakes)
+		expectWG("key1\t100\t200\nkey3\t300\t400\n")
+		Expect(manager.ListConnStatuses()).Should(ConsistOf(
+			tunnel.ConnStatus{Name: "edge1", State: tunnel.ConnStateEstablished, BytesIn: 100, BytesOut: 200},
+			tunnel.ConnStatus{Name: "edge2", State: tunnel.ConnStateDown},
+			tunnel.ConnStatus{Name: "edge3", State: tunnel.ConnStateDown, BytesIn: 300, BytesOut: 400},
+		))
+
+		Expect(commands).Should(Equal([]string{
+			"wg show fabedge-wg0 latest-handshakes",
+			"wg show fabedge-wg0 latest-handshakes",
+			"wg show fabedge-wg0 latest-handshakes",
+			"wg show fabedge-wg0 transfer",
+		}))
+	})
+
+	It("should not be active if no peer has a recent handshake", func() {
+		expectWG("key1\t0\n")
+		Expect(manager.IsActive()).Should(BeFalse())
+	})
+
+	It("should use remote subnets and node subnets as allowed IPs", func() {
+		ips := getAllowedIPs(tunnel.ConnConfig{
+			RemoteSubnets:     []string{"2.2.2.0/24", "fd00::/64"},
+			RemoteNodeSubnets: []string{"10.10.10.1", "10.10.20.0/24", "fd01::1"},
+		})
+		Expect(ips).Should(Equal([]string{"2.2.2.0/24", "fd00::/64", "10.10.10.1/32", "10.10.20.0/24", "fd01::1/128"}))
+	})
+
+	It("should build endpoint of peer with remote port or listen port", func() {
+		Expect(manager.getEndpoint(tunnel.ConnConfig{})).Should(BeEmpty())
+		Expect(manager.getEndpoint(tunnel.ConnConfig{RemoteAddress: []string{"10.10.10.1", "10.10.10.2"}})).Should(Equal("10.10.10.1:51820"))
+
+		port := uint(4500)
+		Expect(manager.getEndpoint(tunnel.ConnConfig{RemoteAddress: []string{"fd01::1"}, RemotePort: &port})).Should(Equal("[fd01::1]:4500"))
+	})
+
+	It("should put private key file under /etc/ipsec.d/private if its path is relative", func() {
+		Expect(manager.getPrivateKeyFile()).Should(Equal("/etc/ipsec.d/private/wireguard.key"))
+
+		manager.privateKeyFile = "/var/lib/fabedge/wireguard.key"
+		Expect(manager.getPrivateKeyFile()).Should(Equal("/var/lib/fabedge/wireguard.key"))
+	})
+})
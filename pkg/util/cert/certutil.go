@@ -15,8 +15,8 @@
 package cert
 
 import (
+	"crypto"
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
@@ -55,6 +55,8 @@ type Config struct {
 
 	ValidityPeriod time.Duration
 	IsCA           bool
+	// KeyAlgorithm is the algorithm of the private key to create, default: RSA
+	KeyAlgorithm KeyAlgorithm
 }
 
 type Request struct {
@@ -62,16 +64,18 @@ type Request struct {
 	Organization []string
 	DNSNames     []string
 	IPs          []net.IP
+	// KeyAlgorithm is the algorithm of the private key to create, default: RSA
+	KeyAlgorithm KeyAlgorithm
 }
 
 // NewSelfSignedCA create a CA cert/key pair
 func NewSelfSignedCA(cfg Config) ([]byte, []byte, error) {
-	caKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	caKey, err := GenerateKey(cfg.KeyAlgorithm, 4096)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	template, err := buildCertTemplate(cfg)
+	template, err := buildCertTemplate(cfg, caKey.Public(), caKey.Public())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -82,7 +86,11 @@ func NewSelfSignedCA(cfg Config) ([]byte, []byte, error) {
 		return nil, nil, err
 	}
 
-	caKeyDER := x509.MarshalPKCS1PrivateKey(caKey)
+	caKeyDER, err := MarshalPrivateKey(caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	return caDER, caKeyDER, nil
 }
 
@@ -92,23 +100,27 @@ func NewCertFromCA2(ca, caKey []byte, cfg Config) ([]byte, []byte, error) {
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to parse a caCert from the given ASN.1 DER data, err: %v", err)
 	}
-	caKeyRSA, err := x509.ParsePKCS1PrivateKey(caKey)
+	caSigner, err := ParsePrivateKey(caKey)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parses an RSA private key in PKCS #1, ASN.1 DER form, err: %v", err)
+		return nil, nil, err
 	}
 
-	return NewCertFromCA(caCert, caKeyRSA, cfg)
+	return NewCertFromCA(caCert, caSigner, cfg)
 }
 
 // NewCertFromCA creates certificate and key from specified CA cert/key pair
-func NewCertFromCA(caCert *x509.Certificate, caKey *rsa.PrivateKey, cfg Config) ([]byte, []byte, error) {
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+func NewCertFromCA(caCert *x509.Certificate, caKey crypto.Signer, cfg Config) ([]byte, []byte, error) {
+	privateKey, err := GenerateKey(cfg.KeyAlgorithm, 2048)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to generate a privateKey, err: %v", err)
 	}
-	privateKeyDER := x509.MarshalPKCS1PrivateKey(privateKey)
 
-	certTemplate, err := buildCertTemplate(cfg)
+	privateKeyDER, err := MarshalPrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certTemplate, err := buildCertTemplate(cfg, privateKey.Public(), caKey.Public())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -122,11 +134,36 @@ func NewCertFromCA(caCert *x509.Certificate, caKey *rsa.PrivateKey, cfg Config)
 }
 
 func NewCertRequest(req Request) ([]byte, []byte, error) {
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	privateKey, err := GenerateKey(req.KeyAlgorithm, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	csr, err := newCertRequest(privateKey, req)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	keyDER, err := MarshalPrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return keyDER, csr, nil
+}
+
+// NewCertRequestWithKey creates a cert request signed by an existing private key,
+// it's used to renew a certificate without changing its key
+func NewCertRequestWithKey(keyDER []byte, req Request) ([]byte, error) {
+	privateKey, err := ParsePrivateKey(keyDER)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCertRequest(privateKey, req)
+}
+
+func newCertRequest(privateKey crypto.Signer, req Request) ([]byte, error) {
 	template := &x509.CertificateRequest{
 		Subject: pkix.Name{
 			CommonName:   req.CommonName,
@@ -136,16 +173,34 @@ func NewCertRequest(req Request) ([]byte, []byte, error) {
 		IPAddresses: req.IPs,
 		DNSNames:    req.DNSNames,
 	}
-	csr, err := x509.CreateCertificateRequest(rand.Reader, template, privateKey)
-	if err != nil {
-		return nil, nil, err
+	return x509.CreateCertificateRequest(rand.Reader, template, privateKey)
+}
+
+// NeedRenewal checks if the fraction of certificate's lifetime has passed, a fraction
+// which is not in (0, 1) means certificates are renewed only when they are invalid
+func NeedRenewal(cert *x509.Certificate, fraction float64) bool {
+	renewalTime, ok := RenewalTime(cert, fraction)
+	if !ok {
+		return false
 	}
 
-	keyDER := x509.MarshalPKCS1PrivateKey(privateKey)
-	return keyDER, csr, nil
+	return !time.Now().Before(renewalTime)
 }
 
-func buildCertTemplate(cfg Config) (*x509.Certificate, error) {
+// RenewalTime returns the time when the fraction of certificate's lifetime has passed,
+// false is returned if fraction is not in (0, 1)
+func RenewalTime(cert *x509.Certificate, fraction float64) (time.Time, bool) {
+	if fraction <= 0 || fraction >= 1 {
+		return time.Time{}, false
+	}
+
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	return cert.NotBefore.Add(time.Duration(float64(lifetime) * fraction)), true
+}
+
+// buildCertTemplate creates a certificate template for publicKey, the signature
+// algorithm is decided by caPublicKey
+func buildCertTemplate(cfg Config, publicKey, caPublicKey crypto.PublicKey) (*x509.Certificate, error) {
 	serialNumber, err := rand.Int(rand.Reader, new(big.Int).SetInt64(math.MaxInt64))
 	if err != nil {
 		return nil, err
@@ -155,11 +210,6 @@ func buildCertTemplate(cfg Config) (*x509.Certificate, error) {
 		return nil, errors.New("must specify a CommonName")
 	}
 
-	keyUsage := x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature
-	if cfg.IsCA {
-		keyUsage |= x509.KeyUsageCertSign
-	}
-
 	template := x509.Certificate{
 		Subject: pkix.Name{
 			CommonName:   cfg.CommonName,
@@ -171,14 +221,13 @@ func buildCertTemplate(cfg Config) (*x509.Certificate, error) {
 		SerialNumber: serialNumber,
 		NotBefore:    time.Now().UTC(),
 		NotAfter:     time.Now().Add(cfg.ValidityPeriod),
-		KeyUsage:     keyUsage,
+		KeyUsage:     keyUsageOf(publicKey, cfg.IsCA),
 		ExtKeyUsage:  cfg.Usages,
 
 		BasicConstraintsValid: cfg.IsCA,
 		IsCA:                  cfg.IsCA,
 
-		PublicKeyAlgorithm: x509.RSA,
-		SignatureAlgorithm: x509.SHA384WithRSA,
+		SignatureAlgorithm: signatureAlgorithmOf(caPublicKey),
 	}
 	return &template, nil
 }
@@ -247,6 +296,33 @@ func DecodePEM(data []byte) ([]byte, error) {
 	return block.Bytes, nil
 }
 
+// DecodeCertsPEM decodes all certificates in PEM data, they are returned as DER bytes
+func DecodeCertsPEM(data []byte) ([][]byte, error) {
+	var certs [][]byte
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type == certutil.CertificateBlockType {
+			certs = append(certs, block.Bytes)
+		}
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found in pem data")
+	}
+
+	return certs, nil
+}
+
+// IsIssuedBy checks if cert is signed by ca
+func IsIssuedBy(cert, ca *x509.Certificate) bool {
+	return cert.CheckSignatureFrom(ca) == nil
+}
+
 func SaveFile(content []byte, filename string) error {
 	return ioutil.WriteFile(filename, content, os.FileMode(0644))
 }
@@ -255,8 +331,21 @@ func EncodeCertPEM(certDER []byte) []byte {
 	return pem.EncodeToMemory(&pem.Block{Type: certutil.CertificateBlockType, Bytes: certDER})
 }
 
+// EncodePrivateKeyPEM encodes private key in PEM form, the block type is decided
+// by the form of privateKeyDER: PKCS #1, SEC 1 or PKCS #8
 func EncodePrivateKeyPEM(privateKeyDER []byte) []byte {
-	return pem.EncodeToMemory(&pem.Block{Type: keyutil.RSAPrivateKeyBlockType, Bytes: privateKeyDER})
+	blockType := keyutil.PrivateKeyBlockType
+	if _, err := x509.ParsePKCS1PrivateKey(privateKeyDER); err == nil {
+		blockType = keyutil.RSAPrivateKeyBlockType
+	} else if _, err = x509.ParseECPrivateKey(privateKeyDER); err == nil {
+		blockType = keyutil.ECPrivateKeyBlockType
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: privateKeyDER})
+}
+
+func EncodeCRLPEM(crlDER []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crlDER})
 }
 
 func EncodeCertRequestPEM(crs []byte) []byte {
@@ -46,7 +46,7 @@ var _ = Describe("Certutil", func() {
 		Expect(caCert.IPAddresses).Should(Equal(caCfg.IPs))
 		Expect(caCert.BasicConstraintsValid).Should(BeTrue())
 		Expect(caCert.IsCA).Should(BeTrue())
-		Expect(caCert.KeyUsage).Should(Equal(x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign))
+		Expect(caCert.KeyUsage).Should(Equal(x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign))
 		Expect(caCert.ExtKeyUsage).Should(BeEmpty())
 	})
 
@@ -105,4 +105,49 @@ var _ = Describe("Certutil", func() {
 		Expect(cr.DNSNames).Should(Equal(req.DNSNames))
 		Expect(cr.PublicKey).Should(Equal(privateKey.Public()))
 	})
+
+	It("support create certificate request with an existing private key", func() {
+		req := certutil.Request{CommonName: "test"}
+		keyDER, _, err := certutil.NewCertRequest(req)
+		Expect(err).Should(BeNil())
+
+		csr, err := certutil.NewCertRequestWithKey(keyDER, req)
+		Expect(err).Should(BeNil())
+
+		privateKey, err := x509.ParsePKCS1PrivateKey(keyDER)
+		Expect(err).Should(BeNil())
+
+		cr, err := x509.ParseCertificateRequest(csr)
+		Expect(err).Should(BeNil())
+		Expect(cr.Subject.CommonName).Should(Equal(req.CommonName))
+		Expect(cr.PublicKey).Should(Equal(privateKey.Public()))
+	})
+
+	It("should tell if a certificate needs renewal by the fraction of its lifetime", func() {
+		now := time.Now()
+		cert := &x509.Certificate{
+			NotBefore: now.Add(-8 * time.Hour),
+			NotAfter:  now.Add(2 * time.Hour),
+		}
+
+		Expect(certutil.NeedRenewal(cert, 0.7)).Should(BeTrue())
+		Expect(certutil.NeedRenewal(cert, 0.9)).Should(BeFalse())
+		Expect(certutil.NeedRenewal(cert, 0)).Should(BeFalse())
+		Expect(certutil.NeedRenewal(cert, 1)).Should(BeFalse())
+	})
+
+	It("should compute the renewal time of a certificate by the fraction of its lifetime", func() {
+		now := time.Now()
+		cert := &x509.Certificate{
+			NotBefore: now.Add(-8 * time.Hour),
+			NotAfter:  now.Add(2 * time.Hour),
+		}
+
+		renewalTime, ok := certutil.RenewalTime(cert, 0.9)
+		Expect(ok).Should(BeTrue())
+		Expect(renewalTime).Should(BeTemporally("==", now.Add(time.Hour)))
+
+		_, ok = certutil.RenewalTime(cert, 0)
+		Expect(ok).Should(BeFalse())
+	})
 })
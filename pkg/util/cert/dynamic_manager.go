@@ -0,0 +1,91 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var _ Manager = &DynamicManager{}
+var _ CRLSigner = &DynamicManager{}
+
+// DynamicManager delegates to a manager which can be replaced at runtime, e.g.
+// when CA is rotated, so holders of DynamicManager don't need to be recreated
+type DynamicManager struct {
+	mu      sync.RWMutex
+	manager Manager
+}
+
+func NewDynamicManager(manager Manager) *DynamicManager {
+	return &DynamicManager{
+		manager: manager,
+	}
+}
+
+func (m *DynamicManager) SetManager(manager Manager) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.manager = manager
+}
+
+func (m *DynamicManager) getManager() Manager {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.manager
+}
+
+func (m *DynamicManager) NewCertKey(cfg Config) ([]byte, []byte, error) {
+	return m.getManager().NewCertKey(cfg)
+}
+
+func (m *DynamicManager) SignCert(csr []byte) ([]byte, error) {
+	return m.getManager().SignCert(csr)
+}
+
+func (m *DynamicManager) VerifyCert(cert *x509.Certificate, usages []x509.ExtKeyUsage) error {
+	return m.getManager().VerifyCert(cert, usages)
+}
+
+func (m *DynamicManager) VerifyCertInPEM(certPEM []byte, usages []x509.ExtKeyUsage) error {
+	return m.getManager().VerifyCertInPEM(certPEM, usages)
+}
+
+func (m *DynamicManager) GetCACert() *x509.Certificate {
+	return m.getManager().GetCACert()
+}
+
+func (m *DynamicManager) GetCACertPEM() []byte {
+	return m.getManager().GetCACertPEM()
+}
+
+func (m *DynamicManager) GetCABundlePEM() []byte {
+	return m.getManager().GetCABundlePEM()
+}
+
+// SignCRL signs a CRL if the current manager is able to
+func (m *DynamicManager) SignCRL(revoked []pkix.RevokedCertificate, validPeriod time.Duration) ([]byte, error) {
+	signer, ok := m.getManager().(CRLSigner)
+	if !ok {
+		return nil, fmt.Errorf("signing CRL is not supported by current cert manager")
+	}
+
+	return signer.SignCRL(revoked, validPeriod)
+}
@@ -0,0 +1,47 @@
+package cert_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+)
+
+var _ = Describe("DynamicManager", func() {
+	createManager := func() certutil.Manager {
+		caDER, keyDER, err := certutil.NewSelfSignedCA(certutil.Config{
+			CommonName:     certutil.DefaultCAName,
+			Organization:   []string{certutil.DefaultOrganization},
+			IsCA:           true,
+			ValidityPeriod: 24 * time.Hour,
+		})
+		Expect(err).Should(BeNil())
+
+		manager, err := certutil.NewManger(caDER, keyDER, 24*time.Hour)
+		Expect(err).Should(BeNil())
+
+		return manager
+	}
+
+	It("should delegate to the manager which is set last", func() {
+		oldManager, newManager := createManager(), createManager()
+
+		manager := certutil.NewDynamicManager(oldManager)
+		Expect(manager.GetCACert()).Should(Equal(oldManager.GetCACert()))
+		Expect(manager.GetCABundlePEM()).Should(Equal(oldManager.GetCABundlePEM()))
+
+		manager.SetManager(newManager)
+		Expect(manager.GetCACert()).Should(Equal(newManager.GetCACert()))
+		Expect(manager.GetCACertPEM()).Should(Equal(newManager.GetCACertPEM()))
+
+		certDER, _, err := manager.NewCertKey(certutil.Config{
+			CommonName:     "test",
+			ValidityPeriod: time.Hour,
+			Usages:         certutil.ExtKeyUsagesServerAndClient,
+		})
+		Expect(err).Should(BeNil())
+		Expect(newManager.VerifyCertInPEM(certutil.EncodeCertPEM(certDER), certutil.ExtKeyUsagesServerAndClient)).Should(Succeed())
+	})
+})
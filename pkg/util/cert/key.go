@@ -0,0 +1,144 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+)
+
+// KeyAlgorithm is the algorithm of private keys of CA and certificates
+type KeyAlgorithm string
+
+const (
+	KeyAlgorithmRSA KeyAlgorithm = "rsa"
+	// KeyAlgorithmECDSA uses P-256 curve
+	KeyAlgorithmECDSA   KeyAlgorithm = "ecdsa"
+	KeyAlgorithmEd25519 KeyAlgorithm = "ed25519"
+)
+
+// ParseKeyAlgorithm returns the key algorithm of name, an empty name means RSA
+func ParseKeyAlgorithm(name string) (KeyAlgorithm, error) {
+	switch algorithm := KeyAlgorithm(name); algorithm {
+	case "":
+		return KeyAlgorithmRSA, nil
+	case KeyAlgorithmRSA, KeyAlgorithmECDSA, KeyAlgorithmEd25519:
+		return algorithm, nil
+	default:
+		return "", fmt.Errorf("unknown key algorithm: %s", name)
+	}
+}
+
+// GenerateKey creates a private key of specified algorithm, rsaBits is only used for RSA
+func GenerateKey(algorithm KeyAlgorithm, rsaBits int) (crypto.Signer, error) {
+	switch algorithm {
+	case "", KeyAlgorithmRSA:
+		return rsa.GenerateKey(rand.Reader, rsaBits)
+	case KeyAlgorithmECDSA:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyAlgorithmEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unknown key algorithm: %s", algorithm)
+	}
+}
+
+// MarshalPrivateKey returns private key in DER form, RSA keys are in PKCS #1 form
+// to be compatible with existing keys, ECDSA keys are in SEC 1 form and Ed25519
+// keys are in PKCS #8 form
+func MarshalPrivateKey(key crypto.Signer) ([]byte, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return x509.MarshalPKCS1PrivateKey(k), nil
+	case *ecdsa.PrivateKey:
+		return x509.MarshalECPrivateKey(k)
+	}
+
+	return x509.MarshalPKCS8PrivateKey(key)
+}
+
+// ParsePrivateKey parses private key in PKCS #1, PKCS #8 or SEC 1 DER form
+func ParsePrivateKey(keyDER []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(keyDER); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(keyDER); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(keyDER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type: %T", key)
+	}
+
+	return signer, nil
+}
+
+// signatureAlgorithmOf returns the signature algorithm used by CA with the public key
+func signatureAlgorithmOf(caPublicKey crypto.PublicKey) x509.SignatureAlgorithm {
+	switch caPublicKey.(type) {
+	case *rsa.PublicKey:
+		return x509.SHA384WithRSA
+	case *ecdsa.PublicKey:
+		return x509.ECDSAWithSHA256
+	case ed25519.PublicKey:
+		return x509.PureEd25519
+	default:
+		return x509.UnknownSignatureAlgorithm
+	}
+}
+
+// keyUsageOf returns key usage of certificate with the public key, only RSA keys are
+// used for key encipherment
+func keyUsageOf(publicKey crypto.PublicKey, isCA bool) x509.KeyUsage {
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := publicKey.(*rsa.PublicKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	if isCA {
+		keyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+
+	return keyUsage
+}
+
+// KeyAlgorithmOf returns the algorithm of public key, an empty value is returned if
+// the algorithm is not supported
+func KeyAlgorithmOf(publicKey crypto.PublicKey) KeyAlgorithm {
+	switch publicKey.(type) {
+	case *rsa.PublicKey:
+		return KeyAlgorithmRSA
+	case *ecdsa.PublicKey:
+		return KeyAlgorithmECDSA
+	case ed25519.PublicKey:
+		return KeyAlgorithmEd25519
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 FabEdge Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	certutil "github.com/fabedge/fabedge/pkg/util/cert"
+)
+
+var _ = Describe("KeyAlgorithm", func() {
+	It("should take empty name as RSA and reject unknown algorithms", func() {
+		Expect(certutil.ParseKeyAlgorithm("")).To(Equal(certutil.KeyAlgorithmRSA))
+		Expect(certutil.ParseKeyAlgorithm("ecdsa")).To(Equal(certutil.KeyAlgorithmECDSA))
+		Expect(certutil.ParseKeyAlgorithm("ed25519")).To(Equal(certutil.KeyAlgorithmEd25519))
+
+		_, err := certutil.ParseKeyAlgorithm("dsa")
+		Expect(err).To(HaveOccurred())
+	})
+
+	table.DescribeTable("CA and certificates of different algorithms",
+		func(caAlgorithm, certAlgorithm certutil.KeyAlgorithm, keyBlockType string) {
+			caDER, caKeyDER, err := certutil.NewSelfSignedCA(certutil.Config{
+				CommonName:     certutil.DefaultCAName,
+				Organization:   []string{certutil.DefaultOrganization},
+				IsCA:           true,
+				ValidityPeriod: 24 * time.Hour,
+				KeyAlgorithm:   caAlgorithm,
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			caCert, err := x509.ParseCertificate(caDER)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(certutil.KeyAlgorithmOf(caCert.PublicKey)).To(Equal(caAlgorithm))
+			Expect(caCert.KeyUsage & x509.KeyUsageCertSign).NotTo(BeZero())
+
+			manager, err := certutil.NewManger(caDER, caKeyDER, 24*time.Hour)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			keyDER, csr, err := certutil.NewCertRequest(certutil.Request{
+				CommonName:   "edge1",
+				KeyAlgorithm: certAlgorithm,
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			key, err := certutil.ParsePrivateKey(keyDER)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(certutil.KeyAlgorithmOf(key.Public())).To(Equal(certAlgorithm))
+
+			block, _ := pem.Decode(certutil.EncodePrivateKeyPEM(keyDER))
+			Expect(block.Type).To(Equal(keyBlockType))
+
+			certDER, err := manager.SignCert(csr)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			cert, err := x509.ParseCertificate(certDER)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cert.PublicKey).To(Equal(key.Public()))
+			Expect(manager.VerifyCert(cert, certutil.ExtKeyUsagesServerAndClient)).To(Succeed())
+
+			// existing key is kept when a certificate request is created again
+			csr, err = certutil.NewCertRequestWithKey(keyDER, certutil.Request{CommonName: "edge1"})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			cr, err := x509.ParseCertificateRequest(csr)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cr.PublicKey).To(Equal(key.Public()))
+		},
+		table.Entry("RSA CA and ECDSA certificate", certutil.KeyAlgorithmRSA, certutil.KeyAlgorithmECDSA, "EC PRIVATE KEY"),
+		table.Entry("ECDSA CA and RSA certificate", certutil.KeyAlgorithmECDSA, certutil.KeyAlgorithmRSA, "RSA PRIVATE KEY"),
+		table.Entry("ECDSA CA and ECDSA certificate", certutil.KeyAlgorithmECDSA, certutil.KeyAlgorithmECDSA, "EC PRIVATE KEY"),
+		table.Entry("Ed25519 CA and Ed25519 certificate", certutil.KeyAlgorithmEd25519, certutil.KeyAlgorithmEd25519, "PRIVATE KEY"),
+	)
+})
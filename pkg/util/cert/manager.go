@@ -15,9 +15,10 @@
 package cert
 
 import (
+	"crypto"
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/pem"
 	"fmt"
 	"time"
@@ -31,32 +32,47 @@ type Manager interface {
 	VerifyCertInPEM(certPEM []byte, usages []x509.ExtKeyUsage) error
 	GetCACert() *x509.Certificate
 	GetCACertPEM() []byte
+	// GetCABundlePEM returns PEM data of all trusted CA certs, the CA cert used to
+	// sign certificates comes first
+	GetCABundlePEM() []byte
+}
+
+// CRLSigner signs certificate revocation lists, only managers holding the CA key can do it
+type CRLSigner interface {
+	// SignCRL returns a CRL in DER form which is valid for validPeriod
+	SignCRL(revoked []pkix.RevokedCertificate, validPeriod time.Duration) ([]byte, error)
 }
 
 type manager struct {
 	caCertPEM   []byte
+	caBundlePEM []byte
 	caCert      *x509.Certificate
-	caKey       *rsa.PrivateKey
+	caKey       crypto.Signer
 	certPool    *x509.CertPool
 	validPeriod time.Duration
 }
 
-func NewManger(caDER, caKeyDER []byte, validPeriod time.Duration) (Manager, error) {
+// NewManger creates a manager which signs certificates with the CA, certificates
+// issued by trustedCAs are also considered valid, they are used when CA is rotated
+func NewManger(caDER, caKeyDER []byte, validPeriod time.Duration, trustedCAs ...[]byte) (Manager, error) {
 	caCert, err := x509.ParseCertificate(caDER)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse a caCert from the given ASN.1 DER data, err: %v", err)
 	}
 
-	caKey, err := x509.ParsePKCS1PrivateKey(caKeyDER)
+	caKey, err := ParsePrivateKey(caKeyDER)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parses an RSA private key in PKCS #1, ASN.1 DER form, err: %v", err)
+		return nil, err
 	}
 
-	pool := x509.NewCertPool()
-	pool.AddCert(caCert)
+	pool, bundlePEM, err := buildTrustBundle(caDER, trustedCAs)
+	if err != nil {
+		return nil, err
+	}
 
 	return &manager{
 		caCertPEM:   EncodeCertPEM(caDER),
+		caBundlePEM: bundlePEM,
 		caCert:      caCert,
 		caKey:       caKey,
 		certPool:    pool,
@@ -68,6 +84,10 @@ func (m manager) GetCACertPEM() []byte {
 	return m.caCertPEM
 }
 
+func (m manager) GetCABundlePEM() []byte {
+	return m.caBundlePEM
+}
+
 func (m manager) GetCACert() *x509.Certificate {
 	return m.caCert
 }
@@ -93,7 +113,7 @@ func (m manager) SignCert(csr []byte) ([]byte, error) {
 		IPs:            req.IPAddresses,
 		ValidityPeriod: m.validPeriod,
 		Usages:         ExtKeyUsagesServerAndClient,
-	})
+	}, req.PublicKey, m.caKey.Public())
 	if err != nil {
 		return nil, err
 	}
@@ -101,6 +121,11 @@ func (m manager) SignCert(csr []byte) ([]byte, error) {
 	return x509.CreateCertificate(rand.Reader, template, m.caCert, req.PublicKey, m.caKey)
 }
 
+func (m manager) SignCRL(revoked []pkix.RevokedCertificate, validPeriod time.Duration) ([]byte, error) {
+	now := time.Now()
+	return m.caCert.CreateCRL(rand.Reader, m.caKey, revoked, now, now.Add(validPeriod))
+}
+
 func (m manager) VerifyCert(cert *x509.Certificate, usages []x509.ExtKeyUsage) error {
 	opts := x509.VerifyOptions{
 		Roots:     m.certPool,
@@ -121,3 +146,19 @@ func (m manager) VerifyCertInPEM(certPEM []byte, usages []x509.ExtKeyUsage) erro
 
 	return m.VerifyCert(cert, usages)
 }
+
+func buildTrustBundle(caDER []byte, trustedCAs [][]byte) (*x509.CertPool, []byte, error) {
+	pool := x509.NewCertPool()
+	var bundlePEM []byte
+	for _, der := range append([][]byte{caDER}, trustedCAs...) {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse a trusted CA cert, err: %v", err)
+		}
+
+		pool.AddCert(cert)
+		bundlePEM = append(bundlePEM, EncodeCertPEM(der)...)
+	}
+
+	return pool, bundlePEM, nil
+}
@@ -2,6 +2,8 @@ package cert_test
 
 import (
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
 	"net"
 	"time"
 
@@ -12,10 +14,14 @@ import (
 )
 
 var _ = Describe("Manager", func() {
-	var manager certutil.Manager
+	var (
+		manager       certutil.Manager
+		caDER, keyDER []byte
+	)
 
 	BeforeEach(func() {
-		caDER, keyDER, err := certutil.NewSelfSignedCA(certutil.Config{
+		var err error
+		caDER, keyDER, err = certutil.NewSelfSignedCA(certutil.Config{
 			CommonName:     certutil.DefaultCAName,
 			Organization:   []string{certutil.DefaultOrganization},
 			IsCA:           true,
@@ -100,4 +106,63 @@ var _ = Describe("Manager", func() {
 		Expect(manager.VerifyCert(cert, certutil.ExtKeyUsagesServerAndClient)).Should(Succeed())
 		Expect(manager.VerifyCertInPEM(certutil.EncodeCertPEM(certDER), certutil.ExtKeyUsagesServerAndClient)).Should(Succeed())
 	})
+
+	It("should trust certificates issued by trusted CAs and sign certificates with the first CA", func() {
+		oldCADER, oldCAKeyDER, err := certutil.NewSelfSignedCA(certutil.Config{
+			CommonName:     certutil.DefaultCAName,
+			Organization:   []string{certutil.DefaultOrganization},
+			IsCA:           true,
+			ValidityPeriod: 24 * time.Hour,
+		})
+		Expect(err).Should(BeNil())
+
+		oldCA, err := x509.ParseCertificate(oldCADER)
+		Expect(err).Should(BeNil())
+
+		oldCAKey, err := x509.ParsePKCS1PrivateKey(oldCAKeyDER)
+		Expect(err).Should(BeNil())
+
+		cfg := certutil.Config{
+			CommonName:     "test",
+			Organization:   []string{certutil.DefaultOrganization},
+			ValidityPeriod: 24 * time.Hour,
+			Usages:         certutil.ExtKeyUsagesServerAndClient,
+		}
+		oldCertDER, _, err := certutil.NewCertFromCA(oldCA, oldCAKey, cfg)
+		Expect(err).Should(BeNil())
+
+		oldCert, err := x509.ParseCertificate(oldCertDER)
+		Expect(err).Should(BeNil())
+		Expect(manager.VerifyCert(oldCert, cfg.Usages)).ShouldNot(Succeed())
+
+		bundleManager, err := certutil.NewManger(caDER, keyDER, 24*time.Hour, oldCADER)
+		Expect(err).Should(BeNil())
+		Expect(bundleManager.VerifyCert(oldCert, cfg.Usages)).Should(Succeed())
+
+		certDER, _, err := bundleManager.NewCertKey(cfg)
+		Expect(err).Should(BeNil())
+		cert, err := x509.ParseCertificate(certDER)
+		Expect(err).Should(BeNil())
+		Expect(certutil.IsIssuedBy(cert, manager.GetCACert())).Should(BeTrue())
+		Expect(certutil.IsIssuedBy(cert, oldCA)).Should(BeFalse())
+
+		bundle, err := certutil.DecodeCertsPEM(bundleManager.GetCABundlePEM())
+		Expect(err).Should(BeNil())
+		Expect(bundle).Should(Equal([][]byte{caDER, oldCADER}))
+	})
+
+	It("should be able to sign CRL", func() {
+		revoked := []pkix.RevokedCertificate{
+			{SerialNumber: big.NewInt(1234), RevocationTime: time.Now().UTC().Truncate(time.Second)},
+		}
+		crlDER, err := manager.(certutil.CRLSigner).SignCRL(revoked, time.Hour)
+		Expect(err).Should(BeNil())
+
+		crl, err := x509.ParseDERCRL(crlDER)
+		Expect(err).Should(BeNil())
+		Expect(manager.GetCACert().CheckCRLSignature(crl)).Should(Succeed())
+		Expect(crl.TBSCertList.RevokedCertificates).Should(HaveLen(1))
+		Expect(crl.TBSCertList.RevokedCertificates[0].SerialNumber).Should(Equal(big.NewInt(1234)))
+		Expect(crl.TBSCertList.NextUpdate).Should(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+	})
 })
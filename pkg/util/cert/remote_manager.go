@@ -13,11 +13,15 @@ type remoteManager struct {
 	caCert   *x509.Certificate
 	signCert SignCertFunc
 
-	caCertPEM []byte
-	certPool  *x509.CertPool
+	caCertPEM   []byte
+	caBundlePEM []byte
+	certPool    *x509.CertPool
 }
 
-func NewRemoteManager(caCertDER []byte, signCert SignCertFunc) (Manager, error) {
+// NewRemoteManager creates a manager which signs certificates by signCert, e.g. through
+// host cluster or an external signer which keeps CA private key, certificates issued by
+// caCert and trustedCAs are considered valid
+func NewRemoteManager(caCertDER []byte, signCert SignCertFunc, trustedCAs ...[]byte) (Manager, error) {
 	caCert, err := x509.ParseCertificate(caCertDER)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse a caCert. err: %v", err)
@@ -27,14 +31,17 @@ func NewRemoteManager(caCertDER []byte, signCert SignCertFunc) (Manager, error)
 		return nil, fmt.Errorf("a signCert function is required")
 	}
 
-	pool := x509.NewCertPool()
-	pool.AddCert(caCert)
+	pool, bundlePEM, err := buildTrustBundle(caCertDER, trustedCAs)
+	if err != nil {
+		return nil, err
+	}
 
 	return &remoteManager{
-		caCertPEM: EncodeCertPEM(caCertDER),
-		caCert:    caCert,
-		certPool:  pool,
-		signCert:  signCert,
+		caCertPEM:   EncodeCertPEM(caCertDER),
+		caBundlePEM: bundlePEM,
+		caCert:      caCert,
+		certPool:    pool,
+		signCert:    signCert,
 	}, nil
 }
 
@@ -42,6 +49,10 @@ func (m remoteManager) GetCACertPEM() []byte {
 	return m.caCertPEM
 }
 
+func (m remoteManager) GetCABundlePEM() []byte {
+	return m.caBundlePEM
+}
+
 func (m remoteManager) GetCACert() *x509.Certificate {
 	return m.caCert
 }
@@ -52,7 +63,11 @@ func (m remoteManager) NewCertKey(cfg Config) ([]byte, []byte, error) {
 		Organization: cfg.Organization,
 		IPs:          cfg.IPs,
 		DNSNames:     cfg.DNSNames,
+		KeyAlgorithm: cfg.KeyAlgorithm,
 	})
+	if err != nil {
+		return nil, nil, err
+	}
 
 	certDER, err := m.signCert(csr)
 
@@ -84,3 +84,8 @@ func HasIPv6CIDRString(cidrs []string) bool {
 
 	return false
 }
+
+// IsCIDROverlapped returns if two CIDRs have common addresses
+func IsCIDROverlapped(cidr1, cidr2 *net.IPNet) bool {
+	return cidr1.Contains(cidr2.IP) || cidr2.Contains(cidr1.IP)
+}
@@ -15,8 +15,12 @@
 package secret
 
 import (
+	"encoding/pem"
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/keyutil"
 
 	certutil "github.com/fabedge/fabedge/pkg/util/cert"
 )
@@ -24,7 +28,12 @@ import (
 const (
 	KeyCACert           = "ca.crt"
 	KeyCAKey            = "ca.key"
+	KeyNextCACert       = "next-ca.crt"
+	KeyNextCAKey        = "next-ca.key"
+	KeyPreviousCACert   = "previous-ca.crt"
 	KeyIPSecSecretsFile = "ipsec.secrets"
+	KeyWireGuardKey     = "wireguard.key"
+	KeyPSK              = "psk"
 )
 
 type TLSSecretBuilder struct {
@@ -35,6 +44,7 @@ type TLSSecretBuilder struct {
 	cacertPEM   []byte
 	certPEM     []byte
 	keyPEM      []byte
+	wgKey       string
 }
 
 func TLSSecret() *TLSSecretBuilder {
@@ -97,8 +107,14 @@ func (b *TLSSecretBuilder) EncodeKey(data []byte) *TLSSecretBuilder {
 	return b
 }
 
+// WireGuardKey set the private key of wireguard, the key is base64 encoded
+func (b *TLSSecretBuilder) WireGuardKey(key string) *TLSSecretBuilder {
+	b.wgKey = key
+	return b
+}
+
 func (b *TLSSecretBuilder) Build() corev1.Secret {
-	return corev1.Secret{
+	secret := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        b.name,
 			Namespace:   b.namespace,
@@ -110,7 +126,30 @@ func (b *TLSSecretBuilder) Build() corev1.Secret {
 			corev1.TLSCertKey:       b.certPEM,
 			corev1.TLSPrivateKeyKey: b.keyPEM,
 			KeyCACert:               b.cacertPEM,
-			KeyIPSecSecretsFile:     []byte(": RSA tls.key\n"),
+			KeyIPSecSecretsFile:     buildIPSecSecrets(b.keyPEM),
 		},
 	}
+
+	if b.wgKey != "" {
+		secret.Data[KeyWireGuardKey] = []byte(b.wgKey)
+	}
+
+	return secret
+}
+
+// buildIPSecSecrets returns content of ipsec.secrets, the type of private key
+// is decided by its PEM block type
+func buildIPSecSecrets(keyPEM []byte) []byte {
+	keyType := "RSA"
+	if block, _ := pem.Decode(keyPEM); block != nil {
+		switch block.Type {
+		case keyutil.ECPrivateKeyBlockType:
+			keyType = "ECDSA"
+		case keyutil.PrivateKeyBlockType:
+			// keys of any algorithm in PKCS #8 form, e.g. ECDSA or Ed25519 keys
+			keyType = "PKCS8"
+		}
+	}
+
+	return []byte(fmt.Sprintf(": %s tls.key\n", keyType))
 }
@@ -40,3 +40,13 @@ func GetCert(secret corev1.Secret) []byte {
 func GetCertAndKey(secret corev1.Secret) ([]byte, []byte) {
 	return secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
 }
+
+// GetWireGuardKey get the wireguard private key from the secret by the key wireguard.key
+func GetWireGuardKey(secret corev1.Secret) string {
+	return string(secret.Data[KeyWireGuardKey])
+}
+
+// GetPSK get the pre-shared key from the secret by the key psk
+func GetPSK(secret corev1.Secret) []byte {
+	return secret.Data[KeyPSK]
+}